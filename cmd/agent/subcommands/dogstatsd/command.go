@@ -35,6 +35,10 @@ type topFlags struct {
 	ntags    int
 }
 
+type limitsFlags struct {
+	ntags int
+}
+
 // Commands initializes dogstatsd sub-command tree.
 func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	c := &cobra.Command{
@@ -63,6 +67,25 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 
 	c.AddCommand(topCmd)
 
+	limitsFlags := limitsFlags{}
+
+	limitsCmd := &cobra.Command{
+		Use:   "limits",
+		Short: "Display metrics which hit the context limits in the aggregator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fxutil.OneShot(contextLimits,
+				fx.Supply(&limitsFlags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath),
+					LogParams:    logimpl.ForOneShot(command.LoggerName, "off", true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	limitsCmd.Flags().IntVarP(&limitsFlags.ntags, "num-tags", "t", 5, "number of tags to show per metric")
+
+	c.AddCommand(limitsCmd)
+
 	c.AddCommand(&cobra.Command{
 		Use:   "dump-contexts",
 		Short: "Write currently tracked contexts as JSON",
@@ -119,6 +142,45 @@ func dumpContexts(config cconfig.Component) error {
 	return nil
 }
 
+func contextLimits(config cconfig.Component, flags *limitsFlags) error {
+	c := util.GetClient(false)
+	addr, err := pkgconfig.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://%v:%v/agent/dogstatsd-contexts-limits", addr, config.GetInt("cmd_port"))
+
+	err = util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	body, err := util.DoGet(c, url, util.LeaveConnectionOpen)
+	if err != nil {
+		return err
+	}
+
+	var limited []aggregator.ContextLimitDebugRepr
+	if err = json.Unmarshal(body, &limited); err != nil {
+		return err
+	}
+
+	if len(limited) == 0 {
+		fmt.Println("No metric hit the context limits.")
+		return nil
+	}
+
+	fmt.Printf(" % 10s\t% 18s\t% 18s\t%s\t(%s)\n", "Contexts", "Dropped samples", "Overflowed samples", "Metric name", "number of unique values for each tag")
+	for _, l := range limited {
+		fmt.Printf(" % 10d\t% 18d\t% 18d\t%s\t(", l.Contexts, l.DroppedSamples, l.OverflowedSamples, l.Name)
+		printTagValues(l.TagValues, flags.ntags)
+		fmt.Println(")")
+	}
+
+	return nil
+}
+
 type metric struct {
 	count uint
 	tags  map[string]struct{}
@@ -226,12 +288,17 @@ func topContexts(config cconfig.Component, flags *topFlags) error {
 }
 
 func printTopTags(m *metric, limit int) {
-	ts := make(map[string]uint)
+	ts := make(map[string]int)
 	for tag := range m.tags {
 		k, _, _ := strings.Cut(tag, ":")
 		ts[k]++
 	}
 
+	printTagValues(ts, limit)
+}
+
+// printTagValues prints the number of values of the `limit` tag keys having the most values.
+func printTagValues(ts map[string]int, limit int) {
 	ks := make([]string, 0, len(ts))
 	for k := range ts {
 		ks = append(ks, k)
@@ -264,7 +331,7 @@ func printTopTags(m *metric, limit int) {
 	}
 
 	if len(rest) > 0 {
-		var sum uint
+		var sum int
 		for _, k := range rest {
 			sum += ts[k]
 		}
@@ -31,4 +31,11 @@ func TestCommand(t *testing.T) {
 			assert.Equal(t, 1, f.nmetrics)
 			assert.Equal(t, 2, f.ntags)
 		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "limits", "-t", "3"},
+		contextLimits,
+		func(f *limitsFlags) {
+			assert.Equal(t, 3, f.ntags)
+		})
 }
@@ -0,0 +1,258 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package dogstatsdinspect implements 'agent dogstatsd-inspect'.
+package dogstatsdinspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const (
+	defaultTop = 20
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	// subcommand-specific flags
+
+	dsdCaptureFilePath string
+	dsdMmapCapture     bool
+	jsonOutput         bool
+	summary            bool
+	top                int
+	names              []string
+	tags               []string
+	pids               []int32
+	containerIDs       []string
+	outputFilePath     string
+	outputCompressed   bool
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	dogstatsdInspectCmd := &cobra.Command{
+		Use:   "dogstatsd-inspect",
+		Short: "Inspect a dogstatsd traffic capture",
+		Long: `Decode the messages of a traffic capture taken with dogstatsd-capture, print them or a
+summary of their volume and cardinality per metric and per origin, and optionally write the
+messages matching the filters to a new capture file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fxutil.OneShot(dogstatsdInspect,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	dogstatsdInspectCmd.Flags().StringVarP(&cliParams.dsdCaptureFilePath, "file", "f", "", "Input file with traffic captured with dogstatsd-capture.")
+	dogstatsdInspectCmd.Flags().BoolVarP(&cliParams.dsdMmapCapture, "mmap", "m", true, "Mmap file for inspection. Set to false to load the entire file into memory instead")
+	dogstatsdInspectCmd.Flags().BoolVarP(&cliParams.jsonOutput, "json", "j", false, "Print out JSON, one object per message.")
+	dogstatsdInspectCmd.Flags().BoolVarP(&cliParams.summary, "summary", "s", false, "Print the volume and cardinality per metric and per origin instead of the messages.")
+	dogstatsdInspectCmd.Flags().IntVarP(&cliParams.top, "top", "t", defaultTop, "Number of metrics and origins in the summary, 0 for all of them.")
+	dogstatsdInspectCmd.Flags().StringSliceVar(&cliParams.names, "name", nil, "Only keep the messages whose name matches one of these patterns, e.g. 'my_app.*'.")
+	dogstatsdInspectCmd.Flags().StringSliceVar(&cliParams.tags, "tag", nil, "Only keep the messages with a tag matching one of these patterns, e.g. 'env:*'.")
+	dogstatsdInspectCmd.Flags().Int32SliceVar(&cliParams.pids, "pid", nil, "Only keep the messages sent by one of these PIDs.")
+	dogstatsdInspectCmd.Flags().StringSliceVar(&cliParams.containerIDs, "container-id", nil, "Only keep the messages sent from one of these containers.")
+	dogstatsdInspectCmd.Flags().StringVarP(&cliParams.outputFilePath, "output", "o", "", "Write the messages kept to a new capture file, which can be replayed.")
+	dogstatsdInspectCmd.Flags().BoolVarP(&cliParams.outputCompressed, "compressed", "z", true, "Should the output capture be zstd compressed.")
+
+	return []*cobra.Command{dogstatsdInspectCmd}
+}
+
+//nolint:revive // TODO(AML) Fix revive linter
+func dogstatsdInspect(log log.Component, config config.Component, cliParams *cliParams) error {
+	return inspectCapture(os.Stdout, cliParams)
+}
+
+// inspectCapture decodes the capture, writes its messages or their summary to w, and writes
+// the messages kept to the output capture file if any.
+func inspectCapture(w io.Writer, cliParams *cliParams) error {
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdCaptureFilePath, 1, cliParams.dsdMmapCapture)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", cliParams.dsdCaptureFilePath, err)
+	}
+
+	pidMap, state, err := reader.ReadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load state from file, container IDs will only be read from the messages: %v\n", err)
+	}
+
+	filter := &replay.CaptureFilter{
+		Names:        cliParams.names,
+		Tags:         cliParams.tags,
+		Pids:         cliParams.pids,
+		ContainerIDs: cliParams.containerIDs,
+	}
+	summary := replay.NewCaptureSummary()
+	encoder := json.NewEncoder(w)
+	var kept []*pb.UnixDogstatsdMsg
+
+	reader.Seek(0)
+	for {
+		msg, err := reader.ReadNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read the capture: %w", err)
+		}
+
+		ts := reader.Time(msg)
+		var payload []byte
+		for _, m := range replay.DecodePacket(msg, ts, pidMap) {
+			if !filter.Match(&m) {
+				continue
+			}
+
+			switch {
+			case cliParams.summary:
+				summary.Add(&m)
+			case cliParams.jsonOutput:
+				if err := encoder.Encode(m); err != nil {
+					return err
+				}
+			default:
+				fmt.Fprintf(w, "%s %s %s\n", m.Timestamp.UTC().Format(time.RFC3339Nano), m.Origin(), m.Message)
+			}
+
+			if len(payload) > 0 {
+				payload = append(payload, '\n')
+			}
+			payload = append(payload, m.Message...)
+		}
+
+		if cliParams.outputFilePath != "" && len(payload) > 0 {
+			kept = append(kept, &pb.UnixDogstatsdMsg{
+				Timestamp:     ts.UnixNano(),
+				PayloadSize:   int32(len(payload)),
+				Payload:       payload,
+				Pid:           msg.Pid,
+				AncillarySize: msg.AncillarySize,
+				Ancillary:     msg.Ancillary,
+			})
+		}
+	}
+
+	if cliParams.summary {
+		if err := writeSummary(w, summary, cliParams.top, cliParams.jsonOutput); err != nil {
+			return err
+		}
+	}
+
+	if cliParams.outputFilePath != "" {
+		if err := writeCapture(cliParams.outputFilePath, cliParams.outputCompressed, kept, pidMap, state); err != nil {
+			return fmt.Errorf("could not write %s: %w", cliParams.outputFilePath, err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d packets to %s\n", len(kept), cliParams.outputFilePath)
+	}
+
+	return nil
+}
+
+// writeSummary writes the top metrics and origins of the summary to w.
+func writeSummary(w io.Writer, summary *replay.CaptureSummary, top int, jsonOutput bool) error {
+	metrics := summary.Metrics()
+	origins := summary.Origins()
+	if top > 0 && len(metrics) > top {
+		metrics = metrics[:top]
+	}
+	if top > 0 && len(origins) > top {
+		origins = origins[:top]
+	}
+
+	if jsonOutput {
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"messages": summary.Messages,
+			"bytes":    summary.Bytes,
+			"metrics":  metrics,
+			"origins":  origins,
+		})
+	}
+
+	fmt.Fprintf(w, "Messages: %d (%d bytes)\n", summary.Messages, summary.Bytes)
+	for _, section := range []struct {
+		title string
+		stats []*replay.VolumeStats
+	}{
+		{"Metrics", metrics},
+		{"Origins", origins},
+	} {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tMESSAGES\tBYTES\tCONTEXTS")
+		for _, st := range section.stats {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", st.Key, st.Messages, st.Bytes, st.Contexts)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCapture writes the packets to a new capture file, with the part of the tagger state
+// their PIDs use.
+func writeCapture(path string, compressed bool, packets []*pb.UnixDogstatsdMsg, pidMap map[int32]string, state map[string]*pb.Entity) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	taggerState := &pb.TaggerState{
+		State:  make(map[string]*pb.Entity),
+		PidMap: make(map[int32]string),
+	}
+	for _, packet := range packets {
+		if id, found := pidMap[packet.Pid]; found {
+			taggerState.PidMap[packet.Pid] = id
+			if entity, found := state[id]; found {
+				taggerState.State[id] = entity
+			}
+		}
+	}
+
+	var target io.Writer = f
+	var zWriter *zstd.Writer
+	if compressed {
+		zWriter = zstd.NewWriter(f)
+		target = zWriter
+	}
+	if err := replay.WriteCapture(target, packets, taggerState); err != nil {
+		return err
+	}
+	if zWriter != nil {
+		if err := zWriter.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
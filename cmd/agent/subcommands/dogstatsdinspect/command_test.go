@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdinspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const testCapture = "../../../../comp/dogstatsd/replay/resources/test/datadog-capture.dog"
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-inspect", "-f", "capture.dog", "--summary", "--tag", "env:*,role:*", "--pid", "1", "--pid", "2"},
+		dogstatsdInspect,
+		func(cliParams *cliParams, coreParams core.BundleParams, secretParams secrets.Params) {
+			require.Equal(t, "capture.dog", cliParams.dsdCaptureFilePath)
+			require.True(t, cliParams.summary)
+			require.Equal(t, []string{"env:*", "role:*"}, cliParams.tags)
+			require.Equal(t, []int32{1, 2}, cliParams.pids)
+			require.Equal(t, false, secretParams.Enabled)
+		})
+}
+
+func TestInspectCapture(t *testing.T) {
+	var out bytes.Buffer
+	err := inspectCapture(&out, &cliParams{dsdCaptureFilePath: testCapture, pids: []int32{2809, 2815}})
+	require.NoError(t, err)
+	assert.Equal(t, "2021-05-17T21:07:54Z pid:2809 jaime.uds.test:8|g|#shell:test\n"+
+		"2021-05-17T21:07:55Z container_id:c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22 jaime.uds.test:8|g|#shell:test\n",
+		out.String())
+}
+
+func TestInspectCaptureJSON(t *testing.T) {
+	var out bytes.Buffer
+	err := inspectCapture(&out, &cliParams{dsdCaptureFilePath: testCapture, jsonOutput: true, pids: []int32{2815}})
+	require.NoError(t, err)
+
+	var m replay.CapturedMessage
+	require.NoError(t, json.Unmarshal(out.Bytes(), &m))
+	assert.Equal(t, int32(2815), m.Pid)
+	assert.Equal(t, "c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22", m.ContainerID)
+	assert.Equal(t, "jaime.uds.test", m.Name)
+	assert.Equal(t, []string{"shell:test"}, m.Tags)
+}
+
+func TestInspectCaptureSummary(t *testing.T) {
+	var out bytes.Buffer
+	err := inspectCapture(&out, &cliParams{dsdCaptureFilePath: testCapture, summary: true, top: 1})
+	require.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "Messages: 21 (630 bytes)", lines[0])
+	assert.Equal(t, "Metrics:", lines[2])
+	assert.Regexp(t, `^metric:jaime\.uds\.test +21 +630 +1$`, lines[4])
+	assert.Equal(t, "Origins:", lines[6])
+	assert.Regexp(t, `^container_id:c1371eaf\S+ +7 +210 +1$`, lines[8])
+	assert.Equal(t, "", lines[9])
+}
+
+func TestInspectCaptureOutput(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		output := filepath.Join(t.TempDir(), "trimmed.dog")
+		err := inspectCapture(&bytes.Buffer{}, &cliParams{
+			dsdCaptureFilePath: testCapture,
+			containerIDs:       []string{"c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22"},
+			outputFilePath:     output,
+			outputCompressed:   compressed,
+		})
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		err = inspectCapture(&out, &cliParams{dsdCaptureFilePath: output, summary: true, jsonOutput: true})
+		require.NoError(t, err)
+		var summary struct {
+			Messages int                   `json:"messages"`
+			Origins  []*replay.VolumeStats `json:"origins"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+		assert.Equal(t, 7, summary.Messages)
+		require.Len(t, summary.Origins, 1)
+		// the tagger state of the trimmed capture still resolves the container IDs
+		assert.Equal(t, "container_id:c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22", summary.Origins[0].Key)
+	}
+}
@@ -87,6 +87,8 @@ import (
 	adScheduler "github.com/DataDog/datadog-agent/pkg/logs/schedulers/ad"
 	pkgMetadata "github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/remotewrite"
+	"github.com/DataDog/datadog-agent/pkg/scrapeendpoint"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
@@ -122,6 +124,7 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/embed"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/net"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/nvidia/jetson"
+	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/openmetrics"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/oracle-dbm"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/orchestrator/pod"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/sbom"
@@ -130,6 +133,7 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/disk"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/filehandles"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/memory"
+	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/process"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/uptime"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/wincrashdetect"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system/winkmem"
@@ -140,6 +144,9 @@ import (
 
 	// register metadata providers
 	_ "github.com/DataDog/datadog-agent/pkg/collector/metadata"
+
+	// register the check plugins loader
+	_ "github.com/DataDog/datadog-agent/pkg/collector/plugin"
 )
 
 type cliParams struct {
@@ -339,6 +346,7 @@ func getSharedFxOption() fx.Option {
 		fx.Provide(func(config config.Component) demultiplexerimpl.Params {
 			params := demultiplexerimpl.NewDefaultParams()
 			params.EnableNoAggregationPipeline = config.GetBool("dogstatsd_no_aggregation_pipeline")
+			params.KeepLastFlush = scrapeendpoint.IsEnabled(config)
 			return params
 		}),
 		demultiplexerimpl.Module(),
@@ -561,6 +569,22 @@ func startAgent(
 		}
 	}
 
+	// Start Prometheus remote_write server
+	if remotewrite.IsEnabled(pkgconfig.Datadog) {
+		err = remotewrite.StartServer(hostnameDetected, demultiplexer, pkgconfig.Datadog)
+		if err != nil {
+			log.Errorf("Failed to start Prometheus remote_write server: %s", err)
+		}
+	}
+
+	// Start Prometheus scrape endpoint
+	if scrapeendpoint.IsEnabled(pkgconfig.Datadog) {
+		err = scrapeendpoint.StartServer(demultiplexer, pkgconfig.Datadog)
+		if err != nil {
+			log.Errorf("Failed to start Prometheus scrape endpoint: %s", err)
+		}
+	}
+
 	// Append version and timestamp to version history log file if this Agent is different than the last run version
 	installinfo.LogVersionHistory()
 
@@ -640,6 +664,8 @@ func stopAgent(cliParams *cliParams, server dogstatsdServer.Component, demultipl
 		common.MetadataScheduler.Stop()
 	}
 	traps.StopServer()
+	remotewrite.StopServer()
+	scrapeendpoint.StopServer()
 	agentAPI.StopServer()
 	clcrunnerapi.StopCLCRunnerServer()
 	jmx.StopJmxfetch()
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/tagrules"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// DsdTagRulesRuntimeSetting wraps operations to change the rules rewriting the tags of
+// the dogstatsd metrics at runtime.
+type DsdTagRulesRuntimeSetting struct{}
+
+// NewDsdTagRulesRuntimeSetting creates a new instance of DsdTagRulesRuntimeSetting
+func NewDsdTagRulesRuntimeSetting() *DsdTagRulesRuntimeSetting {
+	return &DsdTagRulesRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdTagRulesRuntimeSetting) Description() string {
+	return "Replace the dogstatsd tag rules. Possible values: a JSON list of rules"
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdTagRulesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Name() string {
+	return "dogstatsd_tag_rules"
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Get() (interface{}, error) {
+	return config.GetDogstatsdTagRules(config.Datadog)
+}
+
+// Set changes the value of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Set(v interface{}, source model.Source) error {
+	var rules []config.TagRule
+
+	switch value := v.(type) {
+	case string:
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			return fmt.Errorf("DsdTagRulesRuntimeSetting: invalid rules: %v", err)
+		}
+	case []config.TagRule:
+		rules = value
+	default:
+		return fmt.Errorf("DsdTagRulesRuntimeSetting: unsupported type %T", v)
+	}
+
+	// validate the rules before they are loaded by the server
+	if _, err := tagrules.NewRules(rules, 1); err != nil {
+		return fmt.Errorf("DsdTagRulesRuntimeSetting: %v", err)
+	}
+
+	config.Datadog.Set(s.Name(), rules, source)
+	return nil
+}
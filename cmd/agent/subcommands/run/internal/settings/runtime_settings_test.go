@@ -20,6 +20,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/fx"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
@@ -101,3 +102,28 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdTagRules(t *testing.T) {
+	config.Mock(t)
+	s := NewDsdTagRulesRuntimeSetting()
+
+	err := s.Set(`[{"match": "test.*", "drop": ["id"]}]`, model.SourceCLI)
+	require.NoError(t, err)
+	v, err := s.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []config.TagRule{{Match: "test.*", Drop: []string{"id"}}}, v)
+
+	// invalid rules are rejected and the current ones are kept
+	assert.Error(t, s.Set(`[{"match": "test.*"}]`, model.SourceCLI))
+	assert.Error(t, s.Set(`{"match": "test.*"}`, model.SourceCLI))
+	assert.Error(t, s.Set(42, model.SourceCLI))
+	v, err = s.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []config.TagRule{{Match: "test.*", Drop: []string{"id"}}}, v)
+
+	err = s.Set([]config.TagRule{}, model.SourceCLI)
+	require.NoError(t, err)
+	v, err = s.Get()
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
@@ -29,6 +29,9 @@ func initRuntimeSettings(serverDebug dogstatsddebug.Component) error {
 	if err := commonsettings.RegisterRuntimeSetting(settings.NewDsdCaptureDurationRuntimeSetting("dogstatsd_capture_duration")); err != nil {
 		return err
 	}
+	if err := commonsettings.RegisterRuntimeSetting(settings.NewDsdTagRulesRuntimeSetting()); err != nil {
+		return err
+	}
 	if err := commonsettings.RegisterRuntimeSetting(commonsettings.NewLogPayloadsRuntimeSetting()); err != nil {
 		return err
 	}
@@ -15,6 +15,7 @@ import (
 	cmddiagnose "github.com/DataDog/datadog-agent/cmd/agent/subcommands/diagnose"
 	cmddogstatsd "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsd"
 	cmddogstatsdcapture "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdcapture"
+	cmddogstatsdinspect "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdinspect"
 	cmddogstatsdreplay "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdreplay"
 	cmddogstatsdstats "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdstats"
 	cmdflare "github.com/DataDog/datadog-agent/cmd/agent/subcommands/flare"
@@ -48,6 +49,7 @@ func AgentSubcommands() []command.SubcommandFactory {
 		cmddiagnose.Commands,
 		cmddogstatsd.Commands,
 		cmddogstatsdcapture.Commands,
+		cmddogstatsdinspect.Commands,
 		cmddogstatsdreplay.Commands,
 		cmddogstatsdstats.Commands,
 		cmdflare.Commands,
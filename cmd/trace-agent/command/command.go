@@ -10,8 +10,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/capture"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/controlsvc"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/info"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/replay"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/run"
 	"github.com/DataDog/datadog-agent/pkg/cli/subcommands/version"
 )
@@ -44,6 +46,8 @@ func makeCommands(globalParams *subcommands.GlobalParams) *cobra.Command {
 	commands := []*cobra.Command{
 		run.MakeCommand(globalConfGetter),
 		info.MakeCommand(globalConfGetter),
+		capture.MakeCommand(globalConfGetter),
+		replay.MakeCommand(globalConfGetter),
 		version.MakeCommand("trace-agent"),
 	}
 
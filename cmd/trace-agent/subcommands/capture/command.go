@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package capture implements the 'trace-agent capture' subcommand.
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+type cliParams struct {
+	duration time.Duration
+	stop     bool
+}
+
+// MakeCommand returns the capture subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	captureCmd := &cobra.Command{
+		Use:   "capture",
+		Short: "Start a capture of the payloads received by the running trace-agent.",
+		Long: `Use this to record the tracer payloads received by the running trace-agent to a
+compressed capture file, which can later be sent again with 'trace-agent replay'.`,
+		RunE: func(*cobra.Command, []string) error {
+			return fxutil.OneShot(runCapture,
+				fx.Supply(cliParams),
+				config.Module(),
+				fx.Supply(coreconfig.NewAgentParams(globalParamsGetter().ConfPath)),
+				fx.Supply(secrets.NewEnabledParams()),
+				coreconfig.Module(),
+				secretsimpl.Module(),
+			)
+		},
+	}
+	captureCmd.Flags().DurationVarP(&cliParams.duration, "duration", "d", time.Minute, "Duration of the capture")
+	captureCmd.Flags().BoolVar(&cliParams.stop, "stop", false, "Stop the ongoing capture")
+
+	return captureCmd
+}
+
+type captureStatus struct {
+	Path     string `json:"path"`
+	Payloads int64  `json:"payloads"`
+	Ongoing  bool   `json:"ongoing"`
+}
+
+func runCapture(config config.Component, cliParams *cliParams) error {
+	tracecfg := config.Object()
+	if tracecfg == nil {
+		return fmt.Errorf("Unable to successfully parse config")
+	}
+	if tracecfg.DebugServerPort == 0 {
+		return fmt.Errorf("the debug server is disabled (apm_config.debug.port: 0)")
+	}
+	u := fmt.Sprintf("http://127.0.0.1:%d/debug/capture/start?duration=%s", tracecfg.DebugServerPort, url.QueryEscape(cliParams.duration.String()))
+	if cliParams.stop {
+		u = fmt.Sprintf("http://127.0.0.1:%d/debug/capture/stop", tracecfg.DebugServerPort)
+	}
+	resp, err := http.Post(u, "", nil)
+	if err != nil {
+		return fmt.Errorf("could not reach the trace-agent debug server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("capture request failed (%s): %s", resp.Status, msg)
+	}
+	var status captureStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return err
+	}
+	if cliParams.stop {
+		fmt.Printf("Capture stopped: %d payloads written to %s\n", status.Payloads, status.Path)
+		return nil
+	}
+	fmt.Printf("Capturing tracer payloads for %s to %s\n", cliParams.duration, status.Path)
+	return nil
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCaptureCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		[]*cobra.Command{MakeCommand(func() *subcommands.GlobalParams {
+			return &subcommands.GlobalParams{}
+		})},
+		[]string{"capture", "--duration", "30s"},
+		runCapture,
+		func(cliParams *cliParams) {
+			require.Equal(t, 30*time.Second, cliParams.duration)
+			require.False(t, cliParams.stop)
+		})
+}
@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements the 'trace-agent replay' subcommand.
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+type cliParams struct {
+	file    string
+	target  string
+	speed   float64
+	verbose bool
+}
+
+// MakeCommand returns the replay subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay tracer payloads recorded with 'trace-agent capture'.",
+		Long: `Use this to send the payloads of a trace capture file to a running trace-agent,
+either at their original pace or faster using --speed. A speed of 0 sends the
+payloads as fast as possible.`,
+		RunE: func(*cobra.Command, []string) error {
+			return fxutil.OneShot(runReplay,
+				fx.Supply(cliParams),
+				config.Module(),
+				fx.Supply(coreconfig.NewAgentParams(globalParamsGetter().ConfPath)),
+				fx.Supply(secrets.NewEnabledParams()),
+				coreconfig.Module(),
+				secretsimpl.Module(),
+			)
+		},
+	}
+	replayCmd.Flags().StringVarP(&cliParams.file, "file", "f", "", "Input file with payloads captured with 'trace-agent capture'.")
+	replayCmd.Flags().StringVarP(&cliParams.target, "target", "t", "", "Base URL of the receiving trace-agent (defaults to the configured receiver).")
+	replayCmd.Flags().Float64VarP(&cliParams.speed, "speed", "s", 1, "Replay speed multiplier; 0 replays as fast as possible.")
+	replayCmd.Flags().BoolVarP(&cliParams.verbose, "verbose", "v", false, "Print every replayed payload.")
+
+	return replayCmd
+}
+
+func runReplay(config config.Component, cliParams *cliParams) error {
+	if cliParams.file == "" {
+		return fmt.Errorf("no capture file specified, use --file")
+	}
+	if cliParams.speed < 0 {
+		return fmt.Errorf("speed must be positive or zero")
+	}
+	target := cliParams.target
+	if target == "" {
+		tracecfg := config.Object()
+		if tracecfg == nil {
+			return fmt.Errorf("Unable to successfully parse config")
+		}
+		if tracecfg.ReceiverPort == 0 {
+			return fmt.Errorf("the trace-agent HTTP receiver is disabled, use --target")
+		}
+		host := tracecfg.ReceiverHost
+		if host == "" || host == "0.0.0.0" {
+			host = "localhost"
+		}
+		target = "http://" + net.JoinHostPort(host, strconv.Itoa(tracecfg.ReceiverPort))
+	}
+	f, err := os.Open(cliParams.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, failed, err := replay(f, target, cliParams.speed, http.DefaultClient, cliParams.verbose)
+	fmt.Printf("Replayed %d payloads to %s, %d failed\n", n, target, failed)
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d payloads were not accepted by %s", failed, target)
+	}
+	return err
+}
+
+// replay sends every record read from r to the trace-agent listening at target,
+// waiting between records for their original interval divided by speed. It returns
+// the number of payloads accepted by the trace-agent and the number of payloads it
+// answered with a non-2xx status.
+func replay(r io.Reader, target string, speed float64, client *http.Client, verbose bool) (n int, failed int, err error) {
+	cr, err := api.NewCaptureReader(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cr.Close()
+
+	var prev time.Time
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return n, failed, nil
+		}
+		if err != nil {
+			return n, failed, err
+		}
+		if speed > 0 && !prev.IsZero() {
+			if d := rec.Time.Sub(prev); d > 0 {
+				time.Sleep(time.Duration(float64(d) / speed))
+			}
+		}
+		prev = rec.Time
+
+		req, err := http.NewRequest(http.MethodPost, target+rec.Path, bytes.NewReader(rec.Body))
+		if err != nil {
+			return n, failed, err
+		}
+		req.Header = rec.Header
+		req.Header.Del("Content-Length")
+		resp, err := client.Do(req)
+		if err != nil {
+			return n, failed, err
+		}
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			failed++
+		} else {
+			n++
+		}
+		if verbose {
+			fmt.Printf("%s %s (%s, %d bytes): %s\n", rec.Time.Format(time.RFC3339Nano), rec.Path, rec.Version, len(rec.Body), resp.Status)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestReplayCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		[]*cobra.Command{MakeCommand(func() *subcommands.GlobalParams {
+			return &subcommands.GlobalParams{}
+		})},
+		[]string{"replay", "-f", "capture.gz", "--speed", "10"},
+		runReplay,
+		func(cliParams *cliParams) {
+			require.Equal(t, "capture.gz", cliParams.file)
+			require.Equal(t, 10.0, cliParams.speed)
+		})
+}
+
+func TestReplay(t *testing.T) {
+	tc := api.NewTraceCapture(t.TempDir())
+	path, err := tc.Start(time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		tc.Record(&api.CaptureRecord{
+			Time:    now.Add(time.Duration(i) * time.Second),
+			Version: "v0.4",
+			Path:    "/v0.4/traces",
+			Header:  http.Header{"Content-Type": {"application/msgpack"}},
+			Body:    []byte{0x90},
+		})
+	}
+	require.NoError(t, tc.Stop())
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, []byte{0x90}, body)
+		got = append(got, r.URL.Path+" "+r.Header.Get("Content-Type"))
+	}))
+	defer srv.Close()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	start := time.Now()
+	n, failed, err := replay(f, srv.URL, 100, srv.Client(), false)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Zero(t, failed)
+	require.Len(t, got, 3)
+	require.Equal(t, "/v0.4/traces application/msgpack", got[0])
+	// two intervals of 1s each, accelerated 100 times
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// payloads which are not accepted are reported as failures
+	var count int
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count == 2 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+	defer srv.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	n, failed, err = replay(f, srv.URL, 0, srv.Client(), false)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, failed)
+}
@@ -106,6 +106,7 @@ func SetupHandlers(
 	r.HandleFunc("/diagnose", func(w http.ResponseWriter, r *http.Request) { getDiagnose(w, r, senderManager) }).Methods("POST")
 
 	r.HandleFunc("/dogstatsd-contexts-dump", func(w http.ResponseWriter, r *http.Request) { dumpDogstatsdContexts(w, r, demux) }).Methods("POST")
+	r.HandleFunc("/dogstatsd-contexts-limits", func(w http.ResponseWriter, r *http.Request) { getDogstatsdContextLimits(w, r, demux) }).Methods("GET")
 	// Some agent subcommands do not provide these dependencies (such as JMX)
 	if server != nil && serverDebug != nil {
 		r.HandleFunc("/dogstatsd-stats", func(w http.ResponseWriter, r *http.Request) { getDogstatsdStats(w, r, server, serverDebug) }).Methods("GET")
@@ -580,6 +581,22 @@ func dumpDogstatsdContexts(w http.ResponseWriter, _ *http.Request, demux demulti
 	w.Write(resp)
 }
 
+func getDogstatsdContextLimits(w http.ResponseWriter, _ *http.Request, demux demultiplexer.Component) {
+	if demux == nil {
+		setJSONError(w, log.Errorf("Unable to get dogstatsd context limits, demultiplexer is not initialized"), 404)
+		return
+	}
+
+	resp, err := json.Marshal(demux.DogstatsdContextLimits())
+	if err != nil {
+		setJSONError(w, log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 func dumpDogstatsdContextsImpl(demux demultiplexer.Component) (string, error) {
 	path := path.Join(config.Datadog.GetString("run_path"), "dogstatsd_contexts.json.zstd")
 
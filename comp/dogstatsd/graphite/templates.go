@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package graphite implements the templates converting the dotted paths of the Graphite
+// metrics into metric names and tags.
+package graphite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var (
+	allowedWildcardMatchPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.]+$`)
+)
+
+const (
+	// partName adds the part of the path to the metric name
+	partName = "name"
+	// partNameRest adds the part of the path and the following ones to the metric name
+	partNameRest = "name*"
+	// partSkip drops the part of the path
+	partSkip = "_"
+)
+
+// Templates converts the paths of the Graphite metrics with the first template matching
+// them. It is safe for concurrent use.
+type Templates struct {
+	templates []*template
+	// cache holds the result of the conversion of each path
+	cache *lru.Cache[string, *Result]
+}
+
+// template is a compiled config.GraphiteTemplate. Every part of the template applies to
+// the part of the path at the same position: it is either partName, partNameRest,
+// partSkip, or the name of the tag whose value is the part of the path.
+type template struct {
+	regex *regexp.Regexp // nil matches every path
+	parts []string
+	tags  []string
+}
+
+// Result represent the outcome of the conversion of a path
+type Result struct {
+	Name string
+	Tags []string
+}
+
+// NewTemplates creates, validates and prepares new Templates.
+func NewTemplates(configTemplates []config.GraphiteTemplate, cacheSize int) (*Templates, error) {
+	templates := make([]*template, 0, len(configTemplates))
+	for i, configTemplate := range configTemplates {
+		t := &template{tags: configTemplate.Tags}
+		if configTemplate.Match != "" {
+			regex, err := buildRegex(configTemplate.Match)
+			if err != nil {
+				return nil, fmt.Errorf("template num %d: %v", i, err)
+			}
+			t.regex = regex
+		}
+		if configTemplate.Template == "" {
+			return nil, fmt.Errorf("template num %d: template is required", i)
+		}
+		t.parts = strings.Split(configTemplate.Template, ".")
+		hasName := false
+		for j, part := range t.parts {
+			switch part {
+			case "":
+				return nil, fmt.Errorf("template num %d: invalid template `%s`, it should not contain empty parts", i, configTemplate.Template)
+			case partName:
+				hasName = true
+			case partNameRest:
+				if j != len(t.parts)-1 {
+					return nil, fmt.Errorf("template num %d: invalid template `%s`, `%s` must be its last part", i, configTemplate.Template, partNameRest)
+				}
+				hasName = true
+			}
+		}
+		if !hasName {
+			return nil, fmt.Errorf("template num %d: invalid template `%s`, it must contain `%s` or `%s`", i, configTemplate.Template, partName, partNameRest)
+		}
+		templates = append(templates, t)
+	}
+	cache, err := lru.New[string, *Result](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{templates: templates, cache: cache}, nil
+}
+
+func buildRegex(matchRe string) (*regexp.Regexp, error) {
+	if !allowedWildcardMatchPattern.MatchString(matchRe) {
+		return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it does not match allowed match regex `%s`", matchRe, allowedWildcardMatchPattern)
+	}
+	if strings.Contains(matchRe, "**") {
+		return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it should not contain consecutive `*`", matchRe)
+	}
+	matchRe = strings.Replace(matchRe, ".", "\\.", -1)
+	matchRe = strings.Replace(matchRe, "*", "([^.]*)", -1)
+	// the pattern matches the first parts of the paths
+	regex, err := regexp.Compile("^" + matchRe + `(\.|$)`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match `%s`. cannot compile regex: %v", matchRe, err)
+	}
+	return regex, nil
+}
+
+// Apply converts the path with the first template matching it. It returns nil if no
+// template matches the path, or if the matching template does not produce any name.
+func (t *Templates) Apply(path string) *Result {
+	if result, ok := t.cache.Get(path); ok {
+		return result
+	}
+	var result *Result
+	for _, template := range t.templates {
+		if template.regex == nil || template.regex.MatchString(path) {
+			result = template.apply(path)
+			break
+		}
+	}
+	t.cache.Add(path, result)
+	return result
+}
+
+func (t *template) apply(path string) *Result {
+	pathParts := strings.Split(path, ".")
+	var nameParts []string
+	tags := make([]string, 0, len(t.parts)+len(t.tags))
+	for i, part := range t.parts {
+		if i >= len(pathParts) {
+			break
+		}
+		switch part {
+		case partName:
+			nameParts = append(nameParts, pathParts[i])
+		case partNameRest:
+			nameParts = append(nameParts, pathParts[i:]...)
+		case partSkip:
+		default:
+			tags = append(tags, part+":"+pathParts[i])
+		}
+	}
+	if len(nameParts) == 0 {
+		return nil
+	}
+	return &Result{
+		Name: strings.Join(nameParts, "."),
+		Tags: append(tags, t.tags...),
+	}
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package graphite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestNewTemplatesErrors(t *testing.T) {
+	scenarios := []struct {
+		name      string
+		templates []config.GraphiteTemplate
+	}{
+		{
+			name:      "missing template",
+			templates: []config.GraphiteTemplate{{Match: "servers.*"}},
+		},
+		{
+			name:      "invalid wildcard",
+			templates: []config.GraphiteTemplate{{Match: "servers.**", Template: "name*"}},
+		},
+		{
+			name:      "invalid wildcard characters",
+			templates: []config.GraphiteTemplate{{Match: "servers.[a-z]", Template: "name*"}},
+		},
+		{
+			name:      "empty part",
+			templates: []config.GraphiteTemplate{{Template: "host..name"}},
+		},
+		{
+			name:      "name* not last",
+			templates: []config.GraphiteTemplate{{Template: "name*.host"}},
+		},
+		{
+			name:      "no name",
+			templates: []config.GraphiteTemplate{{Template: "_.host.region"}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := NewTemplates(scenario.templates, 10)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	templates, err := NewTemplates([]config.GraphiteTemplate{
+		{Match: "servers.*.cpu.*", Template: "_.host.name.name", Tags: []string{"source:collectd"}},
+		{Match: "servers.*", Template: "_.host.name*"},
+		{Match: "apps.*.*", Template: "_.app.region.name"},
+		{Template: "env.name*"},
+	}, 10)
+	require.NoError(t, err)
+
+	scenarios := []struct {
+		path     string
+		expected *Result
+	}{
+		{
+			path:     "servers.web1.cpu.idle",
+			expected: &Result{Name: "cpu.idle", Tags: []string{"host:web1", "source:collectd"}},
+		},
+		{
+			path:     "servers.web1.disk.sda.used",
+			expected: &Result{Name: "disk.sda.used", Tags: []string{"host:web1"}},
+		},
+		{
+			// the parts of the path beyond the template are ignored
+			path:     "servers.web1.cpu.0.idle",
+			expected: &Result{Name: "cpu.0", Tags: []string{"host:web1", "source:collectd"}},
+		},
+		{
+			// the patterns match whole parts
+			path:     "servers2.web1.load",
+			expected: &Result{Name: "web1.load", Tags: []string{"env:servers2"}},
+		},
+		{
+			// the template does not produce any name
+			path:     "apps.billing.eu",
+			expected: nil,
+		},
+		{
+			// a template without pattern matches every path
+			path:     "prod.requests.count",
+			expected: &Result{Name: "requests.count", Tags: []string{"env:prod"}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.path, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				assert.Equal(t, scenario.expected, templates.Apply(scenario.path))
+			}
+		})
+	}
+}
+
+func TestApplyNoMatch(t *testing.T) {
+	templates, err := NewTemplates([]config.GraphiteTemplate{{Match: "servers.*", Template: "_.host.name*"}}, 10)
+	require.NoError(t, err)
+	assert.Nil(t, templates.Apply("apps.billing"))
+}
@@ -2,7 +2,6 @@
 // under the Apache License Version 2.0.
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
-//go:build windows
 
 package listeners
 
@@ -13,10 +12,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 )
 
+// listenerTelemetry holds the expvars and telemetry metrics of listeners reading packets
+// out of connection streams (named pipes, TCP).
 type listenerTelemetry struct {
-	packetReadingErrors expvar.Int
-	packets             expvar.Int
-	bytes               expvar.Int
+	packetReadingErrors *expvar.Int
+	packets             *expvar.Int
+	bytes               *expvar.Int
 	expvars             *expvar.Map
 	tlmPackets          telemetry.Counter
 	tlmPacketsBytes     telemetry.Counter
@@ -24,17 +25,17 @@ type listenerTelemetry struct {
 
 func newListenerTelemetry(metricName string, name string) *listenerTelemetry {
 	expvars := expvar.NewMap("dogstatsd-" + metricName)
-	packetReadingErrors := expvar.Int{}
-	packets := expvar.Int{}
-	bytes := expvar.Int{}
+	packetReadingErrors := &expvar.Int{}
+	packets := &expvar.Int{}
+	bytes := &expvar.Int{}
 
 	tlmPackets := telemetry.NewCounter("dogstatsd", metricName+"_packets",
 		[]string{"state"}, fmt.Sprintf("Dogstatsd %s packets count", name))
 	tlmPacketsBytes := telemetry.NewCounter("dogstatsd", metricName+"_packets_bytes",
 		nil, fmt.Sprintf("Dogstatsd %s packets bytes count", name))
-	expvars.Set("PacketReadingErrors", &packetReadingErrors)
-	expvars.Set("Packets", &packets)
-	expvars.Set("Bytes", &bytes)
+	expvars.Set("PacketReadingErrors", packetReadingErrors)
+	expvars.Set("Packets", packets)
+	expvars.Set("Bytes", bytes)
 
 	return &listenerTelemetry{
 		expvars:             expvars,
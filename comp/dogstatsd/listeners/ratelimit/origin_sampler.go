@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	tlm "github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmOriginSamplerSamples = tlm.NewCounter("dogstatsd", "origin_sampler_samples",
+		[]string{"state"}, "The number of metric samples of the sampled origins, by whether they were kept or dropped")
+	tlmOriginSamplerKept    = tlmOriginSamplerSamples.WithValues("kept")
+	tlmOriginSamplerDropped = tlmOriginSamplerSamples.WithValues("dropped")
+)
+
+// OriginSampler measures the rate of messages received from each origin (container or PID)
+// and computes the sample rate each origin should use to stay under a maximum rate. These
+// sample rates are exposed as hints for the clients, and can be applied by the server
+// itself: the metric samples it drops are then accounted for by upscaling the sample rate
+// of the ones it keeps. The events and service checks are never dropped.
+//
+// The rate of an origin is measured over fixed windows, and its sample rate is updated at
+// the end of each window. It is safe for concurrent use.
+type OriginSampler struct {
+	m sync.Mutex
+
+	maxRate       float64
+	minSampleRate float64
+	window        time.Duration
+	serverSide    bool
+
+	origins     map[string]*originRate
+	lastCleanup time.Time
+
+	// for testing purposes
+	now   func() time.Time
+	float func() float64
+}
+
+// originRate is the state of an origin.
+type originRate struct {
+	start      time.Time
+	messages   float64
+	rate       float64
+	sampleRate float64
+}
+
+// OriginSamplingHint is the sampling hint of an origin.
+type OriginSamplingHint struct {
+	Origin string `json:"origin"`
+	// Rate is the number of messages per second received from the origin over the last window.
+	Rate float64 `json:"rate"`
+	// SampleRate is the sample rate the origin should apply to stay under the maximum rate.
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// OriginSamplingHints are the sampling hints of the origins.
+type OriginSamplingHints struct {
+	MaxRate            float64              `json:"max_rate"`
+	ServerSideSampling bool                 `json:"server_side_sampling"`
+	Origins            []OriginSamplingHint `json:"origins"`
+}
+
+// BuildOriginSampler builds a new OriginSampler from the configuration, nil if the origin
+// sampling is disabled.
+func BuildOriginSampler(cfg config.Reader) *OriginSampler {
+	if !cfg.GetBool("dogstatsd_origin_sampling.enabled") {
+		return nil
+	}
+	return NewOriginSampler(
+		cfg.GetFloat64("dogstatsd_origin_sampling.max_rate"),
+		cfg.GetFloat64("dogstatsd_origin_sampling.min_sample_rate"),
+		cfg.GetDuration("dogstatsd_origin_sampling.window"),
+		cfg.GetBool("dogstatsd_origin_sampling.server_side"),
+	)
+}
+
+// NewOriginSampler creates a new instance of OriginSampler.
+func NewOriginSampler(maxRate float64, minSampleRate float64, window time.Duration, serverSide bool) *OriginSampler {
+	if minSampleRate <= 0 || minSampleRate > 1 {
+		minSampleRate = 1
+	}
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &OriginSampler{
+		maxRate:       maxRate,
+		minSampleRate: minSampleRate,
+		window:        window,
+		serverSide:    serverSide,
+		origins:       make(map[string]*originRate),
+		now:           time.Now,
+		float:         rand.Float64,
+	}
+}
+
+// Record records a packet of messages received from the origin, and returns the rate at
+// which the server samples its metric samples, 1 when the server-side sampling is disabled.
+func (s *OriginSampler) Record(origin string, messages int) float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	now := s.now()
+	s.cleanup(now)
+
+	o, found := s.origins[origin]
+	if !found {
+		o = &originRate{start: now, sampleRate: 1}
+		s.origins[origin] = o
+	} else if elapsed := now.Sub(o.start); elapsed >= s.window {
+		o.rate = o.messages / elapsed.Seconds()
+		o.sampleRate = s.sampleRateFor(o.rate)
+		o.start = now
+		o.messages = 0
+	}
+	o.messages += float64(messages)
+
+	if !s.serverSide {
+		return 1
+	}
+	return o.sampleRate
+}
+
+// KeepSample returns whether a metric sample of an origin sampled at sampleRate is kept.
+func (s *OriginSampler) KeepSample(sampleRate float64) bool {
+	if sampleRate >= 1 || s.float() < sampleRate {
+		tlmOriginSamplerKept.Inc()
+		return true
+	}
+	tlmOriginSamplerDropped.Inc()
+	return false
+}
+
+// sampleRateFor returns the sample rate keeping the rate under the maximum rate.
+func (s *OriginSampler) sampleRateFor(rate float64) float64 {
+	if rate <= s.maxRate || rate == 0 {
+		return 1
+	}
+	sampleRate := s.maxRate / rate
+	if sampleRate < s.minSampleRate {
+		sampleRate = s.minSampleRate
+	}
+	return sampleRate
+}
+
+// cleanup forgets the origins which didn't send anything for two windows.
+func (s *OriginSampler) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < s.window {
+		return
+	}
+	s.lastCleanup = now
+	for origin, o := range s.origins {
+		if now.Sub(o.start) >= 2*s.window {
+			delete(s.origins, origin)
+		}
+	}
+}
+
+// Hints returns the sampling hints of the origins, sorted by origin.
+func (s *OriginSampler) Hints() OriginSamplingHints {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.cleanup(s.now())
+	hints := OriginSamplingHints{
+		MaxRate:            s.maxRate,
+		ServerSideSampling: s.serverSide,
+		Origins:            make([]OriginSamplingHint, 0, len(s.origins)),
+	}
+	for origin, o := range s.origins {
+		hints.Origins = append(hints.Origins, OriginSamplingHint{
+			Origin:     origin,
+			Rate:       o.rate,
+			SampleRate: o.sampleRate,
+		})
+	}
+	sort.Slice(hints.Origins, func(i, j int) bool { return hints.Origins[i].Origin < hints.Origins[j].Origin })
+	return hints
+}
+
+// ServeHTTP writes the sampling hints in JSON. The `origin` query parameter restricts them
+// to an origin.
+func (s *OriginSampler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hints := s.Hints()
+	if origin := r.URL.Query().Get("origin"); origin != "" {
+		origins := hints.Origins[:0]
+		for _, hint := range hints.Origins {
+			if hint.Origin == origin {
+				origins = append(origins, hint)
+			}
+		}
+		hints.Origins = origins
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hints)
+}
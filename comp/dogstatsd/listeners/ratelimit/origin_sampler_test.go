@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOriginSampler(serverSide bool) (*OriginSampler, *time.Time, *float64) {
+	now := time.Unix(1700000000, 0)
+	float := 0.0
+	s := NewOriginSampler(100, 0.1, 10*time.Second, serverSide)
+	s.now = func() time.Time { return now }
+	s.float = func() float64 { return float }
+	return s, &now, &float
+}
+
+func TestOriginSamplerHints(t *testing.T) {
+	r := require.New(t)
+	s, now, _ := newTestOriginSampler(false)
+
+	// 5000 messages over 10s: 500 messages per second
+	for i := 0; i < 50; i++ {
+		r.Equal(1.0, s.Record("container_id:abc", 100))
+	}
+	s.Record("pid:42", 10)
+
+	*now = now.Add(10 * time.Second)
+	r.Equal(1.0, s.Record("container_id:abc", 1), "the sampling is only recommended")
+	s.Record("pid:42", 10)
+
+	hints := s.Hints()
+	r.Equal(100.0, hints.MaxRate)
+	r.False(hints.ServerSideSampling)
+	r.Equal([]OriginSamplingHint{
+		{Origin: "container_id:abc", Rate: 500, SampleRate: 0.2},
+		{Origin: "pid:42", Rate: 1, SampleRate: 1},
+	}, hints.Origins)
+}
+
+func TestOriginSamplerMinSampleRate(t *testing.T) {
+	r := require.New(t)
+	s, now, _ := newTestOriginSampler(false)
+
+	s.Record("pid:42", 100000)
+	*now = now.Add(10 * time.Second)
+	s.Record("pid:42", 1)
+
+	r.Equal(0.1, s.Hints().Origins[0].SampleRate)
+}
+
+func TestOriginSamplerServerSide(t *testing.T) {
+	r := require.New(t)
+	s, now, float := newTestOriginSampler(true)
+
+	s.Record("pid:42", 4000)
+	*now = now.Add(10 * time.Second)
+	r.Equal(0.25, s.Record("pid:42", 1))
+
+	*float = 0.5
+	r.False(s.KeepSample(0.25))
+	*float = 0.1
+	r.True(s.KeepSample(0.25))
+	*float = 0.99
+	r.True(s.KeepSample(1))
+
+	// the other origins are not sampled
+	r.Equal(1.0, s.Record("pid:43", 1))
+}
+
+func TestOriginSamplerCleanup(t *testing.T) {
+	r := require.New(t)
+	s, now, _ := newTestOriginSampler(false)
+
+	s.Record("pid:42", 1)
+	*now = now.Add(15 * time.Second)
+	s.Record("pid:43", 1)
+	r.Len(s.Hints().Origins, 2)
+
+	*now = now.Add(10 * time.Second)
+	hints := s.Hints()
+	r.Len(hints.Origins, 1)
+	r.Equal("pid:43", hints.Origins[0].Origin)
+}
+
+func TestOriginSamplerServeHTTP(t *testing.T) {
+	r := require.New(t)
+	s, _, _ := newTestOriginSampler(false)
+	s.Record("pid:42", 1)
+	s.Record("pid:43", 1)
+
+	for query, expected := range map[string][]string{
+		"":                    {"pid:42", "pid:43"},
+		"?origin=pid:43":      {"pid:43"},
+		"?origin=pid:unknown": {},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/sampling"+query, nil))
+		r.Equal("application/json", w.Header().Get("Content-Type"))
+
+		var hints OriginSamplingHints
+		r.NoError(json.Unmarshal(w.Body.Bytes(), &hints))
+		origins := []string{}
+		for _, hint := range hints.Origins {
+			origins = append(origins, hint.Origin)
+		}
+		r.Equal(expected, origins, query)
+	}
+}
@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package ratelimit
+
+// SetRandomSource sets the source of the random numbers used by KeepSample.
+func (s *OriginSampler) SetRandomSource(float func() float64) {
+	s.float = float
+}
@@ -0,0 +1,246 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var tcpTelemetry = newListenerTelemetry("tcp", "TCP")
+
+// TCPListener implements the StatsdListener interface for the TCP protocol, optionally
+// using TLS. It accepts connections on a given address and reads newline-separated
+// messages out of them.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	listener        net.Listener
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	connTracker     *ConnectionTracker
+	trafficCapture  replay.Component // Currently ignored
+
+	bufferSize     int
+	idleTimeout    time.Duration
+	maxConnections int32
+	connections    *atomic.Int32
+
+	listenWg sync.WaitGroup
+	connWg   sync.WaitGroup
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, cfg config.Reader, capture replay.Component) (*TCPListener, error) {
+	port := cfg.GetString("dogstatsd_tcp_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	var address string
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		address = fmt.Sprintf(":%s", port)
+	} else {
+		address = net.JoinHostPort(config.GetBindHostFromConfig(cfg), port)
+	}
+
+	tlsConfig, err := tcpTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "tcp")
+
+	l := &TCPListener{
+		listener:        listener,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.TCP),
+		connTracker:     NewConnectionTracker("tcp", 1*time.Second),
+		trafficCapture:  capture,
+		bufferSize:      cfg.GetInt("dogstatsd_buffer_size"),
+		idleTimeout:     cfg.GetDuration("dogstatsd_tcp_idle_timeout"),
+		maxConnections:  cfg.GetInt32("dogstatsd_tcp_max_connections"),
+		connections:     atomic.NewInt32(0),
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized (TLS: %t)", listener.Addr(), tlsConfig != nil)
+	return l, nil
+}
+
+// tcpTLSConfig returns the TLS configuration of the TCP listener, or nil if TLS is disabled.
+func tcpTLSConfig(cfg config.Reader) (*tls.Config, error) {
+	certFile := cfg.GetString("dogstatsd_tcp_tls_cert_file")
+	keyFile := cfg.GetString("dogstatsd_tcp_tls_key_file")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("dogstatsd_tcp_tls_cert_file and dogstatsd_tcp_tls_key_file must both be set to enable TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caFile := cfg.GetString("dogstatsd_tcp_tls_client_ca_file"); caFile != "" {
+		// verify client certificates
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS client CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in TLS client CA file %s", caFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), " use of closed network connection") {
+				log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			}
+			return
+		}
+		if l.maxConnections > 0 && l.connections.Load() >= l.maxConnections {
+			log.Debugf("dogstatsd-tcp: too many connections, rejecting %s", conn.RemoteAddr())
+			tlmTCPConnectionsRejected.Inc("max_connections")
+			conn.Close()
+			continue
+		}
+		l.connections.Inc()
+		tlmTCPConnections.Inc()
+		l.connTracker.Track(conn)
+		l.connWg.Add(1)
+		go func() {
+			defer l.connWg.Done()
+			l.handleConnection(conn)
+			l.connTracker.Close(conn)
+			l.connections.Dec()
+			tlmTCPConnections.Dec()
+		}()
+	}
+}
+
+// handleConnection reads newline-separated messages out of conn until it is closed, or
+// has been idle for longer than the idle timeout.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	log.Debugf("dogstatsd-tcp: start reading from %s", conn.RemoteAddr())
+	buffer := make([]byte, l.bufferSize)
+	startWriteIndex := 0
+	var t1, t2 time.Time
+	for {
+		if l.idleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(l.idleTimeout))
+		}
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+		t1 = time.Now()
+		endIndex := startWriteIndex + bytesRead
+
+		// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageSize is 0.
+		// If there is a '\n', at least one message is completed and '\n' is part of this message.
+		messageSize := bytes.LastIndexByte(buffer[:endIndex], '\n') + 1
+		if err != nil && messageSize < endIndex {
+			// the connection is being closed, the remaining bytes are the last message
+			messageSize = endIndex
+		}
+		if messageSize > 0 {
+			tcpTelemetry.onReadSuccess(messageSize)
+			if message := bytes.TrimSuffix(buffer[:messageSize], []byte{'\n'}); len(message) > 0 {
+				// packetAssembler merges multiple packets together and sends them when its buffer is full
+				l.packetAssembler.AddMessage(message)
+			}
+		}
+
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case err == io.EOF:
+				log.Debugf("dogstatsd-tcp: client %s disconnected", conn.RemoteAddr())
+			case errors.As(err, &netErr) && netErr.Timeout():
+				log.Debugf("dogstatsd-tcp: closing idle connection from %s", conn.RemoteAddr())
+			case strings.HasSuffix(err.Error(), " use of closed network connection"):
+				// connection closed by the connection tracker
+			default:
+				log.Errorf("dogstatsd-tcp: error reading packet: %v", err)
+				tcpTelemetry.onReadError()
+			}
+			return
+		}
+
+		startWriteIndex = endIndex - messageSize
+		if startWriteIndex >= len(buffer) {
+			// The message is bigger than the buffer size, drop it and continue reading next messages.
+			log.Debugf("dogstatsd-tcp: dropping message larger than %d bytes from %s", len(buffer), conn.RemoteAddr())
+			tcpTelemetry.onReadError()
+			startWriteIndex = 0
+		} else {
+			copy(buffer, buffer[messageSize:endIndex])
+		}
+
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), "tcp", "tcp", "tcp")
+	}
+}
+
+// Stop closes the TCP listener along with all its connections, and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	l.listenWg.Wait()
+	l.connTracker.Stop()
+	l.connWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
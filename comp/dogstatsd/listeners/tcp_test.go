@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, overrides map[string]interface{}) (*TCPListener, chan packets.Packets) {
+	overrides["dogstatsd_tcp_port"] = RandomPortName
+	overrides["dogstatsd_packet_buffer_flush_timeout"] = 10 * time.Millisecond
+	config := fulfillDepsWithConfig(t, overrides)
+	packetsChannel := make(chan packets.Packets, 10)
+	s, err := NewTCPListener(packetsChannel, newPacketPoolManagerUDP(config), config, nil)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	s.Listen()
+	t.Cleanup(s.Stop)
+	return s, packetsChannel
+}
+
+func readTCPPackets(t *testing.T, packetsChannel chan packets.Packets, n int) []string {
+	var contents []string
+	timeout := time.After(2 * time.Second)
+	for len(contents) < n {
+		select {
+		case pkts := <-packetsChannel:
+			for _, p := range pkts {
+				assert.Equal(t, packets.TCP, p.Source)
+				contents = append(contents, string(p.Contents))
+			}
+		case <-timeout:
+			require.Failf(t, "timeout", "received %d packets out of %d", len(contents), n)
+		}
+	}
+	return contents
+}
+
+func TestTCPReceive(t *testing.T) {
+	s, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("667|g\n"))
+	require.NoError(t, err)
+
+	contents := readTCPPackets(t, packetsChannel, 1)
+	assert.Equal(t, []string{"daemon:666|g|#sometag1:somevalue1\ndaemon:667|g"}, contents)
+}
+
+func TestTCPReceiveLastMessageOnClose(t *testing.T) {
+	s, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("daemon:666|g"))
+	require.NoError(t, err)
+	conn.Close()
+
+	contents := readTCPPackets(t, packetsChannel, 1)
+	assert.Equal(t, []string{"daemon:666|g"}, contents)
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	s, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_max_connections": 1,
+	})
+
+	first, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool { return s.connections.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	second, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "the connection should have been closed by the listener")
+	assert.EqualValues(t, 1, s.connections.Load())
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+	s, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_idle_timeout": 50 * time.Millisecond,
+	})
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "the idle connection should have been closed by the listener")
+	require.Eventually(t, func() bool { return s.connections.Load() == 0 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestTCPTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeTestCertificate(t, dir, "server")
+	clientCert, clientKey := writeTestCertificate(t, dir, "client")
+
+	s, packetsChannel := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls_cert_file":      serverCert,
+		"dogstatsd_tcp_tls_key_file":       serverKey,
+		"dogstatsd_tcp_tls_client_ca_file": clientCert,
+	})
+
+	roots := x509.NewCertPool()
+	pemData, err := os.ReadFile(serverCert)
+	require.NoError(t, err)
+	require.True(t, roots.AppendCertsFromPEM(pemData))
+
+	t.Run("client certificate", func(t *testing.T) {
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		require.NoError(t, err)
+		conn, err := tls.Dial("tcp", s.LocalAddr(), &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: []tls.Certificate{cert},
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("daemon:666|g\n"))
+		require.NoError(t, err)
+
+		contents := readTCPPackets(t, packetsChannel, 1)
+		assert.Equal(t, []string{"daemon:666|g"}, contents)
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", s.LocalAddr(), &tls.Config{
+			RootCAs:    roots,
+			ServerName: "localhost",
+		})
+		if err == nil {
+			// with TLS 1.3 the client certificate is verified after the client handshake completes
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, err = conn.Read(make([]byte, 1))
+		}
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+	})
+}
+
+func TestTCPTLSMissingKey(t *testing.T) {
+	config := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tcp_port":          RandomPortName,
+		"dogstatsd_tcp_tls_cert_file": "/path/to/cert.pem",
+	})
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(config), config, nil)
+	assert.Error(t, err)
+}
+
+// writeTestCertificate writes a self-signed certificate for localhost and its key in dir,
+// and returns their paths.
+func writeTestCertificate(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certPath, keyPath
+}
@@ -26,6 +26,12 @@ var (
 	tlmUDSConnections = telemetry.NewGauge("dogstatsd", "uds_connections",
 		[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count")
 
+	// TCP
+	tlmTCPConnections = telemetry.NewGauge("dogstatsd", "tcp_connections",
+		nil, "Dogstatsd TCP connections count")
+	tlmTCPConnectionsRejected = telemetry.NewCounter("dogstatsd", "tcp_connections_rejected",
+		[]string{"reason"}, "Dogstatsd TCP connections rejected count")
+
 	tlmListener            = telemetry.NewHistogramNoOp()
 	defaultListenerBuckets = []float64{300, 500, 1000, 1500, 2000, 2500, 3000, 10000, 20000, 50000}
 )
@@ -6,6 +6,7 @@
 package listeners
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"expvar"
@@ -22,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -49,6 +51,7 @@ type UDSListener struct {
 	sharedPacketPoolManager *packets.PoolManager
 	oobPoolManager          *packets.PoolManager
 	trafficCapture          replay.Component
+	originSampler           *ratelimit.OriginSampler
 	OriginDetection         bool
 	config                  config.Reader
 
@@ -126,7 +129,7 @@ func NewUDSOobPoolManager() *packets.PoolManager {
 }
 
 // NewUDSListener returns an idle UDS Statsd listener
-func NewUDSListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, sharedOobPacketPoolManager *packets.PoolManager, cfg config.Reader, capture replay.Component, transport string) (*UDSListener, error) {
+func NewUDSListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, sharedOobPacketPoolManager *packets.PoolManager, cfg config.Reader, capture replay.Component, originSampler *ratelimit.OriginSampler, transport string) (*UDSListener, error) {
 	originDetection := cfg.GetBool("dogstatsd_origin_detection")
 
 	listener := &UDSListener{
@@ -134,6 +137,7 @@ func NewUDSListener(packetOut chan packets.Packets, sharedPacketPoolManager *pac
 		packetOut:                    packetOut,
 		sharedPacketPoolManager:      sharedPacketPoolManager,
 		trafficCapture:               capture,
+		originSampler:                originSampler,
 		dogstatsdMemBasedRateLimiter: cfg.GetBool("dogstatsd_mem_based_rate_limiter.enabled"),
 		config:                       cfg,
 		transport:                    transport,
@@ -289,6 +293,7 @@ func (l *UDSListener) handleConnection(conn *net.UnixConn, closeFunc CloseFuncti
 
 		t1 = time.Now()
 
+		var origin string
 		if oob != nil {
 			// Extract container id from credentials
 			pid, container, taggingErr := processUDSOrigin(oobS[:oobn])
@@ -302,6 +307,7 @@ func (l *UDSListener) handleConnection(conn *net.UnixConn, closeFunc CloseFuncti
 					capBuff.ContainerID = container
 				}
 			}
+			origin = samplingOrigin(pid, container)
 			if capBuff != nil {
 				capBuff.Oob = oob
 				capBuff.Pid = int32(pid)
@@ -337,6 +343,14 @@ func (l *UDSListener) handleConnection(conn *net.UnixConn, closeFunc CloseFuncti
 
 		udsBytes.Add(int64(n))
 		tlmUDSPacketsBytes.Add(float64(n), tlmListenerID, l.transport)
+
+		if l.originSampler != nil && origin != "" {
+			// the server samples the metric samples of the packet once parsed
+			if sampleRate := l.originSampler.Record(origin, countMessages(packet.Buffer[:n])); sampleRate < 1 {
+				packet.SampleRate = sampleRate
+			}
+		}
+
 		packet.Contents = packet.Buffer[:n]
 		packet.Source = packets.UDS
 		packet.ListenerID = listenerID
@@ -346,6 +360,27 @@ func (l *UDSListener) handleConnection(conn *net.UnixConn, closeFunc CloseFuncti
 	}
 }
 
+// samplingOrigin returns the origin of a packet for the origin sampler: its container if
+// known, its PID otherwise.
+func samplingOrigin(pid int, container string) string {
+	if container != packets.NoOrigin {
+		return "container_id:" + strings.TrimPrefix(container, containers.ContainerEntityPrefix)
+	}
+	if pid == 0 {
+		return ""
+	}
+	return "pid:" + strconv.Itoa(pid)
+}
+
+// countMessages returns the number of messages in the contents of a packet.
+func countMessages(contents []byte) int {
+	n := bytes.Count(contents, []byte{'\n'})
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
 func (l *UDSListener) getConnID(conn *net.UnixConn) string {
 	// We use the file descriptor as a unique identifier for the connection. This might
 	// increase the cardinality in the backend, but this option is not designed to be enabled
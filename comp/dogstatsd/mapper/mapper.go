@@ -14,15 +14,12 @@ import (
 	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
-)
-
-var (
-	allowedWildcardMatchPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.]+$`)
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 const (
-	matchTypeWildcard = "wildcard"
-	matchTypeRegex    = "regex"
+	matchTypeWildcard = metrics.MatchTypeWildcard
+	matchTypeRegex    = metrics.MatchTypeRegex
 )
 
 // MetricMapper contains mappings and cache instance
@@ -81,7 +78,7 @@ func NewMetricMapper(configProfiles []config.MappingProfile, cacheSize int) (*Me
 			if currentMapping.Match == "" {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
 			}
-			regex, err := buildRegex(currentMapping.Match, matchType)
+			regex, err := metrics.CompileMatchPattern(currentMapping.Match, matchType)
 			if err != nil {
 				return nil, err
 			}
@@ -96,24 +93,6 @@ func NewMetricMapper(configProfiles []config.MappingProfile, cacheSize int) (*Me
 	return &MetricMapper{Profiles: profiles, cache: cache}, nil
 }
 
-func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
-	if matchType == matchTypeWildcard {
-		if !allowedWildcardMatchPattern.MatchString(matchRe) {
-			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it does not match allowed match regex `%s`", matchRe, allowedWildcardMatchPattern)
-		}
-		if strings.Contains(matchRe, "**") {
-			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it should not contain consecutive `*`", matchRe)
-		}
-		matchRe = strings.Replace(matchRe, ".", "\\.", -1)
-		matchRe = strings.Replace(matchRe, "*", "([^.]*)", -1)
-	}
-	regex, err := regexp.Compile("^" + matchRe + "$")
-	if err != nil {
-		return nil, fmt.Errorf("invalid match `%s`. cannot compile regex: %v", matchRe, err)
-	}
-	return regex, nil
-}
-
 // Map returns a MapResult
 func (m *MetricMapper) Map(metricName string) *MapResult {
 	for _, profile := range m.Profiles {
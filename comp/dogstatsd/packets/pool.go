@@ -55,7 +55,7 @@ func (p *Pool) Get() interface{} {
 	return p.pool.Get()
 }
 
-// Put resets the Packet origin and puts it back in the pool.
+// Put resets the Packet origin and sample rate and puts it back in the pool.
 func (p *Pool) Put(x interface{}) {
 	if x == nil {
 		return
@@ -66,6 +66,9 @@ func (p *Pool) Put(x interface{}) {
 	if ok && packet.Origin != NoOrigin {
 		packet.Origin = NoOrigin
 	}
+	if ok {
+		packet.SampleRate = 0
+	}
 	if p.tlmEnabled {
 		tlmPoolPut.Inc()
 		tlmPool.Dec()
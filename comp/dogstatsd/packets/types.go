@@ -21,6 +21,8 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener
+	TCP
 )
 
 // Packet represents a statsd packet ready to process,
@@ -35,6 +37,7 @@ type Packet struct {
 	Origin     string     // Origin container if identified
 	ListenerID string     // Listener ID
 	Source     SourceType // Type of listener that produced the packet
+	SampleRate float64    // Rate at which the metric samples of the packet are sampled, 0 if they aren't
 }
 
 // Packets is a slice of packet pointers
@@ -0,0 +1,313 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+const (
+	// MessageTypeMetric is the type of the metric messages.
+	MessageTypeMetric = "metric"
+	// MessageTypeEvent is the type of the event messages.
+	MessageTypeEvent = "event"
+	// MessageTypeServiceCheck is the type of the service check messages.
+	MessageTypeServiceCheck = "service_check"
+)
+
+// CapturedMessage is a DogStatsD message decoded from a captured packet.
+type CapturedMessage struct {
+	Timestamp time.Time `json:"timestamp"`
+	Pid       int32     `json:"pid,omitempty"`
+	// ContainerID is the container ID sent in the message, or the one the tagger resolved
+	// from the PID during the capture.
+	ContainerID string   `json:"container_id,omitempty"`
+	Type        string   `json:"type"`
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags,omitempty"`
+	Message     string   `json:"message"`
+}
+
+// Origin returns the origin of the message: its container ID if known, its PID otherwise.
+func (m *CapturedMessage) Origin() string {
+	if m.ContainerID != "" {
+		return "container_id:" + m.ContainerID
+	}
+	return "pid:" + strconv.Itoa(int(m.Pid))
+}
+
+// Time returns the time at which the packet was captured.
+func (tc *TrafficCaptureReader) Time(msg *pb.UnixDogstatsdMsg) time.Time {
+	if tc.Version < minNanoVersion {
+		return time.Unix(msg.Timestamp, 0)
+	}
+	return time.Unix(0, msg.Timestamp)
+}
+
+// DecodePacket splits a captured packet into its messages. pidMap is the PID map of the
+// tagger state of the capture, used to resolve the container ID of the messages.
+func DecodePacket(msg *pb.UnixDogstatsdMsg, ts time.Time, pidMap map[int32]string) []CapturedMessage {
+	payload := msg.Payload
+	if int(msg.PayloadSize) < len(payload) {
+		payload = payload[:msg.PayloadSize]
+	}
+
+	var messages []CapturedMessage
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+		m := CapturedMessage{
+			Timestamp:   ts,
+			Pid:         msg.Pid,
+			ContainerID: strings.TrimPrefix(pidMap[msg.Pid], containers.ContainerEntityPrefix),
+			Message:     string(line),
+		}
+		var containerID string
+		m.Type, m.Name, m.Tags, containerID = parseCapturedMessage(m.Message)
+		if containerID != "" {
+			m.ContainerID = containerID
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// parseCapturedMessage extracts the type, the name, the tags and the container ID of a
+// DogStatsD message. It does not validate the message: the fields it can't find are left
+// empty.
+func parseCapturedMessage(message string) (typ, name string, tags []string, containerID string) {
+	var fields []string
+	switch {
+	case strings.HasPrefix(message, "_e{"):
+		typ = MessageTypeEvent
+		name, fields = parseCapturedEvent(message)
+	case strings.HasPrefix(message, "_sc|"):
+		typ = MessageTypeServiceCheck
+		fields = strings.Split(message, "|")
+		name = fields[1]
+		fields = fields[2:]
+	default:
+		typ = MessageTypeMetric
+		fields = strings.Split(message, "|")
+		name, _, _ = strings.Cut(fields[0], ":")
+		fields = fields[1:]
+	}
+
+	for _, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "#"):
+			tags = append(tags, strings.Split(field[1:], ",")...)
+		case strings.HasPrefix(field, "c:"):
+			containerID = field[2:]
+		}
+	}
+	return typ, name, tags, containerID
+}
+
+// parseCapturedEvent returns the title of an event, `_e{<title length>,<text length>}:<title>|<text>|...`,
+// and its optional fields.
+func parseCapturedEvent(message string) (string, []string) {
+	header, rest, found := strings.Cut(message[len("_e{"):], "}:")
+	if !found {
+		return "", nil
+	}
+	rawTitleLength, rawTextLength, _ := strings.Cut(header, ",")
+	titleLength, err := strconv.Atoi(rawTitleLength)
+	if err != nil || titleLength < 0 || titleLength > len(rest) {
+		return "", nil
+	}
+	title := rest[:titleLength]
+	textLength, err := strconv.Atoi(rawTextLength)
+	if err != nil || textLength < 0 || titleLength+1+textLength > len(rest) {
+		return title, nil
+	}
+	rest = rest[titleLength+1+textLength:]
+	if rest == "" {
+		return title, nil
+	}
+	return title, strings.Split(rest[1:], "|")
+}
+
+// CaptureFilter selects the messages of a capture. The messages must match every criteria
+// set, and a criteria is met if any of its values matches.
+type CaptureFilter struct {
+	// Names are patterns, as supported by path.Match, of the metric names.
+	Names []string
+	// Tags are patterns, as supported by path.Match, of the tags.
+	Tags []string
+	// Pids are the PIDs of the senders.
+	Pids []int32
+	// ContainerIDs are the container IDs of the senders.
+	ContainerIDs []string
+}
+
+// Match returns whether the message matches the filter.
+func (f *CaptureFilter) Match(m *CapturedMessage) bool {
+	if len(f.Names) > 0 && !matchAny(f.Names, m.Name) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		found := false
+		for _, tag := range m.Tags {
+			if matchAny(f.Tags, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Pids) > 0 {
+		found := false
+		for _, pid := range f.Pids {
+			if pid == m.Pid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.ContainerIDs) > 0 {
+		found := false
+		for _, containerID := range f.ContainerIDs {
+			if containerID == m.ContainerID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// VolumeStats are the volume and cardinality of a set of messages.
+type VolumeStats struct {
+	Key      string `json:"key"`
+	Messages int    `json:"messages"`
+	Bytes    int    `json:"bytes"`
+	// Contexts is the number of distinct name and tags combinations.
+	Contexts int `json:"contexts"`
+
+	contexts map[string]struct{}
+}
+
+func (s *VolumeStats) add(m *CapturedMessage, context string) {
+	s.Messages++
+	s.Bytes += len(m.Message)
+	if _, found := s.contexts[context]; !found {
+		s.contexts[context] = struct{}{}
+		s.Contexts++
+	}
+}
+
+// CaptureSummary computes the volume and cardinality of the messages of a capture, per
+// metric name and per origin.
+type CaptureSummary struct {
+	Messages int
+	Bytes    int
+	metrics  map[string]*VolumeStats
+	origins  map[string]*VolumeStats
+}
+
+// NewCaptureSummary returns an empty CaptureSummary.
+func NewCaptureSummary() *CaptureSummary {
+	return &CaptureSummary{
+		metrics: make(map[string]*VolumeStats),
+		origins: make(map[string]*VolumeStats),
+	}
+}
+
+// Add adds a message to the summary.
+func (s *CaptureSummary) Add(m *CapturedMessage) {
+	s.Messages++
+	s.Bytes += len(m.Message)
+
+	tags := append([]string(nil), m.Tags...)
+	sort.Strings(tags)
+	context := m.Type + "|" + m.Name + "|" + strings.Join(tags, ",")
+
+	statsFor(s.metrics, m.Type+":"+m.Name).add(m, context)
+	statsFor(s.origins, m.Origin()).add(m, context)
+}
+
+func statsFor(stats map[string]*VolumeStats, key string) *VolumeStats {
+	st, found := stats[key]
+	if !found {
+		st = &VolumeStats{Key: key, contexts: make(map[string]struct{})}
+		stats[key] = st
+	}
+	return st
+}
+
+// Metrics returns the stats per metric, keyed by `<type>:<name>` and sorted by decreasing
+// number of messages.
+func (s *CaptureSummary) Metrics() []*VolumeStats {
+	return sortedStats(s.metrics)
+}
+
+// Origins returns the stats per origin, keyed by container ID or PID and sorted by
+// decreasing number of messages.
+func (s *CaptureSummary) Origins() []*VolumeStats {
+	return sortedStats(s.origins)
+}
+
+func sortedStats(stats map[string]*VolumeStats) []*VolumeStats {
+	sorted := make([]*VolumeStats, 0, len(stats))
+	for _, st := range stats {
+		sorted = append(sorted, st)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Messages != sorted[j].Messages {
+			return sorted[i].Messages > sorted[j].Messages
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+// WriteCapture writes a capture file holding the packets and the tagger state, for instance
+// to write back a filtered capture. The timestamps of the packets must be in nanoseconds.
+func WriteCapture(w io.Writer, packets []*pb.UnixDogstatsdMsg, state *pb.TaggerState) error {
+	writer := bufio.NewWriter(w)
+	if err := WriteHeader(writer); err != nil {
+		return err
+	}
+	for _, packet := range packets {
+		if _, err := writeMessage(writer, packet); err != nil {
+			return err
+		}
+	}
+	if _, err := writeTaggerState(writer, state); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+func TestDecodePacket(t *testing.T) {
+	payload := []byte("custom.metric:1|c|#env:prod,role:web|c:abcdef\r\n" +
+		"_e{5,4}:title|text|p:low|#env:dev\n" +
+		"_sc|my.check|0|#env:prod|m:all good\n" +
+		"\n" +
+		"other.metric:2|g\n" +
+		"garbage")
+	msg := &pb.UnixDogstatsdMsg{
+		Pid:         42,
+		Payload:     append(payload, "ignored"...),
+		PayloadSize: int32(len(payload)),
+	}
+	ts := time.Unix(1700000000, 0)
+
+	messages := DecodePacket(msg, ts, map[int32]string{42: "container_id://123456"})
+	assert.Equal(t, []CapturedMessage{
+		{
+			Timestamp:   ts,
+			Pid:         42,
+			ContainerID: "abcdef",
+			Type:        MessageTypeMetric,
+			Name:        "custom.metric",
+			Tags:        []string{"env:prod", "role:web"},
+			Message:     "custom.metric:1|c|#env:prod,role:web|c:abcdef",
+		},
+		{
+			Timestamp:   ts,
+			Pid:         42,
+			ContainerID: "123456",
+			Type:        MessageTypeEvent,
+			Name:        "title",
+			Tags:        []string{"env:dev"},
+			Message:     "_e{5,4}:title|text|p:low|#env:dev",
+		},
+		{
+			Timestamp:   ts,
+			Pid:         42,
+			ContainerID: "123456",
+			Type:        MessageTypeServiceCheck,
+			Name:        "my.check",
+			Tags:        []string{"env:prod"},
+			Message:     "_sc|my.check|0|#env:prod|m:all good",
+		},
+		{
+			Timestamp:   ts,
+			Pid:         42,
+			ContainerID: "123456",
+			Type:        MessageTypeMetric,
+			Name:        "other.metric",
+			Message:     "other.metric:2|g",
+		},
+		{
+			Timestamp:   ts,
+			Pid:         42,
+			ContainerID: "123456",
+			Type:        MessageTypeMetric,
+			Name:        "garbage",
+			Message:     "garbage",
+		},
+	}, messages)
+
+	assert.Equal(t, "container_id:abcdef", messages[0].Origin())
+	assert.Equal(t, "pid:7", (&CapturedMessage{Pid: 7}).Origin())
+}
+
+func TestParseCapturedEvent(t *testing.T) {
+	for _, tc := range []struct {
+		message string
+		name    string
+		fields  []string
+	}{
+		{"_e{5,9}:title|text|with|#not:a_tag", "title", []string{"#not:a_tag"}},
+		{"_e{5,4}:title|text", "title", nil},
+		{"_e{5,40}:title|text", "title", nil},
+		{"_e{50,4}:title|text", "", nil},
+		{"_e{a,4}:title|text", "", nil},
+		{"_e{5,4}title|text", "", nil},
+	} {
+		t.Run(tc.message, func(t *testing.T) {
+			name, fields := parseCapturedEvent(tc.message)
+			assert.Equal(t, tc.name, name)
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestCaptureFilter(t *testing.T) {
+	m := &CapturedMessage{
+		Pid:         42,
+		ContainerID: "abcdef",
+		Name:        "my_app.requests",
+		Tags:        []string{"env:prod", "role:web"},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		filter   CaptureFilter
+		expected bool
+	}{
+		{"empty", CaptureFilter{}, true},
+		{"name", CaptureFilter{Names: []string{"other", "my_app.*"}}, true},
+		{"name mismatch", CaptureFilter{Names: []string{"my_app"}}, false},
+		{"tag", CaptureFilter{Tags: []string{"role:*"}}, true},
+		{"tag mismatch", CaptureFilter{Tags: []string{"env:dev"}}, false},
+		{"pid", CaptureFilter{Pids: []int32{1, 42}}, true},
+		{"pid mismatch", CaptureFilter{Pids: []int32{1}}, false},
+		{"container", CaptureFilter{ContainerIDs: []string{"abcdef"}}, true},
+		{"container mismatch", CaptureFilter{ContainerIDs: []string{"123456"}}, false},
+		{"all", CaptureFilter{Names: []string{"my_app.*"}, Tags: []string{"env:prod"}, Pids: []int32{42}}, true},
+		{"one mismatch", CaptureFilter{Names: []string{"my_app.*"}, Tags: []string{"env:prod"}, Pids: []int32{1}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.Match(m))
+		})
+	}
+}
+
+func TestCaptureSummary(t *testing.T) {
+	summary := NewCaptureSummary()
+	for _, m := range []CapturedMessage{
+		{Pid: 1, Type: MessageTypeMetric, Name: "a", Tags: []string{"x:1", "y:1"}, Message: "a:1|c|#x:1,y:1"},
+		{Pid: 1, Type: MessageTypeMetric, Name: "a", Tags: []string{"y:1", "x:1"}, Message: "a:1|c|#y:1,x:1"},
+		{Pid: 1, Type: MessageTypeMetric, Name: "a", Tags: []string{"x:2"}, Message: "a:1|c|#x:2"},
+		{Pid: 2, ContainerID: "abc", Type: MessageTypeMetric, Name: "b", Message: "b:1|g"},
+		{Pid: 2, ContainerID: "abc", Type: MessageTypeServiceCheck, Name: "b", Message: "_sc|b|0"},
+	} {
+		summary.Add(&m)
+	}
+
+	assert.Equal(t, 5, summary.Messages)
+	assert.Equal(t, 50, summary.Bytes)
+
+	metrics := summary.Metrics()
+	require.Len(t, metrics, 3)
+	assert.Equal(t, "metric:a", metrics[0].Key)
+	assert.Equal(t, 3, metrics[0].Messages)
+	assert.Equal(t, 2, metrics[0].Contexts)
+	assert.Equal(t, "metric:b", metrics[1].Key)
+	assert.Equal(t, "service_check:b", metrics[2].Key)
+
+	origins := summary.Origins()
+	require.Len(t, origins, 2)
+	assert.Equal(t, "pid:1", origins[0].Key)
+	assert.Equal(t, 3, origins[0].Messages)
+	assert.Equal(t, 2, origins[0].Contexts)
+	assert.Equal(t, "container_id:abc", origins[1].Key)
+	assert.Equal(t, 2, origins[1].Messages)
+	assert.Equal(t, 12, origins[1].Bytes)
+	assert.Equal(t, 2, origins[1].Contexts)
+}
+
+func TestWriteCapture(t *testing.T) {
+	packets := []*pb.UnixDogstatsdMsg{
+		{Timestamp: 1000, Pid: 1, PayloadSize: 5, Payload: []byte("a:1|c")},
+		{Timestamp: 2000, Pid: 2, PayloadSize: 5, Payload: []byte("b:1|g")},
+	}
+	state := &pb.TaggerState{
+		PidMap: map[int32]string{2: "container_id://abc"},
+		State:  map[string]*pb.Entity{"container_id://abc": {LowCardinalityTags: []string{"image:foo"}}},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, WriteCapture(&b, packets, state))
+	path := filepath.Join(t.TempDir(), "capture.dog")
+	require.NoError(t, os.WriteFile(path, b.Bytes(), 0660))
+
+	reader, err := NewTrafficCaptureReader(path, 1, false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	pidMap, entities, err := reader.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, state.PidMap, pidMap)
+	require.Contains(t, entities, "container_id://abc")
+	assert.Equal(t, []string{"image:foo"}, entities["container_id://abc"].LowCardinalityTags)
+
+	reader.Seek(0)
+	for _, expected := range packets {
+		msg, err := reader.ReadNext()
+		require.NoError(t, err)
+		assert.Equal(t, expected.Payload, msg.Payload)
+		assert.Equal(t, expected.Pid, msg.Pid)
+		assert.Equal(t, time.Unix(0, expected.Timestamp), reader.Time(msg))
+	}
+	_, err = reader.ReadNext()
+	assert.Equal(t, io.EOF, err)
+}
@@ -354,24 +354,29 @@ func (tc *TrafficCaptureWriter) writeState() (int, error) {
 
 	log.Debugf("Going to write STATE: %#v", pbState)
 
+	return writeTaggerState(tc.writer, pbState)
+}
+
+// writeTaggerState writes the tagger state to w, after the state separator.
+func writeTaggerState(w io.Writer, pbState *pb.TaggerState) (int, error) {
 	s, err := proto.Marshal(pbState)
 	if err != nil {
 		return 0, err
 	}
 
 	// Record State Separator
-	if n, err := tc.writer.Write([]byte{0, 0, 0, 0}); err != nil {
+	if n, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
 		return n, err
 	}
 
 	// Record State
-	n, err := tc.writer.Write(s)
+	n, err := w.Write(s)
 
 	// Record size
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(s)))
 
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
@@ -382,27 +387,37 @@ func (tc *TrafficCaptureWriter) writeState() (int, error) {
 // writeNext writes the next CaptureBuffer after serializing it to a protobuf format.
 // Continuing writes after an error calling this function would result in a corrupted file
 func (tc *TrafficCaptureWriter) writeNext(msg *CaptureBuffer) error {
-	buff, err := proto.Marshal(&msg.Pb)
-	if err != nil {
-		return err
-	}
-
-	_, err = tc.Write(buff)
+	_, err := writeMessage(tc.writer, &msg.Pb)
 	return err
 }
 
 // Write writes the byte slice argument to file.
 func (tc *TrafficCaptureWriter) Write(p []byte) (int, error) {
+	return writeRecord(tc.writer, p)
+}
+
+// writeMessage serializes the message to a protobuf format and writes it to w.
+func writeMessage(w io.Writer, msg *pb.UnixDogstatsdMsg) (int, error) {
+	buff, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return writeRecord(w, buff)
+}
+
+// writeRecord writes the byte slice argument to w, prefixed with its size.
+func writeRecord(w io.Writer, p []byte) (int, error) {
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(p)))
 
 	// Record size
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
 	// Record
-	n, err := tc.writer.Write(p)
+	n, err := w.Write(p)
 
 	return n + 4, err
 }
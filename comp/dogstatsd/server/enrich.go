@@ -9,7 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"go.uber.org/atomic"
+
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/constants"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/tagrules"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	metricsevent "github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
@@ -36,6 +39,10 @@ type enrichConfig struct {
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
 	originOptOutEnabled       bool
+
+	// tagRules holds the rules rewriting the tags of the metrics. It is shared
+	// with the server, which swaps the rules when they are reloaded. It may be nil.
+	tagRules *atomic.Pointer[tagrules.Rules]
 }
 
 // extractTagsMetadata returns tags (client tags + host tag) and information needed to query tagger (origins, cardinality).
@@ -173,7 +180,13 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 	}
 
 	if conf.metricBlocklist.test(metricName) {
-		return []metrics.MetricSample{}
+		return dest
+	}
+
+	if conf.tagRules != nil {
+		if rules := conf.tagRules.Load(); rules != nil {
+			tags = rules.Apply(metricName, tags)
+		}
 	}
 
 	if conf.serverlessMode { // we don't want to set the host while running in serverless mode
@@ -57,6 +57,9 @@ type parser struct {
 
 	// Generic Metric Provider
 	provider provider.Provider
+
+	// statsdSamples is reused to parse the samples of the Etsy StatsD messages
+	statsdSamples []dogstatsdMetricSample
 }
 
 func newParser(cfg config.Reader, float64List *float64ListPool, workerNum int) *parser {
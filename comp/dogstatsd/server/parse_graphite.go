@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	graphiteFieldSeparators = " \t"
+	graphiteTagSeparator    = []byte(";")
+	graphiteTagValueSep     = []byte("=")
+)
+
+// nextGraphiteField returns the data found before the first space or tab, ignoring the
+// leading ones, and the remainder. If the separator is not found, the remainder is nil.
+func nextGraphiteField(message []byte) ([]byte, []byte) {
+	message = bytes.TrimLeft(message, graphiteFieldSeparators)
+	sepIndex := bytes.IndexAny(message, graphiteFieldSeparators)
+	if sepIndex == -1 {
+		return message, nil
+	}
+	return message[:sepIndex], message[sepIndex+1:]
+}
+
+// parseGraphiteMetricSample parses a message of the Graphite plaintext protocol:
+// `<path>[;<tag>=<value>...] <value> [<timestamp>]`, as a gauge named after the path.
+func (p *parser) parseGraphiteMetricSample(message []byte) (dogstatsdMetricSample, error) {
+	rawPath, message := nextGraphiteField(message)
+	rawValue, message := nextGraphiteField(message)
+	rawTimestamp, message := nextGraphiteField(message)
+	if len(rawPath) == 0 || len(rawValue) == 0 {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite message format")
+	}
+	if len(bytes.TrimLeft(message, graphiteFieldSeparators)) > 0 {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite message format: too many fields")
+	}
+
+	rawPath, rawTags, _ := bytes.Cut(rawPath, graphiteTagSeparator)
+	if len(rawPath) == 0 {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite message format: empty path")
+	}
+	tags, err := p.parseGraphiteTags(rawTags)
+	if err != nil {
+		return dogstatsdMetricSample{}, err
+	}
+
+	value, err := parseFloat64(rawValue)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return dogstatsdMetricSample{}, fmt.Errorf("could not parse graphite metric value %q", rawValue)
+	}
+
+	// as with carbon, a negative timestamp means that the sample has no timestamp
+	var timestamp time.Time
+	if len(rawTimestamp) > 0 {
+		ts, err := parseFloat64(rawTimestamp)
+		if err != nil {
+			return dogstatsdMetricSample{}, fmt.Errorf("could not parse graphite timestamp %q: %v", rawTimestamp, err)
+		}
+		if p.readTimestamps && ts > 0 {
+			timestamp = time.Unix(int64(ts), 0)
+		}
+	}
+
+	return dogstatsdMetricSample{
+		name:       p.interner.LoadOrStore(rawPath),
+		value:      value,
+		metricType: gaugeType,
+		sampleRate: 1,
+		tags:       tags,
+		ts:         timestamp,
+	}, nil
+}
+
+// parseGraphiteTags parses the tags of a Graphite path, `<tag>=<value>` separated by
+// semicolons, into `<tag>:<value>` tags.
+func (p *parser) parseGraphiteTags(rawTags []byte) ([]string, error) {
+	if len(rawTags) == 0 {
+		return nil, nil
+	}
+	tags := make([]string, 0, bytes.Count(rawTags, graphiteTagSeparator)+1)
+	var buf []byte
+	for len(rawTags) > 0 {
+		var rawTag []byte
+		rawTag, rawTags, _ = bytes.Cut(rawTags, graphiteTagSeparator)
+		key, value, found := bytes.Cut(rawTag, graphiteTagValueSep)
+		if !found || len(key) == 0 || len(value) == 0 {
+			return nil, fmt.Errorf("invalid graphite tag %q", rawTag)
+		}
+		buf = append(append(append(buf[:0], key...), colonSeparator...), value...)
+		tags = append(tags, p.interner.LoadOrStore(buf))
+	}
+	return tags, nil
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func parseGraphiteMetricSample(t *testing.T, overrides map[string]any, rawSample []byte) (dogstatsdMetricSample, error) {
+	cfg := fxutil.Test[config.Component](t, fx.Options(
+		config.MockModule(),
+		fx.Replace(config.MockParams{Overrides: overrides}),
+	))
+
+	p := newParser(cfg, newFloat64ListPool(), 1)
+	return p.parseGraphiteMetricSample(rawSample)
+}
+
+func TestParseGraphite(t *testing.T) {
+	sample, err := parseGraphiteMetricSample(t, map[string]any{}, []byte("servers.web1.cpu.idle 42.5 1700000000"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "servers.web1.cpu.idle", sample.name)
+	assert.Equal(t, 42.5, sample.value)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, 1.0, sample.sampleRate)
+	assert.Empty(t, sample.tags)
+	assert.Equal(t, time.Unix(1700000000, 0), sample.ts)
+}
+
+func TestParseGraphiteTags(t *testing.T) {
+	sample, err := parseGraphiteMetricSample(t, map[string]any{}, []byte("  disk.used;datacenter=dc1;rack=a1  \t 1e3"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "disk.used", sample.name)
+	assert.Equal(t, 1000.0, sample.value)
+	assert.Equal(t, []string{"datacenter:dc1", "rack:a1"}, sample.tags)
+	assert.Zero(t, sample.ts)
+}
+
+func TestParseGraphiteTimestamp(t *testing.T) {
+	// a negative timestamp means no timestamp
+	sample, err := parseGraphiteMetricSample(t, map[string]any{}, []byte("cpu.idle 42 -1"))
+	require.NoError(t, err)
+	assert.Zero(t, sample.ts)
+
+	// timestamps are ignored without the no-aggregation pipeline
+	sample, err = parseGraphiteMetricSample(t, map[string]any{"dogstatsd_no_aggregation_pipeline": false}, []byte("cpu.idle 42 1700000000"))
+	require.NoError(t, err)
+	assert.Zero(t, sample.ts)
+}
+
+func TestParseGraphiteErrors(t *testing.T) {
+	for _, message := range []string{
+		"",
+		"cpu.idle",
+		"cpu.idle abc 1700000000",
+		"cpu.idle nan 1700000000",
+		"cpu.idle 42 abc",
+		"cpu.idle 42 1700000000 extra",
+		";dc=dc1 42",
+		"cpu.idle;dc 42",
+		"cpu.idle;=dc1 42",
+	} {
+		t.Run(message, func(t *testing.T) {
+			_, err := parseGraphiteMetricSample(t, map[string]any{}, []byte(message))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -50,6 +50,8 @@ type dogstatsdMetricSample struct {
 	containerID []byte
 	// timestamp read in the message if any
 	ts time.Time
+	// relativeGauge is true for the Etsy StatsD gauges modifying the last value of the gauge
+	relativeGauge bool
 }
 
 // sanity checks a given message against the metric sample format
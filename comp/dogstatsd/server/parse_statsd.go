@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// statsdGaugesSize is the number of gauges whose value is kept to apply the relative
+// gauges of the Etsy StatsD protocol.
+const statsdGaugesSize = 10000
+
+// parseStatsdMetricSamples parses a message of the Etsy StatsD protocol and appends its
+// samples to dest. A message holds one or more samples of a metric:
+// `<name>:<value>|<type>[|@<sample rate>][:<value>|<type>[|@<sample rate>]...]`.
+// Only the counter, gauge, timer and set types are supported, and the DogStatsD extensions
+// (tags, timestamps, multiple values, ...) are rejected. The metric name is sanitized like
+// Etsy StatsD does, and the gauges whose value starts with a sign are relative.
+func (p *parser) parseStatsdMetricSamples(message []byte, dest []dogstatsdMetricSample) ([]dogstatsdMetricSample, error) {
+	rawName, message, found := bytes.Cut(message, colonSeparator)
+	if !found || len(message) == 0 {
+		return dest, fmt.Errorf("invalid statsd message format")
+	}
+	rawName = sanitizeStatsdName(rawName)
+	if len(rawName) == 0 {
+		return dest, fmt.Errorf("invalid statsd message format: empty name")
+	}
+	name := p.interner.LoadOrStore(rawName)
+
+	for message != nil {
+		var rawSample []byte
+		rawSample, message, _ = bytes.Cut(message, colonSeparator)
+
+		rawValue, rest := nextField(rawSample)
+		if rest == nil || len(rawValue) == 0 {
+			return dest, fmt.Errorf("invalid statsd sample %q", rawSample)
+		}
+		rawMetricType, rest := nextField(rest)
+		sample := dogstatsdMetricSample{
+			name:       name,
+			sampleRate: 1,
+		}
+		switch {
+		case bytes.Equal(rawMetricType, countSymbol):
+			sample.metricType = countType
+		case bytes.Equal(rawMetricType, gaugeSymbol):
+			sample.metricType = gaugeType
+		case bytes.Equal(rawMetricType, timingSymbol):
+			sample.metricType = timingType
+		case bytes.Equal(rawMetricType, setSymbol):
+			sample.metricType = setType
+		default:
+			return dest, fmt.Errorf("invalid statsd metric type: %q", rawMetricType)
+		}
+
+		if rest != nil {
+			rawSampleRate, rest := nextField(rest)
+			if rest != nil || !bytes.HasPrefix(rawSampleRate, sampleRateFieldPrefix) {
+				return dest, fmt.Errorf("invalid statsd sample %q", rawSampleRate)
+			}
+			sampleRate, err := parseMetricSampleSampleRate(rawSampleRate[len(sampleRateFieldPrefix):])
+			if err != nil || sampleRate <= 0 || sampleRate > 1 {
+				return dest, fmt.Errorf("invalid statsd sample rate %q", rawSampleRate)
+			}
+			// as with Etsy StatsD, the sample rate of the gauges and sets is ignored
+			if sample.metricType == countType || sample.metricType == timingType {
+				sample.sampleRate = sampleRate
+			}
+		}
+
+		if sample.metricType == setType {
+			sample.setValue = string(rawValue)
+		} else {
+			value, err := parseFloat64(rawValue)
+			if err != nil {
+				return dest, fmt.Errorf("could not parse statsd metric value %q: %v", rawValue, err)
+			}
+			sample.value = value
+			sample.relativeGauge = sample.metricType == gaugeType && (rawValue[0] == '+' || rawValue[0] == '-')
+		}
+		dest = append(dest, sample)
+	}
+	return dest, nil
+}
+
+// sanitizeStatsdName sanitizes a metric name like Etsy StatsD does: the sequences of
+// whitespaces are replaced by `_`, the slashes by `-`, and the characters other than
+// letters, digits, `_`, `-` and `.` are removed.
+func sanitizeStatsdName(name []byte) []byte {
+	valid := true
+	for _, c := range name {
+		if !isStatsdNameChar(c) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return name
+	}
+
+	sanitized := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isStatsdNameChar(c):
+			sanitized = append(sanitized, c)
+		case c == '/':
+			sanitized = append(sanitized, '-')
+		case isStatsdWhitespace(c):
+			if i == 0 || !isStatsdWhitespace(name[i-1]) {
+				sanitized = append(sanitized, '_')
+			}
+		}
+	}
+	return sanitized
+}
+
+func isStatsdNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.'
+}
+
+func isStatsdWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\v' || c == '\f'
+}
+
+// statsdGauges holds the last value of the Etsy StatsD gauges, which the relative gauges
+// modify. It is safe for concurrent use.
+type statsdGauges struct {
+	mu     sync.Mutex
+	values *simplelru.LRU[string, float64]
+}
+
+func newStatsdGauges(size int) *statsdGauges {
+	values, _ := simplelru.NewLRU[string, float64](size, nil)
+	return &statsdGauges{values: values}
+}
+
+// resolve records the value of the gauge and returns it. The value of a relative gauge
+// is added to the last value of the gauge, or to 0 if it has none.
+func (g *statsdGauges) resolve(name string, value float64, relative bool) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if relative {
+		if last, found := g.values.Get(name); found {
+			value += last
+		}
+	}
+	g.values.Add(name, value)
+	return value
+}
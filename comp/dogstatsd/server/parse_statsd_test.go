@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func parseStatsdMetricSamples(t *testing.T, rawSample []byte) ([]dogstatsdMetricSample, error) {
+	cfg := fxutil.Test[config.Component](t, fx.Options(
+		config.MockModule(),
+		fx.Replace(config.MockParams{Overrides: map[string]any{}}),
+	))
+
+	p := newParser(cfg, newFloat64ListPool(), 1)
+	return p.parseStatsdMetricSamples(rawSample, nil)
+}
+
+func TestParseStatsd(t *testing.T) {
+	for _, tc := range []struct {
+		message  string
+		expected dogstatsdMetricSample
+	}{
+		{"gorets:1|c", dogstatsdMetricSample{name: "gorets", value: 1, metricType: countType, sampleRate: 1}},
+		{"gorets:-3|c|@0.1", dogstatsdMetricSample{name: "gorets", value: -3, metricType: countType, sampleRate: 0.1}},
+		{"glork:320|ms|@0.5", dogstatsdMetricSample{name: "glork", value: 320, metricType: timingType, sampleRate: 0.5}},
+		{"gaugor:333|g|@0.5", dogstatsdMetricSample{name: "gaugor", value: 333, metricType: gaugeType, sampleRate: 1}},
+		{"gaugor:-10|g", dogstatsdMetricSample{name: "gaugor", value: -10, metricType: gaugeType, sampleRate: 1, relativeGauge: true}},
+		{"gaugor:+4|g", dogstatsdMetricSample{name: "gaugor", value: 4, metricType: gaugeType, sampleRate: 1, relativeGauge: true}},
+		{"uniques:765|s", dogstatsdMetricSample{name: "uniques", setValue: "765", metricType: setType, sampleRate: 1}},
+		{"my app/req ms:1|c", dogstatsdMetricSample{name: "my_app-req_ms", value: 1, metricType: countType, sampleRate: 1}},
+		{"my  app$:1|c", dogstatsdMetricSample{name: "my_app", value: 1, metricType: countType, sampleRate: 1}},
+	} {
+		t.Run(tc.message, func(t *testing.T) {
+			samples, err := parseStatsdMetricSamples(t, []byte(tc.message))
+			require.NoError(t, err)
+			assert.Equal(t, []dogstatsdMetricSample{tc.expected}, samples)
+		})
+	}
+}
+
+func TestParseStatsdMultiple(t *testing.T) {
+	samples, err := parseStatsdMetricSamples(t, []byte("gorets:1|c:2|c|@0.5:320|ms"))
+	require.NoError(t, err)
+	assert.Equal(t, []dogstatsdMetricSample{
+		{name: "gorets", value: 1, metricType: countType, sampleRate: 1},
+		{name: "gorets", value: 2, metricType: countType, sampleRate: 0.5},
+		{name: "gorets", value: 320, metricType: timingType, sampleRate: 1},
+	}, samples)
+}
+
+func TestParseStatsdErrors(t *testing.T) {
+	for _, message := range []string{
+		"",
+		"gorets",
+		"gorets:",
+		":1|c",
+		"$$:1|c",
+		"gorets:1",
+		"gorets:|c",
+		"gorets:abc|c",
+		"gorets:1|h",
+		"gorets:1|d",
+		"gorets:1|c|#tag:value",
+		"gorets:1|c|@0",
+		"gorets:1|c|@1.5",
+		"gorets:1|c|@0.5|#tag",
+		"gorets:1|c::2|c",
+		"_e{5,4}:title|text",
+	} {
+		t.Run(message, func(t *testing.T) {
+			_, err := parseStatsdMetricSamples(t, []byte(message))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStatsdGauges(t *testing.T) {
+	gauges := newStatsdGauges(2)
+
+	assert.Equal(t, 5.0, gauges.resolve("a", 5, true))
+	assert.Equal(t, 3.0, gauges.resolve("a", -2, true))
+	assert.Equal(t, 10.0, gauges.resolve("a", 10, false))
+	assert.Equal(t, 12.0, gauges.resolve("a", 2, true))
+
+	// the least recently updated gauges are forgotten
+	gauges.resolve("b", 1, false)
+	gauges.resolve("c", 1, false)
+	assert.Equal(t, 2.0, gauges.resolve("a", 2, true))
+}
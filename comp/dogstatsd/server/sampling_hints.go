@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners/ratelimit"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// samplingHintsPath is the path of the sampling hints on the hints socket.
+	samplingHintsPath = "/sampling"
+
+	samplingHintsStopTimeout = 5 * time.Second
+)
+
+// samplingHintsServer exposes the sampling hints of the origin sampler over HTTP on a
+// Unix socket, for the clients to adjust their sample rate.
+type samplingHintsServer struct {
+	socketPath string
+	server     *http.Server
+}
+
+func newSamplingHintsServer(socketPath string, sampler *ratelimit.OriginSampler) (*samplingHintsServer, error) {
+	if fileInfo, err := os.Stat(socketPath); err == nil {
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot reuse %s socket path: path already exists and is not a UNIX socket", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("cannot remove stale UNIX socket: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %v", socketPath, err)
+	}
+	// the clients need the write permission to connect
+	if err := os.Chmod(socketPath, 0722); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("can't set the permissions of %s: %v", socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(samplingHintsPath, sampler)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd sampling hints server stopped: %v", err)
+		}
+	}()
+	log.Infof("dogstatsd sampling hints available on %s%s", socketPath, samplingHintsPath)
+
+	return &samplingHintsServer{
+		socketPath: socketPath,
+		server:     server,
+	}, nil
+}
+
+// stop stops the server, waiting for the requests in flight for a few seconds.
+func (s *samplingHintsServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), samplingHintsStopTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Errorf("Stopping dogstatsd sampling hints server: %v", err)
+	}
+}
@@ -15,17 +15,21 @@ import (
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	"go.uber.org/fx"
 
 	configComponent "github.com/DataDog/datadog-agent/comp/core/config"
 	logComponent "github.com/DataDog/datadog-agent/comp/core/log"
 	logComponentImpl "github.com/DataDog/datadog-agent/comp/core/log/logimpl"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/graphite"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners/ratelimit"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug/serverdebugimpl"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/tagrules"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -64,6 +68,17 @@ var (
 	once                  sync.Once
 )
 
+// protocol is the protocol spoken by the clients of a listener
+type protocol int
+
+const (
+	protocolDogStatsD protocol = iota
+	// protocolGraphite is the Graphite plaintext protocol
+	protocolGraphite
+	// protocolStatsD is the Etsy StatsD protocol
+	protocolStatsD
+)
+
 type dependencies struct {
 	fx.In
 
@@ -116,10 +131,16 @@ type server struct {
 	Debug                   serverdebug.Component
 
 	tCapture                replay.Component
+	originSampler           *ratelimit.OriginSampler
+	samplingHints           *samplingHintsServer
 	mapper                  *mapper.MetricMapper
 	eolTerminationUDP       bool
 	eolTerminationUDS       bool
 	eolTerminationNamedPipe bool
+	// protocols holds the protocol of the listeners not speaking DogStatsD
+	protocols         map[packets.SourceType]protocol
+	graphiteTemplates *graphite.Templates
+	statsdGauges      *statsdGauges
 	// disableVerboseLogs is a feature flag to disable the logs capable
 	// of flooding the logger output (e.g. parsing messages error).
 	// NOTE(remy): this should probably be dropped and use a throttler logger, see
@@ -143,6 +164,9 @@ type server struct {
 	originTelemetry bool
 
 	enrichConfig enrichConfig
+
+	// tagRulesLock serializes the reloads of the tag rules
+	tagRulesLock sync.Mutex
 }
 
 func initTelemetry(cfg config.Reader, logger logComponent.Component) {
@@ -262,6 +286,33 @@ func newServerCompat(cfg config.Reader, log logComponent.Component, capture repl
 		}
 	}
 
+	protocols := make(map[packets.SourceType]protocol)
+	for listener, name := range cfg.GetStringMapString("dogstatsd_listener_protocols") {
+		var sourceType packets.SourceType
+		switch listener {
+		case "udp":
+			sourceType = packets.UDP
+		case "uds":
+			sourceType = packets.UDS
+		case "tcp":
+			sourceType = packets.TCP
+		case "named_pipe":
+			sourceType = packets.NamedPipe
+		default:
+			log.Errorf("Invalid dogstatsd_listener_protocols listener: %s", listener)
+			continue
+		}
+		switch name {
+		case "dogstatsd":
+		case "graphite":
+			protocols[sourceType] = protocolGraphite
+		case "statsd":
+			protocols[sourceType] = protocolStatsD
+		default:
+			log.Errorf("Invalid dogstatsd_listener_protocols protocol for the %s listener: %s", listener, name)
+		}
+	}
+
 	s := &server{
 		log:                     log,
 		config:                  cfg,
@@ -283,6 +334,8 @@ func newServerCompat(cfg config.Reader, log logComponent.Component, capture repl
 		eolTerminationUDP:       eolTerminationUDP,
 		eolTerminationUDS:       eolTerminationUDS,
 		eolTerminationNamedPipe: eolTerminationNamedPipe,
+		protocols:               protocols,
+		statsdGauges:            newStatsdGauges(statsdGaugesSize),
 		disableVerboseLogs:      cfg.GetBool("dogstatsd_disable_verbose_logs"),
 		Debug:                   debug,
 		originTelemetry: cfg.GetBool("telemetry.enabled") &&
@@ -299,6 +352,7 @@ func newServerCompat(cfg config.Reader, log logComponent.Component, capture repl
 			defaultHostname:           defaultHostname,
 			serverlessMode:            serverless,
 			originOptOutEnabled:       cfg.GetBool("dogstatsd_origin_optout_enabled"),
+			tagRules:                  atomic.NewPointer[tagrules.Rules](nil),
 		},
 	}
 	return s
@@ -343,8 +397,11 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 		}
 	}
 
+	// the origin sampler measures the rate of the origins detected by the UDS listeners
+	s.originSampler = ratelimit.BuildOriginSampler(s.config)
+
 	if len(socketPath) > 0 {
-		unixListener, err := listeners.NewUDSDatagramListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture)
+		unixListener, err := listeners.NewUDSDatagramListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture, s.originSampler)
 		if err != nil {
 			s.log.Errorf("Can't init listener: %s", err.Error())
 		} else {
@@ -355,7 +412,7 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 
 	if len(socketStreamPath) > 0 {
 		s.log.Warnf("dogstatsd_stream_socket is not yet supported, run it at your own risk")
-		unixListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture)
+		unixListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture, s.originSampler)
 		if err != nil {
 			s.log.Errorf("Can't init listener: %s", err.Error())
 		} else {
@@ -373,6 +430,15 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture)
+		if err != nil {
+			s.log.Errorf("tcp listener error: %v", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture)
@@ -394,6 +460,23 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 	s.sharedPacketPoolManager = sharedPacketPoolManager
 	s.listeners = tmpListeners
 
+	// expose the sampling hints of the origins
+	// ----------------------
+
+	if s.originSampler != nil {
+		if !originDetection {
+			s.log.Warnf("dogstatsd_origin_sampling requires dogstatsd_origin_detection, no origin will be sampled")
+		}
+		if hintsSocket := s.config.GetString("dogstatsd_origin_sampling.hints_socket"); hintsSocket != "" {
+			hints, err := newSamplingHintsServer(hintsSocket, s.originSampler)
+			if err != nil {
+				s.log.Errorf("Can't expose the sampling hints: %v", err)
+			} else {
+				s.samplingHints = hints
+			}
+		}
+	}
+
 	// packets forwarding
 	// ----------------------
 
@@ -410,6 +493,21 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 		}
 	}
 
+	// convert the graphite paths
+	// ----------------------
+
+	templates, err := config.GetDogstatsdGraphiteTemplates(s.config)
+	if err != nil {
+		s.log.Warnf("Could not parse graphite templates: %v", err)
+	} else if len(templates) != 0 {
+		templatesInstance, err := graphite.NewTemplates(templates, s.config.GetInt("dogstatsd_mapper_cache_size"))
+		if err != nil {
+			s.log.Warnf("Could not create graphite templates: %v", err)
+		} else {
+			s.graphiteTemplates = templatesInstance
+		}
+	}
+
 	// start the workers processing the packets read on the socket
 	// ----------------------
 
@@ -441,9 +539,44 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 			s.mapper = mapperInstance
 		}
 	}
+
+	// rewrite some metric tags
+	// ----------------------
+
+	s.reloadTagRules()
+	s.config.OnUpdate(func(key string) {
+		if key == "dogstatsd_tag_rules" {
+			// the receivers are called while the configuration is locked
+			go s.reloadTagRules()
+		}
+	})
 	return nil
 }
 
+// reloadTagRules loads the tag rules from the configuration. The current rules are
+// kept if the new ones are invalid.
+func (s *server) reloadTagRules() {
+	s.tagRulesLock.Lock()
+	defer s.tagRulesLock.Unlock()
+
+	rules, err := config.GetDogstatsdTagRules(s.config)
+	if err != nil {
+		s.log.Warnf("Could not parse tag rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		s.enrichConfig.tagRules.Store(nil)
+		return
+	}
+	rulesInstance, err := tagrules.NewRules(rules, s.config.GetInt("dogstatsd_mapper_cache_size"))
+	if err != nil {
+		s.log.Warnf("Could not create tag rules: %v", err)
+		return
+	}
+	s.enrichConfig.tagRules.Store(rulesInstance)
+	s.log.Infof("Dogstatsd: %d tag rules loaded", len(rules))
+}
+
 func (s *server) Stop() {
 	if !s.IsRunning() {
 		return
@@ -458,6 +591,10 @@ func (s *server) Stop() {
 	if s.tCapture != nil {
 		s.tCapture.Stop()
 	}
+	if s.samplingHints != nil {
+		s.samplingHints.stop()
+		s.samplingHints = nil
+	}
 	s.health.Deregister() //nolint:errcheck
 	s.Started = false
 }
@@ -595,6 +732,10 @@ func (s *server) eolEnabled(sourceType packets.SourceType) bool {
 	return false
 }
 
+func (s *server) protocol(sourceType packets.SourceType) protocol {
+	return s.protocols[sourceType]
+}
+
 func (s *server) errLog(format string, params ...interface{}) {
 	if s.disableVerboseLogs {
 		s.log.Debugf(format, params...)
@@ -607,6 +748,7 @@ func (s *server) errLog(format string, params ...interface{}) {
 func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packets.Packet, samples metrics.MetricSampleBatch) metrics.MetricSampleBatch {
 	for _, packet := range packets {
 		s.log.Tracef("Dogstatsd receive: %q", packet.Contents)
+		protocol := s.protocol(packet.Source)
 		for {
 			message := nextMessage(&packet.Contents, s.eolEnabled(packet.Source))
 			if message == nil {
@@ -618,7 +760,11 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 			if s.Statistics != nil {
 				s.Statistics.StatEvent(1)
 			}
-			messageType := findMessageType(message)
+			// only the DogStatsD protocol supports service checks and events
+			messageType := metricSampleType
+			if protocol == protocolDogStatsD {
+				messageType = findMessageType(message)
+			}
 
 			switch messageType {
 			case serviceCheckType:
@@ -640,13 +786,27 @@ func (s *server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 
 				samples = samples[0:0]
 
-				samples, err = s.parseMetricMessage(samples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+				switch protocol {
+				case protocolGraphite:
+					samples, err = s.parseGraphiteMessage(samples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+				case protocolStatsD:
+					samples, err = s.parseStatsdMessage(samples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+				default:
+					samples, err = s.parseMetricMessage(samples, parser, message, packet.Origin, packet.ListenerID, s.originTelemetry)
+				}
 				if err != nil {
 					s.errLog("Dogstatsd: error parsing metric message '%q': %s", message, err)
 					continue
 				}
 
 				for idx := range samples {
+					if packet.SampleRate > 0 && packet.SampleRate < 1 && s.originSampler != nil {
+						// the origin of the packet is sampled, upscale the samples kept
+						if !s.originSampler.KeepSample(packet.SampleRate) {
+							continue
+						}
+						samples[idx].SampleRate *= packet.SampleRate
+					}
 					s.Debug.StoreMetricStats(samples[idx])
 
 					if samples[idx].Timestamp > 0.0 {
@@ -716,11 +876,7 @@ func (s *server) getOriginCounter(origin string) (okCnt telemetry.SimpleCounter,
 // is the first part aware of processing a late metric. Also, it may help us having a telemetry of a "late_metrics" type here
 // which we can't do today.
 func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, origin string, listenerID string, originTelemetry bool) ([]metrics.MetricSample, error) {
-	okCnt := tlmProcessedOk
-	errorCnt := tlmProcessedError
-	if origin != "" && originTelemetry {
-		okCnt, errorCnt = s.getOriginCounter(origin)
-	}
+	okCnt, errorCnt := s.processedCounters(origin, originTelemetry)
 
 	sample, err := parser.parseMetricSample(message)
 	if err != nil {
@@ -729,6 +885,65 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
+	return s.appendMetricSample(metricSamples, sample, origin, listenerID, okCnt), nil
+}
+
+// parseGraphiteMessage parses a message of the Graphite plaintext protocol, whose path is
+// converted by the graphite templates.
+func (s *server) parseGraphiteMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, origin string, listenerID string, originTelemetry bool) ([]metrics.MetricSample, error) {
+	okCnt, errorCnt := s.processedCounters(origin, originTelemetry)
+
+	sample, err := parser.parseGraphiteMetricSample(message)
+	if err != nil {
+		dogstatsdMetricParseErrors.Add(1)
+		errorCnt.Inc()
+		return metricSamples, err
+	}
+
+	if s.graphiteTemplates != nil {
+		if result := s.graphiteTemplates.Apply(sample.name); result != nil {
+			sample.name = result.Name
+			sample.tags = append(sample.tags, result.Tags...)
+		}
+	}
+
+	return s.appendMetricSample(metricSamples, sample, origin, listenerID, okCnt), nil
+}
+
+// parseStatsdMessage parses a message of the Etsy StatsD protocol, which may hold several
+// samples.
+func (s *server) parseStatsdMessage(metricSamples []metrics.MetricSample, parser *parser, message []byte, origin string, listenerID string, originTelemetry bool) ([]metrics.MetricSample, error) {
+	okCnt, errorCnt := s.processedCounters(origin, originTelemetry)
+
+	samples, err := parser.parseStatsdMetricSamples(message, parser.statsdSamples[:0])
+	parser.statsdSamples = samples
+	if err != nil {
+		dogstatsdMetricParseErrors.Add(1)
+		errorCnt.Inc()
+		return metricSamples, err
+	}
+
+	for _, sample := range samples {
+		if sample.metricType == gaugeType {
+			sample.value = s.statsdGauges.resolve(sample.name, sample.value, sample.relativeGauge)
+		}
+		metricSamples = s.appendMetricSample(metricSamples, sample, origin, listenerID, okCnt)
+	}
+	return metricSamples, nil
+}
+
+// processedCounters returns the telemetry counters of the processed metrics, tagged with
+// the origin if originTelemetry is true.
+func (s *server) processedCounters(origin string, originTelemetry bool) (telemetry.SimpleCounter, telemetry.SimpleCounter) {
+	if origin != "" && originTelemetry {
+		return s.getOriginCounter(origin)
+	}
+	return tlmProcessedOk, tlmProcessedError
+}
+
+// appendMetricSample maps and enriches the sample, and appends the resulting metric
+// samples to metricSamples.
+func (s *server) appendMetricSample(metricSamples []metrics.MetricSample, sample dogstatsdMetricSample, origin string, listenerID string, okCnt telemetry.SimpleCounter) []metrics.MetricSample {
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
@@ -738,24 +953,25 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		}
 	}
 
+	first := len(metricSamples)
 	metricSamples = enrichMetricSample(metricSamples, sample, origin, listenerID, s.enrichConfig)
 
 	if len(sample.values) > 0 {
 		s.sharedFloat64List.put(sample.values)
 	}
 
-	for idx := range metricSamples {
-		// All metricSamples already share the same Tags slice. We can
+	for idx := first; idx < len(metricSamples); idx++ {
+		// All the metricSamples of the sample already share the same Tags slice. We can
 		// extends the first one and reuse it for the rest.
-		if idx == 0 {
+		if idx == first {
 			metricSamples[idx].Tags = append(metricSamples[idx].Tags, s.extraTags...)
 		} else {
-			metricSamples[idx].Tags = metricSamples[0].Tags
+			metricSamples[idx].Tags = metricSamples[first].Tags
 		}
 		dogstatsdMetricPackets.Add(1)
 		okCnt.Inc()
 	}
-	return metricSamples, nil
+	return metricSamples
 }
 
 func (s *server) parseEventMessage(parser *parser, message []byte, origin string) (*event.Event, error) {
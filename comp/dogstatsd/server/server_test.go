@@ -8,8 +8,12 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -26,10 +30,13 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/log"
 	"github.com/DataDog/datadog-agent/comp/core/log/logimpl"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners/ratelimit"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug/serverdebugimpl"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
@@ -803,6 +810,210 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestTagRules(t *testing.T) {
+	datadogYaml := `
+dogstatsd_tag_rules:
+  - match: "test.*"
+    drop: ["id"]
+    rename:
+      environment: env
+`
+	deps := fulfillDepsWithConfigYaml(t, datadogYaml)
+	s := deps.Server.(*server)
+	cw := deps.Config.(config.ReaderWriter)
+	cw.SetWithoutSource("dogstatsd_port", listeners.RandomPortName)
+
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+
+	parse := func(message string) []string {
+		parser := newParser(deps.Config, newFloat64ListPool(), 1)
+		samples, err := s.parseMetricMessage(nil, parser, []byte(message), "", "", false)
+		require.NoError(t, err)
+		require.Len(t, samples, 1)
+		return samples[0].Tags
+	}
+
+	assert.Equal(t, []string{"env:prod"}, parse("test.metric:666|g|#environment:prod,id:1"))
+	assert.Equal(t, []string{"environment:prod", "id:1"}, parse("other.metric:666|g|#environment:prod,id:1"))
+
+	// the rules are reloaded when the configuration is updated
+	cw.Set("dogstatsd_tag_rules", []config.TagRule{{Match: "other.*", KeepOnly: []string{"id"}}}, pkgconfigmodel.SourceAgentRuntime)
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"id:1"}, parse("other.metric:666|g|#environment:prod,id:1"))
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"environment:prod", "id:1"}, parse("test.metric:666|g|#environment:prod,id:1"))
+
+	// invalid rules are ignored
+	cw.Set("dogstatsd_tag_rules", []config.TagRule{{Match: "test.*"}}, pkgconfigmodel.SourceAgentRuntime)
+	s.reloadTagRules()
+	assert.Equal(t, []string{"id:1"}, parse("other.metric:666|g|#environment:prod,id:1"))
+
+	// removing the rules disables them
+	cw.Set("dogstatsd_tag_rules", []config.TagRule{}, pkgconfigmodel.SourceAgentRuntime)
+	require.Eventually(t, func() bool {
+		return s.enrichConfig.tagRules.Load() == nil
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestGraphiteProtocol(t *testing.T) {
+	datadogYaml := `
+dogstatsd_listener_protocols:
+  udp: graphite
+dogstatsd_graphite_templates:
+  - match: "servers.*"
+    template: "_.host.name*"
+    tags: ["source:collectd"]
+`
+	deps := fulfillDepsWithConfigYaml(t, datadogYaml)
+	s := deps.Server.(*server)
+	deps.Config.(config.ReaderWriter).SetWithoutSource("dogstatsd_port", listeners.RandomPortName)
+
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.UDPLocalAddr())
+	require.NoError(t, err, "cannot connect to DSD socket")
+	defer conn.Close()
+
+	conn.Write([]byte("servers.web1.cpu.idle 42 1700000000\napps.requests;env=prod 3\n"))
+	samples, timedSamples := demux.WaitForSamples(time.Second * 2)
+	require.Len(t, samples, 1)
+	require.Len(t, timedSamples, 1)
+
+	assert.Equal(t, "cpu.idle", timedSamples[0].Name)
+	assert.Equal(t, 42.0, timedSamples[0].Value)
+	assert.Equal(t, metrics.GaugeType, timedSamples[0].Mtype)
+	// as with DogStatsD, the host tag sets the hostname
+	assert.Equal(t, "web1", timedSamples[0].Host)
+	assert.ElementsMatch(t, []string{"source:collectd"}, timedSamples[0].Tags)
+	assert.Equal(t, 1700000000.0, timedSamples[0].Timestamp)
+
+	assert.Equal(t, "apps.requests", samples[0].Name)
+	assert.Equal(t, 3.0, samples[0].Value)
+	assert.ElementsMatch(t, []string{"env:prod"}, samples[0].Tags)
+}
+
+func TestStatsdProtocol(t *testing.T) {
+	deps := fulfillDepsWithConfigOverride(t, map[string]interface{}{
+		"dogstatsd_port":               listeners.RandomPortName,
+		"dogstatsd_listener_protocols": map[string]string{"udp": "statsd"},
+	})
+	s := deps.Server.(*server)
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.UDPLocalAddr())
+	require.NoError(t, err, "cannot connect to DSD socket")
+	defer conn.Close()
+
+	conn.Write([]byte("gorets:1|c|@0.5:320|ms\ngaugor:10|g\ngaugor:-3|g\ngaugor:1|g|#tag:value\n"))
+	samples, _ := demux.WaitForNumberOfSamples(4, 0, time.Second*2)
+	require.Len(t, samples, 4)
+
+	assert.Equal(t, "gorets", samples[0].Name)
+	assert.Equal(t, metrics.CounterType, samples[0].Mtype)
+	assert.Equal(t, 0.5, samples[0].SampleRate)
+	assert.Equal(t, "gorets", samples[1].Name)
+	assert.Equal(t, metrics.HistogramType, samples[1].Mtype)
+	assert.Equal(t, 320.0, samples[1].Value)
+	assert.Equal(t, "gaugor", samples[2].Name)
+	assert.Equal(t, 10.0, samples[2].Value)
+	assert.Equal(t, "gaugor", samples[3].Name)
+	assert.Equal(t, 7.0, samples[3].Value)
+}
+
+func TestSampledPacket(t *testing.T) {
+	deps := fulfillDeps(t)
+	s := deps.Server.(*server)
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+
+	// the second metric sample is dropped
+	draws := []float64{0.1, 0.5, 0.2}
+	s.originSampler = ratelimit.NewOriginSampler(100, 0.1, time.Second, true)
+	s.originSampler.SetRandomSource(func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	})
+
+	eventOut, serviceOut := demux.GetEventsAndServiceChecksChannels()
+	batcher := newBatcher(demux)
+	parser := newParser(deps.Config, newFloat64ListPool(), 1)
+	packet := s.sharedPacketPoolManager.Get().(*packets.Packet)
+	packet.Contents = []byte("requests:1|c\n_e{5,4}:title|text\nrequests:1|c|@0.5\n_sc|agent.up|0\nlatency:10|g")
+	packet.SampleRate = 0.25
+	// the events and service checks are flushed to unbuffered channels
+	go s.parsePackets(batcher, parser, packets.Packets{packet}, nil)
+
+	// they are never dropped
+	select {
+	case events := <-eventOut:
+		assert.Len(t, events, 1)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+	select {
+	case serviceChecks := <-serviceOut:
+		assert.Len(t, serviceChecks, 1)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+
+	samples, _ := demux.WaitForNumberOfSamples(2, 0, time.Second*2)
+	require.Len(t, samples, 2)
+	assert.Equal(t, "requests", samples[0].Name)
+	assert.Equal(t, 0.25, samples[0].SampleRate)
+	assert.Equal(t, "latency", samples[1].Name)
+	assert.Equal(t, 0.25, samples[1].SampleRate)
+	assert.Empty(t, draws)
+	assert.Equal(t, 0.0, packet.SampleRate, "the sample rate is reset when the packet is put back in the pool")
+}
+
+func TestSamplingHintsSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "hints.socket")
+	deps := fulfillDepsWithConfigOverride(t, map[string]interface{}{
+		"dogstatsd_port":                         listeners.RandomPortName,
+		"dogstatsd_origin_sampling.enabled":      true,
+		"dogstatsd_origin_sampling.max_rate":     500,
+		"dogstatsd_origin_sampling.hints_socket": socketPath,
+	})
+	s := deps.Server.(*server)
+	demux := deps.Demultiplexer
+	defer demux.Stop(false)
+	requireStart(t, s, demux)
+	defer s.Stop()
+	require.NotNil(t, s.originSampler)
+	s.originSampler.Record("pid:42", 1)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	resp, err := client.Get("http://localhost" + samplingHintsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var hints ratelimit.OriginSamplingHints
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hints))
+	assert.Equal(t, 500.0, hints.MaxRate)
+	require.Len(t, hints.Origins, 1)
+	assert.Equal(t, "pid:42", hints.Origins[0].Origin)
+	assert.Equal(t, 1.0, hints.Origins[0].SampleRate)
+}
+
 func TestNewServerExtraTags(t *testing.T) {
 	cfg := make(map[string]interface{})
 
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package tagrules implements the rules rewriting the tags of the dogstatsd metrics.
+package tagrules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	matchTypeWildcard = metrics.MatchTypeWildcard
+	matchTypeRegex    = metrics.MatchTypeRegex
+)
+
+// Rules rewrites the tags of the metrics matching their patterns.
+// It is safe for concurrent use.
+type Rules struct {
+	rules []*rule
+	// cache holds the rules matching each metric name
+	cache *lru.Cache[string, []*rule]
+}
+
+// rule is a compiled config.TagRule. Its actions are applied in the following order:
+// drop, keep only, rename, rewrite.
+type rule struct {
+	regex    *regexp.Regexp
+	drop     map[string]struct{}
+	keepOnly map[string]struct{}
+	rename   map[string]string
+	rewrite  []*valueRewrite
+}
+
+type valueRewrite struct {
+	tag     string
+	regex   *regexp.Regexp
+	replace string
+}
+
+// NewRules creates, validates and prepares new Rules.
+func NewRules(configRules []config.TagRule, cacheSize int) (*Rules, error) {
+	rules := make([]*rule, 0, len(configRules))
+	for i, configRule := range configRules {
+		matchType := configRule.MatchType
+		if matchType == "" {
+			matchType = matchTypeWildcard
+		}
+		if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+			return nil, fmt.Errorf("rule num %d: invalid match type, must be `wildcard` or `regex`", i)
+		}
+		if configRule.Match == "" {
+			return nil, fmt.Errorf("rule num %d: match is required", i)
+		}
+		if len(configRule.Drop) == 0 && len(configRule.KeepOnly) == 0 && len(configRule.Rename) == 0 && len(configRule.Rewrite) == 0 {
+			return nil, fmt.Errorf("rule num %d: at least one of drop, keep_only, rename or rewrite is required", i)
+		}
+		regex, err := metrics.CompileMatchPattern(configRule.Match, matchType)
+		if err != nil {
+			return nil, fmt.Errorf("rule num %d: %v", i, err)
+		}
+		r := &rule{
+			regex:  regex,
+			rename: configRule.Rename,
+		}
+		if len(configRule.Drop) > 0 {
+			r.drop = toSet(configRule.Drop)
+		}
+		if len(configRule.KeepOnly) > 0 {
+			r.keepOnly = toSet(configRule.KeepOnly)
+		}
+		for j, rw := range configRule.Rewrite {
+			if rw.Tag == "" || rw.Match == "" {
+				return nil, fmt.Errorf("rule num %d, rewrite num %d: tag and match are required", i, j)
+			}
+			re, err := regexp.Compile(rw.Match)
+			if err != nil {
+				return nil, fmt.Errorf("rule num %d, rewrite num %d: cannot compile regex: %v", i, j, err)
+			}
+			r.rewrite = append(r.rewrite, &valueRewrite{tag: rw.Tag, regex: re, replace: rw.Replace})
+		}
+		rules = append(rules, r)
+	}
+	cache, err := lru.New[string, []*rule](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Rules{rules: rules, cache: cache}, nil
+}
+
+func toSet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set
+}
+
+// match returns the rules matching the metric name.
+func (r *Rules) match(metricName string) []*rule {
+	if matched, ok := r.cache.Get(metricName); ok {
+		return matched
+	}
+	var matched []*rule
+	for _, rule := range r.rules {
+		if rule.regex.MatchString(metricName) {
+			matched = append(matched, rule)
+		}
+	}
+	r.cache.Add(metricName, matched)
+	return matched
+}
+
+// Apply applies the rules matching the metric name to its tags, and returns the resulting
+// tags. The given slice is modified in place.
+func (r *Rules) Apply(metricName string, tags []string) []string {
+	for _, rule := range r.match(metricName) {
+		tags = rule.apply(tags)
+	}
+	return tags
+}
+
+func (r *rule) apply(tags []string) []string {
+	n := 0
+	for _, tag := range tags {
+		key, value, hasValue := strings.Cut(tag, ":")
+		if _, found := r.drop[key]; found {
+			continue
+		}
+		if _, found := r.keepOnly[key]; r.keepOnly != nil && !found {
+			continue
+		}
+		if newKey, found := r.rename[key]; found {
+			key = newKey
+			tag = key
+			if hasValue {
+				tag += ":" + value
+			}
+		}
+		if hasValue && len(r.rewrite) > 0 {
+			original := value
+			for _, rw := range r.rewrite {
+				if rw.tag == key {
+					value = rw.regex.ReplaceAllString(value, rw.replace)
+				}
+			}
+			if value != original {
+				if value == "" {
+					// the value was rewritten to nothing
+					continue
+				}
+				tag = key + ":" + value
+			}
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
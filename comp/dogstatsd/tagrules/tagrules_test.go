@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tagrules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestNewRulesErrors(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		rules []config.TagRule
+	}{
+		{
+			name:  "missing match",
+			rules: []config.TagRule{{Drop: []string{"id"}}},
+		},
+		{
+			name:  "invalid match type",
+			rules: []config.TagRule{{Match: "foo", MatchType: "glob", Drop: []string{"id"}}},
+		},
+		{
+			name:  "no action",
+			rules: []config.TagRule{{Match: "foo"}},
+		},
+		{
+			name:  "invalid wildcard",
+			rules: []config.TagRule{{Match: "foo.**", Drop: []string{"id"}}},
+		},
+		{
+			name:  "invalid wildcard characters",
+			rules: []config.TagRule{{Match: "foo.[a-z]", Drop: []string{"id"}}},
+		},
+		{
+			name:  "invalid regex",
+			rules: []config.TagRule{{Match: "foo.(", MatchType: "regex", Drop: []string{"id"}}},
+		},
+		{
+			name: "rewrite without tag",
+			rules: []config.TagRule{{Match: "foo", Rewrite: []config.TagValueRewrite{
+				{Match: "a", Replace: "b"},
+			}}},
+		},
+		{
+			name: "invalid rewrite regex",
+			rules: []config.TagRule{{Match: "foo", Rewrite: []config.TagValueRewrite{
+				{Tag: "id", Match: "(", Replace: "b"},
+			}}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := NewRules(scenario.rules, 10)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		rules        []config.TagRule
+		metricName   string
+		tags         []string
+		expectedTags []string
+	}{
+		{
+			name:         "drop",
+			rules:        []config.TagRule{{Match: "foo.*", Drop: []string{"id", "debug"}}},
+			metricName:   "foo.bar",
+			tags:         []string{"env:prod", "id:1", "debug", "id:2"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name:         "keep only",
+			rules:        []config.TagRule{{Match: "foo.*", KeepOnly: []string{"env", "service"}}},
+			metricName:   "foo.bar",
+			tags:         []string{"env:prod", "id:1", "service:web", "debug"},
+			expectedTags: []string{"env:prod", "service:web"},
+		},
+		{
+			name:         "rename",
+			rules:        []config.TagRule{{Match: "foo.*", Rename: map[string]string{"environment": "env", "debug": "dbg"}}},
+			metricName:   "foo.bar",
+			tags:         []string{"environment:prod", "id:1", "debug"},
+			expectedTags: []string{"env:prod", "id:1", "dbg"},
+		},
+		{
+			name: "rewrite",
+			rules: []config.TagRule{{Match: "foo.*", Rewrite: []config.TagValueRewrite{
+				{Tag: "path", Match: "/users/[0-9]+", Replace: "/users/_id_"},
+				{Tag: "code", Match: "^([0-9])[0-9]{2}$", Replace: "${1}xx"},
+			}}},
+			metricName:   "foo.bar",
+			tags:         []string{"path:/users/42/posts", "code:404", "other:/users/42"},
+			expectedTags: []string{"path:/users/_id_/posts", "code:4xx", "other:/users/42"},
+		},
+		{
+			name: "rewrite to an empty value drops the tag",
+			rules: []config.TagRule{{Match: "foo.*", Rewrite: []config.TagValueRewrite{
+				{Tag: "session", Match: ".*", Replace: ""},
+			}}},
+			metricName:   "foo.bar",
+			tags:         []string{"env:prod", "session:abc"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "actions order",
+			rules: []config.TagRule{{
+				Match:    "foo.*",
+				Drop:     []string{"id"},
+				KeepOnly: []string{"id", "environment"},
+				Rename:   map[string]string{"environment": "env"},
+				Rewrite:  []config.TagValueRewrite{{Tag: "env", Match: "^production$", Replace: "prod"}},
+			}},
+			metricName:   "foo.bar",
+			tags:         []string{"environment:production", "id:1", "service:web"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "rules are applied in order",
+			rules: []config.TagRule{
+				{Match: "foo.*", Rename: map[string]string{"environment": "env"}},
+				{Match: "foo.bar", Drop: []string{"env"}},
+				{Match: "foo.baz", KeepOnly: []string{"env"}},
+			},
+			metricName:   "foo.bar",
+			tags:         []string{"environment:prod", "id:1"},
+			expectedTags: []string{"id:1"},
+		},
+		{
+			name:         "wildcard does not match across dots",
+			rules:        []config.TagRule{{Match: "foo.*", Drop: []string{"id"}}},
+			metricName:   "foo.bar.baz",
+			tags:         []string{"env:prod", "id:1"},
+			expectedTags: []string{"env:prod", "id:1"},
+		},
+		{
+			name:         "regex",
+			rules:        []config.TagRule{{Match: `^foo\..+`, MatchType: "regex", Drop: []string{"id"}}},
+			metricName:   "foo.bar.baz",
+			tags:         []string{"env:prod", "id:1"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name:         "no match",
+			rules:        []config.TagRule{{Match: "foo.*", Drop: []string{"id"}}},
+			metricName:   "bar.foo",
+			tags:         []string{"env:prod", "id:1"},
+			expectedTags: []string{"env:prod", "id:1"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			rules, err := NewRules(scenario.rules, 10)
+			require.NoError(t, err)
+			assert.Equal(t, scenario.expectedTags, rules.Apply(scenario.metricName, scenario.tags))
+		})
+	}
+}
+
+func TestApplyCache(t *testing.T) {
+	rules, err := NewRules([]config.TagRule{{Match: "foo.*", Drop: []string{"id"}}}, 1)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, []string{"env:prod"}, rules.Apply("foo.bar", []string{"env:prod", "id:1"}))
+		assert.Equal(t, []string{"env:prod", "id:1"}, rules.Apply("bar.foo", []string{"env:prod", "id:1"}))
+	}
+	assert.Equal(t, 1, rules.cache.Len())
+}
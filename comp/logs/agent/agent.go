@@ -14,6 +14,7 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/fx"
 
+	"github.com/DataDog/datadog-agent/comp/aggregator/demultiplexer"
 	configComponent "github.com/DataDog/datadog-agent/comp/core/config"
 	logComponent "github.com/DataDog/datadog-agent/comp/core/log"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -56,6 +57,9 @@ type dependencies struct {
 	Log            logComponent.Component
 	Config         configComponent.Component
 	InventoryAgent inventoryagent.Component
+	// Demultiplexer receives the metrics of the generate_metric processing rules, which are
+	// ignored without it
+	Demultiplexer demultiplexer.Component `optional:"true"`
 }
 
 // agent represents the data pipeline that collects, decodes,
@@ -65,6 +69,7 @@ type agent struct {
 	log            logComponent.Component
 	config         pkgConfig.Reader
 	inventoryAgent inventoryagent.Component
+	demultiplexer  demultiplexer.Component
 
 	sources                   *sources.LogSources
 	services                  *service.Services
@@ -92,6 +97,7 @@ func newLogsAgent(deps dependencies) optional.Option[Component] {
 			log:            deps.Log,
 			config:         deps.Config,
 			inventoryAgent: deps.InventoryAgent,
+			demultiplexer:  deps.Demultiplexer,
 			started:        atomic.NewBool(false),
 
 			sources:  sources.NewLogSources(),
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	pkgConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -27,6 +29,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 )
 
+// metricSenderID is the ID of the sender of the metrics generated from the logs
+const metricSenderID checkid.ID = "logs_agent_generated_metrics"
+
 // NewAgent returns a new Logs Agent
 func (a *agent) SetupPipeline(
 	processingRules []*config.ProcessingRule,
@@ -41,8 +46,17 @@ func (a *agent) SetupPipeline(
 	destinationsCtx := client.NewDestinationsContext()
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver(nil)
 
+	// setup the sender of the metrics generated by the generate_metric processing rules
+	var metricSender sender.Sender
+	if a.demultiplexer != nil {
+		var err error
+		if metricSender, err = a.demultiplexer.GetSender(metricSenderID); err != nil {
+			a.log.Warnf("Metrics won't be generated from the logs: %v", err)
+		}
+	}
+
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, a.endpoints, destinationsCtx, metricSender)
 
 	// setup the launchers
 	lnchrs := launchers.NewLaunchers(a.sources, pipelineProvider, auditor, a.tracker)
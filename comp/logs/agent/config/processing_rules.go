@@ -16,15 +16,29 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	GenerateMetric = "generate_metric"
+)
+
+// Types of the metrics generated by the generate_metric rules
+const (
+	MetricTypeCount        = "count"
+	MetricTypeGauge        = "gauge"
+	MetricTypeDistribution = "distribution"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
-// be applied on log lines
+// be applied on log lines, or a rule generating metrics from them
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// MetricName, MetricType and ValueGroup configure generate_metric rules: the value of
+	// gauges and distributions is the named capture group ValueGroup, and the other named
+	// capture groups are submitted as tags
+	MetricName string `mapstructure:"metric_name" json:"metric_name,omitempty"`
+	MetricType string `mapstructure:"metric_type" json:"metric_type,omitempty"`
+	ValueGroup string `mapstructure:"value_group" json:"value_group,omitempty"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -42,7 +56,7 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, GenerateMetric:
 			break
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
@@ -53,10 +67,40 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+
+		if rule.Type == GenerateMetric {
+			if err := validateGenerateMetricRule(rule, re); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateGenerateMetricRule validates the metric of a generate_metric rule, and sets its default type
+func validateGenerateMetricRule(rule *ProcessingRule, re *regexp.Regexp) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric_name provided for processing rule: %s", rule.Name)
+	}
+
+	switch rule.MetricType {
+	case "":
+		rule.MetricType = MetricTypeCount
+	case MetricTypeCount:
+	case MetricTypeGauge, MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("no value_group provided for the %s of processing rule: %s", rule.MetricType, rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule `%s`", rule.MetricType, rule.Name)
+	}
+
+	if rule.ValueGroup != "" && re.SubexpIndex(rule.ValueGroup) < 0 {
+		return fmt.Errorf("value_group %s is not a named capture group of the pattern of processing rule: %s", rule.ValueGroup, rule.Name)
 	}
 	return nil
 }
@@ -69,7 +113,7 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -33,3 +33,22 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	rule := &ProcessingRule{Type: GenerateMetric, Name: "errors", Pattern: `" (?P<status>5\d\d) `, MetricName: "nginx.errors"}
+	assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+	assert.Equal(t, MetricTypeCount, rule.MetricType)
+
+	rule = &ProcessingRule{Type: GenerateMetric, Name: "bytes", Pattern: `(?P<bytes>\d+) bytes`, MetricName: "bytes", MetricType: MetricTypeDistribution, ValueGroup: "bytes"}
+	assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}))
+
+	invalidRules := []*ProcessingRule{
+		{Type: GenerateMetric, Name: "no_metric", Pattern: "error"},
+		{Type: GenerateMetric, Name: "invalid_type", Pattern: "error", MetricName: "errors", MetricType: "rate"},
+		{Type: GenerateMetric, Name: "no_value", Pattern: `(?P<bytes>\d+)`, MetricName: "bytes", MetricType: MetricTypeGauge},
+		{Type: GenerateMetric, Name: "unknown_value", Pattern: `(\d+)`, MetricName: "bytes", MetricType: MetricTypeGauge, ValueGroup: "bytes"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -2396,6 +2396,48 @@ func TestComputeStatsBySpanKind(t *testing.T) {
 	})
 }
 
+func TestFailoverEndpoints(t *testing.T) {
+	t.Run("api-key", func(t *testing.T) {
+		overrides := map[string]interface{}{
+			"apm_config.failover.endpoints": []string{"https://failover1.example.com", "https://failover2.example.com"},
+			"apm_config.failover.api_key":   "failover_key",
+		}
+
+		config := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{Overrides: overrides}),
+			MockModule(),
+		))
+		// underlying config
+		cfg := config.Object()
+
+		require.NotNil(t, cfg)
+		require.Len(t, cfg.FailoverEndpoints, 2)
+		assert.Equal(t, "https://failover1.example.com", cfg.FailoverEndpoints[0].Host)
+		assert.Equal(t, "failover_key", cfg.FailoverEndpoints[0].APIKey)
+		assert.Equal(t, "https://failover2.example.com", cfg.FailoverEndpoints[1].Host)
+		assert.Equal(t, "failover_key", cfg.FailoverEndpoints[1].APIKey)
+	})
+
+	t.Run("no-api-key", func(t *testing.T) {
+		overrides := map[string]interface{}{
+			"api_key":                       "main_key",
+			"apm_config.failover.endpoints": []string{"https://failover1.example.com"},
+		}
+
+		config := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{Overrides: overrides}),
+			MockModule(),
+		))
+		// underlying config
+		cfg := config.Object()
+
+		require.NotNil(t, cfg)
+		assert.Empty(t, cfg.FailoverEndpoints, "the main API key must not be sent to the failover endpoints")
+	})
+}
+
 func TestGenerateInstallSignature(t *testing.T) {
 	cfgDir := t.TempDir()
 	defer func() {
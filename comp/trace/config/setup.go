@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -635,6 +636,40 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.EVPProxy.MaxPayloadSize = core.GetInt64(k)
 	}
 	c.DebugServerPort = core.GetInt("apm_config.debug.port")
+	c.OpenMetricsStats = core.GetBool("apm_config.openmetrics_stats.enabled")
+	if k := "apm_config.openmetrics_stats.max_series"; core.IsSet(k) {
+		c.OpenMetricsStatsMaxSeries = core.GetInt(k)
+	}
+	c.CapturePath = filepath.Join(core.GetString("run_path"), "apm-capture")
+	if k := "apm_config.capture.path"; core.IsSet(k) {
+		c.CapturePath = core.GetString(k)
+	}
+	if k := "apm_config.failover.endpoints"; core.IsSet(k) {
+		// the failover endpoints may belong to another organization, so their API key
+		// must be given explicitly
+		apiKey := configUtils.SanitizeAPIKey(core.GetString("apm_config.failover.api_key"))
+		if apiKey == "" {
+			log.Errorf("'%s' requires 'apm_config.failover.api_key' to be set, failover is disabled", k)
+		} else {
+			for _, host := range core.GetStringSlice(k) {
+				c.FailoverEndpoints = append(c.FailoverEndpoints, &config.Endpoint{Host: host, APIKey: apiKey})
+			}
+		}
+	}
+	if k := "apm_config.failover.after_seconds"; core.IsSet(k) {
+		c.FailoverAfter = getDuration(core.GetInt(k))
+	}
+	c.SpillEnabled = core.GetBool("apm_config.spill.enabled")
+	c.SpillPath = filepath.Join(core.GetString("run_path"), "apm-spill")
+	if k := "apm_config.spill.path"; core.IsSet(k) {
+		c.SpillPath = core.GetString(k)
+	}
+	if k := "apm_config.spill.max_size_mb"; core.IsSet(k) {
+		c.SpillMaxSize = core.GetInt64(k) * 1024 * 1024
+	}
+	if k := "apm_config.spill.max_age_seconds"; core.IsSet(k) {
+		c.SpillMaxAge = getDuration(core.GetInt(k))
+	}
 	return nil
 }
 
@@ -227,6 +227,7 @@ type BufferedAggregator struct {
 
 	tagsStore              *tags.Store
 	checkSamplers          map[checkid.ID]*CheckSampler
+	histogramOverrides     *metrics.HistogramOverrides // nil when there are no overrides
 	serviceChecks          servicecheck.ServiceChecks
 	events                 event.Events
 	manifests              []*senderOrchestratorManifest
@@ -298,6 +299,7 @@ func NewBufferedAggregator(s serializer.MetricSerializer, eventPlatformForwarder
 
 		tagsStore:                   tagsStore,
 		checkSamplers:               make(map[checkid.ID]*CheckSampler),
+		histogramOverrides:          newHistogramOverrides(config.Datadog),
 		flushInterval:               flushInterval,
 		serializer:                  s,
 		eventPlatformForwarder:      eventPlatformForwarder,
@@ -920,6 +922,18 @@ func (agg *BufferedAggregator) handleRegisterSampler(id checkid.ID) {
 		config.Datadog.GetBool("check_sampler_context_metrics"),
 		config.Datadog.GetDuration("check_sampler_stateful_metric_expiration_time"),
 		agg.tagsStore,
+		agg.histogramOverrides,
 		id,
 	)
 }
+
+// newHistogramOverrides returns the overrides of the histogram configuration, or nil if
+// there are none or they are invalid.
+func newHistogramOverrides(cfg config.Reader) *metrics.HistogramOverrides {
+	overrides, err := metrics.NewHistogramOverrides(cfg)
+	if err != nil {
+		log.Errorf("Ignoring histogram_overrides: %v", err)
+		return nil
+	}
+	return overrides
+}
@@ -7,6 +7,7 @@ package aggregator
 
 import (
 	"math"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
@@ -22,30 +23,50 @@ const checksSourceTypeName = "System"
 // CheckSampler aggregates metrics from one Check instance
 type CheckSampler struct {
 	id                     checkid.ID
+	checkName              string
+	instanceName           string
 	series                 []*metrics.Serie
 	sketches               metrics.SketchSeriesList
 	contextResolver        *countBasedContextResolver
 	metrics                metrics.CheckMetrics
 	sketchMap              sketchMap
+	histogramOverrides     *metrics.HistogramOverrides
+	histToDistPrefix       string
 	lastBucketValue        map[ckey.ContextKey]int64
 	deregistered           bool
 	contextResolverMetrics bool
 }
 
-// newCheckSampler returns a newly initialized CheckSampler
-func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID) *CheckSampler {
+// newCheckSampler returns a newly initialized CheckSampler. histogramOverrides may be nil
+// for all the histograms to use the default configuration.
+func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, histogramOverrides *metrics.HistogramOverrides, id checkid.ID) *CheckSampler {
+	checkName, instanceName := checkAndInstanceNames(id)
 	return &CheckSampler{
 		id:                     id,
+		checkName:              checkName,
+		instanceName:           instanceName,
 		series:                 make([]*metrics.Serie, 0),
 		sketches:               make(metrics.SketchSeriesList, 0),
 		contextResolver:        newCountBasedContextResolver(expirationCount, cache, string(id)),
 		metrics:                metrics.NewCheckMetrics(expireMetrics, statefulTimeout),
 		sketchMap:              make(sketchMap),
+		histogramOverrides:     histogramOverrides,
+		histToDistPrefix:       config.Datadog.GetString("histogram_copy_to_distribution_prefix"),
 		lastBucketValue:        make(map[ckey.ContextKey]int64),
 		contextResolverMetrics: contextResolverMetrics,
 	}
 }
 
+// checkAndInstanceNames returns the check name and the instance name of a check ID built by
+// checkid.BuildID. The instance name is empty when the instance has no name.
+func checkAndInstanceNames(id checkid.ID) (string, string) {
+	parts := strings.Split(string(id), ":")
+	if len(parts) < 3 {
+		return parts[0], ""
+	}
+	return parts[0], strings.Join(parts[1:len(parts)-1], ":")
+}
+
 func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
 	contextKey := cs.contextResolver.trackContext(metricSample)
 
@@ -54,7 +75,27 @@ func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
 		return
 	}
 
-	if err := cs.metrics.AddSample(contextKey, metricSample, metricSample.Timestamp, 1, config.Datadog); err != nil {
+	var histogramConfig *metrics.HistogramConfig
+	if cs.histogramOverrides != nil && (metricSample.Mtype == metrics.HistogramType || metricSample.Mtype == metrics.HistorateType) {
+		if context, ok := cs.contextResolver.get(contextKey); ok {
+			histogramConfig = context.histogramConfig(cs.histogramOverrides, cs.checkName, cs.instanceName)
+		}
+	}
+
+	if histogramConfig != nil && metricSample.Mtype == metrics.HistogramType {
+		switch histogramConfig.Distribution {
+		case metrics.HistogramDistributionConvert:
+			cs.sketchMap.insert(int64(metricSample.Timestamp), contextKey, metricSample.Value, metricSample.SampleRate)
+			return
+		case metrics.HistogramDistributionCopy:
+			distSample := *metricSample
+			distSample.Name = cs.histToDistPrefix + distSample.Name
+			distSample.Mtype = metrics.DistributionType
+			cs.addSample(&distSample)
+		}
+	}
+
+	if err := cs.metrics.AddSampleWithHistogramConfig(contextKey, metricSample, metricSample.Timestamp, 1, config.Datadog, histogramConfig); err != nil {
 		log.Debugf("Ignoring sample '%s' on host '%s' and tags '%s': %s", metricSample.Name, metricSample.Host, metricSample.Tags, err)
 	}
 }
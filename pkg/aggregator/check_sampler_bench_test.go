@@ -38,7 +38,7 @@ func benchmarkAddBucket(bucketValue int64, b *testing.B) {
 	demux := InitAndStartAgentDemultiplexer(log, sharedForwarder, &orchestratorForwarder, options, "hostname")
 	defer demux.Stop(true)
 
-	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), nil, checkid.ID("hello:world:1234"))
 
 	bucket := &metrics.HistogramBucket{
 		Name:       "my.histogram",
@@ -57,7 +57,7 @@ func benchmarkAddBucket(bucketValue int64, b *testing.B) {
 }
 
 func benchmarkAddBucketWideBounds(bucketValue int64, b *testing.B) {
-	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), nil, checkid.ID("hello:world:1234"))
 
 	bounds := []float64{0, .0005, .001, .003, .005, .007, .01, .015, .02, .025, .03, .04, .05, .06, .07, .08, .09, .1, .5, 1, 5, 10}
 	bucket := &metrics.HistogramBucket{
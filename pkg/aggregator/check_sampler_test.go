@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -32,7 +33,7 @@ func generateContextKey(sample metrics.MetricSampleContext) ckey.ContextKey {
 }
 
 func testCheckGaugeSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -95,7 +96,7 @@ func TestCheckGaugeSampling(t *testing.T) {
 }
 
 func testCheckRateSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -148,7 +149,7 @@ func TestCheckRateSampling(t *testing.T) {
 }
 
 func testHistogramCountSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -213,7 +214,7 @@ func TestHistogramCountSampling(t *testing.T) {
 }
 
 func testCheckHistogramBucketSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -290,7 +291,7 @@ func TestCheckHistogramBucketSampling(t *testing.T) {
 }
 
 func testCheckHistogramBucketDontFlushFirstValue(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -345,7 +346,7 @@ func TestCheckHistogramBucketDontFlushFirstValue(t *testing.T) {
 }
 
 func testCheckHistogramBucketInfinityBucket(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:       "my.histogram",
@@ -380,7 +381,7 @@ func TestCheckHistogramBucketInfinityBucket(t *testing.T) {
 }
 
 func testCheckDistribution(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"))
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, nil, checkid.ID("hello:world:1234"))
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -412,3 +413,60 @@ func testCheckDistribution(t *testing.T, store *tags.Store) {
 func TestCheckDistribution(t *testing.T) {
 	testWithTagsStore(t, testCheckDistribution)
 }
+
+func TestCheckHistogramOverrides(t *testing.T) {
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "my.*", "check": "hello", "instance": "world", "aggregates": []string{"max"}, "percentiles": []string{}},
+		{"match": "my.*", "check": "hello", "distribution": "convert"},
+	})
+	overrides, err := metrics.NewHistogramOverrides(cfg)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		id               checkid.ID
+		expectedSeries   []string
+		expectedSketches []string
+	}{
+		{id: "hello:world:1234", expectedSeries: []string{"my.histogram.max"}},
+		{id: "hello:1234", expectedSketches: []string{"my.histogram"}},
+		{id: "bye:world:1234", expectedSeries: []string{
+			"my.histogram.max", "my.histogram.median", "my.histogram.avg", "my.histogram.count", "my.histogram.95percentile",
+		}},
+	} {
+		t.Run(string(tc.id), func(t *testing.T) {
+			checkSampler := newCheckSampler(1, true, true, 1*time.Second, tags.NewStore(false, "test"), overrides, tc.id)
+			checkSampler.addSample(&metrics.MetricSample{
+				Name:       "my.histogram",
+				Value:      1,
+				Mtype:      metrics.HistogramType,
+				SampleRate: 1,
+				Timestamp:  12345.0,
+			})
+			checkSampler.commit(12349.0)
+			series, sketches := checkSampler.flush()
+
+			var seriesNames, sketchNames []string
+			for _, serie := range series {
+				seriesNames = append(seriesNames, serie.Name)
+			}
+			for _, sketch := range sketches {
+				sketchNames = append(sketchNames, sketch.Name)
+			}
+			assert.ElementsMatch(t, tc.expectedSeries, seriesNames)
+			assert.ElementsMatch(t, tc.expectedSketches, sketchNames)
+		})
+	}
+}
+
+func TestCheckAndInstanceNames(t *testing.T) {
+	for id, expected := range map[checkid.ID][2]string{
+		"cpu":                        {"cpu", ""},
+		"cpu:1234":                   {"cpu", ""},
+		"http_check:My service:1234": {"http_check", "My service"},
+		"redis:host:6379:1234":       {"redis", "host:6379"},
+	} {
+		checkName, instanceName := checkAndInstanceNames(id)
+		assert.Equal(t, expected, [2]string{checkName, instanceName}, id)
+	}
+}
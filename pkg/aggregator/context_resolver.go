@@ -8,9 +8,11 @@ package aggregator
 import (
 	"fmt"
 	"io"
+	"strings"
 	"unsafe"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
@@ -28,14 +30,35 @@ type Context struct {
 	metricTags *tags.Entry
 	noIndex    bool
 	source     metrics.MetricSource
+	// histogramOverride is the configuration override of a histogram context, resolved
+	// once by the sampler. It is nil until resolved, and noHistogramOverride if none applies.
+	histogramOverride *metrics.HistogramConfig
 }
 
+// noHistogramOverride marks the contexts whose histogram configuration is not overridden.
+var noHistogramOverride = &metrics.HistogramConfig{}
+
 const (
 	// ContextSizeInBytes is the size of a context in bytes
 	// We count the size of the context key with the context.
 	ContextSizeInBytes = int(unsafe.Sizeof(Context{})) + int(unsafe.Sizeof(ckey.ContextKey(0)))
 )
 
+// histogramConfig returns the override of the configuration of the histogram context,
+// resolving it on the first call.
+func (c *Context) histogramConfig(overrides *metrics.HistogramOverrides, checkName, instanceName string) *metrics.HistogramConfig {
+	if c.histogramOverride == nil {
+		c.histogramOverride = overrides.Resolve(c.Name, checkName, instanceName)
+		if c.histogramOverride == nil {
+			c.histogramOverride = noHistogramOverride
+		}
+	}
+	if c.histogramOverride == noHistogramOverride {
+		return nil
+	}
+	return c.histogramOverride
+}
+
 // Tags returns tags for the context.
 func (c *Context) Tags() tagset.CompositeTags {
 	return tagset.NewCompositeTags(c.taggerTags.Tags(), c.metricTags.Tags())
@@ -71,6 +94,7 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	limiter          *limiter.Limiter // nil when contexts are not limited
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -78,7 +102,7 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 	return cr.keyGenerator.GenerateWithTags2(metricSampleContext.GetName(), metricSampleContext.GetHost(), cr.taggerBuffer, cr.metricBuffer)
 }
 
-func newContextResolver(cache *tags.Store, id string) *contextResolver {
+func newContextResolver(cache *tags.Store, id string, contextLimiter *limiter.Limiter) *contextResolver {
 	return &contextResolver{
 		id:               id,
 		contextsByKey:    make(map[ckey.ContextKey]*Context),
@@ -90,11 +114,13 @@ func newContextResolver(cache *tags.Store, id string) *contextResolver {
 		keyGenerator:     ckey.NewKeyGenerator(),
 		taggerBuffer:     tagset.NewHashingTagsAccumulator(),
 		metricBuffer:     tagset.NewHashingTagsAccumulator(),
+		limiter:          contextLimiter,
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false if the context is over the limits of the resolver and must be dropped.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
@@ -102,6 +128,22 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
+		for cr.limiter != nil {
+			ok, overflowKey := cr.limiter.Track(metricSampleContext.GetName(), cr.metricBuffer.Get())
+			if ok {
+				break
+			}
+			if overflowKey == "" {
+				return contextKey, false
+			}
+			// aggregate the context into an overflow context, which may already exist
+			cr.overflowTag(overflowKey)
+			contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+			if _, ok := cr.contextsByKey[contextKey]; ok {
+				return contextKey, true
+			}
+		}
+
 		mtype := metricSampleContext.GetMetricType()
 		context := &Context{
 			Name:       metricSampleContext.GetName(),
@@ -119,7 +161,19 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
 	}
 
-	return contextKey
+	return contextKey, true
+}
+
+// overflowTag replaces the value of the metric tags with the given key by limiter.OverflowValue.
+func (cr *contextResolver) overflowTag(key string) {
+	metricTags := append([]string(nil), cr.metricBuffer.Get()...)
+	cr.metricBuffer.Reset()
+	for _, t := range metricTags {
+		if k, _, _ := strings.Cut(t, ":"); k == key {
+			t = key + ":" + limiter.OverflowValue
+		}
+		cr.metricBuffer.Append(t)
+	}
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -136,6 +190,9 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 	delete(cr.contextsByKey, expiredContextKey)
 
 	if context != nil {
+		if cr.limiter != nil {
+			cr.limiter.Remove(context.Name, context.metricTags.Tags())
+		}
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
@@ -203,9 +260,9 @@ type timestampContextResolver struct {
 	lastSeenByKey map[ckey.ContextKey]float64
 }
 
-func newTimestampContextResolver(cache *tags.Store, id string) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, id string, contextLimiter *limiter.Limiter) *timestampContextResolver {
 	return &timestampContextResolver{
-		resolver:      newContextResolver(cache, id),
+		resolver:      newContextResolver(cache, id, contextLimiter),
 		lastSeenByKey: make(map[ckey.ContextKey]float64),
 	}
 }
@@ -221,11 +278,14 @@ func (cr *timestampContextResolver) updateTrackedContext(contextKey ckey.Context
 	return nil
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext)
-	cr.lastSeenByKey[contextKey] = currentTimestamp
-	return contextKey
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false if the context is over the limits of the resolver and must be dropped.
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) (ckey.ContextKey, bool) {
+	contextKey, ok := cr.resolver.trackContext(metricSampleContext)
+	if ok {
+		cr.lastSeenByKey[contextKey] = currentTimestamp
+	}
+	return contextKey, ok
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -275,7 +335,7 @@ type countBasedContextResolver struct {
 
 func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, id string) *countBasedContextResolver {
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(cache, id),
+		resolver:            newContextResolver(cache, id, nil),
 		expireCountByKey:    make(map[ckey.ContextKey]int64),
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
@@ -293,7 +353,7 @@ func (cr *countBasedContextResolver) updateMetrics(countsByMTypeGauge telemetry.
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
 func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext)
+	contextKey, _ := cr.resolver.trackContext(metricSampleContext) // contexts of checks are not limited
 	cr.expireCountByKey[contextKey] = cr.expireCount
 	return contextKey
 }
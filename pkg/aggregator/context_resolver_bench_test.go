@@ -26,7 +26,7 @@ func benchmarkContextResolver(numContexts int, b *testing.B) {
 		})
 	}
 	cache := tags.NewStore(true, "test")
-	cr := newContextResolver(cache, "0")
+	cr := newContextResolver(cache, "0", nil)
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
@@ -23,6 +23,16 @@ type ContextDebugRepr struct {
 	Source     metrics.MetricSource
 }
 
+// ContextLimitDebugRepr is the representation of a metric which hit the context limits.
+type ContextLimitDebugRepr struct {
+	Name              string
+	Contexts          int
+	DroppedSamples    uint64
+	OverflowedSamples uint64
+	// TagValues is the number of distinct values of each tag key of the metric.
+	TagValues map[string]int
+}
+
 func (cr *contextResolver) dumpContexts(dest io.Writer) error {
 	enc := json.NewEncoder(dest)
 
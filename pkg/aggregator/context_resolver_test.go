@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -74,12 +75,12 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 		SampleRate: 1,
 	}
 
-	contextResolver := newContextResolver(store, "test")
+	contextResolver := newContextResolver(store, "test", nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1)
-	contextKey2 := contextResolver.trackContext(&mSample2)
-	contextKey3 := contextResolver.trackContext(&mSample3)
+	contextKey1, _ := contextResolver.trackContext(&mSample1)
+	contextKey2, _ := contextResolver.trackContext(&mSample2)
+	contextKey3, _ := contextResolver.trackContext(&mSample3)
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1]
@@ -97,8 +98,8 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 
 	// If the struct changes it's ok to change these, but be careful if you notice that
 	// the size increases a lot.
-	assert.Equal(t, uint64(0x90), contextResolver.bytesByMtype[metrics.GaugeType])
-	assert.Equal(t, uint64(0x48), contextResolver.bytesByMtype[metrics.CountType])
+	assert.Equal(t, uint64(0xa0), contextResolver.bytesByMtype[metrics.GaugeType])
+	assert.Equal(t, uint64(0x50), contextResolver.bytesByMtype[metrics.CountType])
 	assert.Equal(t, uint64(0), contextResolver.bytesByMtype[metrics.RateType])
 	assert.Equal(t, uint64(0x2b), contextResolver.dataBytesByMtype[metrics.GaugeType])
 	assert.Equal(t, uint64(0x26), contextResolver.dataBytesByMtype[metrics.CountType])
@@ -131,11 +132,11 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo", "bar", "baz"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store, "test")
+	contextResolver := newTimestampContextResolver(store, "test", nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4)
-	contextKey2 := contextResolver.trackContext(&mSample2, 6)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6)
 
 	// With an expireTimestap of 3, both contexts are still valid
 	contextResolver.expireContexts(3, nil)
@@ -173,11 +174,11 @@ func testExpireContextsWithKeep(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo", "bar", "baz"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store, "test")
+	contextResolver := newTimestampContextResolver(store, "test", nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4)
-	contextKey2 := contextResolver.trackContext(&mSample2, 7)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 7)
 
 	keeperCalled := 0
 	keep := true
@@ -250,9 +251,9 @@ func TestCountBasedExpireContexts(t *testing.T) {
 }
 
 func testTagDeduplication(t *testing.T, store *tags.Store) {
-	resolver := newContextResolver(store, "test")
+	resolver := newContextResolver(store, "test", nil)
 
-	ckey := resolver.trackContext(&metrics.MetricSample{
+	ckey, _ := resolver.trackContext(&metrics.MetricSample{
 		Name: "foo",
 		Tags: []string{"bar", "bar"},
 	})
@@ -265,6 +266,54 @@ func TestTagDeduplication(t *testing.T) {
 	testWithTagsStore(t, testTagDeduplication)
 }
 
+func testContextLimiterDrop(t *testing.T, store *tags.Store) {
+	cr := newTimestampContextResolver(store, "test", limiter.New(2, nil, limiter.StrategyDrop))
+
+	key1, ok := cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"id:1"}}, 1)
+	require.True(t, ok)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"id:2"}}, 1)
+	require.True(t, ok)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"id:3"}}, 1)
+	assert.False(t, ok)
+	assert.Equal(t, 2, cr.length())
+
+	// existing contexts are still tracked
+	key, ok := cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"id:1"}}, 2)
+	assert.True(t, ok)
+	assert.Equal(t, key1, key)
+
+	// expired contexts make room for new ones
+	cr.expireContexts(2, nil)
+	_, ok = cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"id:3"}}, 3)
+	assert.True(t, ok)
+	assert.Equal(t, 2, cr.length())
+}
+
+func TestContextLimiterDrop(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterDrop)
+}
+
+func testContextLimiterOverflow(t *testing.T, store *tags.Store) {
+	cr := newContextResolver(store, "test", limiter.New(2, nil, limiter.StrategyOverflow))
+
+	for _, id := range []string{"id:1", "id:2", "id:3", "id:4"} {
+		_, ok := cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"env:prod", id}})
+		require.True(t, ok)
+	}
+	require.Equal(t, 3, cr.length())
+
+	key, ok := cr.trackContext(&metrics.MetricSample{Name: "foo", Tags: []string{"env:prod", "id:5"}})
+	require.True(t, ok)
+	context, found := cr.get(key)
+	require.True(t, found)
+	metrics.AssertCompositeTagsEqual(t, tagset.CompositeTagsFromSlice([]string{"env:prod", "id:overflow"}), context.Tags())
+	assert.Equal(t, 3, cr.length())
+}
+
+func TestContextLimiterOverflow(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterOverflow)
+}
+
 type mockSink []*metrics.Serie
 
 func (s *mockSink) Append(ms *metrics.Serie) {
@@ -288,7 +337,7 @@ func (s *mockSample) GetTags(tb, mb tagset.TagsAccumulator, _ metrics.EnrichTags
 }
 
 func TestOriginTelemetry(t *testing.T) {
-	r := newContextResolver(tags.NewStore(true, "test"), "test")
+	r := newContextResolver(tags.NewStore(true, "test"), "test", nil)
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"ook"}})
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"eek"}})
 	r.trackContext(&mockSample{"foo", []string{"bar"}, []string{"ook"}})
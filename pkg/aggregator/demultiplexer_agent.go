@@ -12,9 +12,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/spf13/cast"
+
 	"github.com/DataDog/datadog-agent/comp/core/log"
 	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
 	orchestratorforwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
@@ -39,6 +42,9 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (epforwarder.EventPlatformForwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	DogstatsdContextLimits() []ContextLimitDebugRepr
+	// LastFlushedMetrics returns the series and sketches of the last flush, when KeepLastFlush is enabled.
+	LastFlushedMetrics() (metrics.Series, metrics.SketchSeriesList)
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -64,6 +70,9 @@ type AgentDemultiplexer struct {
 
 	// sharded statsd time samplers
 	statsd
+
+	// lastFlush keeps the series and sketches of the last flush, nil when disabled.
+	lastFlush *lastFlush
 }
 
 // AgentDemultiplexerOptions are the options used to initialize a Demultiplexer.
@@ -78,6 +87,10 @@ type AgentDemultiplexerOptions struct {
 
 	UseDogstatsdContextLimiter bool
 	DogstatsdMaxMetricsTags    int
+
+	// KeepLastFlush keeps the series and sketches of the last flush in memory to expose them
+	// locally, for instance on the Prometheus scrape endpoint.
+	KeepLastFlush bool
 }
 
 // DefaultAgentDemultiplexerOptions returns the default options to initialize an AgentDemultiplexer.
@@ -98,6 +111,8 @@ type statsd struct {
 	// every metric to distribute.
 	pipelinesCount int
 	workers        []*timeSamplerWorker
+	// contextLimiter limits the contexts of each metric across all the workers, nil when disabled.
+	contextLimiter *limiter.Limiter
 	// shared metric sample pool between the dogstatsd server & the time sampler
 	metricSamplePool *metrics.MetricSamplePool
 
@@ -168,12 +183,13 @@ func initAgentDemultiplexer(log log.Component, sharedForwarder forwarder.Forward
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	contextLimiter := newDogstatsdContextLimiter(log, config.Datadog)
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(config.Datadog.GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, contextLimiter, agg.histogramOverrides, agg.hostname)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -222,11 +238,16 @@ func initAgentDemultiplexer(log log.Component, sharedForwarder forwarder.Forward
 		statsd: statsd{
 			pipelinesCount:    statsdPipelinesCount,
 			workers:           statsdWorkers,
+			contextLimiter:    contextLimiter,
 			metricSamplePool:  metricSamplePool,
 			noAggStreamWorker: noAggWorker,
 		},
 	}
 
+	if options.KeepLastFlush {
+		demux.lastFlush = &lastFlush{}
+	}
+
 	return demux
 }
 
@@ -449,6 +470,12 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			if d.lastFlush != nil {
+				var done func()
+				seriesSink, sketchesSink, done = d.lastFlush.record(seriesSink, sketchesSink)
+				defer done()
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -600,6 +627,46 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// DogstatsdContextLimits returns the dogstatsd metrics which hit the context limits.
+func (d *AgentDemultiplexer) DogstatsdContextLimits() []ContextLimitDebugRepr {
+	if d.statsd.contextLimiter == nil {
+		return nil
+	}
+	stats := d.statsd.contextLimiter.Stats()
+	reprs := make([]ContextLimitDebugRepr, 0, len(stats))
+	for _, s := range stats {
+		reprs = append(reprs, ContextLimitDebugRepr(s))
+	}
+	return reprs
+}
+
+// LastFlushedMetrics returns the series and sketches of the last flush, when KeepLastFlush is enabled.
+func (d *AgentDemultiplexer) LastFlushedMetrics() (metrics.Series, metrics.SketchSeriesList) {
+	if d.lastFlush == nil {
+		return nil, nil
+	}
+	return d.lastFlush.get()
+}
+
+// newDogstatsdContextLimiter returns the limiter of the dogstatsd contexts, nil if no limit is configured.
+func newDogstatsdContextLimiter(log log.Component, cfg model.Reader) *limiter.Limiter {
+	tagValueLimits := make(map[string]int)
+	for k, v := range cfg.GetStringMap("dogstatsd_context_limiter.tag_value_limits") {
+		limit, err := cast.ToIntE(v)
+		if err != nil || limit < 0 {
+			log.Warnf("Ignoring invalid dogstatsd_context_limiter.tag_value_limits value for tag %s: %v", k, v)
+			continue
+		}
+		tagValueLimits[k] = limit
+	}
+	strategy := cfg.GetString("dogstatsd_context_limiter.overflow_strategy")
+	if strategy != limiter.StrategyDrop && strategy != limiter.StrategyOverflow {
+		log.Warnf("Unknown dogstatsd_context_limiter.overflow_strategy %q, using %q", strategy, limiter.StrategyDrop)
+		strategy = limiter.StrategyDrop
+	}
+	return limiter.New(cfg.GetInt("dogstatsd_context_limiter.metric_limit"), tagValueLimits, strategy)
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
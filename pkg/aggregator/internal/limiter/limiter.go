@@ -0,0 +1,277 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package limiter limits the number of contexts tracked by the aggregator for each metric.
+package limiter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// OverflowValue is the value given to the tags aggregated in an overflow context.
+const OverflowValue = "overflow"
+
+const (
+	// StrategyDrop drops the contexts over the limits.
+	StrategyDrop = "drop"
+	// StrategyOverflow aggregates the contexts over the limits into contexts where the
+	// value of the highest cardinality tag is replaced by OverflowValue.
+	StrategyOverflow = "overflow"
+)
+
+var tlmLimited = telemetry.NewCounter("aggregator", "dogstatsd_samples_limited",
+	[]string{"reason", "action"}, "Count of dogstatsd samples of contexts over the per-metric limits, by reason and action taken")
+
+// metricEntry tracks the contexts of a metric.
+type metricEntry struct {
+	contexts int
+	// overflowContexts is the number of contexts having aggregated tags, which are
+	// limited separately so that they can be created once the metric hit its limit.
+	overflowContexts int
+	// tagValues holds the number of contexts having each value of each tag key,
+	// aggregated tags excluded.
+	tagValues map[string]map[string]int
+}
+
+// MetricStats describes a metric which hit the limits. The contexts over the limits are
+// not recorded, so each of their samples is counted.
+type MetricStats struct {
+	Name              string
+	Contexts          int
+	DroppedSamples    uint64
+	OverflowedSamples uint64
+	// TagValues is the number of distinct values of each tag key of the metric.
+	TagValues map[string]int
+}
+
+// Limiter limits the number of contexts of each metric, and optionally the number
+// of distinct values of some tag keys for each metric. Only the tags sent by the
+// client are considered, not the ones added by origin detection.
+//
+// A single Limiter is shared by all the samplers, and it is safe for concurrent use.
+// It is only consulted when a context is created or removed.
+type Limiter struct {
+	metricLimit    int
+	tagValueLimits map[string]int
+	overflow       bool
+
+	mu      sync.Mutex // guards below
+	metrics map[string]*metricEntry
+	limited map[string]*MetricStats
+}
+
+// New returns a Limiter allowing up to metricLimit contexts per metric (0 meaning
+// no limit), and up to tagValueLimits[key] distinct values of each given tag key per
+// metric. It returns nil when no limit is set.
+func New(metricLimit int, tagValueLimits map[string]int, strategy string) *Limiter {
+	if metricLimit <= 0 && len(tagValueLimits) == 0 {
+		return nil
+	}
+	return &Limiter{
+		metricLimit:    metricLimit,
+		tagValueLimits: tagValueLimits,
+		overflow:       strategy == StrategyOverflow,
+		metrics:        make(map[string]*metricEntry),
+		limited:        make(map[string]*MetricStats),
+	}
+}
+
+// Track records a new context of the metric name with the given metric tags and returns
+// true if it is within the limits. Otherwise the context is not recorded and,
+// when the overflow strategy is used, the key of the tag to aggregate is returned.
+// Contexts having aggregated tags are limited to metricLimit as well, on top of the
+// other contexts, and a context whose tags have all been aggregated is always recorded.
+func (l *Limiter) Track(name string, tags []string) (bool, string) {
+	tags = uniqueTags(tags)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.metrics[name]
+	if e == nil {
+		e = &metricEntry{tagValues: make(map[string]map[string]int)}
+		l.metrics[name] = e
+	}
+
+	reason, key := "", ""
+	for _, t := range tags {
+		k, v := splitTag(t)
+		limit, ok := l.tagValueLimits[k]
+		if !ok || v == OverflowValue {
+			continue
+		}
+		if values := e.tagValues[k]; values[v] == 0 && len(values) >= limit {
+			reason, key = "tag_limit", k
+			break
+		}
+	}
+	overflow := hasOverflowTag(tags)
+	if reason == "" && l.metricLimit > 0 {
+		if (!overflow && e.contexts >= l.metricLimit) || (overflow && e.overflowContexts >= l.metricLimit) {
+			reason, key = "metric_limit", e.highestCardinalityKey(tags)
+		}
+	}
+
+	if reason != "" {
+		if !l.overflow {
+			l.hit(name, reason, false)
+			return false, ""
+		}
+		if key != "" {
+			l.hit(name, reason, true)
+			return false, key
+		}
+	}
+
+	if overflow {
+		e.overflowContexts++
+	} else {
+		e.contexts++
+	}
+	for _, t := range tags {
+		k, v := splitTag(t)
+		if v == OverflowValue {
+			continue
+		}
+		values := e.tagValues[k]
+		if values == nil {
+			values = make(map[string]int)
+			e.tagValues[k] = values
+		}
+		values[v]++
+	}
+	return true, ""
+}
+
+// Remove forgets a context previously recorded by Track.
+func (l *Limiter) Remove(name string, tags []string) {
+	tags = uniqueTags(tags)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.metrics[name]
+	if e == nil {
+		return
+	}
+	if hasOverflowTag(tags) {
+		e.overflowContexts--
+	} else {
+		e.contexts--
+	}
+	if e.contexts <= 0 && e.overflowContexts <= 0 {
+		delete(l.metrics, name)
+		return
+	}
+	for _, t := range tags {
+		k, v := splitTag(t)
+		if v == OverflowValue {
+			continue
+		}
+		values := e.tagValues[k]
+		if values[v] <= 1 {
+			delete(values, v)
+		} else {
+			values[v]--
+		}
+		if len(values) == 0 {
+			delete(e.tagValues, k)
+		}
+	}
+}
+
+// Stats returns the metrics which hit the limits, from the most to the least limited.
+func (l *Limiter) Stats() []MetricStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]MetricStats, 0, len(l.limited))
+	for name, s := range l.limited {
+		s := *s
+		s.Contexts = 0
+		s.TagValues = make(map[string]int)
+		if e := l.metrics[name]; e != nil {
+			s.Contexts = e.contexts + e.overflowContexts
+			for k, values := range e.tagValues {
+				s.TagValues[k] = len(values)
+			}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		n, m := stats[i].DroppedSamples+stats[i].OverflowedSamples, stats[j].DroppedSamples+stats[j].OverflowedSamples
+		if n == m {
+			return stats[i].Name < stats[j].Name
+		}
+		return n > m
+	})
+	return stats
+}
+
+// hit records that a sample of the metric name was over the limits. Callers must guard!
+func (l *Limiter) hit(name string, reason string, overflowed bool) {
+	s := l.limited[name]
+	if s == nil {
+		s = &MetricStats{Name: name}
+		l.limited[name] = s
+	}
+	if overflowed {
+		s.OverflowedSamples++
+		tlmLimited.Inc(reason, "overflow")
+	} else {
+		s.DroppedSamples++
+		tlmLimited.Inc(reason, "drop")
+	}
+}
+
+// highestCardinalityKey returns the key of the given tags having the most distinct
+// values for the metric, ignoring the tags already aggregated.
+func (e *metricEntry) highestCardinalityKey(tags []string) string {
+	key, max := "", 0
+	for _, t := range tags {
+		k, v := splitTag(t)
+		if v == OverflowValue {
+			continue
+		}
+		if n := len(e.tagValues[k]); key == "" || n > max || (n == max && k < key) {
+			key, max = k, n
+		}
+	}
+	return key
+}
+
+// uniqueTags returns the sorted tags without duplicates, for Track and Remove to count the
+// tag values of a context the same way whatever the order of its tags.
+func uniqueTags(tags []string) []string {
+	unique := make([]string, len(tags))
+	copy(unique, tags)
+	sort.Strings(unique)
+	n := 0
+	for i, t := range unique {
+		if i == 0 || t != unique[n-1] {
+			unique[n] = t
+			n++
+		}
+	}
+	return unique[:n]
+}
+
+func hasOverflowTag(tags []string) bool {
+	for _, t := range tags {
+		if _, v := splitTag(t); v == OverflowValue {
+			return true
+		}
+	}
+	return false
+}
+
+func splitTag(tag string) (string, string) {
+	k, v, _ := strings.Cut(tag, ":")
+	return k, v
+}
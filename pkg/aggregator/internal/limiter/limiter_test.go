@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDisabled(t *testing.T) {
+	assert.Nil(t, New(0, nil, StrategyDrop))
+	assert.NotNil(t, New(1, nil, StrategyDrop))
+	assert.NotNil(t, New(0, map[string]int{"id": 1}, StrategyDrop))
+}
+
+func TestMetricLimitDrop(t *testing.T) {
+	l := New(2, nil, StrategyDrop)
+
+	ok, key := l.Track("foo", []string{"id:1"})
+	assert.True(t, ok)
+	assert.Empty(t, key)
+	ok, _ = l.Track("foo", []string{"id:2"})
+	assert.True(t, ok)
+	ok, key = l.Track("foo", []string{"id:3"})
+	assert.False(t, ok)
+	assert.Empty(t, key)
+
+	// other metrics are not affected
+	ok, _ = l.Track("bar", []string{"id:3"})
+	assert.True(t, ok)
+
+	// removing a context makes room for a new one
+	l.Remove("foo", []string{"id:1"})
+	ok, _ = l.Track("foo", []string{"id:3"})
+	assert.True(t, ok)
+
+	assert.Equal(t, []MetricStats{{
+		Name:           "foo",
+		Contexts:       2,
+		DroppedSamples: 1,
+		TagValues:      map[string]int{"id": 2},
+	}}, l.Stats())
+}
+
+func TestMetricLimitOverflow(t *testing.T) {
+	l := New(2, nil, StrategyOverflow)
+
+	ok, _ := l.Track("foo", []string{"env:prod", "id:1"})
+	require.True(t, ok)
+	ok, _ = l.Track("foo", []string{"env:prod", "id:2"})
+	require.True(t, ok)
+
+	// the tag with the most distinct values is aggregated
+	ok, key := l.Track("foo", []string{"env:prod", "id:3"})
+	assert.False(t, ok)
+	assert.Equal(t, "id", key)
+	ok, _ = l.Track("foo", []string{"env:prod", "id:overflow"})
+	assert.True(t, ok)
+	ok, _ = l.Track("foo", []string{"env:staging", "id:overflow"})
+	assert.True(t, ok)
+
+	// once the contexts with aggregated tags hit the limit too, the next tag is aggregated
+	ok, key = l.Track("foo", []string{"env:dev", "id:overflow"})
+	assert.False(t, ok)
+	assert.Equal(t, "env", key)
+
+	// the context is recorded once all its tags have been aggregated
+	ok, _ = l.Track("foo", []string{"env:overflow", "id:overflow"})
+	assert.True(t, ok)
+
+	stats := l.Stats()
+	require.Len(t, stats, 1)
+	assert.EqualValues(t, 2, stats[0].OverflowedSamples)
+	assert.EqualValues(t, 0, stats[0].DroppedSamples)
+	assert.Equal(t, 5, stats[0].Contexts)
+	assert.Equal(t, map[string]int{"env": 2, "id": 2}, stats[0].TagValues)
+
+	// removing contexts with aggregated tags makes room for new ones
+	l.Remove("foo", []string{"env:prod", "id:overflow"})
+	l.Remove("foo", []string{"env:overflow", "id:overflow"})
+	ok, _ = l.Track("foo", []string{"env:dev", "id:overflow"})
+	assert.True(t, ok)
+}
+
+func TestTagValueLimit(t *testing.T) {
+	l := New(0, map[string]int{"id": 2}, StrategyOverflow)
+
+	for _, tags := range [][]string{
+		{"env:prod", "id:1"},
+		{"env:prod", "id:2"},
+		{"env:staging", "id:2"},
+		{"env:dev", "id:1"},
+	} {
+		ok, _ := l.Track("foo", tags)
+		require.True(t, ok, tags)
+	}
+
+	ok, key := l.Track("foo", []string{"env:prod", "id:3"})
+	assert.False(t, ok)
+	assert.Equal(t, "id", key)
+	ok, _ = l.Track("foo", []string{"env:prod", "id:overflow"})
+	assert.True(t, ok)
+
+	// the limit applies per metric
+	ok, _ = l.Track("bar", []string{"id:3"})
+	assert.True(t, ok)
+}
+
+func TestStatsOrder(t *testing.T) {
+	l := New(1, nil, StrategyDrop)
+	l.Track("a", nil)
+	l.Track("b", nil)
+	for i := 0; i < 2; i++ {
+		l.Track("a", nil)
+		l.Track("b", nil)
+	}
+	l.Track("b", nil)
+
+	stats := l.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, "b", stats[0].Name)
+	assert.EqualValues(t, 3, stats[0].DroppedSamples)
+	assert.Equal(t, "a", stats[1].Name)
+	assert.EqualValues(t, 2, stats[1].DroppedSamples)
+}
+
+func TestDroppedSamples(t *testing.T) {
+	l := New(1, nil, StrategyDrop)
+	ok, _ := l.Track("foo", []string{"id:1"})
+	require.True(t, ok)
+
+	// the dropped context isn't recorded, each of its samples is counted
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Track("foo", []string{"id:2"})
+		assert.False(t, ok)
+	}
+
+	stats := l.Stats()
+	require.Len(t, stats, 1)
+	assert.EqualValues(t, 3, stats[0].DroppedSamples)
+	assert.Equal(t, 1, stats[0].Contexts)
+}
+
+func TestRemoveUniqueTags(t *testing.T) {
+	l := New(10, nil, StrategyDrop)
+
+	// the tags given to Track may be unsorted and contain duplicates
+	ok, _ := l.Track("foo", []string{"id:1", "env:prod", "id:1"})
+	require.True(t, ok)
+	ok, _ = l.Track("foo", []string{"id:2"})
+	require.True(t, ok)
+
+	l.Remove("foo", []string{"env:prod", "id:1"})
+	assert.Equal(t, map[string]map[string]int{"id": {"2": 1}}, l.metrics["foo"].tagValues)
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// lastFlush keeps the series and sketches of the last flush of the demultiplexer,
+// to expose them locally. They are snapshots of what was flushed, as the serializer
+// updates the flushed series and sketches in place.
+type lastFlush struct {
+	m        sync.RWMutex
+	series   metrics.Series
+	sketches metrics.SketchSeriesList
+}
+
+// record returns the sinks recording what is appended to seriesSink and sketchesSink, and
+// a function storing the recorded series and sketches once the flush is done.
+func (l *lastFlush) record(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink, func()) {
+	series := &seriesRecorder{sink: seriesSink}
+	sketches := &sketchesRecorder{sink: sketchesSink}
+	return series, sketches, func() {
+		l.m.Lock()
+		defer l.m.Unlock()
+		l.series = series.series
+		l.sketches = sketches.sketches
+	}
+}
+
+// get returns the series and sketches of the last flush.
+func (l *lastFlush) get() (metrics.Series, metrics.SketchSeriesList) {
+	l.m.RLock()
+	defer l.m.RUnlock()
+	return l.series, l.sketches
+}
+
+type seriesRecorder struct {
+	m      sync.Mutex
+	sink   metrics.SerieSink
+	series metrics.Series
+}
+
+// Append implements metrics.SerieSink.
+func (r *seriesRecorder) Append(serie *metrics.Serie) {
+	snapshot := snapshotSerie(serie)
+	r.m.Lock()
+	r.series = append(r.series, snapshot)
+	r.m.Unlock()
+	r.sink.Append(serie)
+}
+
+type sketchesRecorder struct {
+	m        sync.Mutex
+	sink     metrics.SketchesSink
+	sketches metrics.SketchSeriesList
+}
+
+// Append implements metrics.SketchesSink.
+func (r *sketchesRecorder) Append(sketch *metrics.SketchSeries) {
+	snapshot := snapshotSketch(sketch)
+	r.m.Lock()
+	r.sketches = append(r.sketches, snapshot)
+	r.m.Unlock()
+	r.sink.Append(sketch)
+}
+
+// snapshotSerie copies the name, the tags, the host, the device and the last point of a serie,
+// before it's handed to the serializer.
+func snapshotSerie(serie *metrics.Serie) *metrics.Serie {
+	snapshot := &metrics.Serie{
+		Name:   serie.Name,
+		Tags:   copyTags(serie.Tags),
+		Host:   serie.Host,
+		Device: serie.Device,
+	}
+	if len(serie.Points) > 0 {
+		snapshot.Points = []metrics.Point{serie.Points[len(serie.Points)-1]}
+	}
+	return snapshot
+}
+
+// snapshotSketch copies the name, the tags, the host and the last point of a sketch, before
+// it's handed to the serializer.
+func snapshotSketch(sketch *metrics.SketchSeries) *metrics.SketchSeries {
+	snapshot := &metrics.SketchSeries{
+		Name: sketch.Name,
+		Tags: copyTags(sketch.Tags),
+		Host: sketch.Host,
+	}
+	if len(sketch.Points) > 0 {
+		point := sketch.Points[len(sketch.Points)-1]
+		if point.Sketch != nil {
+			point.Sketch = point.Sketch.Copy()
+		}
+		snapshot.Points = []metrics.SketchPoint{point}
+	}
+	return snapshot
+}
+
+func copyTags(tags tagset.CompositeTags) tagset.CompositeTags {
+	return tagset.CompositeTagsFromSlice(append([]string(nil), tags.UnsafeToReadOnlySliceString()...))
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/log/logimpl"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestLastFlush(t *testing.T) {
+	l := &lastFlush{}
+	series, sketches := l.get()
+	assert.Empty(t, series)
+	assert.Empty(t, sketches)
+
+	var sentSeries metrics.Series
+	var sentSketches metrics.SketchSeriesList
+	seriesSink, sketchesSink, done := l.record(&sentSeries, &sentSketches)
+	seriesSink.Append(&metrics.Serie{Name: "first"})
+	seriesSink.Append(&metrics.Serie{Name: "second"})
+	sketchesSink.Append(&metrics.SketchSeries{Name: "third"})
+
+	// the recorded metrics are forwarded to the sinks right away, and kept once the flush is done
+	assert.Len(t, sentSeries, 2)
+	assert.Len(t, sentSketches, 1)
+	series, _ = l.get()
+	assert.Empty(t, series)
+
+	done()
+	series, sketches = l.get()
+	assert.Equal(t, sentSeries, series)
+	assert.Equal(t, sentSketches, sketches)
+
+	// the recorded metrics are snapshots, not updated by the serializer
+	sentSeries[0].Tags = tagset.CompositeTagsFromSlice([]string{"env:prod"})
+	sentSeries[0].Device = "sda"
+	series, _ = l.get()
+	assert.Zero(t, series[0].Tags.Len())
+	assert.Empty(t, series[0].Device)
+
+	// the next flush replaces them
+	_, _, done = l.record(&sentSeries, &sentSketches)
+	done()
+	series, sketches = l.get()
+	assert.Empty(t, series)
+	assert.Empty(t, sketches)
+}
+
+func TestDemuxLastFlushedMetrics(t *testing.T) {
+	s := &MockSerializerIterableSerie{}
+	s.On("SendServiceChecks", mock.Anything).Return(nil)
+	opts := demuxTestOptions()
+	opts.KeepLastFlush = true
+	deps := fxutil.Test[TestDeps](t, defaultforwarder.MockModule(), config.MockModule(), logimpl.MockModule())
+	demux := InitAndStartAgentDemultiplexerForTest(deps, opts, "")
+	defer demux.Stop(false)
+	demux.aggregator.serializer = s
+	demux.sharedSerializer = s
+
+	series, sketches := demux.LastFlushedMetrics()
+	assert.Empty(t, series)
+	assert.Empty(t, sketches)
+
+	demux.ForceFlushToSerializer(time.Now(), true)
+	series, sketches = demux.LastFlushedMetrics()
+	require.NotEmpty(t, series)
+	var sentNames, names []string
+	for _, serie := range s.series {
+		sentNames = append(sentNames, serie.Name)
+	}
+	for _, serie := range series {
+		names = append(names, serie.Name)
+		assert.Len(t, serie.Points, 1)
+	}
+	assert.ElementsMatch(t, sentNames, names)
+	assert.Empty(t, sketches)
+}
+
+func TestDemuxLastFlushedMetricsDisabled(t *testing.T) {
+	opts := demuxTestOptions()
+	deps := fxutil.Test[TestDeps](t, defaultforwarder.MockModule(), config.MockModule(), logimpl.MockModule())
+	demux := InitAndStartAgentDemultiplexerForTest(deps, opts, "")
+	defer demux.Stop(false)
+
+	assert.Nil(t, demux.lastFlush)
+	series, sketches := demux.LastFlushedMetrics()
+	assert.Nil(t, series)
+	assert.Nil(t, sketches)
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -35,6 +36,9 @@ type TimeSampler struct {
 	counterLastSampledByContext map[ckey.ContextKey]float64
 	lastCutOffTime              int64
 	sketchMap                   sketchMap
+	histogramOverrides          *metrics.HistogramOverrides
+	histToDistPrefix            string
+	histogramSketches           bool // the histograms are sent as sketches unless overridden
 
 	// id is a number to differentiate multiple time samplers
 	// since we start running more than one with the demultiplexer introduction
@@ -44,8 +48,10 @@ type TimeSampler struct {
 	hostname string
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostname string) *TimeSampler {
+// NewTimeSampler returns a newly initialized TimeSampler. contextLimiter may be nil
+// for the contexts not to be limited, and histogramOverrides may be nil for all the
+// histograms to use the default configuration.
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, contextLimiter *limiter.Limiter, histogramOverrides *metrics.HistogramOverrides, hostname string) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -55,10 +61,13 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostnam
 
 	s := &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newTimestampContextResolver(cache, idString),
+		contextResolver:             newTimestampContextResolver(cache, idString, contextLimiter),
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
+		histogramOverrides:          histogramOverrides,
+		histToDistPrefix:            config.Datadog.GetString("histogram_copy_to_distribution_prefix"),
+		histogramSketches:           config.Datadog.GetBool("histogram_percentiles_sketch.enabled"),
 		id:                          id,
 		idString:                    idString,
 		hostname:                    hostname,
@@ -82,9 +91,42 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	contextKey, ok := s.contextResolver.trackContext(metricSample, timestamp)
+	if !ok {
+		// the context is over the limits
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
+	var histogramConfig *metrics.HistogramConfig
+	if s.histogramOverrides != nil && (metricSample.Mtype == metrics.HistogramType || metricSample.Mtype == metrics.HistorateType) {
+		if context, ok := s.contextResolver.get(contextKey); ok {
+			histogramConfig = context.histogramConfig(s.histogramOverrides, "", "")
+		}
+	}
+
+	if metricSample.Mtype == metrics.HistogramType {
+		distribution := ""
+		if histogramConfig != nil {
+			distribution = histogramConfig.Distribution
+		}
+		// the histograms are sent as sketches, their percentiles are computed server side
+		if distribution == "" && s.histogramSketches {
+			distribution = metrics.HistogramDistributionConvert
+		}
+
+		switch distribution {
+		case metrics.HistogramDistributionConvert:
+			s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
+			return
+		case metrics.HistogramDistributionCopy:
+			distSample := *metricSample
+			distSample.Name = s.histToDistPrefix + distSample.Name
+			distSample.Mtype = metrics.DistributionType
+			s.sample(&distSample, timestamp)
+		}
+	}
+
 	switch metricSample.Mtype {
 	case metrics.DistributionType:
 		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
@@ -101,7 +143,7 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 		}
 
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil, config.Datadog); err != nil {
+		if err := bucketMetrics.AddSampleWithHistogramConfig(contextKey, metricSample, timestamp, s.interval, nil, config.Datadog, histogramConfig); err != nil {
 			log.Debugf("TimeSampler #%d Ignoring sample '%s' on host '%s' and tags '%s': %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
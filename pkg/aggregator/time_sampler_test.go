@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
@@ -32,7 +33,7 @@ func generateSerieContextKey(serie *metrics.Serie) ckey.ContextKey {
 }
 
 func testTimeSampler() *TimeSampler {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"), "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"), nil, nil, "host")
 	return sampler
 }
 
@@ -545,8 +546,92 @@ func TestFlushMissingContext(t *testing.T) {
 	assert.Len(t, sketches, 0)
 }
 
+func TestHistogramOverrides(t *testing.T) {
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("histogram_copy_to_distribution_prefix", "dist.")
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "latency.*", "aggregates": []string{"avg", "count"}, "percentiles": []string{"0.99"}},
+		{"match": "size.*", "distribution": "copy"},
+		{"match": "time.*", "distribution": "convert"},
+	})
+	overrides, err := metrics.NewHistogramOverrides(cfg)
+	require.NoError(t, err)
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"), nil, overrides, "host")
+
+	for _, name := range []string{"latency.request", "size.request", "time.request", "other.request"} {
+		sampler.sample(&metrics.MetricSample{
+			Name:       name,
+			Value:      1,
+			Mtype:      metrics.HistogramType,
+			SampleRate: 1,
+		}, 12345.0)
+	}
+
+	series, sketches := flushSerie(sampler, 12360.0)
+
+	var seriesNames []string
+	for _, serie := range series {
+		seriesNames = append(seriesNames, serie.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"latency.request.avg", "latency.request.count", "latency.request.99percentile",
+		"size.request.max", "size.request.median", "size.request.avg", "size.request.count", "size.request.95percentile",
+		"other.request.max", "other.request.median", "other.request.avg", "other.request.count", "other.request.95percentile",
+	}, seriesNames)
+
+	var sketchNames []string
+	for _, sketch := range sketches {
+		sketchNames = append(sketchNames, sketch.Name)
+	}
+	assert.ElementsMatch(t, []string{"dist.size.request", "time.request"}, sketchNames)
+}
+
+func TestHistogramSketches(t *testing.T) {
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("histogram_percentiles_sketch.enabled", true)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "size.*", "distribution": "copy"},
+		{"match": "latency.*", "percentiles": []string{"0.99"}},
+	})
+	overrides, err := metrics.NewHistogramOverrides(cfg)
+	require.NoError(t, err)
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"), nil, overrides, "host")
+
+	for _, name := range []string{"latency.request", "size.request", "other.request"} {
+		for _, value := range []float64{1, 2} {
+			sampler.sample(&metrics.MetricSample{
+				Name:       name,
+				Value:      value,
+				Mtype:      metrics.HistogramType,
+				SampleRate: 1,
+			}, 12345.0)
+		}
+	}
+
+	series, sketches := flushSerie(sampler, 12360.0)
+
+	// the copied histograms still send their aggregates
+	var seriesNames []string
+	for _, serie := range series {
+		seriesNames = append(seriesNames, serie.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"size.request.max", "size.request.median", "size.request.avg", "size.request.count", "size.request.95percentile",
+	}, seriesNames)
+
+	expSketch := &quantile.Sketch{}
+	expSketch.Insert(quantile.Default(), 1, 2)
+	var sketchNames []string
+	for _, sketch := range sketches {
+		sketchNames = append(sketchNames, sketch.Name)
+		require.Len(t, sketch.Points, 1)
+		assert.Equal(t, expSketch, sketch.Points[0].Sketch)
+	}
+	assert.ElementsMatch(t, []string{"latency.request", "size.request", "other.request"}, sketchNames)
+}
+
 func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nil, nil, "host")
 
 	sample := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -17,10 +17,21 @@ import (
 )
 
 const (
-	openmetricsCheckName  = "openmetrics"
-	openmetricsInitConfig = "{}"
+	openmetricsCheckName     = "openmetrics"
+	openmetricsCoreCheckName = "openmetrics_core"
+	openmetricsInitConfig    = "{}"
 )
 
+// checkName returns the name of the check to schedule, the openmetrics integration or its
+// core check counterpart. The core check only implements the version 2 of the integration,
+// so the version 1 instances are always scheduled on the integration.
+func checkName() string {
+	if config.Datadog.GetBool("prometheus_scrape.use_core_check") && config.Datadog.GetInt("prometheus_scrape.version") >= 2 {
+		return openmetricsCoreCheckName
+	}
+	return openmetricsCheckName
+}
+
 // buildInstances generates check config instances based on the Prometheus config and the object annotations
 // The second returned value is true if more than one instance is found
 func buildInstances(pc *types.PrometheusCheck, annotations map[string]string, namespacedName string) ([]integration.Data, bool) {
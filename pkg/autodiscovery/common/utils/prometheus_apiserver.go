@@ -41,7 +41,7 @@ func ConfigsForService(pc *types.PrometheusCheck, svc *v1.Service) []integration
 	if found {
 		serviceID := apiserver.EntityForService(svc)
 		configs = append(configs, integration.Config{
-			Name:          openmetricsCheckName,
+			Name:          checkName(),
 			InitConfig:    integration.Data(openmetricsInitConfig),
 			Instances:     instances,
 			ClusterCheck:  true,
@@ -67,7 +67,7 @@ func ConfigsForServiceEndpoints(pc *types.PrometheusCheck, svc *v1.Service, ep *
 
 				epConfig := integration.Config{
 					ServiceID:     endpointsID,
-					Name:          openmetricsCheckName,
+					Name:          checkName(),
 					InitConfig:    integration.Data(openmetricsInitConfig),
 					Instances:     instances,
 					ClusterCheck:  true,
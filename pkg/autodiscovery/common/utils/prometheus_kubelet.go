@@ -53,7 +53,7 @@ func ConfigsForPod(pc *types.PrometheusCheck, pod *kubelet.Pod) []integration.Co
 				continue
 			}
 			configs = append(configs, integration.Config{
-				Name:          openmetricsCheckName,
+				Name:          checkName(),
 				InitConfig:    integration.Data(openmetricsInitConfig),
 				Instances:     instances,
 				Provider:      names.PrometheusPods,
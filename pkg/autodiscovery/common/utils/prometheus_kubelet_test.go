@@ -21,12 +21,13 @@ import (
 
 func TestConfigsForPod(t *testing.T) {
 	tests := []struct {
-		name    string
-		check   *types.PrometheusCheck
-		version int
-		pod     *kubelet.Pod
-		want    []integration.Config
-		matched bool
+		name      string
+		check     *types.PrometheusCheck
+		version   int
+		coreCheck bool
+		pod       *kubelet.Pod
+		want      []integration.Config
+		matched   bool
 	}{
 		{
 			name:    "nominal case v1",
@@ -98,6 +99,78 @@ func TestConfigsForPod(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "nominal case core check",
+			check:     types.DefaultPrometheusCheck,
+			version:   2,
+			coreCheck: true,
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Name:        "foo-pod",
+					Annotations: map[string]string{"prometheus.io/scrape": "true"},
+				},
+				Status: kubelet.Status{
+					Containers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+					AllContainers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+				},
+			},
+			want: []integration.Config{
+				{
+					Name:          "openmetrics_core",
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data(`{"namespace":"","metrics":[".*"],"openmetrics_endpoint":"http://%%host%%:%%port%%/metrics"}`)},
+					Provider:      names.PrometheusPods,
+					Source:        "prometheus_pods:foo-ctr-id",
+					ADIdentifiers: []string{"foo-ctr-id"},
+				},
+			},
+		},
+		{
+			name:      "core check v1",
+			check:     types.DefaultPrometheusCheck,
+			version:   1,
+			coreCheck: true,
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Name:        "foo-pod",
+					Annotations: map[string]string{"prometheus.io/scrape": "true"},
+				},
+				Status: kubelet.Status{
+					Containers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+					AllContainers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+				},
+			},
+			want: []integration.Config{
+				{
+					Name:          "openmetrics",
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data(`{"prometheus_url":"http://%%host%%:%%port%%/metrics","namespace":"","metrics":["*"]}`)},
+					Provider:      names.PrometheusPods,
+					Source:        "prometheus_pods:foo-ctr-id",
+					ADIdentifiers: []string{"foo-ctr-id"},
+				},
+			},
+		},
 		{
 			name: "custom openmetrics_endpoint",
 			check: &types.PrometheusCheck{
@@ -516,6 +589,7 @@ func TestConfigsForPod(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config.Datadog.SetWithoutSource("prometheus_scrape.version", tt.version)
+			config.Datadog.SetWithoutSource("prometheus_scrape.use_core_check", tt.coreCheck)
 			tt.check.Init(tt.version)
 			assert.ElementsMatch(t, tt.want, ConfigsForPod(tt.check, tt.pod))
 		})
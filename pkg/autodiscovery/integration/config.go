@@ -114,11 +114,15 @@ type CommonInstanceConfig struct {
 	Name                  string   `yaml:"name"`
 	Namespace             string   `yaml:"namespace"`
 	NoIndex               bool     `yaml:"no_index"`
+	RunTimeout            int      `yaml:"run_timeout,omitempty"`
+	Schedule              string   `yaml:"schedule,omitempty"`
+	ScheduleJitter        int      `yaml:"schedule_jitter,omitempty"`
 }
 
 // CommonGlobalConfig holds the reserved fields for the yaml init_config data
 type CommonGlobalConfig struct {
-	Service string `yaml:"service"`
+	Service    string `yaml:"service"`
+	RunTimeout int    `yaml:"run_timeout"`
 }
 
 // AdvancedADIdentifier contains user-defined autodiscovery information
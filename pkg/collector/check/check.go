@@ -72,6 +72,13 @@ type Info interface {
 	InstanceConfig() string
 }
 
+// GoCheck is implemented by the checks written in Go and running in the Agent process, which can
+// be cancelled while they run.
+type GoCheck interface {
+	// IsGoCheck returns whether the check is written in Go and runs in the Agent process
+	IsGoCheck() bool
+}
+
 // ErrSkipCheckInstance is returned from Configure() when a check is intentionally refusing to load a
 // check instance, and NOT due to an error. The distinction is important for deciding whether or not
 // to log the error and report it on the status page.
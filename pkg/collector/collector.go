@@ -159,6 +159,21 @@ func (c *collector) Stop() {
 		c.runner.Stop()
 		c.runner = nil
 	}
+
+	// cancel the checks for them to release their resources, like the plugin processes
+	var wg sync.WaitGroup
+	for _, ch := range c.checks {
+		wg.Add(1)
+		go func(ch check.Check) {
+			defer wg.Done()
+			if err := c.cancelCheck(ch, c.cancelCheckTimeout); err != nil {
+				log.Warnf("Error while stopping the collector: %v", err)
+			}
+		}(ch)
+	}
+	wg.Wait()
+	c.checks = make(map[checkid.ID]*middleware.CheckWrapper)
+
 	c.state.Store(stopped)
 }
 
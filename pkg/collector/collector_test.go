@@ -107,6 +107,16 @@ func (suite *CollectorTestSuite) TestStop() {
 	assert.Equal(suite.T(), stopped, suite.c.state.Load())
 }
 
+func (suite *CollectorTestSuite) TestStopCancelsChecks() {
+	ch := NewCheck()
+	_, err := suite.c.RunCheck(ch)
+	assert.Nil(suite.T(), err)
+
+	suite.c.Stop()
+	assert.Zero(suite.T(), len(suite.c.checks))
+	ch.AssertNumberOfCalls(suite.T(), "Cancel", 1)
+}
+
 func (suite *CollectorTestSuite) TestRunCheck() {
 	ch := NewCheck()
 
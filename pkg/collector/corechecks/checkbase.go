@@ -193,6 +193,11 @@ func (c *CheckBase) Stop() {}
 func (c *CheckBase) Cancel() {
 }
 
+// IsGoCheck returns true, the checks embedding CheckBase are written in Go.
+func (c *CheckBase) IsGoCheck() bool {
+	return true
+}
+
 // Interval returns the scheduling time for the check.
 // Long-running checks should override to return 0.
 func (c *CheckBase) Interval() time.Duration {
@@ -141,6 +141,9 @@ func (p *Processor) processContainer(sender sender.Sender, tags []string, contai
 		p.sendMetric(sender.Rate, "container.cpu.throttled", containerStats.CPU.ThrottledTime, tags)
 		p.sendMetric(sender.Rate, "container.cpu.throttled.periods", containerStats.CPU.ThrottledPeriods, tags)
 		p.sendMetric(sender.Rate, "container.cpu.partial_stall", containerStats.CPU.PartialStallTime, tags)
+		p.sendMetric(sender.Rate, "container.cpu.full_stall", containerStats.CPU.FullStallTime, tags)
+		p.sendPressureStats(sender, "container.cpu.partial_stall", containerStats.CPU.PartialStall, tags)
+		p.sendPressureStats(sender, "container.cpu.full_stall", containerStats.CPU.FullStall, tags)
 		// Convert CPU Limit to nanoseconds to allow easy percentage computation in the App.
 		if containerStats.CPU.Limit != nil {
 			p.sendMetric(sender.Gauge, "container.cpu.limit", pointer.Ptr(*containerStats.CPU.Limit*float64(time.Second/100)), tags)
@@ -162,6 +165,9 @@ func (p *Processor) processContainer(sender sender.Sender, tags []string, contai
 		p.sendMetric(sender.Gauge, "container.memory.commit.peak", containerStats.Memory.CommitPeakBytes, tags)
 		p.sendMetric(sender.Gauge, "container.memory.usage.peak", containerStats.Memory.Peak, tags)
 		p.sendMetric(sender.Rate, "container.memory.partial_stall", containerStats.Memory.PartialStallTime, tags)
+		p.sendMetric(sender.Rate, "container.memory.full_stall", containerStats.Memory.FullStallTime, tags)
+		p.sendPressureStats(sender, "container.memory.partial_stall", containerStats.Memory.PartialStall, tags)
+		p.sendPressureStats(sender, "container.memory.full_stall", containerStats.Memory.FullStall, tags)
 		p.sendMetric(sender.MonotonicCount, "container.memory.page_faults", containerStats.Memory.Pgfault, tags)
 		p.sendMetric(sender.MonotonicCount, "container.memory.major_page_faults", containerStats.Memory.Pgmajfault, tags)
 	}
@@ -183,6 +189,9 @@ func (p *Processor) processContainer(sender sender.Sender, tags []string, contai
 		}
 
 		p.sendMetric(sender.Rate, "container.io.partial_stall", containerStats.IO.PartialStallTime, tags)
+		p.sendMetric(sender.Rate, "container.io.full_stall", containerStats.IO.FullStallTime, tags)
+		p.sendPressureStats(sender, "container.io.partial_stall", containerStats.IO.PartialStall, tags)
+		p.sendPressureStats(sender, "container.io.full_stall", containerStats.IO.FullStall, tags)
 	}
 
 	if containerStats.PID != nil {
@@ -193,6 +202,17 @@ func (p *Processor) processContainer(sender sender.Sender, tags []string, contai
 	return nil
 }
 
+// sendPressureStats sends the PSI averages, as percentages of the time the container was stalled
+func (p *Processor) sendPressureStats(sender sender.Sender, metricPrefix string, stats *metrics.PressureStats, tags []string) {
+	if stats == nil {
+		return
+	}
+
+	p.sendMetric(sender.Gauge, metricPrefix+".avg10", stats.Avg10, tags)
+	p.sendMetric(sender.Gauge, metricPrefix+".avg60", stats.Avg60, tags)
+	p.sendMetric(sender.Gauge, metricPrefix+".avg300", stats.Avg300, tags)
+}
+
 func (p *Processor) sendMetric(senderFunc func(string, float64, string, []string), metricName string, value *float64, tags []string) {
 	if value == nil {
 		return
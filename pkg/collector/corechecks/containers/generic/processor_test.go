@@ -13,7 +13,9 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/workloadmeta"
 	taggerUtils "github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics/mock"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
 )
 
 func TestProcessorRunFullStatsLinux(t *testing.T) {
@@ -24,8 +26,21 @@ func TestProcessorRunFullStatsLinux(t *testing.T) {
 		CreateContainerMeta("docker", "cID101"),
 	}
 
+	fullStatsEntry := mock.GetFullSampleContainerEntry()
+	fullStatsEntry.ContainerStats.Memory.FullStallTime = pointer.Ptr(42000.0)
+	fullStatsEntry.ContainerStats.Memory.FullStall = &metrics.PressureStats{
+		Avg10:  pointer.Ptr(1.5),
+		Avg60:  pointer.Ptr(0.5),
+		Avg300: pointer.Ptr(0.25),
+	}
+	fullStatsEntry.ContainerStats.IO.PartialStall = &metrics.PressureStats{
+		Avg10:  pointer.Ptr(12.5),
+		Avg60:  pointer.Ptr(10.0),
+		Avg300: pointer.Ptr(8.0),
+	}
+
 	containersStats := map[string]mock.ContainerEntry{
-		"cID100": mock.GetFullSampleContainerEntry(),
+		"cID100": fullStatsEntry,
 		"cID101": {
 			ContainerStats: nil,
 		},
@@ -36,8 +51,8 @@ func TestProcessorRunFullStatsLinux(t *testing.T) {
 	assert.ErrorIs(t, err, nil)
 
 	expectedTags := []string{"runtime:docker"}
-	mockSender.AssertNumberOfCalls(t, "Rate", 20)
-	mockSender.AssertNumberOfCalls(t, "Gauge", 16)
+	mockSender.AssertNumberOfCalls(t, "Rate", 21)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 22)
 
 	mockSender.AssertMetricInRange(t, "Gauge", "container.uptime", 0, 600, "", expectedTags)
 	mockSender.AssertMetric(t, "Rate", "container.cpu.usage", 100, "", expectedTags)
@@ -59,8 +74,15 @@ func TestProcessorRunFullStatsLinux(t *testing.T) {
 	mockSender.AssertMetric(t, "Gauge", "container.memory.oom_events", 10, "", expectedTags)
 	mockSender.AssertMetric(t, "Gauge", "container.memory.usage.peak", 50000, "", expectedTags)
 	mockSender.AssertMetric(t, "Rate", "container.memory.partial_stall", 97000, "", expectedTags)
+	mockSender.AssertMetric(t, "Rate", "container.memory.full_stall", 42000, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.full_stall.avg10", 1.5, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.full_stall.avg60", 0.5, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.full_stall.avg300", 0.25, "", expectedTags)
 
 	mockSender.AssertMetric(t, "Rate", "container.io.partial_stall", 98000, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.io.partial_stall.avg10", 12.5, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.io.partial_stall.avg60", 10, "", expectedTags)
+	mockSender.AssertMetric(t, "Gauge", "container.io.partial_stall.avg300", 8, "", expectedTags)
 	expectedFooTags := taggerUtils.ConcatenateStringTags(expectedTags, "device:/dev/foo", "device_name:/dev/foo")
 	mockSender.AssertMetric(t, "Rate", "container.io.read", 100, "", expectedFooTags)
 	mockSender.AssertMetric(t, "Rate", "container.io.read.operations", 10, "", expectedFooTags)
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+// availability tracks the status of the target of an availability check, to send an event
+// when it goes down and when it recovers
+type availability struct {
+	sourceType string
+	lastStatus servicecheck.ServiceCheckStatus
+	reported   bool
+}
+
+// update records the status of the target, and sends an event when it changed from the
+// previous run. The first run only sends an event when the target is down.
+func (a *availability) update(s sender.Sender, name, target string, status servicecheck.ServiceCheckStatus, message string, tags []string) {
+	previous, reported := a.lastStatus, a.reported
+	a.lastStatus, a.reported = status, true
+
+	if status == previous && reported {
+		return
+	}
+	if status == servicecheck.ServiceCheckOK && !reported {
+		return
+	}
+
+	e := event.Event{
+		Ts:             time.Now().Unix(),
+		Priority:       event.EventPriorityNormal,
+		Tags:           tags,
+		AggregationKey: target,
+		SourceTypeName: a.sourceType,
+		EventType:      a.sourceType,
+	}
+	if status == servicecheck.ServiceCheckOK {
+		e.Title = fmt.Sprintf("[Recovered] %s is UP", name)
+		e.Text = fmt.Sprintf("%s is UP again: %s", name, target)
+		e.AlertType = event.EventAlertTypeSuccess
+	} else {
+		e.Title = fmt.Sprintf("[Alert] %s is DOWN", name)
+		e.Text = fmt.Sprintf("%s is DOWN: %s\n%s", name, target, message)
+		e.AlertType = event.EventAlertTypeError
+	}
+	s.Event(e)
+}
+
+// canConnect returns the value of the can_connect and cant_connect metrics for a status
+func canConnect(status servicecheck.ServiceCheckStatus) (float64, float64) {
+	if status == servicecheck.ServiceCheckOK {
+		return 1, 0
+	}
+	return 0, 1
+}
@@ -0,0 +1,441 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	httpCheckName = "http_check_core"
+
+	defaultHTTPTimeout        = 10 * time.Second
+	defaultHTTPStatusCode     = `(1|2|3)\d\d`
+	defaultDaysWarning        = 14
+	defaultDaysCritical       = 7
+	maxIncludedContentLength  = 200
+	httpEventSourceType       = "http_check"
+	httpCanConnectServiceName = "http.can_connect"
+	httpSSLCertServiceName    = "http.ssl_cert"
+)
+
+// HTTPCheck sends HTTP requests to an endpoint and reports its availability and response time,
+// with the metrics and service checks of the http_check integration
+type HTTPCheck struct {
+	core.CheckBase
+	cfg          *httpConfig
+	client       *http.Client
+	tags         []string
+	availability availability
+}
+
+type httpProxyConfig struct {
+	HTTP    string   `yaml:"http"`
+	HTTPS   string   `yaml:"https"`
+	NoProxy []string `yaml:"no_proxy"`
+}
+
+type httpInstanceConfig struct {
+	Name                       string            `yaml:"name"`
+	URL                        string            `yaml:"url"`
+	Method                     string            `yaml:"method"`
+	Data                       interface{}       `yaml:"data"`
+	Headers                    map[string]string `yaml:"headers"`
+	ExtraHeaders               map[string]string `yaml:"extra_headers"`
+	IncludeDefaultHeaders      *bool             `yaml:"include_default_headers"`
+	HTTPResponseStatusCode     string            `yaml:"http_response_status_code"`
+	ContentMatch               string            `yaml:"content_match"`
+	ReverseContentMatch        bool              `yaml:"reverse_content_match"`
+	IncludeContent             bool              `yaml:"include_content"`
+	Timeout                    float64           `yaml:"timeout"`
+	CollectResponseTime        *bool             `yaml:"collect_response_time"`
+	AllowRedirects             *bool             `yaml:"allow_redirects"`
+	CheckCertificateExpiration *bool             `yaml:"check_certificate_expiration"`
+	DaysWarning                int               `yaml:"days_warning"`
+	DaysCritical               int               `yaml:"days_critical"`
+	SecondsWarning             int               `yaml:"seconds_warning"`
+	SecondsCritical            int               `yaml:"seconds_critical"`
+	TLSVerify                  *bool             `yaml:"tls_verify"`
+	TLSCACert                  string            `yaml:"tls_ca_cert"`
+	TLSCert                    string            `yaml:"tls_cert"`
+	TLSPrivateKey              string            `yaml:"tls_private_key"`
+	Proxy                      *httpProxyConfig  `yaml:"proxy"`
+	SkipProxy                  bool              `yaml:"skip_proxy"`
+	Username                   string            `yaml:"username"`
+	Password                   string            `yaml:"password"`
+	SkipEvent                  *bool             `yaml:"skip_event"`
+	Tags                       []string          `yaml:"tags"`
+}
+
+type httpConfig struct {
+	instance          httpInstanceConfig
+	url               *url.URL
+	body              string
+	contentType       string
+	timeout           time.Duration
+	statusCodeRe      *regexp.Regexp
+	contentMatchRe    *regexp.Regexp
+	secondsWarning    int64
+	secondsCritical   int64
+	collectRespTime   bool
+	checkCertificate  bool
+	includeDefHeaders bool
+	skipEvent         bool
+}
+
+func (c *httpConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, &c.instance); err != nil {
+		return err
+	}
+	instance := &c.instance
+
+	if instance.URL == "" {
+		return errors.New("the url option is required")
+	}
+	u, err := url.Parse(instance.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %s", instance.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %s: the scheme must be http or https", instance.URL)
+	}
+	c.url = u
+
+	if instance.Name == "" {
+		instance.Name = instance.URL
+	}
+	instance.Method = strings.ToUpper(instance.Method)
+	if instance.Method == "" {
+		instance.Method = http.MethodGet
+	}
+
+	switch d := instance.Data.(type) {
+	case nil:
+	case string:
+		c.body = d
+	case map[interface{}]interface{}:
+		values := url.Values{}
+		for k, v := range d {
+			values.Set(fmt.Sprint(k), fmt.Sprint(v))
+		}
+		c.body = values.Encode()
+		c.contentType = "application/x-www-form-urlencoded"
+	default:
+		return fmt.Errorf("invalid data: %v", instance.Data)
+	}
+
+	c.timeout = defaultHTTPTimeout
+	if instance.Timeout > 0 {
+		c.timeout = time.Duration(instance.Timeout * float64(time.Second))
+	}
+
+	statusCode := instance.HTTPResponseStatusCode
+	if statusCode == "" {
+		statusCode = defaultHTTPStatusCode
+	}
+	// like the http_check integration, the expression only has to match the start of the status code
+	if c.statusCodeRe, err = regexp.Compile("^(?:" + statusCode + ")"); err != nil {
+		return fmt.Errorf("invalid http_response_status_code: %s", err)
+	}
+
+	if instance.ContentMatch != "" {
+		if c.contentMatchRe, err = regexp.Compile(instance.ContentMatch); err != nil {
+			return fmt.Errorf("invalid content_match: %s", err)
+		}
+	}
+
+	if instance.DaysWarning == 0 {
+		instance.DaysWarning = defaultDaysWarning
+	}
+	if instance.DaysCritical == 0 {
+		instance.DaysCritical = defaultDaysCritical
+	}
+	c.secondsWarning = int64(instance.DaysWarning) * 24 * 3600
+	if instance.SecondsWarning > 0 {
+		c.secondsWarning = int64(instance.SecondsWarning)
+	}
+	c.secondsCritical = int64(instance.DaysCritical) * 24 * 3600
+	if instance.SecondsCritical > 0 {
+		c.secondsCritical = int64(instance.SecondsCritical)
+	}
+
+	c.collectRespTime = pointer.ValueOrDefault(instance.CollectResponseTime, true)
+	c.checkCertificate = pointer.ValueOrDefault(instance.CheckCertificateExpiration, true) && u.Scheme == "https"
+	c.includeDefHeaders = pointer.ValueOrDefault(instance.IncludeDefaultHeaders, true)
+	c.skipEvent = pointer.ValueOrDefault(instance.SkipEvent, true)
+
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the requests and of the certificate expiration check
+func (c *httpConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !pointer.ValueOrDefault(c.instance.TLSVerify, true),
+	}
+
+	if c.instance.TLSCACert != "" {
+		caCert, err := os.ReadFile(c.instance.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA certificate: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificate found in %s", c.instance.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.instance.TLSCert != "" {
+		keyFile := c.instance.TLSPrivateKey
+		if keyFile == "" {
+			keyFile = c.instance.TLSCert
+		}
+		cert, err := tls.LoadX509KeyPair(c.instance.TLSCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *HTTPCheck) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	cfg := &httpConfig{}
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	transport := httputils.CreateHTTPTransport(config.Datadog)
+	transport.TLSClientConfig = tlsConfig
+	// a new connection is opened at each run, as the response time includes it
+	transport.DisableKeepAlives = true
+	if cfg.instance.Proxy != nil {
+		transport.Proxy = httputils.GetProxyTransportFunc(&model.Proxy{
+			HTTP:    cfg.instance.Proxy.HTTP,
+			HTTPS:   cfg.instance.Proxy.HTTPS,
+			NoProxy: cfg.instance.Proxy.NoProxy,
+		}, config.Datadog)
+	}
+	if cfg.instance.SkipProxy {
+		transport.Proxy = nil
+	}
+
+	c.client = &http.Client{
+		Transport: transport,
+		Timeout:   cfg.timeout,
+	}
+	if !pointer.ValueOrDefault(cfg.instance.AllowRedirects, true) {
+		c.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	c.cfg = cfg
+	c.tags = append([]string{"url:" + cfg.instance.URL, "instance:" + cfg.instance.Name}, cfg.instance.Tags...)
+	c.availability = availability{sourceType: httpEventSourceType}
+
+	return nil
+}
+
+// Run executes the check
+func (c *HTTPCheck) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	status, message := c.checkEndpoint(sender)
+
+	canConnect, cantConnect := canConnect(status)
+	sender.Gauge("network.http.can_connect", canConnect, "", c.tags)
+	sender.Gauge("network.http.cant_connect", cantConnect, "", c.tags)
+	sender.ServiceCheck(httpCanConnectServiceName, status, "", c.tags, message)
+	if !c.cfg.skipEvent {
+		c.availability.update(sender, c.cfg.instance.Name, c.cfg.instance.URL, status, message, c.tags)
+	}
+
+	if c.cfg.checkCertificate {
+		status, message := c.checkCertificate(sender)
+		sender.ServiceCheck(httpSSLCertServiceName, status, "", c.tags, message)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// checkEndpoint sends the request and returns the status of the endpoint
+func (c *HTTPCheck) checkEndpoint(sender sender.Sender) (servicecheck.ServiceCheckStatus, string) {
+	req, err := c.newRequest()
+	if err != nil {
+		return servicecheck.ServiceCheckCritical, err.Error()
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return servicecheck.ServiceCheckCritical, fmt.Sprintf("%s timed out after %s.", c.cfg.instance.URL, c.cfg.timeout)
+		}
+		return servicecheck.ServiceCheckCritical, err.Error()
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return servicecheck.ServiceCheckCritical, err.Error()
+	}
+	if c.cfg.collectRespTime {
+		sender.Gauge("network.http.response_time", time.Since(start).Seconds(), "", c.tags)
+	}
+
+	statusCode := fmt.Sprint(resp.StatusCode)
+	if !c.cfg.statusCodeRe.MatchString(statusCode) {
+		message := fmt.Sprintf("Incorrect HTTP return code for url %s. Expected %s, got %s.", c.cfg.instance.URL, c.cfg.statusCodeRe, statusCode)
+		if c.cfg.instance.IncludeContent {
+			message += "\nContent: " + truncate(string(content), maxIncludedContentLength)
+		}
+		return servicecheck.ServiceCheckCritical, message
+	}
+
+	if c.cfg.contentMatchRe != nil {
+		found := c.cfg.contentMatchRe.Match(content)
+		switch {
+		case found && c.cfg.instance.ReverseContentMatch:
+			return servicecheck.ServiceCheckCritical, fmt.Sprintf("Content %q found in response.", c.cfg.instance.ContentMatch)
+		case !found && !c.cfg.instance.ReverseContentMatch:
+			return servicecheck.ServiceCheckCritical, fmt.Sprintf("Content %q not found in response.", c.cfg.instance.ContentMatch)
+		}
+	}
+
+	return servicecheck.ServiceCheckOK, ""
+}
+
+func (c *HTTPCheck) newRequest() (*http.Request, error) {
+	var body io.Reader
+	if c.cfg.body != "" {
+		body = strings.NewReader(c.cfg.body)
+	}
+	req, err := http.NewRequestWithContext(context.TODO(), c.cfg.instance.Method, c.cfg.instance.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cfg.contentType != "" {
+		req.Header.Set("Content-Type", c.cfg.contentType)
+	}
+	if len(c.cfg.instance.Headers) > 0 {
+		// the headers replace the default ones
+		for k, v := range c.cfg.instance.Headers {
+			req.Header.Set(k, v)
+		}
+	} else if c.cfg.includeDefHeaders {
+		req.Header.Set("User-Agent", "Datadog Agent/"+version.AgentVersion)
+		req.Header.Set("Accept", "*/*")
+	}
+	for k, v := range c.cfg.instance.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
+	if c.cfg.instance.Username != "" {
+		req.SetBasicAuth(c.cfg.instance.Username, c.cfg.instance.Password)
+	}
+
+	return req, nil
+}
+
+// checkCertificate connects to the endpoint and returns the status of the expiration of its certificate
+func (c *HTTPCheck) checkCertificate(sender sender.Sender) (servicecheck.ServiceCheckStatus, string) {
+	host := c.cfg.url.Host
+	if c.cfg.url.Port() == "" {
+		host = net.JoinHostPort(c.cfg.url.Hostname(), "443")
+	}
+
+	tlsConfig := c.client.Transport.(*http.Transport).TLSClientConfig.Clone()
+	tlsConfig.ServerName = c.cfg.url.Hostname()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: c.cfg.timeout}, "tcp", host, tlsConfig)
+	if err != nil {
+		return servicecheck.ServiceCheckUnknown, err.Error()
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return servicecheck.ServiceCheckUnknown, "no certificate presented by " + host
+	}
+
+	secondsLeft := int64(time.Until(certs[0].NotAfter).Seconds())
+	daysLeft := math.Floor(float64(secondsLeft) / (24 * 3600))
+	sender.Gauge("http.ssl.days_left", daysLeft, "", c.tags)
+	sender.Gauge("http.ssl.seconds_left", float64(secondsLeft), "", c.tags)
+
+	switch {
+	case secondsLeft <= 0:
+		return servicecheck.ServiceCheckCritical, fmt.Sprintf("Certificate expired %s ago", time.Duration(-secondsLeft)*time.Second)
+	case secondsLeft < c.cfg.secondsCritical:
+		return servicecheck.ServiceCheckCritical, fmt.Sprintf("This cert TTL is critical: only %.0f days before it expires", daysLeft)
+	case secondsLeft < c.cfg.secondsWarning:
+		return servicecheck.ServiceCheckWarning, fmt.Sprintf("This cert is almost expired, only %.0f days left", daysLeft)
+	default:
+		return servicecheck.ServiceCheckOK, fmt.Sprintf("Days left: %.0f", daysLeft)
+	}
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length]
+}
+
+func httpFactory() check.Check {
+	return &HTTPCheck{
+		CheckBase: core.NewCheckBase(httpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(httpCheckName, httpFactory)
+}
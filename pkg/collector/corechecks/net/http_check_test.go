@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+func newHTTPCheck(t *testing.T, instance string) (*HTTPCheck, *mocksender.MockSender) {
+	c := httpFactory().(*HTTPCheck)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+	return c, mockSender
+}
+
+func TestHTTPCheckOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "ping", string(body))
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "secret", password)
+		fmt.Fprint(w, "status: healthy")
+	}))
+	defer server.Close()
+
+	c, s := newHTTPCheck(t, fmt.Sprintf(`
+name: web
+url: %s
+method: post
+data: ping
+headers:
+  X-Foo: bar
+username: user
+password: secret
+content_match: "status: (healthy|ok)"
+tags:
+  - env:dev
+`, server.URL))
+	require.NoError(t, c.Run())
+
+	tags := []string{"url:" + server.URL, "instance:web", "env:dev"}
+	s.AssertMetric(t, "Gauge", "network.http.can_connect", 1, "", tags)
+	s.AssertMetric(t, "Gauge", "network.http.cant_connect", 0, "", tags)
+	s.AssertMetricInRange(t, "Gauge", "network.http.response_time", 0, 10, "", tags)
+	s.AssertServiceCheck(t, "http.can_connect", servicecheck.ServiceCheckOK, "", tags, "")
+	s.AssertNotCalled(t, "ServiceCheck", "http.ssl_cert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.AssertNotCalled(t, "Event", mock.Anything)
+}
+
+func TestHTTPCheckFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "internal error")
+		default:
+			fmt.Fprint(w, "status: unhealthy")
+		}
+	}))
+	defer server.Close()
+
+	for name, tc := range map[string]struct {
+		instance string
+		message  string
+	}{
+		"status code": {
+			instance: "url: %s/error\ninclude_content: true",
+			message:  fmt.Sprintf("Incorrect HTTP return code for url %s/error. Expected ^(?:(1|2|3)\\d\\d), got 500.\nContent: internal error", server.URL),
+		},
+		"expected status code": {
+			instance: "url: %s/redirect\nallow_redirects: false\nhttp_response_status_code: 200",
+			message:  fmt.Sprintf("Incorrect HTTP return code for url %s/redirect. Expected ^(?:200), got 302.", server.URL),
+		},
+		"content match": {
+			instance: "url: %s\ncontent_match: healthy$\nreverse_content_match: true",
+			message:  `Content "healthy$" found in response.`,
+		},
+		"content not matched": {
+			instance: "url: %s/redirect\ncontent_match: ok",
+			message:  `Content "ok" not found in response.`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, s := newHTTPCheck(t, fmt.Sprintf(tc.instance, server.URL))
+			require.NoError(t, c.Run())
+
+			s.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", nil)
+			s.AssertMetric(t, "Gauge", "network.http.cant_connect", 1, "", nil)
+			s.AssertServiceCheck(t, "http.can_connect", servicecheck.ServiceCheckCritical, "", nil, tc.message)
+		})
+	}
+}
+
+func TestHTTPCheckEvents(t *testing.T) {
+	up := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c, s := newHTTPCheck(t, fmt.Sprintf("name: web\nurl: %s\nskip_event: false", server.URL))
+	expected := event.Event{
+		AggregationKey: server.URL,
+		Priority:       event.EventPriorityNormal,
+		SourceTypeName: "http_check",
+		EventType:      "http_check",
+		Tags:           []string{"instance:web"},
+		Ts:             time.Now().Unix(),
+	}
+
+	require.NoError(t, c.Run())
+	require.NoError(t, c.Run())
+	s.AssertNumberOfCalls(t, "Event", 1)
+	s.AssertEvent(t, expected, time.Minute)
+
+	up = true
+	require.NoError(t, c.Run())
+	require.NoError(t, c.Run())
+	s.AssertNumberOfCalls(t, "Event", 2)
+
+	var alertTypes []event.EventAlertType
+	for _, call := range s.Calls {
+		if call.Method == "Event" {
+			alertTypes = append(alertTypes, call.Arguments.Get(0).(event.Event).AlertType)
+		}
+	}
+	assert.Equal(t, []event.EventAlertType{event.EventAlertTypeError, event.EventAlertTypeSuccess}, alertTypes)
+}
+
+func TestHTTPCheckCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the certificate of the test server expires in 2084
+	c, s := newHTTPCheck(t, fmt.Sprintf("url: %s\ntls_verify: false", server.URL))
+	require.NoError(t, c.Run())
+
+	s.AssertServiceCheck(t, "http.can_connect", servicecheck.ServiceCheckOK, "", nil, "")
+	s.AssertServiceCheck(t, "http.ssl_cert", servicecheck.ServiceCheckOK, "", nil, mock.Anything)
+	s.AssertMetricInRange(t, "Gauge", "http.ssl.days_left", 365, 365*100, "", nil)
+
+	c, s = newHTTPCheck(t, fmt.Sprintf("url: %s\ntls_verify: false\ndays_critical: 36500", server.URL))
+	require.NoError(t, c.Run())
+	s.AssertServiceCheck(t, "http.ssl_cert", servicecheck.ServiceCheckCritical, "", nil, mock.Anything)
+
+	// the certificate of the test server isn't trusted
+	c, s = newHTTPCheck(t, "url: "+server.URL)
+	require.NoError(t, c.Run())
+	s.AssertServiceCheck(t, "http.can_connect", servicecheck.ServiceCheckCritical, "", nil, mock.Anything)
+}
+
+func TestHTTPCheckHeaders(t *testing.T) {
+	for name, tc := range map[string]struct {
+		instance string
+		want     http.Header
+	}{
+		"default": {
+			instance: "url: http://localhost\nextra_headers:\n  X-Foo: bar",
+			want:     http.Header{"User-Agent": {"Datadog Agent/" + version.AgentVersion}, "Accept": {"*/*"}, "X-Foo": {"bar"}},
+		},
+		"headers": {
+			instance: "url: http://localhost\nheaders:\n  Accept: text/plain\nextra_headers:\n  X-Foo: bar",
+			want:     http.Header{"Accept": {"text/plain"}, "X-Foo": {"bar"}},
+		},
+		"no default headers": {
+			instance: "url: http://localhost\ninclude_default_headers: false",
+			want:     http.Header{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newHTTPCheck(t, tc.instance)
+			req, err := c.newRequest()
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, req.Header)
+		})
+	}
+}
+
+func TestHTTPCheckConfigure(t *testing.T) {
+	for name, instance := range map[string]string{
+		"no url":         "name: web",
+		"invalid scheme": "url: ftp://localhost",
+		"invalid status": "url: http://localhost\nhttp_response_status_code: '('",
+		"invalid match":  "url: http://localhost\ncontent_match: '('",
+		"invalid CA":     "url: http://localhost\ntls_ca_cert: /does/not/exist",
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := httpFactory().(*HTTPCheck)
+			assert.Error(t, c.Configure(mocksender.CreateDefaultDemultiplexer(), integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+)
+
+const (
+	tcpCheckName = "tcp_check_core"
+
+	defaultTCPTimeout        = 10 * time.Second
+	tcpEventSourceType       = "tcp_check"
+	tcpCanConnectServiceName = "tcp.can_connect"
+)
+
+// TCPCheck opens TCP connections to an endpoint and reports its availability and response time,
+// with the metrics and service checks of the tcp_check integration
+type TCPCheck struct {
+	core.CheckBase
+	cfg          *tcpConfig
+	tags         []string
+	serviceTags  []string
+	availability availability
+}
+
+type tcpInstanceConfig struct {
+	Name                string   `yaml:"name"`
+	Host                string   `yaml:"host"`
+	Port                int      `yaml:"port"`
+	Timeout             float64  `yaml:"timeout"`
+	CollectResponseTime bool     `yaml:"collect_response_time"`
+	SkipEvent           *bool    `yaml:"skip_event"`
+	Tags                []string `yaml:"tags"`
+}
+
+type tcpConfig struct {
+	instance  tcpInstanceConfig
+	address   string
+	timeout   time.Duration
+	skipEvent bool
+}
+
+func (c *tcpConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, &c.instance); err != nil {
+		return err
+	}
+	instance := &c.instance
+
+	if instance.Host == "" {
+		return errors.New("the host option is required")
+	}
+	if instance.Port <= 0 || instance.Port > 65535 {
+		return fmt.Errorf("invalid port %d", instance.Port)
+	}
+	c.address = net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port))
+
+	if instance.Name == "" {
+		instance.Name = c.address
+	}
+
+	c.timeout = defaultTCPTimeout
+	if instance.Timeout > 0 {
+		c.timeout = time.Duration(instance.Timeout * float64(time.Second))
+	}
+	c.skipEvent = pointer.ValueOrDefault(instance.SkipEvent, true)
+
+	return nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *TCPCheck) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	cfg := &tcpConfig{}
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+
+	c.cfg = cfg
+	c.tags = append([]string{
+		fmt.Sprintf("url:%s:%d", cfg.instance.Host, cfg.instance.Port),
+		"instance:" + cfg.instance.Name,
+	}, cfg.instance.Tags...)
+	c.serviceTags = append([]string{
+		"target_host:" + cfg.instance.Host,
+		"port:" + strconv.Itoa(cfg.instance.Port),
+	}, c.tags...)
+	c.availability = availability{sourceType: tcpEventSourceType}
+
+	return nil
+}
+
+// Run executes the check
+func (c *TCPCheck) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	status, message := servicecheck.ServiceCheckOK, ""
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", c.cfg.address, c.cfg.timeout)
+	if err != nil {
+		status = servicecheck.ServiceCheckCritical
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			message = fmt.Sprintf("Timeout: %s. Connection failed after %d ms", err, time.Since(start).Milliseconds())
+		} else {
+			message = fmt.Sprintf("Connection failed: %s", err)
+		}
+	} else {
+		elapsed := time.Since(start)
+		conn.Close()
+		if c.cfg.instance.CollectResponseTime {
+			sender.Gauge("network.tcp.response_time", elapsed.Seconds(), "", c.tags)
+		}
+	}
+
+	canConnect, _ := canConnect(status)
+	sender.Gauge("network.tcp.can_connect", canConnect, "", c.tags)
+	sender.ServiceCheck(tcpCanConnectServiceName, status, "", c.serviceTags, message)
+	if !c.cfg.skipEvent {
+		c.availability.update(sender, c.cfg.instance.Name, c.cfg.address, status, message, c.tags)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+func tcpFactory() check.Check {
+	return &TCPCheck{
+		CheckBase: core.NewCheckBase(tcpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(tcpCheckName, tcpFactory)
+}
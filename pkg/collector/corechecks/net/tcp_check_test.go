@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+func newTCPCheck(t *testing.T, instance string) (*TCPCheck, *mocksender.MockSender) {
+	c := tcpFactory().(*TCPCheck)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+	return c, mockSender
+}
+
+func TestTCPCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	c, s := newTCPCheck(t, fmt.Sprintf("name: db\nhost: 127.0.0.1\nport: %d\ncollect_response_time: true\nskip_event: false\ntags: [env:dev]", port))
+	require.NoError(t, c.Run())
+
+	tags := []string{fmt.Sprintf("url:127.0.0.1:%d", port), "instance:db", "env:dev"}
+	s.AssertMetric(t, "Gauge", "network.tcp.can_connect", 1, "", tags)
+	s.AssertMetricInRange(t, "Gauge", "network.tcp.response_time", 0, 10, "", tags)
+	s.AssertServiceCheck(t, "tcp.can_connect", servicecheck.ServiceCheckOK, "", append([]string{"target_host:127.0.0.1", "port:" + strconv.Itoa(port)}, tags...), "")
+	s.AssertNotCalled(t, "Event", mock.Anything)
+
+	// the port is closed once the listener is
+	require.NoError(t, listener.Close())
+	s.ResetCalls()
+	require.NoError(t, c.Run())
+
+	s.AssertMetric(t, "Gauge", "network.tcp.can_connect", 0, "", tags)
+	s.AssertServiceCheck(t, "tcp.can_connect", servicecheck.ServiceCheckCritical, "", tags, mock.Anything)
+	s.AssertNumberOfCalls(t, "Event", 1)
+	s.AssertNotCalled(t, "Gauge", "network.tcp.response_time", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTCPCheckConfigure(t *testing.T) {
+	for name, instance := range map[string]string{
+		"no host":      "port: 80",
+		"no port":      "host: localhost",
+		"invalid port": "host: localhost\nport: 70000",
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := tcpFactory().(*TCPCheck)
+			assert.Error(t, c.Configure(mocksender.CreateDefaultDemultiplexer(), integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+		})
+	}
+}
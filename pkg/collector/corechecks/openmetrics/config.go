@@ -0,0 +1,302 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/common/types"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+)
+
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultBearerTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// metricTypes are the types which can be set with the `type_overrides` option, or the
+// `type` of an entry of the `metrics` option
+var metricTypes = map[string]bool{
+	"gauge":     true,
+	"counter":   true,
+	"histogram": true,
+	"summary":   true,
+	"untyped":   true,
+}
+
+// metricMapping is how a metric listed by its exact name is submitted
+type metricMapping struct {
+	name       string
+	metricType string
+}
+
+// labelJoin adds the labels of a metric, the source, to the metrics sharing the values of its
+// `match` labels
+type labelJoin struct {
+	match  []string
+	labels []string // all the labels of the source metric when empty
+	values map[float64]bool
+}
+
+// instanceConfig is the parsed configuration of a check instance. It accepts the instance
+// options of the openmetrics integration, as generated by the Prometheus autodiscovery.
+type instanceConfig struct {
+	types.OpenmetricsInstance `yaml:",inline"`
+
+	endpoint           string
+	timeout            time.Duration
+	metricMappings     map[string]metricMapping
+	metricsRe          *regexp.Regexp
+	excludeRe          *regexp.Regexp
+	excludeByLabels    map[string]map[string]bool // label -> values, any value when empty
+	labelJoins         map[string][]labelJoin     // source metric -> joins
+	renameLabels       map[string]string
+	includeLabels      map[string]bool
+	excludeLabels      map[string]bool
+	typeOverrides      map[string]string
+	collectBuckets     bool
+	nonCumulative      bool
+	healthServiceCheck bool
+	tagByEndpoint      bool
+	tlsVerify          bool
+}
+
+func (c *instanceConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+
+	c.endpoint = c.OpenMetricsEndpoint
+	if c.endpoint == "" {
+		c.endpoint = c.PrometheusURL
+	}
+	if c.endpoint == "" {
+		return errors.New("the openmetrics_endpoint option is required")
+	}
+
+	c.timeout = defaultTimeout
+	if c.Timeout > 0 {
+		c.timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	if c.BearerTokenAuth && c.BearerTokenPath == "" {
+		c.BearerTokenPath = defaultBearerTokenPath
+	}
+
+	if err := c.parseMetrics(); err != nil {
+		return err
+	}
+
+	if len(c.ExcludeMetrics) > 0 {
+		re, err := compileAlternatives(c.ExcludeMetrics)
+		if err != nil {
+			return fmt.Errorf("invalid exclude_metrics: %s", err)
+		}
+		c.excludeRe = re
+	}
+
+	c.excludeByLabels = make(map[string]map[string]bool)
+	excludeByLabels := c.ExcludeMetricsByLabels
+	if len(excludeByLabels) == 0 {
+		excludeByLabels = c.IgnoreMetricsByLabels
+	}
+	for label, values := range excludeByLabels {
+		c.excludeByLabels[label] = make(map[string]bool)
+		switch v := values.(type) {
+		case bool:
+			if !v {
+				delete(c.excludeByLabels, label)
+			}
+		case []interface{}:
+			for _, value := range v {
+				if value == "*" {
+					c.excludeByLabels[label] = map[string]bool{}
+					break
+				}
+				c.excludeByLabels[label][fmt.Sprint(value)] = true
+			}
+		default:
+			return fmt.Errorf("invalid exclude_metrics_by_labels value for label %s: %v", label, values)
+		}
+	}
+
+	c.parseLabelJoins()
+
+	c.renameLabels = c.RenameLabels
+	if len(c.renameLabels) == 0 {
+		c.renameLabels = c.LabelsMapper
+	}
+	c.includeLabels = toSet(c.IncludeLabels)
+	c.excludeLabels = toSet(c.ExcludeLabels)
+
+	c.typeOverrides = make(map[string]string)
+	for name, metricType := range c.TypeOverride {
+		if !metricTypes[metricType] {
+			return fmt.Errorf("invalid type %q in type_overrides for metric %s", metricType, name)
+		}
+		c.typeOverrides[name] = metricType
+	}
+
+	c.collectBuckets = pointer.ValueOrDefault(c.CollectHistogramBuckets, pointer.ValueOrDefault(c.SendHistogramBuckets, true))
+	c.nonCumulative = pointer.ValueOrDefault(c.NonCumulativeHistogramBuckets, false) || c.HistogramBucketsAsDistributions
+	c.healthServiceCheck = pointer.ValueOrDefault(c.EnableHealthCheck, pointer.ValueOrDefault(c.HealthCheck, true))
+	c.tagByEndpoint = pointer.ValueOrDefault(c.TagByEndpoint, true)
+	c.tlsVerify = pointer.ValueOrDefault(c.TLSVerify, true)
+
+	return nil
+}
+
+// parseMetrics parses the `metrics` option. Its entries are either regular expressions matching
+// the names of the metrics to collect, `*` matching all of them, or maps from the exact name of
+// a metric to its new name, or to a map setting its new `name` and its `type`. All the metrics
+// are collected when it's not set.
+func (c *instanceConfig) parseMetrics() error {
+	c.metricMappings = make(map[string]metricMapping)
+
+	if len(c.Metrics) == 0 {
+		c.metricsRe = regexp.MustCompile(".*")
+		return nil
+	}
+
+	var patterns []string
+	for _, entry := range c.Metrics {
+		switch v := entry.(type) {
+		case string:
+			if v == "*" {
+				v = ".*"
+			}
+			patterns = append(patterns, v)
+		case map[interface{}]interface{}:
+			for raw, mapping := range v {
+				if err := c.addMetricMapping(fmt.Sprint(raw), mapping); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			for raw, mapping := range v {
+				if err := c.addMetricMapping(raw, mapping); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("invalid entry in metrics: %v", entry)
+		}
+	}
+
+	if len(patterns) > 0 {
+		re, err := compileAlternatives(patterns)
+		if err != nil {
+			return fmt.Errorf("invalid metrics: %s", err)
+		}
+		c.metricsRe = re
+	}
+
+	return nil
+}
+
+func (c *instanceConfig) addMetricMapping(raw string, mapping interface{}) error {
+	switch m := mapping.(type) {
+	case string:
+		c.metricMappings[raw] = metricMapping{name: m}
+	case map[interface{}]interface{}, map[string]interface{}:
+		var parsed struct {
+			Name string `yaml:"name"`
+			Type string `yaml:"type"`
+		}
+		// round-trip through YAML to decode both map types
+		out, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(out, &parsed); err != nil {
+			return err
+		}
+		if parsed.Type != "" && !metricTypes[parsed.Type] {
+			return fmt.Errorf("invalid type %q in metrics for metric %s", parsed.Type, raw)
+		}
+		if parsed.Name == "" {
+			parsed.Name = raw
+		}
+		c.metricMappings[raw] = metricMapping{name: parsed.Name, metricType: parsed.Type}
+	default:
+		return fmt.Errorf("invalid mapping in metrics for metric %s: %v", raw, mapping)
+	}
+	return nil
+}
+
+// parseLabelJoins merges the `label_joins` and `share_labels` options. The joins without
+// labels to match are ignored.
+func (c *instanceConfig) parseLabelJoins() {
+	c.labelJoins = make(map[string][]labelJoin)
+
+	for source, join := range c.LabelJoins {
+		if len(join.LabelsToMatch) == 0 {
+			continue
+		}
+		labels := join.LabelsToGet
+		if len(labels) == 1 && labels[0] == "*" {
+			labels = nil
+		}
+		c.labelJoins[source] = append(c.labelJoins[source], labelJoin{match: join.LabelsToMatch, labels: labels})
+	}
+
+	for source, share := range c.ShareLabels {
+		if len(share.Match) == 0 {
+			continue
+		}
+		join := labelJoin{match: share.Match, labels: share.Labels}
+		if len(share.Values) > 0 {
+			join.values = make(map[float64]bool)
+			for _, value := range share.Values {
+				var parsed float64
+				if _, err := fmt.Sscan(value, &parsed); err == nil {
+					join.values[parsed] = true
+				}
+			}
+		}
+		c.labelJoins[source] = append(c.labelJoins[source], join)
+	}
+}
+
+// metricMapping returns how to submit the metric with the given raw name, and whether it
+// should be collected
+func (c *instanceConfig) metricMapping(raw string) (metricMapping, bool) {
+	if c.excludeRe != nil && c.excludeRe.MatchString(raw) {
+		return metricMapping{}, false
+	}
+
+	mapping, found := c.metricMappings[raw]
+	if !found {
+		if c.metricsRe == nil || !c.metricsRe.MatchString(raw) {
+			return metricMapping{}, false
+		}
+		mapping = metricMapping{name: raw}
+	}
+
+	if metricType, ok := c.typeOverrides[raw]; ok && mapping.metricType == "" {
+		mapping.metricType = metricType
+	}
+	return mapping, true
+}
+
+// compileAlternatives compiles regular expressions matching whole names
+func compileAlternatives(patterns []string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + strings.Join(patterns, "|") + ")$")
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
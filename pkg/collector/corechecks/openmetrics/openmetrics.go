@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics implements a core check scraping OpenMetrics and Prometheus endpoints,
+// in the text and protobuf formats. It accepts the instance options of the openmetrics
+// integration and follows its metric naming, without the cost of running it in Python.
+package openmetrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// CheckName is the name of the check
+	CheckName = "openmetrics_core"
+
+	healthServiceCheck = "openmetrics.health"
+)
+
+// Check scrapes an OpenMetrics or Prometheus endpoint
+type Check struct {
+	core.CheckBase
+	cfg     *instanceConfig
+	scraper *scraper
+	tags    []string
+}
+
+// Configure parses the check configuration and init the check
+func (c *Check) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	cfg := &instanceConfig{}
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+
+	scraper, err := newScraper(cfg)
+	if err != nil {
+		return err
+	}
+
+	c.cfg = cfg
+	c.scraper = scraper
+	c.tags = append([]string{}, cfg.Tags...)
+	if cfg.tagByEndpoint {
+		c.tags = append(c.tags, "endpoint:"+cfg.endpoint)
+	}
+
+	return nil
+}
+
+// Run executes the check
+func (c *Check) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	families, err := c.scraper.scrape(context.TODO())
+	if err != nil {
+		c.sendHealth(sender, servicecheck.ServiceCheckCritical, err.Error())
+		sender.Commit()
+		return err
+	}
+
+	newSubmitter(c.cfg, sender, c.tags).submit(families)
+	c.sendHealth(sender, servicecheck.ServiceCheckOK, "")
+
+	sender.Commit()
+	return nil
+}
+
+func (c *Check) sendHealth(sender sender.Sender, status servicecheck.ServiceCheckStatus, message string) {
+	if !c.cfg.healthServiceCheck {
+		return
+	}
+
+	name := healthServiceCheck
+	if c.cfg.Namespace != "" {
+		name = strings.TrimSuffix(c.cfg.Namespace, ".") + "." + name
+	}
+	sender.ServiceCheck(name, status, "", c.tags, message)
+}
+
+func factory() check.Check {
+	return &Check{
+		CheckBase: core.NewCheckBase(CheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(CheckName, factory)
+}
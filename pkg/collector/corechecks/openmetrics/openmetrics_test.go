@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+const payload = `# TYPE http_requests_total counter
+http_requests_total{code="200",pod="web-1"} 10
+http_requests_total{code="500",pod="web-2"} 2
+# TYPE temp gauge
+temp{pod="web-1"} 20.5
+# TYPE latency histogram
+latency_bucket{le="0.1"} 1
+latency_bucket{le="1"} 3
+latency_bucket{le="+Inf"} 4
+latency_sum 2.5
+latency_count 4
+# TYPE rpc summary
+rpc{quantile="0.5"} 0.2
+rpc_sum 10
+rpc_count 5
+# TYPE pod_info gauge
+pod_info{pod="web-1",team="frontend"} 1
+# TYPE debug_info gauge
+debug_info 1
+`
+
+func newServer(t *testing.T, protobuf bool, check func(*http.Request)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		if !protobuf {
+			w.Header().Set("Content-Type", string(expfmt.FmtText))
+			fmt.Fprint(w, payload)
+			return
+		}
+
+		families, err := prometheus.ParseMetricFamiliesWithFilter([]byte(payload), expfmt.FmtText, nil)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", string(expfmt.FmtProtoDelim))
+		encoder := expfmt.NewEncoder(w, expfmt.FmtProtoDelim)
+		for _, family := range families {
+			require.NoError(t, encoder.Encode(family))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func runCheck(t *testing.T, instance string) *mocksender.MockSender {
+	c := factory().(*Check)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+
+	require.NoError(t, c.Run())
+	return mockSender
+}
+
+func TestRun(t *testing.T) {
+	for _, protobuf := range []bool{false, true} {
+		t.Run(fmt.Sprintf("protobuf=%t", protobuf), func(t *testing.T) {
+			server := newServer(t, protobuf, nil)
+			endpointTag := "endpoint:" + server.URL
+
+			s := runCheck(t, fmt.Sprintf(`
+openmetrics_endpoint: %s
+namespace: test
+metrics:
+  - http_requests.*
+  - latency
+  - rpc
+  - temp: temperature
+tags:
+  - env:dev
+rename_labels:
+  code: status_code
+share_labels:
+  pod_info:
+    match:
+      - pod
+    labels:
+      - team
+`, server.URL))
+
+			s.AssertMetric(t, "MonotonicCount", "test.http_requests.count", 10, "", []string{"env:dev", endpointTag, "status_code:200", "pod:web-1", "team:frontend"})
+			s.AssertMetric(t, "MonotonicCount", "test.http_requests.count", 2, "", []string{"status_code:500", "pod:web-2"})
+			s.AssertMetricNotTaggedWith(t, "MonotonicCount", "test.http_requests.count", []string{"pod:web-2", "team:frontend"})
+			s.AssertMetric(t, "Gauge", "test.temperature", 20.5, "", []string{"pod:web-1", "team:frontend"})
+
+			s.AssertMetric(t, "MonotonicCount", "test.latency.count", 4, "", []string{endpointTag})
+			s.AssertMetric(t, "MonotonicCount", "test.latency.sum", 2.5, "", []string{endpointTag})
+			s.AssertMetric(t, "MonotonicCount", "test.latency.bucket", 1, "", []string{"upper_bound:0.1"})
+			s.AssertMetric(t, "MonotonicCount", "test.latency.bucket", 3, "", []string{"upper_bound:1"})
+			s.AssertMetric(t, "MonotonicCount", "test.latency.bucket", 4, "", []string{"upper_bound:inf"})
+
+			s.AssertMetric(t, "MonotonicCount", "test.rpc.count", 5, "", []string{endpointTag})
+			s.AssertMetric(t, "MonotonicCount", "test.rpc.sum", 10, "", []string{endpointTag})
+			s.AssertMetric(t, "Gauge", "test.rpc.quantile", 0.2, "", []string{"quantile:0.5"})
+
+			s.AssertMetricNotTaggedWith(t, "Gauge", "test.pod_info", nil)
+			s.AssertMetricNotTaggedWith(t, "Gauge", "test.debug_info", nil)
+			s.AssertServiceCheck(t, "test.openmetrics.health", servicecheck.ServiceCheckOK, "", []string{"env:dev", endpointTag}, "")
+		})
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("secret\n"), 0600))
+
+	server := newServer(t, false, func(r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Accept"), "application/vnd.google.protobuf"))
+	})
+
+	s := runCheck(t, fmt.Sprintf(`
+prometheus_url: %s
+namespace: test
+metrics:
+  - latency
+  - debug_info:
+      name: debug
+      type: counter
+type_overrides:
+  temp: counter
+exclude_metrics:
+  - http_.*
+exclude_metrics_by_labels:
+  pod:
+    - web-2
+histogram_buckets_as_distributions: true
+tag_by_endpoint: false
+bearer_token_auth: true
+bearer_token_path: %s
+extra_headers:
+  X-Foo: bar
+`, server.URL, tokenPath))
+
+	s.AssertMetric(t, "MonotonicCount", "test.debug.count", 1, "", nil)
+	s.AssertMetricNotTaggedWith(t, "MonotonicCount", "test.temp.count", nil)
+	s.AssertMetricNotTaggedWith(t, "MonotonicCount", "test.http_requests.count", nil)
+
+	// the buckets are submitted as distributions, without the count and sum
+	s.AssertHistogramBucket(t, "HistogramBucket", "test.latency", 1, 0, 0.1, true, "", []string{}, false)
+	s.AssertHistogramBucket(t, "HistogramBucket", "test.latency", 2, 0.1, 1, true, "", []string{}, false)
+	s.AssertMetricNotTaggedWith(t, "MonotonicCount", "test.latency.count", nil)
+	s.AssertServiceCheck(t, "test.openmetrics.health", servicecheck.ServiceCheckOK, "", nil, "")
+}
+
+func TestRunEndpointDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := factory().(*Check)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data("openmetrics_endpoint: "+server.URL), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+
+	assert.Error(t, c.Run())
+	mockSender.AssertServiceCheck(t, "openmetrics.health", servicecheck.ServiceCheckCritical, "", []string{"endpoint:" + server.URL}, "unexpected status code 503 from "+server.URL)
+}
+
+func TestConfigure(t *testing.T) {
+	for name, instance := range map[string]string{
+		"no endpoint":       "namespace: test",
+		"invalid regex":     "openmetrics_endpoint: http://localhost\nmetrics: ['(']",
+		"invalid type":      "openmetrics_endpoint: http://localhost\ntype_overrides: {foo: bar}",
+		"invalid CA":        "openmetrics_endpoint: http://localhost\ntls_ca_cert: /does/not/exist",
+		"invalid exclusion": "openmetrics_endpoint: http://localhost\nexclude_metrics_by_labels: {pod: web}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := factory().(*Check)
+			assert.Error(t, c.Configure(mocksender.CreateDefaultDemultiplexer(), integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+		})
+	}
+}
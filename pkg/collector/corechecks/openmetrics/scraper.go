@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+// acceptHeader prefers the delimited protobuf format, which is cheaper to parse, over the text format
+const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
+// scraper fetches and parses the metrics exposed by an endpoint
+type scraper struct {
+	cfg    *instanceConfig
+	client *http.Client
+}
+
+func newScraper(cfg *instanceConfig) (*scraper, error) {
+	transport := httputils.CreateHTTPTransport(config.Datadog)
+	if cfg.SkipProxy {
+		transport.Proxy = nil
+	}
+
+	tlsConfig := transport.TLSClientConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+		transport.TLSClientConfig = tlsConfig
+	}
+	tlsConfig.InsecureSkipVerify = !cfg.tlsVerify
+
+	if cfg.TLSCACert != "" {
+		caCert, err := os.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA certificate: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificate found in %s", cfg.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCert != "" {
+		keyFile := cfg.TLSPrivateKey
+		if keyFile == "" {
+			keyFile = cfg.TLSCert
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &scraper{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.timeout,
+		},
+	}, nil
+}
+
+// scrape fetches the metric families exposed by the endpoint
+func (s *scraper) scrape(ctx context.Context) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setHeaders(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, s.cfg.endpoint)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return prometheus.ParseMetricFamiliesWithFilter(data, expfmt.ResponseFormat(resp.Header), s.cfg.RawLineFilters)
+}
+
+// setHeaders sets the request headers: `headers` replaces the default ones, `extra_headers` adds to them
+func (s *scraper) setHeaders(req *http.Request) error {
+	headers := map[string]string{"Accept": acceptHeader}
+	if len(s.cfg.Headers) > 0 {
+		headers = s.cfg.Headers
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range s.cfg.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
+
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	if s.cfg.BearerTokenAuth {
+		// the token is read at each run, as it may be rotated
+		token, err := os.ReadFile(s.cfg.BearerTokenPath)
+		if err != nil {
+			return fmt.Errorf("unable to read the bearer token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	return nil
+}
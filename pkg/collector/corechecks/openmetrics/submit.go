@@ -0,0 +1,303 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// joinedLabels holds the labels to add to the metrics matching a label join
+type joinedLabels struct {
+	match  []string
+	labels map[string][]*dto.LabelPair // values of the match labels -> labels to add
+}
+
+// submitter submits the metric families of a scrape, following the metric naming
+// of the openmetrics integration
+type submitter struct {
+	cfg    *instanceConfig
+	sender sender.Sender
+	tags   []string
+	joins  []joinedLabels
+}
+
+func newSubmitter(cfg *instanceConfig, s sender.Sender, tags []string) *submitter {
+	return &submitter{
+		cfg:    cfg,
+		sender: s,
+		tags:   tags,
+	}
+}
+
+// submit submits the metric families
+func (s *submitter) submit(families []*dto.MetricFamily) {
+	s.collectJoins(families)
+
+	for _, family := range families {
+		raw := strings.TrimPrefix(family.GetName(), s.cfg.RawPrefix)
+		mapping, ok := s.cfg.metricMapping(raw)
+		if !ok {
+			continue
+		}
+
+		metricType := mapping.metricType
+		if metricType == "" {
+			metricType = strings.ToLower(family.GetType().String())
+		}
+
+		name := mapping.name
+		if metricType == "counter" && name == raw {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		if s.cfg.Namespace != "" {
+			name = strings.TrimSuffix(s.cfg.Namespace, ".") + "." + name
+		}
+
+		for _, metric := range family.Metric {
+			if s.isExcluded(metric) {
+				continue
+			}
+
+			hostname, tags := s.metricTags(metric)
+			switch metricType {
+			case "gauge", "untyped":
+				if value, ok := scalarValue(metric); ok {
+					s.sender.Gauge(name, value, hostname, tags)
+				}
+			case "counter":
+				if value, ok := scalarValue(metric); ok {
+					s.sender.MonotonicCount(name+".count", value, hostname, tags)
+				}
+			case "histogram":
+				s.submitHistogram(name, metric.GetHistogram(), hostname, tags)
+			case "summary":
+				s.submitSummary(name, metric.GetSummary(), hostname, tags)
+			default:
+				log.Debugf("Unsupported type %s for metric %s", metricType, family.GetName())
+			}
+		}
+	}
+}
+
+func (s *submitter) submitHistogram(name string, histogram *dto.Histogram, hostname string, tags []string) {
+	if histogram == nil {
+		return
+	}
+
+	if !s.cfg.HistogramBucketsAsDistributions || s.cfg.CollectCountersWithDistributions {
+		s.sender.MonotonicCount(name+".count", float64(histogram.GetSampleCount()), hostname, tags)
+		s.sender.MonotonicCount(name+".sum", histogram.GetSampleSum(), hostname, tags)
+	}
+
+	if !s.cfg.HistogramBucketsAsDistributions && !s.cfg.collectBuckets {
+		return
+	}
+
+	buckets := histogram.GetBucket()
+	// the +Inf bucket is implicit in the protobuf format
+	if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].GetUpperBound(), 1) {
+		count := histogram.GetSampleCount()
+		upperBound := math.Inf(1)
+		buckets = append(buckets, &dto.Bucket{CumulativeCount: &count, UpperBound: &upperBound})
+	}
+
+	var previousCount uint64
+	lowerBound := math.Inf(-1)
+	if buckets[0].GetUpperBound() > 0 {
+		lowerBound = 0
+	}
+	for _, bucket := range buckets {
+		upperBound := bucket.GetUpperBound()
+		count := bucket.GetCumulativeCount()
+		if s.cfg.nonCumulative {
+			count -= previousCount
+		}
+
+		if s.cfg.HistogramBucketsAsDistributions {
+			s.sender.HistogramBucket(name, int64(count), lowerBound, upperBound, true, hostname, tags, false)
+		} else {
+			bucketTags := append(append([]string{}, tags...), "upper_bound:"+formatBound(upperBound))
+			if s.cfg.nonCumulative {
+				bucketTags = append(bucketTags, "lower_bound:"+formatBound(lowerBound))
+			}
+			s.sender.MonotonicCount(name+".bucket", float64(count), hostname, bucketTags)
+		}
+
+		previousCount = bucket.GetCumulativeCount()
+		lowerBound = upperBound
+	}
+}
+
+func (s *submitter) submitSummary(name string, summary *dto.Summary, hostname string, tags []string) {
+	if summary == nil {
+		return
+	}
+
+	s.sender.MonotonicCount(name+".count", float64(summary.GetSampleCount()), hostname, tags)
+	s.sender.MonotonicCount(name+".sum", summary.GetSampleSum(), hostname, tags)
+
+	for _, quantile := range summary.GetQuantile() {
+		value := quantile.GetValue()
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		quantileTags := append(append([]string{}, tags...), "quantile:"+formatBound(quantile.GetQuantile()))
+		s.sender.Gauge(name+".quantile", value, hostname, quantileTags)
+	}
+}
+
+// collectJoins collects the labels of the source metrics of the label joins
+func (s *submitter) collectJoins(families []*dto.MetricFamily) {
+	s.joins = s.joins[:0]
+
+	for _, family := range families {
+		joins, ok := s.cfg.labelJoins[strings.TrimPrefix(family.GetName(), s.cfg.RawPrefix)]
+		if !ok {
+			continue
+		}
+
+		for _, join := range joins {
+			joined := joinedLabels{match: join.match, labels: make(map[string][]*dto.LabelPair)}
+			for _, metric := range family.Metric {
+				if join.values != nil {
+					value, ok := scalarValue(metric)
+					if !ok || !join.values[value] {
+						continue
+					}
+				}
+
+				key, ok := labelsKey(metric.Label, join.match)
+				if !ok {
+					continue
+				}
+				for _, label := range metric.Label {
+					if contains(join.match, label.GetName()) {
+						continue
+					}
+					if len(join.labels) == 0 || contains(join.labels, label.GetName()) {
+						joined.labels[key] = append(joined.labels[key], label)
+					}
+				}
+			}
+			s.joins = append(s.joins, joined)
+		}
+	}
+}
+
+// isExcluded returns whether the metric is excluded by the `exclude_metrics_by_labels` option
+func (s *submitter) isExcluded(metric *dto.Metric) bool {
+	for _, label := range metric.Label {
+		values, ok := s.cfg.excludeByLabels[label.GetName()]
+		if ok && (len(values) == 0 || values[label.GetValue()]) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricTags returns the hostname and the tags of a metric, built from its labels and the
+// labels joined to it
+func (s *submitter) metricTags(metric *dto.Metric) (string, []string) {
+	labels := metric.Label
+	for _, join := range s.joins {
+		if key, ok := labelsKey(metric.Label, join.match); ok {
+			labels = append(labels[:len(labels):len(labels)], join.labels[key]...)
+		}
+	}
+
+	hostnameLabel := s.cfg.HostnameLabel
+	if hostnameLabel == "" {
+		hostnameLabel = s.cfg.LabelToHostname
+	}
+
+	hostname := ""
+	tags := make([]string, 0, len(s.tags)+len(labels))
+	tags = append(tags, s.tags...)
+	for _, label := range labels {
+		name := label.GetName()
+		if name == hostnameLabel && label.GetValue() != "" {
+			hostname = label.GetValue()
+			if s.cfg.HostnameFormat != "" {
+				hostname = strings.ReplaceAll(s.cfg.HostnameFormat, "<HOSTNAME>", hostname)
+			}
+		}
+
+		if s.cfg.excludeLabels[name] || (len(s.cfg.includeLabels) > 0 && !s.cfg.includeLabels[name]) {
+			continue
+		}
+		if renamed, ok := s.cfg.renameLabels[name]; ok {
+			name = renamed
+		}
+		tags = append(tags, name+":"+label.GetValue())
+	}
+
+	return hostname, tags
+}
+
+// scalarValue returns the value of a gauge, counter or untyped metric
+func scalarValue(metric *dto.Metric) (float64, bool) {
+	var value float64
+	switch {
+	case metric.Gauge != nil:
+		value = metric.Gauge.GetValue()
+	case metric.Counter != nil:
+		value = metric.Counter.GetValue()
+	case metric.Untyped != nil:
+		value = metric.Untyped.GetValue()
+	default:
+		return 0, false
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// labelsKey returns the values of the given labels, joined, and whether they are all set
+func labelsKey(labels []*dto.LabelPair, names []string) (string, bool) {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, label := range labels {
+			if label.GetName() == name {
+				values = append(values, label.GetValue())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+func formatBound(bound float64) string {
+	if math.IsInf(bound, 1) {
+		return "inf"
+	}
+	if math.IsInf(bound, -1) {
+		return "-inf"
+	}
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -24,6 +25,7 @@ const checkName = "cpu"
 // For testing purpose
 var times = cpu.Times
 var cpuInfo = cpu.Info
+var submitPressure = system.SubmitPressure
 
 // Check doesn't need additional fields
 type Check struct {
@@ -47,6 +49,10 @@ func (c *Check) Run() error {
 		// read the context switches
 	}
 
+	if err = submitPressure(sender, "cpu", "system.cpu"); err != nil {
+		log.Debugf("cpu.Check could not read the CPU pressure: %s", err)
+	}
+
 	cpuTimes, err := times(false)
 	if err != nil {
 		log.Errorf("cpu.Check: could not retrieve cpu stats: %s", err)
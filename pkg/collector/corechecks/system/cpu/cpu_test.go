@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 
@@ -76,9 +77,15 @@ func CPUInfo() ([]cpu.InfoStat, error) {
 	}, nil
 }
 
+// noPressure skips the host Pressure Stall Information, which depends on the kernel
+func noPressure(sender.Sender, string, string) error {
+	return nil
+}
+
 func TestCPUCheckLinux(t *testing.T) {
 	times = CPUTimes
 	cpuInfo = CPUInfo
+	submitPressure = noPressure
 	cpuCheck := new(Check)
 	m := mocksender.NewMockSender(cpuCheck.ID())
 	cpuCheck.Configure(m.GetSenderManager(), integration.FakeConfigHash, nil, nil, "test")
@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/kernel"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	healthCheckName        = "disk_health"
+	healthMetric           = "system.disk.health.%s"
+	healthServiceCheckName = "disk.health"
+
+	driveTypeNVMe = "nvme"
+	driveTypeATA  = "ata"
+)
+
+var ataDiskRe = regexp.MustCompile(`^sd[a-z]+$`)
+
+// For testing purpose
+var (
+	sysfsRoot     = kernel.SysFSRoot
+	devRoot       = "/dev"
+	nvmeHealthLog = readNVMeHealthLog
+	ataSMARTData  = readATASMART
+)
+
+// HealthCheck reports the health of the NVMe and ATA drives, from their SMART data
+type HealthCheck struct {
+	core.CheckBase
+	devices map[string]bool
+	tags    []string
+}
+
+type healthInstanceConfig struct {
+	Devices []string `yaml:"devices"`
+	Tags    []string `yaml:"tags"`
+}
+
+// drive is an NVMe controller or an ATA drive
+type drive struct {
+	name      string
+	driveType string
+	sysPath   string
+}
+
+// Configure parses the check configuration and init the check
+func (c *HealthCheck) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	conf := healthInstanceConfig{}
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+
+	if len(conf.Devices) > 0 {
+		c.devices = make(map[string]bool, len(conf.Devices))
+		for _, device := range conf.Devices {
+			c.devices[filepath.Base(device)] = true
+		}
+	}
+	c.tags = conf.Tags
+
+	return nil
+}
+
+// Run executes the check
+func (c *HealthCheck) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range c.drives() {
+		report := c.driveHealth(d)
+		tags := append(d.tags(), c.tags...)
+		for name, value := range report.metrics {
+			sender.Gauge(fmt.Sprintf(healthMetric, name), value, "", tags)
+		}
+		sender.ServiceCheck(healthServiceCheckName, report.status, "", tags, strings.Join(report.messages, ", "))
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// drives returns the NVMe controllers and the ATA drives of the host, or the ones listed in
+// the devices option
+func (c *HealthCheck) drives() []drive {
+	var drives []drive
+
+	nvmeClass := filepath.Join(sysfsRoot(), "class", "nvme")
+	if entries, err := os.ReadDir(nvmeClass); err == nil {
+		for _, entry := range entries {
+			drives = append(drives, drive{name: entry.Name(), driveType: driveTypeNVMe, sysPath: filepath.Join(nvmeClass, entry.Name())})
+		}
+	}
+
+	// the SATA drives are exposed as SCSI disks, whose vendor is ATA
+	block := filepath.Join(sysfsRoot(), "block")
+	if entries, err := os.ReadDir(block); err == nil {
+		for _, entry := range entries {
+			sysPath := filepath.Join(block, entry.Name())
+			if ataDiskRe.MatchString(entry.Name()) && readSysfsString(filepath.Join(sysPath, "device", "vendor")) == "ATA" {
+				drives = append(drives, drive{name: entry.Name(), driveType: driveTypeATA, sysPath: sysPath})
+			}
+		}
+	}
+
+	if c.devices == nil {
+		return drives
+	}
+	selected := drives[:0]
+	for _, d := range drives {
+		if c.devices[d.name] {
+			selected = append(selected, d)
+		}
+	}
+	return selected
+}
+
+// driveHealth reads the SMART data of a drive. When it can't be read, for instance when the
+// agent isn't allowed to, its temperature is read from hwmon and its status is unknown.
+func (c *HealthCheck) driveHealth(d drive) *healthReport {
+	device := filepath.Join(devRoot, d.name)
+
+	var report *healthReport
+	var err error
+	switch d.driveType {
+	case driveTypeNVMe:
+		var data []byte
+		if data, err = nvmeHealthLog(device); err == nil {
+			report, err = parseNVMeHealthLog(data)
+		}
+	case driveTypeATA:
+		var data, thresholds []byte
+		if data, thresholds, err = ataSMARTData(device); err == nil {
+			report, err = parseATASMART(data, thresholds)
+		}
+	}
+	if err == nil {
+		return report
+	}
+
+	log.Debugf("Unable to read the SMART data of %s, falling back to sysfs: %s", d.name, err)
+	report = newHealthReport()
+	report.status = servicecheck.ServiceCheckUnknown
+	report.messages = []string{fmt.Sprintf("SMART data unavailable: %s", err)}
+	if temperature, found := hwmonTemperature(d); found {
+		report.metrics["temperature"] = temperature
+	}
+	return report
+}
+
+func (d drive) tags() []string {
+	tags := []string{"device:" + d.name, "device_type:" + d.driveType}
+
+	modelPath := filepath.Join(d.sysPath, "model")
+	if d.driveType == driveTypeATA {
+		modelPath = filepath.Join(d.sysPath, "device", "model")
+	}
+	if model := readSysfsString(modelPath); model != "" {
+		tags = append(tags, "model:"+model)
+	}
+	return tags
+}
+
+// hwmonTemperature returns the temperature of a drive in Celsius, as reported by the hwmon
+// device of the NVMe controller or by the drivetemp module for the ATA drives
+func hwmonTemperature(d drive) (float64, bool) {
+	for _, pattern := range []string{"hwmon*/temp1_input", "device/hwmon/hwmon*/temp1_input"} {
+		paths, _ := filepath.Glob(filepath.Join(d.sysPath, pattern))
+		for _, path := range paths {
+			if millis, err := strconv.ParseFloat(readSysfsString(path), 64); err == nil {
+				return millis / 1000, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func healthFactory() check.Check {
+	return &HealthCheck{
+		CheckBase: core.NewCheckBase(healthCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(healthCheckName, healthFactory)
+}
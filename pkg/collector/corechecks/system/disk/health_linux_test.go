@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package disk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+func writeSysfsFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content+"\n"), 0644))
+}
+
+// setupHealthCheck creates a sysfs with an NVMe controller, an ATA drive and a virtual disk,
+// whose SMART data is read from the fixtures unless ataErr is set
+func setupHealthCheck(t *testing.T, instance string, ataErr error) (*HealthCheck, *mocksender.MockSender) {
+	root := t.TempDir()
+	writeSysfsFile(t, filepath.Join(root, "class/nvme/nvme0/model"), "Samsung SSD 980 PRO 1TB")
+	writeSysfsFile(t, filepath.Join(root, "block/sda/device/vendor"), "ATA")
+	writeSysfsFile(t, filepath.Join(root, "block/sda/device/model"), "WDC WD40EFRX")
+	writeSysfsFile(t, filepath.Join(root, "block/sda/device/hwmon/hwmon3/temp1_input"), "41000")
+	writeSysfsFile(t, filepath.Join(root, "block/sdb/device/vendor"), "QEMU")
+
+	nvmeLog := readFixture(t, "nvme_health_log.bin")
+	ataData := readFixture(t, "ata_smart_data.bin")
+	ataThresholds := readFixture(t, "ata_smart_thresholds.bin")
+
+	oldSysfsRoot, oldNVMeHealthLog, oldATASMARTData := sysfsRoot, nvmeHealthLog, ataSMARTData
+	t.Cleanup(func() {
+		sysfsRoot, nvmeHealthLog, ataSMARTData = oldSysfsRoot, oldNVMeHealthLog, oldATASMARTData
+	})
+	sysfsRoot = func() string { return root }
+	nvmeHealthLog = func(device string) ([]byte, error) {
+		require.Equal(t, "/dev/nvme0", device)
+		return nvmeLog, nil
+	}
+	ataSMARTData = func(device string) ([]byte, []byte, error) {
+		require.Equal(t, "/dev/sda", device)
+		return ataData, ataThresholds, ataErr
+	}
+
+	c := healthFactory().(*HealthCheck)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+	return c, mockSender
+}
+
+func TestHealthCheck(t *testing.T) {
+	c, s := setupHealthCheck(t, "tags: [env:test]", nil)
+	require.NoError(t, c.Run())
+
+	nvmeTags := []string{"device:nvme0", "device_type:nvme", "model:Samsung SSD 980 PRO 1TB", "env:test"}
+	ataTags := []string{"device:sda", "device_type:ata", "model:WDC WD40EFRX", "env:test"}
+
+	s.AssertMetric(t, "Gauge", "system.disk.health.temperature", 37, "", nvmeTags)
+	s.AssertMetric(t, "Gauge", "system.disk.health.percentage_used", 3, "", nvmeTags)
+	s.AssertMetric(t, "Gauge", "system.disk.health.media_errors", 0, "", nvmeTags)
+	s.AssertServiceCheck(t, "disk.health", servicecheck.ServiceCheckOK, "", nvmeTags, "")
+
+	s.AssertMetric(t, "Gauge", "system.disk.health.temperature", 35, "", ataTags)
+	s.AssertMetric(t, "Gauge", "system.disk.health.reallocated_sectors", 0, "", ataTags)
+	s.AssertServiceCheck(t, "disk.health", servicecheck.ServiceCheckOK, "", ataTags, "")
+
+	s.AssertNotCalled(t, "ServiceCheck", "disk.health", servicecheck.ServiceCheckOK, "", []string{"device:sdb", "device_type:ata", "env:test"}, "")
+	s.AssertNumberOfCalls(t, "ServiceCheck", 2)
+}
+
+func TestHealthCheckSysfsFallback(t *testing.T) {
+	c, s := setupHealthCheck(t, "devices: [/dev/sda]", errors.New("permission denied"))
+	require.NoError(t, c.Run())
+
+	tags := []string{"device:sda", "device_type:ata", "model:WDC WD40EFRX"}
+	s.AssertMetric(t, "Gauge", "system.disk.health.temperature", 41, "", tags)
+	s.AssertServiceCheck(t, "disk.health", servicecheck.ServiceCheckUnknown, "", tags, "SMART data unavailable: permission denied")
+	s.AssertNumberOfCalls(t, "ServiceCheck", 1)
+}
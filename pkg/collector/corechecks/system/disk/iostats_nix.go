@@ -19,13 +19,15 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // For testing purpose
 var (
-	ioCounters = disk.IOCounters
-	swapMemory = mem.SwapMemory
+	ioCounters     = disk.IOCounters
+	swapMemory     = mem.SwapMemory
+	submitPressure = system.SubmitPressure
 
 	// for test purpose
 	nowNano = func() int64 { return time.Now().UnixNano() }
@@ -185,6 +187,10 @@ func (c *IOCheck) nixIO() error {
 		log.Errorf("system.IOCheck: could not retrieve I/O block stats: %s", errSwap)
 	}
 
+	if err = submitPressure(sender, "io", "system.io"); err != nil {
+		log.Debugf("system.IOCheck: could not read the I/O pressure: %s", err)
+	}
+
 	c.stats = iomap
 	c.ts = now
 	return nil
@@ -92,6 +92,7 @@ func TestIoStatsOverflow(t *testing.T) {
 		return currentStats, nil
 	}
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 
 	mock.On("Rate", "system.io.r_s", 41.0, "", []string{"device:sda", "device_name:sda"}).Return().Times(1)
 	mock.On("Rate", "system.io.w_s", 41.0, "", []string{"device:sda", "device_name:sda"}).Return().Times(1)
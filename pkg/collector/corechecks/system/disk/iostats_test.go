@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 )
 
@@ -85,6 +86,11 @@ var (
 	ioSamplerDM = func(names ...string) (map[string]disk.IOCountersStat, error) { return sampler(ioSamplesDM, names...) }
 )
 
+// noPressure skips the host Pressure Stall Information, which depends on the kernel
+func noPressure(sender.Sender, string, string) error {
+	return nil
+}
+
 func SwapMemory() (*mem.SwapMemoryStat, error) {
 	return &mem.SwapMemoryStat{
 		Total:       100000,
@@ -110,6 +116,7 @@ func sampler(samples []map[string]disk.IOCountersStat, names ...string) (map[str
 func TestIOCheckDM(t *testing.T) {
 	ioCounters = ioSamplerDM
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	ioCheck := new(IOCheck)
 	ioCheck.Configure(aggregator.NewNoOpSenderManager(), integration.FakeConfigHash, nil, nil, "test")
 
@@ -136,6 +143,7 @@ func TestIOCheck(t *testing.T) {
 
 	ioCounters = ioSampler
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	ioCheck := new(IOCheck)
 	mock := mocksender.NewMockSender(ioCheck.ID())
 	ioCheck.Configure(mock.GetSenderManager(), integration.FakeConfigHash, nil, nil, "test")
@@ -203,6 +211,7 @@ func TestIOCheck(t *testing.T) {
 func TestIOCheckBlacklist(t *testing.T) {
 	ioCounters = ioSampler
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	ioCheck := new(IOCheck)
 	mock := mocksender.NewMockSender(ioCheck.ID())
 	ioCheck.Configure(mock.GetSenderManager(), integration.FakeConfigHash, nil, nil, "test")
@@ -0,0 +1,364 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package disk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+const smartPageSize = 512
+
+// NVMe admin commands, see the NVM Express Base Specification
+const (
+	nvmeIoctlAdminCmd  = 0xC0484E41 // _IOWR('N', 0x41, struct nvme_admin_cmd)
+	nvmeAdminGetLog    = 0x02
+	nvmeLogSMARTHealth = 0x02
+	nvmeNSIDAll        = 0xFFFFFFFF
+)
+
+// ATA SMART commands, sent through the SCSI ATA PASS-THROUGH (16) command, see the ATA/ATAPI
+// Command Set and the SCSI / ATA Translation specifications
+const (
+	sgIO               = 0x2285
+	sgDxferFromDev     = -3
+	ataPassThrough16   = 0x85
+	ataProtocolPIOIn   = 4 << 1
+	ataTransferFromDev = 0x0E // T_DIR from the device, BYT_BLOK, T_LENGTH in the sector count
+	ataSMART           = 0xB0
+	ataSMARTReadData   = 0xD0
+	ataSMARTReadThresh = 0xD1
+	ataSMARTLBAMid     = 0x4F
+	ataSMARTLBAHigh    = 0xC2
+	ataIOTimeoutMillis = 5000
+)
+
+// ATA SMART attributes, as commonly assigned by the drive vendors
+const (
+	ataAttrReallocatedSectors   = 5
+	ataAttrPowerOnHours         = 9
+	ataAttrPowerCycles          = 12
+	ataAttrWearLevelingCount    = 177
+	ataAttrReportedUncorrect    = 187
+	ataAttrAirflowTemperature   = 190
+	ataAttrTemperature          = 194
+	ataAttrPendingSectors       = 197
+	ataAttrOfflineUncorrectable = 198
+	ataAttrSSDLifeLeft          = 231
+	ataAttrMediaWearout         = 233
+)
+
+// nvmeCriticalWarnings are the bits of the critical warning of the NVMe health log
+var nvmeCriticalWarnings = []string{
+	"available spare below threshold",
+	"temperature outside of the thresholds",
+	"reliability degraded",
+	"media in read only mode",
+	"volatile memory backup failed",
+	"persistent memory region read only",
+}
+
+// nvmeAdminCmd is struct nvme_admin_cmd of linux/nvme_ioctl.h
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+// sgIOHdr is struct sg_io_hdr of scsi/sg.h
+type sgIOHdr struct {
+	interfaceID    int32
+	dxferDirection int32
+	cmdLen         uint8
+	mxSbLen        uint8
+	iovecCount     uint16
+	dxferLen       uint32
+	dxferp         uintptr
+	cmdp           uintptr
+	sbp            uintptr
+	timeout        uint32
+	flags          uint32
+	packID         int32
+	usrPtr         uintptr
+	status         uint8
+	maskedStatus   uint8
+	msgStatus      uint8
+	sbLenWr        uint8
+	hostStatus     uint16
+	driverStatus   uint16
+	resid          int32
+	duration       uint32
+	info           uint32
+}
+
+// healthReport is the health of a drive, read from its SMART data or from sysfs
+type healthReport struct {
+	metrics  map[string]float64
+	status   servicecheck.ServiceCheckStatus
+	messages []string
+}
+
+func newHealthReport() *healthReport {
+	return &healthReport{
+		metrics: make(map[string]float64),
+		status:  servicecheck.ServiceCheckOK,
+	}
+}
+
+// degrade lowers the status of the report, keeping the reason in its messages
+func (r *healthReport) degrade(status servicecheck.ServiceCheckStatus, format string, args ...interface{}) {
+	if status > r.status {
+		r.status = status
+	}
+	r.messages = append(r.messages, fmt.Sprintf(format, args...))
+}
+
+// readNVMeHealthLog reads the SMART / health information log page of an NVMe controller
+func readNVMeHealthLog(device string) ([]byte, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, smartPageSize)
+	cmd := nvmeAdminCmd{
+		opcode:  nvmeAdminGetLog,
+		nsid:    nvmeNSIDAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&data[0]))),
+		dataLen: smartPageSize,
+		// number of dwords to read minus one, and log page identifier
+		cdw10: (smartPageSize/4-1)<<16 | nvmeLogSMARTHealth,
+	}
+	err = ioctl(f.Fd(), nvmeIoctlAdminCmd, unsafe.Pointer(&cmd))
+	runtime.KeepAlive(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the NVMe health log of %s: %w", device, err)
+	}
+	return data, nil
+}
+
+// readATASMART reads the SMART data and thresholds of an ATA drive
+func readATASMART(device string) ([]byte, []byte, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	data, err := ataSMARTCommand(f, ataSMARTReadData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the SMART data of %s: %w", device, err)
+	}
+	thresholds, err := ataSMARTCommand(f, ataSMARTReadThresh)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the SMART thresholds of %s: %w", device, err)
+	}
+	return data, thresholds, nil
+}
+
+// ataSMARTCommand sends a SMART command reading a sector to an ATA drive
+func ataSMARTCommand(f *os.File, feature byte) ([]byte, error) {
+	data := make([]byte, smartPageSize)
+	sense := make([]byte, 32)
+	// the thresholds are read from the sector 1, the LBA low register is ignored otherwise
+	var lbaLow byte
+	if feature == ataSMARTReadThresh {
+		lbaLow = 1
+	}
+	cdb := []byte{
+		0:  ataPassThrough16,
+		1:  ataProtocolPIOIn,
+		2:  ataTransferFromDev,
+		4:  feature,
+		6:  1, // sector count
+		8:  lbaLow,
+		10: ataSMARTLBAMid,
+		12: ataSMARTLBAHigh,
+		14: ataSMART,
+		15: 0,
+	}
+
+	hdr := sgIOHdr{
+		interfaceID:    'S',
+		dxferDirection: sgDxferFromDev,
+		cmdLen:         uint8(len(cdb)),
+		mxSbLen:        uint8(len(sense)),
+		dxferLen:       smartPageSize,
+		dxferp:         uintptr(unsafe.Pointer(&data[0])),
+		cmdp:           uintptr(unsafe.Pointer(&cdb[0])),
+		sbp:            uintptr(unsafe.Pointer(&sense[0])),
+		timeout:        ataIOTimeoutMillis,
+	}
+	err := ioctl(f.Fd(), sgIO, unsafe.Pointer(&hdr))
+	runtime.KeepAlive(data)
+	runtime.KeepAlive(cdb)
+	runtime.KeepAlive(sense)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.status != 0 || hdr.hostStatus != 0 || hdr.driverStatus&0x07 != 0 {
+		return nil, fmt.Errorf("command failed with status %#x, host status %#x and driver status %#x", hdr.status, hdr.hostStatus, hdr.driverStatus)
+	}
+	return data, nil
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// parseNVMeHealthLog parses the SMART / health information log page of an NVMe controller
+func parseNVMeHealthLog(data []byte) (*healthReport, error) {
+	if len(data) < smartPageSize {
+		return nil, fmt.Errorf("invalid NVMe health log of %d bytes", len(data))
+	}
+
+	r := newHealthReport()
+	criticalWarning := data[0]
+	r.metrics["critical_warning"] = float64(criticalWarning)
+	if kelvin := binary.LittleEndian.Uint16(data[1:3]); kelvin > 0 {
+		r.metrics["temperature"] = float64(kelvin) - 273
+	}
+	r.metrics["available_spare"] = float64(data[3])
+	r.metrics["available_spare_threshold"] = float64(data[4])
+	r.metrics["percentage_used"] = float64(data[5])
+	r.metrics["power_cycles"] = uint128(data[112:128])
+	r.metrics["power_on_hours"] = uint128(data[128:144])
+	r.metrics["unsafe_shutdowns"] = uint128(data[144:160])
+	r.metrics["media_errors"] = uint128(data[160:176])
+
+	for bit, warning := range nvmeCriticalWarnings {
+		if criticalWarning&(1<<bit) != 0 {
+			r.degrade(servicecheck.ServiceCheckCritical, "critical warning: %s", warning)
+		}
+	}
+	if errs := r.metrics["media_errors"]; errs > 0 {
+		r.degrade(servicecheck.ServiceCheckWarning, "%.0f media and data integrity errors", errs)
+	}
+	if used := data[5]; used >= 100 {
+		r.degrade(servicecheck.ServiceCheckWarning, "%d%% of the rated endurance used", used)
+	}
+
+	return r, nil
+}
+
+// uint128 returns the value of a little endian 128 bits counter
+func uint128(b []byte) float64 {
+	return float64(binary.LittleEndian.Uint64(b[:8])) + float64(binary.LittleEndian.Uint64(b[8:16]))*math.Pow(2, 64)
+}
+
+// parseATASMART parses the SMART data and thresholds of an ATA drive
+func parseATASMART(data, thresholds []byte) (*healthReport, error) {
+	if len(data) < smartPageSize || len(thresholds) < smartPageSize {
+		return nil, errors.New("invalid SMART data")
+	}
+	if !ataChecksumValid(data) {
+		return nil, errors.New("invalid SMART data checksum")
+	}
+
+	thresholdByID := make(map[byte]byte)
+	if ataChecksumValid(thresholds) {
+		for i := 2; i+12 <= 362; i += 12 {
+			if id := thresholds[i]; id != 0 {
+				thresholdByID[id] = thresholds[i+1]
+			}
+		}
+	}
+
+	r := newHealthReport()
+	wearAttributes := map[byte]byte{}
+	// 30 attributes of 12 bytes: ID, flags, normalized value, worst value, raw value
+	for i := 2; i+12 <= 362; i += 12 {
+		attr := data[i : i+12]
+		id, value := attr[0], attr[3]
+		if id == 0 {
+			continue
+		}
+		raw := uint64(attr[5]) | uint64(attr[6])<<8 | uint64(attr[7])<<16 | uint64(attr[8])<<24 | uint64(attr[9])<<32 | uint64(attr[10])<<40
+
+		if threshold := thresholdByID[id]; threshold > 0 && value <= threshold {
+			r.degrade(servicecheck.ServiceCheckCritical, "attribute %d below its threshold: %d <= %d", id, value, threshold)
+		}
+
+		switch id {
+		case ataAttrReallocatedSectors:
+			r.metrics["reallocated_sectors"] = float64(raw)
+		case ataAttrPowerOnHours:
+			// the upper bytes are used by some vendors for the minutes
+			r.metrics["power_on_hours"] = float64(raw & 0xFFFFFFFF)
+		case ataAttrPowerCycles:
+			r.metrics["power_cycles"] = float64(raw)
+		case ataAttrReportedUncorrect:
+			r.metrics["media_errors"] = float64(raw)
+		case ataAttrPendingSectors:
+			r.metrics["pending_sectors"] = float64(raw)
+		case ataAttrOfflineUncorrectable:
+			r.metrics["offline_uncorrectable"] = float64(raw)
+		case ataAttrTemperature:
+			r.metrics["temperature"] = float64(raw & 0xFF)
+		case ataAttrAirflowTemperature:
+			if _, found := r.metrics["temperature"]; !found {
+				r.metrics["temperature"] = float64(raw & 0xFF)
+			}
+		case ataAttrWearLevelingCount, ataAttrSSDLifeLeft, ataAttrMediaWearout:
+			wearAttributes[id] = value
+		}
+	}
+
+	// the normalized value of the wear attributes starts at 100 and decreases with the wear
+	for _, id := range []byte{ataAttrMediaWearout, ataAttrSSDLifeLeft, ataAttrWearLevelingCount} {
+		if value, found := wearAttributes[id]; found && value <= 100 {
+			r.metrics["percentage_used"] = float64(100 - value)
+			break
+		}
+	}
+
+	for _, metric := range []string{"reallocated_sectors", "pending_sectors", "offline_uncorrectable", "media_errors"} {
+		if count := r.metrics[metric]; count > 0 {
+			r.degrade(servicecheck.ServiceCheckWarning, "%.0f %s", count, metric)
+		}
+	}
+
+	return r, nil
+}
+
+// ataChecksumValid returns whether the sum of the bytes of a SMART sector is 0 modulo 256
+func ataChecksumValid(sector []byte) bool {
+	var sum byte
+	for _, b := range sector[:smartPageSize] {
+		sum += b
+	}
+	return sum == 0
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package disk
+
+import (
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+// setATAAttributeRaw sets the raw value of an attribute of a SMART data sector, and fixes its checksum
+func setATAAttributeRaw(data []byte, id byte, raw byte) {
+	for i := 2; i+12 <= 362; i += 12 {
+		if data[i] == id {
+			data[i+5] = raw
+		}
+	}
+	data[511] = 0
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	data[511] = -sum
+}
+
+func TestIoctlStructSizes(t *testing.T) {
+	assert.EqualValues(t, 72, unsafe.Sizeof(nvmeAdminCmd{}))
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		assert.EqualValues(t, 88, unsafe.Sizeof(sgIOHdr{}))
+	}
+}
+
+func TestParseNVMeHealthLog(t *testing.T) {
+	data := readFixture(t, "nvme_health_log.bin")
+
+	report, err := parseNVMeHealthLog(data)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckOK, report.status)
+	assert.Empty(t, report.messages)
+	assert.Equal(t, map[string]float64{
+		"critical_warning":          0,
+		"temperature":               37,
+		"available_spare":           100,
+		"available_spare_threshold": 10,
+		"percentage_used":           3,
+		"power_cycles":              42,
+		"power_on_hours":            1234,
+		"unsafe_shutdowns":          7,
+		"media_errors":              0,
+	}, report.metrics)
+
+	// media errors
+	data[160] = 3
+	report, err = parseNVMeHealthLog(data)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckWarning, report.status)
+	assert.Equal(t, []string{"3 media and data integrity errors"}, report.messages)
+
+	// spare below threshold and read only media
+	data[0] = 0x09
+	report, err = parseNVMeHealthLog(data)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckCritical, report.status)
+	assert.Equal(t, []string{
+		"critical warning: available spare below threshold",
+		"critical warning: media in read only mode",
+		"3 media and data integrity errors",
+	}, report.messages)
+
+	_, err = parseNVMeHealthLog(data[:64])
+	assert.Error(t, err)
+}
+
+func TestParseATASMART(t *testing.T) {
+	data := readFixture(t, "ata_smart_data.bin")
+	thresholds := readFixture(t, "ata_smart_thresholds.bin")
+
+	report, err := parseATASMART(data, thresholds)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckOK, report.status)
+	assert.Equal(t, map[string]float64{
+		"reallocated_sectors":   0,
+		"power_on_hours":        21000,
+		"power_cycles":          512,
+		"media_errors":          0,
+		"temperature":           35,
+		"pending_sectors":       0,
+		"offline_uncorrectable": 0,
+		"percentage_used":       3,
+	}, report.metrics)
+
+	// reallocated sectors
+	setATAAttributeRaw(data, ataAttrReallocatedSectors, 8)
+	report, err = parseATASMART(data, thresholds)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckWarning, report.status)
+	assert.Equal(t, []string{"8 reallocated_sectors"}, report.messages)
+
+	// the wear leveling count reaching its threshold
+	for i := 2; i+12 <= 362; i += 12 {
+		if data[i] == ataAttrWearLevelingCount {
+			data[i+3] = 5
+		}
+	}
+	setATAAttributeRaw(data, ataAttrReallocatedSectors, 8)
+	report, err = parseATASMART(data, thresholds)
+	require.NoError(t, err)
+	assert.Equal(t, servicecheck.ServiceCheckCritical, report.status)
+	assert.Equal(t, []string{"attribute 177 below its threshold: 5 <= 5", "8 reallocated_sectors"}, report.messages)
+	assert.Equal(t, 95.0, report.metrics["percentage_used"])
+
+	// corrupted data
+	data[100]++
+	_, err = parseATASMART(data, thresholds)
+	assert.Error(t, err)
+}
@@ -16,12 +16,14 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 )
 
 // For testing purpose
 var virtualMemory = mem.VirtualMemory
 var swapMemory = mem.SwapMemory
 var runtimeOS = runtime.GOOS
+var submitPressure = system.SubmitPressure
 
 // Check doesn't need additional fields
 type Check struct {
@@ -77,6 +79,10 @@ func (c *Check) Run() error {
 		return fmt.Errorf("failed to gather any memory information")
 	}
 
+	if err = submitPressure(sender, "memory", "system.mem"); err != nil {
+		log.Debugf("memory.Check: could not read the memory pressure: %s", err)
+	}
+
 	sender.Commit()
 	return nil
 }
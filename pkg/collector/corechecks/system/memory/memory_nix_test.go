@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 )
 
 func VirtualMemory() (*mem.VirtualMemoryStat, error) {
@@ -56,9 +57,15 @@ func SwapMemory() (*mem.SwapMemoryStat, error) {
 	}, nil
 }
 
+// noPressure skips the host Pressure Stall Information, which depends on the kernel
+func noPressure(sender.Sender, string, string) error {
+	return nil
+}
+
 func TestMemoryCheckLinux(t *testing.T) {
 	virtualMemory = VirtualMemory
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
@@ -100,6 +107,7 @@ func TestMemoryCheckLinux(t *testing.T) {
 func TestMemoryCheckFreebsd(t *testing.T) {
 	virtualMemory = VirtualMemory
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
@@ -133,6 +141,7 @@ func TestMemoryCheckFreebsd(t *testing.T) {
 func TestMemoryCheckDarwin(t *testing.T) {
 	virtualMemory = VirtualMemory
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
@@ -166,6 +175,7 @@ func TestMemoryCheckDarwin(t *testing.T) {
 func TestMemoryError(t *testing.T) {
 	virtualMemory = func() (*mem.VirtualMemoryStat, error) { return nil, fmt.Errorf("some error") }
 	swapMemory = func() (*mem.SwapMemoryStat, error) { return nil, fmt.Errorf("some error") }
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
@@ -184,6 +194,7 @@ func TestMemoryError(t *testing.T) {
 func TestSwapMemoryError(t *testing.T) {
 	virtualMemory = VirtualMemory
 	swapMemory = func() (*mem.SwapMemoryStat, error) { return nil, fmt.Errorf("some error") }
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
@@ -219,6 +230,7 @@ func TestSwapMemoryError(t *testing.T) {
 func TestVirtualMemoryError(t *testing.T) {
 	virtualMemory = func() (*mem.VirtualMemoryStat, error) { return nil, fmt.Errorf("some error") }
 	swapMemory = SwapMemory
+	submitPressure = noPressure
 	memCheck := new(Check)
 
 	mock := mocksender.NewMockSender(memCheck.ID())
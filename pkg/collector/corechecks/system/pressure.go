@@ -0,0 +1,17 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+
+package system
+
+import "github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+
+// SubmitPressure does nothing, Pressure Stall Information being only available on Linux
+//
+//nolint:revive // TODO(PLINT) Fix revive linter
+func SubmitPressure(sender sender.Sender, resource string, metricPrefix string) error {
+	return nil
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package system
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/cgroups"
+)
+
+// SubmitPressure sends the Pressure Stall Information of a resource (cpu, memory or io) of the
+// host, read from /proc/pressure. The stall times are sent as rates, in nanoseconds like the
+// container ones, and the averages as gauges. Nothing is sent when the kernel doesn't expose PSI.
+func SubmitPressure(sender sender.Sender, resource string, metricPrefix string) error {
+	procfsPath := "/proc"
+	if config.Datadog.IsSet("procfs_path") {
+		procfsPath = config.Datadog.GetString("procfs_path")
+	}
+
+	var some, full cgroups.PSIStats
+	if err := cgroups.ParsePSI(filepath.Join(procfsPath, "pressure", resource), &some, &full); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	submitPSIStats(sender, metricPrefix+".partial_stall", some)
+	submitPSIStats(sender, metricPrefix+".full_stall", full)
+	return nil
+}
+
+func submitPSIStats(sender sender.Sender, metricName string, stats cgroups.PSIStats) {
+	if stats.Total != nil {
+		sender.Rate(metricName, float64(*stats.Total)*float64(time.Microsecond), "", nil)
+	}
+	if stats.Avg10 != nil {
+		sender.Gauge(metricName+".avg10", *stats.Avg10, "", nil)
+	}
+	if stats.Avg60 != nil {
+		sender.Gauge(metricName+".avg60", *stats.Avg60, "", nil)
+	}
+	if stats.Avg300 != nil {
+		sender.Gauge(metricName+".avg300", *stats.Avg300, "", nil)
+	}
+}
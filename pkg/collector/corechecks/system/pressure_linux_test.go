@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestSubmitPressure(t *testing.T) {
+	procfsPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procfsPath, "pressure"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(procfsPath, "pressure", "memory"), []byte(
+		"some avg10=1.50 avg60=2.25 avg300=0.75 total=2047009\n"+
+			"full avg10=0.50 avg60=1.00 avg300=0.25 total=1024\n"), 0644))
+
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("procfs_path", procfsPath)
+
+	s := mocksender.NewMockSender("pressure")
+	s.SetupAcceptAll()
+
+	require.NoError(t, SubmitPressure(s, "memory", "system.mem"))
+	s.AssertMetric(t, "Rate", "system.mem.partial_stall", 2047009000, "", nil)
+	s.AssertMetric(t, "Gauge", "system.mem.partial_stall.avg10", 1.5, "", nil)
+	s.AssertMetric(t, "Gauge", "system.mem.partial_stall.avg60", 2.25, "", nil)
+	s.AssertMetric(t, "Gauge", "system.mem.partial_stall.avg300", 0.75, "", nil)
+	s.AssertMetric(t, "Rate", "system.mem.full_stall", 1024000, "", nil)
+	s.AssertMetric(t, "Gauge", "system.mem.full_stall.avg10", 0.5, "", nil)
+	s.AssertNumberOfCalls(t, "Rate", 2)
+	s.AssertNumberOfCalls(t, "Gauge", 6)
+
+	// the kernel doesn't expose PSI for this resource
+	s.ResetCalls()
+	require.NoError(t, SubmitPressure(s, "irq", "system.irq"))
+	s.AssertNumberOfCalls(t, "Rate", 0)
+	s.AssertNumberOfCalls(t, "Gauge", 0)
+}
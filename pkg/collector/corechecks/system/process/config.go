@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultPIDCacheDuration = 120 * time.Second
+
+// instanceConfig is the configuration of a check instance. It accepts the instance options
+// of the process integration.
+type instanceConfig struct {
+	Name             string              `yaml:"name"`
+	SearchString     []string            `yaml:"search_string"`
+	ExactMatch       *bool               `yaml:"exact_match"`
+	PID              int32               `yaml:"pid"`
+	PIDFile          string              `yaml:"pid_file"`
+	User             string              `yaml:"user"`
+	AggregateByName  *bool               `yaml:"aggregate_by_name"`
+	PIDCacheDuration int                 `yaml:"pid_cache_duration"`
+	Thresholds       map[string][]uint64 `yaml:"thresholds"`
+	Tags             []string            `yaml:"tags"`
+}
+
+// threshold is the range of the number of processes outside of which the process.up service
+// check reports the status of the threshold
+type threshold struct {
+	min, max uint64
+}
+
+type config struct {
+	instance instanceConfig
+
+	exactMatch       bool
+	cmdlineRes       []*regexp.Regexp
+	uid              *int32
+	aggregateByName  bool
+	pidCacheDuration time.Duration
+	critical         *threshold
+	warning          *threshold
+}
+
+func (c *config) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, &c.instance); err != nil {
+		return err
+	}
+	instance := &c.instance
+
+	if instance.Name == "" {
+		return errors.New("the name option is required")
+	}
+	if len(instance.SearchString) == 0 && instance.PID == 0 && instance.PIDFile == "" && instance.User == "" {
+		return errors.New("one of the search_string, pid, pid_file or user options is required")
+	}
+
+	c.exactMatch = instance.ExactMatch == nil || *instance.ExactMatch
+	if !c.exactMatch {
+		for _, s := range instance.SearchString {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return fmt.Errorf("invalid search_string %q: %s", s, err)
+			}
+			c.cmdlineRes = append(c.cmdlineRes, re)
+		}
+	}
+
+	if instance.User != "" {
+		uid, err := lookupUID(instance.User)
+		if err != nil {
+			return err
+		}
+		c.uid = uid
+	}
+
+	c.aggregateByName = instance.AggregateByName == nil || *instance.AggregateByName
+
+	c.pidCacheDuration = defaultPIDCacheDuration
+	if instance.PIDCacheDuration > 0 {
+		c.pidCacheDuration = time.Duration(instance.PIDCacheDuration) * time.Second
+	}
+
+	for name, bounds := range instance.Thresholds {
+		if len(bounds) != 2 || bounds[0] > bounds[1] {
+			return fmt.Errorf("the %s threshold must be a [min, max] range", name)
+		}
+		t := &threshold{min: bounds[0], max: bounds[1]}
+		switch name {
+		case "critical":
+			c.critical = t
+		case "warning":
+			c.warning = t
+		default:
+			return fmt.Errorf("unknown threshold %s, it must be critical or warning", name)
+		}
+	}
+
+	return nil
+}
+
+// lookupUID returns the numeric ID of a user, given by its name or ID. It returns nil on
+// systems, like Windows, whose user IDs aren't numeric: the processes are then matched by
+// user name.
+func lookupUID(name string) (*int32, error) {
+	if uid, err := strconv.ParseInt(name, 10, 32); err == nil {
+		id := int32(uid)
+		return &id, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the user %s: %s", name, err)
+	}
+	uid, err := strconv.ParseInt(u.Uid, 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	id := int32(uid)
+	return &id, nil
+}
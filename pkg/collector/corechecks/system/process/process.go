@@ -0,0 +1,340 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package process implements a core check reporting the resource usage of the processes
+// selected by name, command line, PID file or user. It accepts the instance options of the
+// process integration and reports its metrics, from the data collected by the procutil probe.
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/process/procutil"
+	"github.com/DataDog/datadog-agent/pkg/util/cachedfetch"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	checkName = "process_core"
+
+	metricPrefix       = "system.processes."
+	upServiceCheckName = "process.up"
+
+	// the list of the processes is shared by the check instances, which select their
+	// processes from it when their PID cache expires
+	processesCacheKey = "process_core:processes"
+	processesCacheTTL = 10 * time.Second
+
+	// maxProcNameLength is the length to which the kernel truncates the process names
+	maxProcNameLength = 15
+)
+
+var (
+	probeOnce sync.Once
+	probe     procutil.Probe
+)
+
+// getProbe returns the probe shared by the check instances
+func getProbe() procutil.Probe {
+	probeOnce.Do(func() {
+		probe = procutil.NewProcessProbe(procutil.WithPermission(true))
+	})
+	return probe
+}
+
+// Check reports the resource usage of the processes selected by its instance
+type Check struct {
+	core.CheckBase
+	cfg         *config
+	probe       procutil.Probe
+	cache       *cachedfetch.SharedCache
+	tags        []string
+	serviceTags []string
+
+	pids       []int32
+	pidsExpiry time.Time
+	cpuSamples map[int32]cpuSample
+	numCPU     float64
+}
+
+// cpuSample is the CPU time used by a process at a point in time, to compute its CPU usage
+// between two runs
+type cpuSample struct {
+	createTime int64
+	cpuTime    float64
+	timestamp  time.Time
+}
+
+// Configure parses the check configuration and init the check
+func (c *Check) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	cfg := &config{}
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+
+	if c.probe == nil {
+		c.probe = getProbe()
+	}
+	c.cfg = cfg
+	c.tags = append([]string{"process_name:" + cfg.instance.Name}, cfg.instance.Tags...)
+	c.serviceTags = append([]string{"process:" + cfg.instance.Name}, cfg.instance.Tags...)
+	c.cpuSamples = make(map[int32]cpuSample)
+	c.numCPU = float64(runtime.NumCPU())
+
+	return nil
+}
+
+// Run executes the check
+func (c *Check) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pids, err := c.findPIDs(now)
+	if err != nil {
+		_ = c.Warnf("Unable to find the processes of %s: %s", c.cfg.instance.Name, err)
+	}
+
+	var statsByPID map[int32]*procutil.Stats
+	if len(pids) > 0 {
+		if statsByPID, err = c.probe.StatsForPIDs(pids, now); err != nil {
+			return err
+		}
+	}
+
+	c.submitMetrics(sender, statsByPID, now)
+	c.sendServiceCheck(sender, len(statsByPID))
+
+	sender.Commit()
+	return nil
+}
+
+// findPIDs returns the PIDs of the processes selected by the instance. The processes selected
+// by search_string or user are searched again once the PID cache expires.
+func (c *Check) findPIDs(now time.Time) ([]int32, error) {
+	if c.cfg.instance.PID != 0 {
+		return []int32{c.cfg.instance.PID}, nil
+	}
+	if c.cfg.instance.PIDFile != "" {
+		return readPIDFile(c.cfg.instance.PIDFile)
+	}
+
+	if c.pids != nil && now.Before(c.pidsExpiry) {
+		return c.pids, nil
+	}
+
+	value, err := c.cache.Fetch(context.TODO(), processesCacheKey, processesCacheTTL, func(context.Context) (interface{}, error) {
+		return c.probe.ProcessesByPID(now, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int32{}
+	for pid, proc := range value.(map[int32]*procutil.Process) {
+		if c.matches(proc) {
+			pids = append(pids, pid)
+		}
+	}
+	c.pids, c.pidsExpiry = pids, now.Add(c.cfg.pidCacheDuration)
+	return pids, nil
+}
+
+// matches returns whether a process is selected by the search_string and user options
+func (c *Check) matches(proc *procutil.Process) bool {
+	if c.cfg.instance.User != "" && !c.matchesUser(proc) {
+		return false
+	}
+	if len(c.cfg.instance.SearchString) == 0 {
+		return true
+	}
+
+	if c.cfg.exactMatch {
+		name := processName(proc)
+		for _, s := range c.cfg.instance.SearchString {
+			if name == s || (runtime.GOOS == "windows" && strings.EqualFold(name, s)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	cmdline := strings.Join(proc.Cmdline, " ")
+	for _, re := range c.cfg.cmdlineRes {
+		if re.MatchString(cmdline) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesUser returns whether a process runs as the user of the instance, by real user ID,
+// or by name when the probe reports it instead of IDs
+func (c *Check) matchesUser(proc *procutil.Process) bool {
+	if c.cfg.uid != nil && len(proc.Uids) > 0 {
+		return proc.Uids[0] == *c.cfg.uid
+	}
+	if proc.Username == "" {
+		return false
+	}
+	username := proc.Username
+	if i := strings.LastIndexByte(username, '\\'); i >= 0 {
+		username = username[i+1:]
+	}
+	return strings.EqualFold(username, c.cfg.instance.User)
+}
+
+// processName returns the name of a process. The names truncated by the kernel are completed
+// with the name of the executable in the command line.
+func processName(proc *procutil.Process) string {
+	if len(proc.Name) == maxProcNameLength && len(proc.Cmdline) > 0 {
+		if exe := filepath.Base(proc.Cmdline[0]); strings.HasPrefix(exe, proc.Name) {
+			return exe
+		}
+	}
+	return proc.Name
+}
+
+// readPIDFile returns the PID written in a file
+func readPIDFile(path string) ([]int32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PID in %s: %s", path, err)
+	}
+	return []int32{int32(pid)}, nil
+}
+
+// submitMetrics submits the metrics of the processes, summed under the process_name tag or
+// per process when aggregate_by_name is false
+func (c *Check) submitMetrics(sender sender.Sender, statsByPID map[int32]*procutil.Stats, now time.Time) {
+	sender.Gauge(metricPrefix+"number", float64(len(statsByPID)), "", c.tags)
+
+	aggregate := make(map[string]float64)
+	for pid, stats := range statsByPID {
+		metrics := c.processMetrics(pid, stats, now)
+		if c.cfg.aggregateByName {
+			for name, value := range metrics {
+				aggregate[name] += value
+			}
+			continue
+		}
+
+		tags := append([]string{"pid:" + strconv.Itoa(int(pid))}, c.tags...)
+		for name, value := range metrics {
+			sender.Gauge(metricPrefix+name, value, "", tags)
+		}
+	}
+	for name, value := range aggregate {
+		sender.Gauge(metricPrefix+name, value, "", c.tags)
+	}
+
+	for pid := range c.cpuSamples {
+		if _, found := statsByPID[pid]; !found {
+			delete(c.cpuSamples, pid)
+		}
+	}
+}
+
+// processMetrics returns the metrics of a process, without the ones its stats don't have,
+// like the ones the agent isn't allowed to collect
+func (c *Check) processMetrics(pid int32, stats *procutil.Stats, now time.Time) map[string]float64 {
+	metrics := map[string]float64{
+		"threads": float64(stats.NumThreads),
+	}
+
+	if stats.MemInfo != nil {
+		metrics["mem.rss"] = float64(stats.MemInfo.RSS)
+		metrics["mem.vms"] = float64(stats.MemInfo.VMS)
+	}
+	if stats.OpenFdCount >= 0 {
+		metrics["open_file_descriptors"] = float64(stats.OpenFdCount)
+	}
+	if stats.CtxSwitches != nil {
+		metrics["voluntary_ctx_switches"] = float64(stats.CtxSwitches.Voluntary)
+		metrics["involuntary_ctx_switches"] = float64(stats.CtxSwitches.Involuntary)
+	}
+	if io := stats.IOStat; io != nil && io.ReadBytes >= 0 {
+		metrics["ioread_count"] = float64(io.ReadCount)
+		metrics["iowrite_count"] = float64(io.WriteCount)
+		metrics["ioread_bytes"] = float64(io.ReadBytes)
+		metrics["iowrite_bytes"] = float64(io.WriteBytes)
+	}
+
+	if stats.CPUTime != nil {
+		sample := cpuSample{
+			createTime: stats.CreateTime,
+			cpuTime:    stats.CPUTime.User + stats.CPUTime.System,
+			timestamp:  now,
+		}
+		// the usage is computed from the previous run, if the PID wasn't reused since
+		previous, found := c.cpuSamples[pid]
+		if elapsed := now.Sub(previous.timestamp).Seconds(); found && previous.createTime == sample.createTime && elapsed > 0 {
+			pct := (sample.cpuTime - previous.cpuTime) / elapsed * 100
+			metrics["cpu.pct"] = pct
+			metrics["cpu.normalized_pct"] = pct / c.numCPU
+		}
+		c.cpuSamples[pid] = sample
+	}
+
+	return metrics
+}
+
+// sendServiceCheck sends the process.up service check, whose status depends on the number
+// of processes found and the thresholds of the instance
+func (c *Check) sendServiceCheck(sender sender.Sender, count int) {
+	n := uint64(count)
+	status := servicecheck.ServiceCheckOK
+	switch {
+	case c.cfg.critical != nil && (n < c.cfg.critical.min || n > c.cfg.critical.max):
+		status = servicecheck.ServiceCheckCritical
+	case c.cfg.warning != nil && (n < c.cfg.warning.min || n > c.cfg.warning.max):
+		status = servicecheck.ServiceCheckWarning
+	case c.cfg.critical == nil && c.cfg.warning == nil && n == 0:
+		status = servicecheck.ServiceCheckCritical
+	}
+
+	message := ""
+	if status != servicecheck.ServiceCheckOK {
+		message = fmt.Sprintf("%d processes found for %s", count, c.cfg.instance.Name)
+	}
+	sender.ServiceCheck(upServiceCheckName, status, "", c.serviceTags, message)
+}
+
+func factory() check.Check {
+	return &Check{
+		CheckBase: core.NewCheckBase(checkName),
+		cache:     cachedfetch.Shared,
+	}
+}
+
+func init() {
+	core.RegisterCheck(checkName, factory)
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/process/procutil"
+	"github.com/DataDog/datadog-agent/pkg/process/procutil/mocks"
+	"github.com/DataDog/datadog-agent/pkg/util/cachedfetch"
+)
+
+var processes = map[int32]*procutil.Process{
+	10: {Pid: 10, Name: "nginx", Cmdline: []string{"nginx: master process", "-g", "daemon off;"}, Uids: []int32{0}},
+	11: {Pid: 11, Name: "nginx", Cmdline: []string{"nginx: worker process"}, Uids: []int32{33}},
+	20: {Pid: 20, Name: "java", Cmdline: []string{"/usr/bin/java", "-jar", "/opt/app/server.jar"}, Uids: []int32{1000}},
+	30: {Pid: 30, Name: "postgres-export", Cmdline: []string{"/usr/lib/postgres-exporter"}, Uids: []int32{1000}},
+}
+
+func newStats(cpuTime float64, fds int32) *procutil.Stats {
+	return &procutil.Stats{
+		CreateTime:  1000,
+		OpenFdCount: fds,
+		NumThreads:  4,
+		CPUTime:     &procutil.CPUTimesStat{User: cpuTime, System: cpuTime},
+		MemInfo:     &procutil.MemoryInfoStat{RSS: 1024, VMS: 4096},
+		IOStat:      &procutil.IOCountersStat{ReadCount: 1, WriteCount: 2, ReadBytes: 100, WriteBytes: 200},
+		CtxSwitches: &procutil.NumCtxSwitchesStat{Voluntary: 5, Involuntary: 1},
+	}
+}
+
+func newCheck(t *testing.T, probe procutil.Probe, instance string) (*Check, *mocksender.MockSender) {
+	c := factory().(*Check)
+	c.probe = probe
+	c.cache = cachedfetch.NewSharedCache()
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, integration.Data(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	mockSender.SetupAcceptAll()
+	return c, mockSender
+}
+
+func TestConfigure(t *testing.T) {
+	for _, instance := range []string{
+		`search_string: [nginx]`,
+		`name: nginx`,
+		"name: nginx\nsearch_string: ['(']\nexact_match: false",
+		"name: nginx\nsearch_string: [nginx]\nthresholds:\n  critical: [2, 1]",
+		"name: nginx\nsearch_string: [nginx]\nthresholds:\n  unknown: [1, 2]",
+	} {
+		c := factory().(*Check)
+		err := c.Configure(mocksender.CreateDefaultDemultiplexer(), integration.FakeConfigHash, integration.Data(instance), nil, "test")
+		assert.Error(t, err, instance)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	for _, tc := range []struct {
+		instance string
+		expected []int32
+	}{
+		{"name: nginx\nsearch_string: [nginx]", []int32{10, 11}},
+		{"name: nginx\nsearch_string: [nginx]\nuser: '33'", []int32{11}},
+		{"name: java\nsearch_string: ['server\\.jar$']\nexact_match: false", []int32{20}},
+		{"name: exporter\nsearch_string: [postgres-exporter]", []int32{30}},
+		{"name: app\nuser: '1000'", []int32{20, 30}},
+	} {
+		c, _ := newCheck(t, mocks.NewProbe(t), tc.instance)
+
+		var pids []int32
+		for pid, proc := range processes {
+			if c.matches(proc) {
+				pids = append(pids, pid)
+			}
+		}
+		assert.ElementsMatch(t, tc.expected, pids, tc.instance)
+	}
+}
+
+func TestRunAggregate(t *testing.T) {
+	probe := mocks.NewProbe(t)
+	probe.On("ProcessesByPID", mock.Anything, false).Return(processes, nil).Once()
+	probe.On("StatsForPIDs", mock.MatchedBy(func(pids []int32) bool { return assert.ElementsMatch(t, []int32{10, 11}, pids) }), mock.Anything).
+		Return(map[int32]*procutil.Stats{10: newStats(1, 10), 11: newStats(1, -1)}, nil).Once()
+	probe.On("StatsForPIDs", mock.Anything, mock.Anything).
+		Return(map[int32]*procutil.Stats{10: newStats(2, 10), 11: newStats(2, -1)}, nil).Once()
+
+	c, s := newCheck(t, probe, "name: nginx\nsearch_string: [nginx]\ntags: [env:test]")
+	tags := []string{"process_name:nginx", "env:test"}
+
+	require.NoError(t, c.Run())
+	s.AssertMetric(t, "Gauge", "system.processes.number", 2, "", tags)
+	s.AssertMetric(t, "Gauge", "system.processes.mem.rss", 2048, "", tags)
+	s.AssertMetric(t, "Gauge", "system.processes.threads", 8, "", tags)
+	s.AssertMetric(t, "Gauge", "system.processes.open_file_descriptors", 10, "", tags)
+	s.AssertMetric(t, "Gauge", "system.processes.iowrite_bytes", 400, "", tags)
+	s.AssertMetric(t, "Gauge", "system.processes.voluntary_ctx_switches", 10, "", tags)
+	s.AssertMetricNotTaggedWith(t, "Gauge", "system.processes.cpu.pct", tags)
+	s.AssertServiceCheck(t, "process.up", servicecheck.ServiceCheckOK, "", []string{"process:nginx", "env:test"}, "")
+
+	// the cached PIDs are used, and the CPU usage is computed from the previous run
+	s.ResetCalls()
+	require.NoError(t, c.Run())
+	s.AssertMetricTaggedWith(t, "Gauge", "system.processes.cpu.pct", tags)
+	probe.AssertNumberOfCalls(t, "ProcessesByPID", 1)
+}
+
+func TestRunPerPID(t *testing.T) {
+	probe := mocks.NewProbe(t)
+	probe.On("ProcessesByPID", mock.Anything, false).Return(processes, nil)
+	probe.On("StatsForPIDs", mock.Anything, mock.Anything).
+		Return(map[int32]*procutil.Stats{10: newStats(1, 10), 11: newStats(1, 20)}, nil)
+
+	c, s := newCheck(t, probe, "name: nginx\nsearch_string: [nginx]\naggregate_by_name: false")
+
+	require.NoError(t, c.Run())
+	s.AssertMetric(t, "Gauge", "system.processes.number", 2, "", []string{"process_name:nginx"})
+	s.AssertMetric(t, "Gauge", "system.processes.open_file_descriptors", 10, "", []string{"pid:10", "process_name:nginx"})
+	s.AssertMetric(t, "Gauge", "system.processes.open_file_descriptors", 20, "", []string{"pid:11", "process_name:nginx"})
+}
+
+func TestRunPIDFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "app.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("20\n"), 0644))
+
+	probe := mocks.NewProbe(t)
+	probe.On("StatsForPIDs", []int32{20}, mock.Anything).Return(map[int32]*procutil.Stats{20: newStats(1, 10)}, nil)
+
+	c, s := newCheck(t, probe, "name: app\npid_file: "+pidFile)
+	require.NoError(t, c.Run())
+	s.AssertMetric(t, "Gauge", "system.processes.number", 1, "", []string{"process_name:app"})
+	s.AssertServiceCheck(t, "process.up", servicecheck.ServiceCheckOK, "", []string{"process:app"}, "")
+
+	// the process is reported as down when the PID file is removed
+	require.NoError(t, os.Remove(pidFile))
+	s.ResetCalls()
+	require.NoError(t, c.Run())
+	s.AssertMetric(t, "Gauge", "system.processes.number", 0, "", []string{"process_name:app"})
+	s.AssertServiceCheck(t, "process.up", servicecheck.ServiceCheckCritical, "", []string{"process:app"}, "0 processes found for app")
+}
+
+func TestServiceCheckThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		count    int
+		expected servicecheck.ServiceCheckStatus
+	}{
+		{0, servicecheck.ServiceCheckCritical},
+		{1, servicecheck.ServiceCheckWarning},
+		{2, servicecheck.ServiceCheckOK},
+		{4, servicecheck.ServiceCheckWarning},
+		{6, servicecheck.ServiceCheckCritical},
+	} {
+		c, s := newCheck(t, mocks.NewProbe(t), "name: nginx\nsearch_string: [nginx]\nthresholds:\n  critical: [1, 5]\n  warning: [2, 3]")
+		c.sendServiceCheck(s, tc.count)
+		s.AssertServiceCheck(t, "process.up", tc.expected, "", []string{"process:nginx"}, mock.Anything)
+	}
+}
@@ -63,6 +63,11 @@ func (c *CheckWrapper) destroySender() {
 	c.senderManager.DestroySender(c.ID())
 }
 
+// Inner returns the wrapped check instance.
+func (c *CheckWrapper) Inner() check.Check {
+	return c.inner
+}
+
 // Stop implements Check#Stop
 func (c *CheckWrapper) Stop() {
 	c.inner.Stop()
@@ -7,15 +7,6 @@
 
 package middleware
 
-import (
-	"github.com/DataDog/datadog-agent/pkg/collector/check"
-)
-
-// Inner returns the wrapped check instance.
-func (c *CheckWrapper) Inner() check.Check {
-	return c.inner
-}
-
 // Wait blocks until Run() finishes execution in another
 // goroutine. Does not block if Run() is not executing.
 func (c *CheckWrapper) Wait() {
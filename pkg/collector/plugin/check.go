@@ -0,0 +1,257 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// PluginCheck is a check running in a plugin. The plugin process is shared by the checks
+// using it: it is launched when the first one is configured, and stopped when the last one
+// is cancelled.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+type PluginCheck struct {
+	corechecks.CheckBase
+	endpoint       endpoint
+	plugin         *pluginClient
+	version        string
+	initConfig     integration.Data
+	instanceConfig integration.Data
+
+	m         sync.Mutex
+	cancelRun context.CancelFunc
+}
+
+func newPluginCheck(name string, e endpoint) *PluginCheck {
+	return &PluginCheck{
+		CheckBase: corechecks.NewCheckBase(name),
+		endpoint:  e,
+	}
+}
+
+// Configure starts the plugin if needed and configures the check instance in it.
+func (c *PluginCheck) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, instance integration.Data, initConfig integration.Data, source string) error {
+	c.BuildID(integrationConfigDigest, instance, initConfig)
+	if err := c.CommonConfigure(senderManager, integrationConfigDigest, initConfig, instance, source); err != nil {
+		return err
+	}
+	c.initConfig = initConfig
+	c.instanceConfig = instance
+
+	plugin, err := acquirePlugin(c.endpoint)
+	if err != nil {
+		return err
+	}
+	c.plugin = plugin
+
+	if err := c.configure(); err != nil {
+		c.plugin.release()
+		c.plugin = nil
+		return err
+	}
+	return nil
+}
+
+// configure configures the check instance in the plugin.
+func (c *PluginCheck) configure() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.plugin.startTimeout)
+	defer cancel()
+
+	resp, err := c.plugin.client.Configure(ctx, &pb.ConfigureRequest{
+		CheckId:        string(c.ID()),
+		CheckName:      c.String(),
+		InitConfig:     c.initConfig,
+		InstanceConfig: c.instanceConfig,
+		Source:         c.ConfigSource(),
+	})
+	if err != nil {
+		return fmt.Errorf("plugin %s could not configure the check: %v", c.endpoint, err)
+	}
+	if resp.GetSkip() {
+		return check.ErrSkipCheckInstance
+	}
+	c.version = resp.GetVersion()
+	return nil
+}
+
+// Run runs the check in the plugin, submitting what it sends. A plugin which doesn't know
+// the check anymore, because it was restarted, is configured again.
+func (c *PluginCheck) Run() error {
+	err := c.run()
+	if status.Code(err) == codes.NotFound {
+		log.Debugf("Check plugin %s doesn't know check %s anymore, configuring it again", c.endpoint, c.ID())
+		if err := c.plugin.handshake(); err != nil {
+			return err
+		}
+		if err := c.configure(); err != nil {
+			return err
+		}
+		err = c.run()
+	}
+	return err
+}
+
+func (c *PluginCheck) run() error {
+	s, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.m.Lock()
+	c.cancelRun = cancel
+	c.m.Unlock()
+	defer func() {
+		c.m.Lock()
+		c.cancelRun = nil
+		c.m.Unlock()
+		cancel()
+	}()
+
+	stream, err := c.plugin.client.Run(ctx, &pb.RunRequest{CheckId: string(c.ID())})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("check %s was stopped", c.ID())
+			}
+			return err
+		}
+		c.submit(s, msg)
+	}
+
+	s.Commit()
+	return nil
+}
+
+// submit forwards a message of the plugin to the sender.
+func (c *PluginCheck) submit(s sender.Sender, msg *pb.SenderMessage) {
+	switch {
+	case msg.GetMetric() != nil:
+		m := msg.GetMetric()
+		switch m.GetType() {
+		case pb.MetricType_GAUGE:
+			s.Gauge(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_RATE:
+			s.Rate(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_COUNT:
+			s.Count(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_MONOTONIC_COUNT:
+			s.MonotonicCountWithFlushFirstValue(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags(), m.GetFlushFirstValue())
+		case pb.MetricType_COUNTER:
+			s.Counter(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_HISTOGRAM:
+			s.Histogram(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_HISTORATE:
+			s.Historate(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		case pb.MetricType_DISTRIBUTION:
+			s.Distribution(m.GetName(), m.GetValue(), m.GetHostname(), m.GetTags())
+		default:
+			log.Warnf("Check %s: unknown metric type %v for metric %s", c.ID(), m.GetType(), m.GetName())
+		}
+	case msg.GetServiceCheck() != nil:
+		sc := msg.GetServiceCheck()
+		s.ServiceCheck(sc.GetName(), servicecheck.ServiceCheckStatus(sc.GetStatus()), sc.GetHostname(), sc.GetTags(), sc.GetMessage())
+	case msg.GetEvent() != nil:
+		e := msg.GetEvent()
+		s.Event(event.Event{
+			Title:          e.GetTitle(),
+			Text:           e.GetText(),
+			Ts:             e.GetTimestamp(),
+			Priority:       event.EventPriority(e.GetPriority()),
+			Host:           e.GetHostname(),
+			Tags:           e.GetTags(),
+			AlertType:      event.EventAlertType(e.GetAlertType()),
+			AggregationKey: e.GetAggregationKey(),
+			SourceTypeName: e.GetSourceTypeName(),
+			EventType:      e.GetEventType(),
+		})
+	case msg.GetWarning() != "":
+		_ = c.Warn(msg.GetWarning())
+	}
+}
+
+// Stop interrupts the run in progress.
+func (c *PluginCheck) Stop() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.cancelRun != nil {
+		c.cancelRun()
+	}
+}
+
+// Cancel removes the check instance from the plugin, and stops the plugin if no other check
+// uses it.
+func (c *PluginCheck) Cancel() {
+	if c.plugin == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.plugin.startTimeout)
+	defer cancel()
+	if _, err := c.plugin.client.Cancel(ctx, &pb.CancelRequest{CheckId: string(c.ID())}); err != nil {
+		log.Debugf("Check plugin %s could not cancel check %s: %v", c.endpoint, c.ID(), err)
+	}
+	c.plugin.release()
+	c.plugin = nil
+}
+
+// IsGoCheck returns false, the check runs in the plugin process: Stop interrupts its runs.
+func (c *PluginCheck) IsGoCheck() bool {
+	return false
+}
+
+// Version returns the version of the check reported by the plugin.
+func (c *PluginCheck) Version() string {
+	return c.version
+}
+
+// GetDiagnoses returns the diagnoses of the check reported by the plugin.
+func (c *PluginCheck) GetDiagnoses() ([]diagnosis.Diagnosis, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.plugin.startTimeout)
+	defer cancel()
+
+	resp, err := c.plugin.client.GetDiagnoses(ctx, &pb.GetDiagnosesRequest{CheckId: string(c.ID())})
+	if err != nil {
+		return nil, err
+	}
+	diagnoses := make([]diagnosis.Diagnosis, 0, len(resp.GetDiagnoses()))
+	for _, d := range resp.GetDiagnoses() {
+		diagnoses = append(diagnoses, diagnosis.Diagnosis{
+			Result:      diagnosis.Result(d.GetResult()),
+			Name:        d.GetName(),
+			Diagnosis:   d.GetDiagnosis(),
+			Category:    d.GetCategory(),
+			Description: d.GetDescription(),
+			Remediation: d.GetRemediation(),
+			RawError:    d.GetRawError(),
+		})
+	}
+	return diagnoses, nil
+}
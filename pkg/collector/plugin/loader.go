@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package plugin implements the loader of the checks running in their own process, the check
+// plugins, which the Agent drives over gRPC.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// pluginInitConfig is the part of the init_config telling how to reach the plugin of a check.
+type pluginInitConfig struct {
+	// PluginCommand is the command launching the plugin.
+	PluginCommand []string `yaml:"plugin_command"`
+	// PluginAddress is the gRPC address of an already running plugin.
+	PluginAddress string `yaml:"plugin_address"`
+}
+
+// CheckLoader is a specific loader for the checks running in a plugin
+type CheckLoader struct{}
+
+// NewCheckLoader creates a loader for the check plugins
+func NewCheckLoader() (*CheckLoader, error) {
+	return &CheckLoader{}, nil
+}
+
+// Name returns the plugin loader name
+func (l *CheckLoader) Name() string {
+	return "plugin"
+}
+
+// Load returns a check running in a plugin. The plugin is the one set in the init_config with
+// `plugin_command` or `plugin_address`, or the executable named after the check in the plugins
+// directory.
+func (l *CheckLoader) Load(senderManager sender.SenderManager, config integration.Config, instance integration.Data) (check.Check, error) {
+	endpoint, err := resolveEndpoint(config.Name, config.InitConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newPluginCheck(config.Name, endpoint)
+	if err := c.Configure(senderManager, config.FastDigest(), instance, config.InitConfig, config.Source); err != nil {
+		if errors.Is(err, check.ErrSkipCheckInstance) {
+			return c, err
+		}
+		log.Errorf("plugin.loader: could not configure check %s: %s", c, err)
+		return c, fmt.Errorf("Could not configure check %s: %s", c, err)
+	}
+
+	return c, nil
+}
+
+func (l *CheckLoader) String() string {
+	return "Plugin Check Loader"
+}
+
+// resolveEndpoint returns how to reach the plugin of a check.
+func resolveEndpoint(name string, initConfig integration.Data) (endpoint, error) {
+	var conf pluginInitConfig
+	if err := yaml.Unmarshal(initConfig, &conf); err != nil {
+		return endpoint{}, fmt.Errorf("invalid init_config: %v", err)
+	}
+	switch {
+	case conf.PluginAddress != "" && len(conf.PluginCommand) > 0:
+		return endpoint{}, errors.New("plugin_address and plugin_command are mutually exclusive")
+	case conf.PluginAddress != "":
+		return endpoint{address: conf.PluginAddress}, nil
+	case len(conf.PluginCommand) > 0:
+		return endpoint{command: conf.PluginCommand}, nil
+	}
+
+	path := filepath.Join(pluginsDirectory(), name)
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return endpoint{}, fmt.Errorf("no plugin found for check %s", name)
+	}
+	return endpoint{command: []string{path}}, nil
+}
+
+// pluginsDirectory returns the directory of the plugin executables.
+func pluginsDirectory() string {
+	if dir := config.Datadog.GetString("check_plugins.directory"); dir != "" {
+		return dir
+	}
+	return filepath.Join(config.Datadog.GetString("additional_checksd"), "plugins")
+}
+
+func init() {
+	factory := func(sender.SenderManager) (check.Loader, error) {
+		return NewCheckLoader()
+	}
+
+	loaders.RegisterLoader(40, factory)
+}
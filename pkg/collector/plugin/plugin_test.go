@@ -0,0 +1,276 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+)
+
+// fakePlugin is a plugin reporting a few things for every check configured in it, and
+// skipping the instances with `skip: true`.
+type fakePlugin struct {
+	pb.UnimplementedCheckPluginServer
+
+	m      sync.Mutex
+	checks map[string]bool
+}
+
+func newFakePlugin() *fakePlugin {
+	return &fakePlugin{checks: make(map[string]bool)}
+}
+
+func (p *fakePlugin) Handshake(_ context.Context, req *pb.HandshakeRequest) (*pb.HandshakeResponse, error) {
+	return &pb.HandshakeResponse{ProtocolVersion: req.ProtocolVersion}, nil
+}
+
+func (p *fakePlugin) Configure(_ context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	if strings.Contains(string(req.InstanceConfig), "skip: true") {
+		return &pb.ConfigureResponse{Skip: true}, nil
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.checks[req.CheckId] = true
+	return &pb.ConfigureResponse{Version: "1.2.3"}, nil
+}
+
+func (p *fakePlugin) Run(req *pb.RunRequest, stream pb.CheckPlugin_RunServer) error {
+	p.m.Lock()
+	found := p.checks[req.CheckId]
+	p.m.Unlock()
+	if !found {
+		return status.Errorf(codes.NotFound, "unknown check %s", req.CheckId)
+	}
+
+	messages := []*pb.SenderMessage{
+		{Message: &pb.SenderMessage_Metric{Metric: &pb.Metric{Type: pb.MetricType_GAUGE, Name: "plugin.gauge", Value: 1, Tags: []string{"foo:bar"}}}},
+		{Message: &pb.SenderMessage_Metric{Metric: &pb.Metric{Type: pb.MetricType_MONOTONIC_COUNT, Name: "plugin.count", Value: 2, FlushFirstValue: true}}},
+		{Message: &pb.SenderMessage_ServiceCheck{ServiceCheck: &pb.ServiceCheck{Name: "plugin.can_connect", Status: pb.ServiceCheckStatus_CRITICAL, Message: "down"}}},
+		{Message: &pb.SenderMessage_Event{Event: &pb.Event{Title: "title", Text: "text", Timestamp: 42, AlertType: "error"}}},
+		{Message: &pb.SenderMessage_Warning{Warning: "careful"}},
+	}
+	for _, msg := range messages {
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakePlugin) Cancel(_ context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	delete(p.checks, req.CheckId)
+	return &pb.CancelResponse{}, nil
+}
+
+func (p *fakePlugin) GetDiagnoses(context.Context, *pb.GetDiagnosesRequest) (*pb.GetDiagnosesResponse, error) {
+	return &pb.GetDiagnosesResponse{Diagnoses: []*pb.Diagnosis{
+		{Result: pb.DiagnosisResult_DIAGNOSIS_FAIL, Name: "connectivity", Diagnosis: "cannot connect", RawError: "refused"},
+	}}, nil
+}
+
+func serveFakePlugin(listener net.Listener) *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterCheckPluginServer(server, newFakePlugin())
+	go server.Serve(listener) //nolint:errcheck
+	return server
+}
+
+// TestMain turns the test binary into the fake plugin when it is launched by the loader.
+func TestMain(m *testing.M) {
+	if address := os.Getenv(AddressEnvVar); address != "" {
+		listener, err := net.Listen("unix", strings.TrimPrefix(address, "unix://"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		serveFakePlugin(listener)
+		select {}
+	}
+	os.Exit(m.Run())
+}
+
+func setupMockSender(conf integration.Config, instance integration.Data) *mocksender.MockSender {
+	s := mocksender.NewMockSender(checkid.BuildID(conf.Name, conf.FastDigest(), instance, conf.InitConfig))
+	s.SetupAcceptAll()
+	return s
+}
+
+func assertSubmitted(t *testing.T, s *mocksender.MockSender) {
+	s.AssertMetric(t, "Gauge", "plugin.gauge", 1, "", []string{"foo:bar"})
+	s.AssertCalled(t, "MonotonicCountWithFlushFirstValue", "plugin.count", 2.0, "", []string(nil), true)
+	s.AssertServiceCheck(t, "plugin.can_connect", servicecheck.ServiceCheckCritical, "", nil, "down")
+	s.AssertEvent(t, event.Event{Title: "title", Text: "text", Ts: 42, AlertType: event.EventAlertTypeError}, 0)
+	s.AssertCalled(t, "Commit")
+}
+
+func TestPluginAddress(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	server := serveFakePlugin(listener)
+	defer server.Stop()
+
+	initConfig := integration.Data("plugin_address: unix://" + socketPath)
+	instance := integration.Data("host: localhost")
+	conf := integration.Config{Name: "fake", InitConfig: initConfig}
+	s := setupMockSender(conf, instance)
+	s.On("MonotonicCountWithFlushFirstValue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	loader, _ := NewCheckLoader()
+	c, err := loader.Load(s.GetSenderManager(), conf, instance)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", c.Version())
+
+	require.NoError(t, c.Run())
+	assertSubmitted(t, s)
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 1)
+	assert.EqualError(t, warnings[0], "careful (check:fake)")
+
+	diagnoses, err := c.GetDiagnoses()
+	require.NoError(t, err)
+	assert.Equal(t, []diagnosis.Diagnosis{
+		{Result: diagnosis.DiagnosisFail, Name: "connectivity", Diagnosis: "cannot connect", RawError: "refused"},
+	}, diagnoses)
+
+	c.Cancel()
+}
+
+func TestPluginSkip(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	server := serveFakePlugin(listener)
+	defer server.Stop()
+
+	initConfig := integration.Data("plugin_address: unix://" + socketPath)
+	instance := integration.Data("skip: true")
+	conf := integration.Config{Name: "fake", InitConfig: initConfig}
+	s := setupMockSender(conf, instance)
+
+	loader, _ := NewCheckLoader()
+	_, err = loader.Load(s.GetSenderManager(), conf, instance)
+	assert.ErrorIs(t, err, check.ErrSkipCheckInstance)
+}
+
+func TestPluginCommandRestart(t *testing.T) {
+	config.Datadog.SetWithoutSource("check_plugins.max_restart_backoff", time.Second)
+	defer config.Datadog.SetWithoutSource("check_plugins.max_restart_backoff", 5*time.Minute)
+
+	initConfig := integration.Data(fmt.Sprintf("plugin_command: [%q]", os.Args[0]))
+	instance := integration.Data("host: localhost")
+	conf := integration.Config{Name: "fake", InitConfig: initConfig}
+	s := setupMockSender(conf, instance)
+	s.On("MonotonicCountWithFlushFirstValue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	loader, _ := NewCheckLoader()
+	c, err := loader.Load(s.GetSenderManager(), conf, instance)
+	require.NoError(t, err)
+	defer c.Cancel()
+
+	require.NoError(t, c.Run())
+	assertSubmitted(t, s)
+
+	// the restarted plugin doesn't know the check, it is configured again
+	plugin := c.(*PluginCheck).plugin
+	plugin.m.Lock()
+	require.NoError(t, plugin.cmd.Process.Kill())
+	plugin.m.Unlock()
+
+	assert.Eventually(t, func() bool { return c.Run() == nil }, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestPluginCommandShared(t *testing.T) {
+	initConfig := integration.Data(fmt.Sprintf("plugin_command: [%q]", os.Args[0]))
+	instance1 := integration.Data("host: foo")
+	instance2 := integration.Data("host: bar")
+	conf := integration.Config{Name: "fake", InitConfig: initConfig}
+	s1 := setupMockSender(conf, instance1)
+	s1.On("MonotonicCountWithFlushFirstValue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	s2 := setupMockSender(conf, instance2)
+	s2.On("MonotonicCountWithFlushFirstValue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	loader, _ := NewCheckLoader()
+	c1, err := loader.Load(s1.GetSenderManager(), conf, instance1)
+	require.NoError(t, err)
+	c2, err := loader.Load(s2.GetSenderManager(), conf, instance2)
+	require.NoError(t, err)
+
+	// the instances share the plugin process
+	plugin := c1.(*PluginCheck).plugin
+	require.Same(t, plugin, c2.(*PluginCheck).plugin)
+	plugin.m.Lock()
+	process := plugin.cmd.Process
+	plugin.m.Unlock()
+
+	require.NoError(t, c1.Run())
+	require.NoError(t, c2.Run())
+	assertSubmitted(t, s1)
+	assertSubmitted(t, s2)
+
+	// the plugin is stopped with its last check
+	c1.Cancel()
+	require.NoError(t, c2.Run())
+	c2.Cancel()
+	plugins.m.Lock()
+	assert.Empty(t, plugins.clients)
+	plugins.m.Unlock()
+	assert.Error(t, process.Signal(syscall.Signal(0)), "the plugin process is still running")
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	config.Datadog.SetWithoutSource("check_plugins.directory", dir)
+	defer config.Datadog.SetWithoutSource("check_plugins.directory", "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo"), []byte("#!/bin/sh\n"), 0755))
+
+	e, err := resolveEndpoint("foo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, endpoint{command: []string{filepath.Join(dir, "foo")}}, e)
+
+	e, err = resolveEndpoint("foo", integration.Data("plugin_command: [/bin/bar, --baz]"))
+	require.NoError(t, err)
+	assert.Equal(t, endpoint{command: []string{"/bin/bar", "--baz"}}, e)
+
+	e, err = resolveEndpoint("foo", integration.Data("plugin_address: localhost:1234"))
+	require.NoError(t, err)
+	assert.Equal(t, endpoint{address: "localhost:1234"}, e)
+
+	_, err = resolveEndpoint("foo", integration.Data("plugin_address: localhost:1234\nplugin_command: [/bin/bar]"))
+	assert.Error(t, err)
+
+	_, err = resolveEndpoint("bar", nil)
+	assert.EqualError(t, err, "no plugin found for check bar")
+}
@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	// ProtocolVersion is the version of the protocol spoken with the plugins.
+	ProtocolVersion = 1
+
+	// AddressEnvVar is the environment variable holding the address the plugins launched by
+	// the Agent must listen on, `unix://<socket path>`.
+	AddressEnvVar = "DD_CHECK_PLUGIN_ADDRESS"
+	// ProtocolVersionEnvVar is the environment variable holding the protocol version of the Agent.
+	ProtocolVersionEnvVar = "DD_CHECK_PLUGIN_PROTOCOL_VERSION"
+
+	socketName = "plugin.sock"
+)
+
+// endpoint tells how to reach a plugin: either the command launching it, or the address of
+// an already running one.
+type endpoint struct {
+	command []string
+	address string
+}
+
+func (e endpoint) String() string {
+	if e.address != "" {
+		return e.address
+	}
+	return strings.Join(e.command, " ")
+}
+
+// plugins are the clients of the plugins in use, by endpoint: the checks of a plugin share
+// its process and connection.
+var plugins = struct {
+	m       sync.Mutex
+	clients map[string]*pluginClient
+}{clients: make(map[string]*pluginClient)}
+
+// acquirePlugin returns the client of a plugin, which is started unless another check
+// already uses it. It must be released when the check doesn't use it anymore.
+func acquirePlugin(e endpoint) (*pluginClient, error) {
+	plugins.m.Lock()
+	defer plugins.m.Unlock()
+
+	if p, found := plugins.clients[e.String()]; found {
+		p.refCount++
+		return p, nil
+	}
+
+	p := newPluginClient(e)
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	p.refCount = 1
+	plugins.clients[e.String()] = p
+	return p, nil
+}
+
+// release stops the plugin when no check uses it anymore.
+func (p *pluginClient) release() {
+	plugins.m.Lock()
+	defer plugins.m.Unlock()
+
+	p.refCount--
+	if p.refCount > 0 {
+		return
+	}
+	delete(plugins.clients, p.endpoint.String())
+	p.stop()
+}
+
+// pluginClient is the connection to a plugin. When the plugin is launched by the Agent, it
+// is restarted with an exponential backoff when it exits.
+type pluginClient struct {
+	endpoint        endpoint
+	refCount        int // guarded by plugins.m
+	startTimeout    time.Duration
+	stableAfter     time.Duration
+	backoffPolicy   backoff.Policy
+	conn            *grpc.ClientConn
+	client          pb.CheckPluginClient
+	socketDir       string
+	socketPath      string
+	supervisorDone  chan struct{}
+	stopSupervisor  chan struct{}
+	stopSupervision sync.Once
+
+	m   sync.Mutex
+	cmd *exec.Cmd
+}
+
+// newPluginClient returns a client of the plugin.
+func newPluginClient(e endpoint) *pluginClient {
+	maxBackoff := config.Datadog.GetDuration("check_plugins.max_restart_backoff")
+	if maxBackoff < time.Second {
+		maxBackoff = time.Second
+	}
+	return &pluginClient{
+		endpoint:       e,
+		startTimeout:   config.Datadog.GetDuration("check_plugins.start_timeout"),
+		stableAfter:    maxBackoff,
+		backoffPolicy:  backoff.NewExpBackoffPolicy(2, 1, maxBackoff.Seconds(), 1, true),
+		supervisorDone: make(chan struct{}),
+		stopSupervisor: make(chan struct{}),
+	}
+}
+
+// start launches the plugin if needed, and connects to it.
+func (p *pluginClient) start() error {
+	address := p.endpoint.address
+	if len(p.endpoint.command) > 0 {
+		dir, err := os.MkdirTemp("", "dd-check-plugin-")
+		if err != nil {
+			return fmt.Errorf("could not create the plugin socket directory: %v", err)
+		}
+		p.socketDir = dir
+		p.socketPath = filepath.Join(dir, socketName)
+		address = "unix://" + p.socketPath
+	}
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		p.cleanup()
+		return fmt.Errorf("could not connect to %s: %v", address, err)
+	}
+	p.conn = conn
+	p.client = pb.NewCheckPluginClient(conn)
+
+	if len(p.endpoint.command) > 0 {
+		cmd, err := p.launch(address)
+		if err != nil {
+			p.cleanup()
+			return err
+		}
+		go p.supervise(cmd, address)
+	} else {
+		close(p.supervisorDone)
+	}
+
+	if err := p.handshake(); err != nil {
+		p.stop()
+		return err
+	}
+	return nil
+}
+
+// handshake checks that the plugin speaks the protocol version of the Agent, waiting for it
+// to be ready.
+func (p *pluginClient) handshake() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.startTimeout)
+	defer cancel()
+
+	agentVersion, _ := version.Agent()
+	resp, err := p.client.Handshake(ctx, &pb.HandshakeRequest{
+		ProtocolVersion: ProtocolVersion,
+		AgentVersion:    agentVersion.GetNumber(),
+	}, grpc.WaitForReady(true))
+	if err != nil {
+		return fmt.Errorf("handshake with plugin %s failed: %v", p.endpoint, err)
+	}
+	if resp.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("plugin %s speaks the protocol version %d, the Agent speaks the version %d", p.endpoint, resp.ProtocolVersion, ProtocolVersion)
+	}
+	return nil
+}
+
+// launch starts the plugin process, listening on address.
+func (p *pluginClient) launch(address string) (*exec.Cmd, error) {
+	// a crashed plugin leaves its socket behind
+	_ = os.Remove(p.socketPath)
+
+	cmd := exec.Command(p.endpoint.command[0], p.endpoint.command[1:]...)
+	cmd.Env = append(os.Environ(),
+		AddressEnvVar+"="+address,
+		ProtocolVersionEnvVar+"="+strconv.Itoa(ProtocolVersion),
+	)
+	setProcAttr(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not launch plugin %s: %v", p.endpoint, err)
+	}
+	go p.forwardOutput(stdout)
+	go p.forwardOutput(stderr)
+
+	p.m.Lock()
+	p.cmd = cmd
+	p.m.Unlock()
+	log.Debugf("Launched check plugin %s with PID %d", p.endpoint, cmd.Process.Pid)
+	return cmd, nil
+}
+
+// forwardOutput logs the lines the plugin writes on its standard output or error.
+func (p *pluginClient) forwardOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			log.Infof("Check plugin %s: %s", p.endpoint, line)
+		}
+	}
+}
+
+// supervise restarts the plugin when it exits, with an exponential backoff. The backoff is
+// reset once the plugin has been running for longer than the maximum backoff.
+func (p *pluginClient) supervise(cmd *exec.Cmd, address string) {
+	defer close(p.supervisorDone)
+
+	numErrors := 0
+	for {
+		started := time.Now()
+		err := cmd.Wait()
+		select {
+		case <-p.stopSupervisor:
+			return
+		default:
+		}
+
+		if time.Since(started) > p.stableAfter {
+			numErrors = p.backoffPolicy.DecError(numErrors)
+		}
+		for {
+			numErrors = p.backoffPolicy.IncError(numErrors)
+			delay := p.backoffPolicy.GetBackoffDuration(numErrors)
+			log.Warnf("Check plugin %s exited (%v), restarting it in %s", p.endpoint, err, delay)
+
+			select {
+			case <-p.stopSupervisor:
+				return
+			case <-time.After(delay):
+			}
+
+			cmd, err = p.launch(address)
+			if err == nil {
+				break
+			}
+		}
+	}
+}
+
+// stop stops the plugin if the Agent launched it, and closes the connection.
+func (p *pluginClient) stop() {
+	p.stopSupervision.Do(func() {
+		close(p.stopSupervisor)
+	})
+
+	p.m.Lock()
+	if p.cmd != nil && p.cmd.Process != nil {
+		_ = killProcess(p.cmd)
+	}
+	p.m.Unlock()
+
+	<-p.supervisorDone
+	p.cleanup()
+}
+
+func (p *pluginClient) cleanup() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	if p.socketDir != "" {
+		_ = os.RemoveAll(p.socketDir)
+	}
+}
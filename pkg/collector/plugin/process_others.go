@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+
+package plugin
+
+import (
+	"os/exec"
+)
+
+func setProcAttr(*exec.Cmd) {}
+
+// killProcess kills the plugin process.
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"sync"
+	"time"
 	"unsafe"
 
 	yaml "gopkg.in/yaml.v2"
@@ -21,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	"github.com/DataDog/datadog-agent/pkg/persistentcache"
 	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/cachedfetch"
 	hostnameUtil "github.com/DataDog/datadog-agent/pkg/util/hostname"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/clustername"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -203,6 +205,67 @@ func ReadPersistentCache(key *C.char) *C.char {
 	return TrackedCString(data)
 }
 
+// WriteSharedCache stores a value in the cache shared by all the checks, for a TTL in seconds
+// Indirectly used by the C function `write_shared_cache` that's mapped to `datadog_agent.write_shared_cache`.
+//
+//export WriteSharedCache
+func WriteSharedCache(key, value *C.char, ttl C.int) {
+	cachedfetch.Shared.Set(C.GoString(key), C.GoString(value), time.Duration(ttl)*time.Second)
+}
+
+// ReadSharedCache retrieves a value from the cache shared by all the checks, or NULL if it isn't
+// found or has expired. Only the string values can be shared with Python checks.
+// Indirectly used by the C function `read_shared_cache` that's mapped to `datadog_agent.read_shared_cache`.
+//
+//export ReadSharedCache
+func ReadSharedCache(key *C.char) *C.char {
+	keyName := C.GoString(key)
+	value, found := cachedfetch.Shared.Get(keyName)
+	if !found {
+		return nil
+	}
+	return sharedCacheCString(keyName, value)
+}
+
+// ClaimSharedCache retrieves a value from the cache shared by all the checks, like ReadSharedCache,
+// but it coalesces the fetches of the key: when it returns NULL, the caller claimed the key and
+// must fetch its value and store it with WriteSharedCache. Until then, or until the timeout in
+// seconds, the other callers wait for this value instead of fetching it too.
+// Indirectly used by the C function `claim_shared_cache` that's mapped to `datadog_agent.claim_shared_cache`.
+//
+//export ClaimSharedCache
+func ClaimSharedCache(key *C.char, timeout C.int) *C.char {
+	keyName := C.GoString(key)
+	// the claim is released when the context is done, so it must outlive this call
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(time.Duration(timeout)*time.Second, cancel)
+	value, claimed, err := cachedfetch.Shared.Claim(ctx, keyName)
+	if claimed {
+		return nil
+	}
+	timer.Stop()
+	cancel()
+	if err != nil {
+		log.Debugf("Failed to wait for the value of %s in the shared cache: %s", keyName, err)
+		return nil
+	}
+	return sharedCacheCString(keyName, value)
+}
+
+// sharedCacheCString converts a value of the shared cache to a C string. Only the string values
+// can be shared with Python checks.
+func sharedCacheCString(key string, value interface{}) *C.char {
+	switch v := value.(type) {
+	case string:
+		return TrackedCString(v)
+	case []byte:
+		return TrackedCString(string(v))
+	default:
+		log.Debugf("The value cached for %s can't be shared with Python checks: %T", key, value)
+		return nil
+	}
+}
+
 var (
 	// one obfuscator instance is shared across all python checks. It is not threadsafe but that is ok because
 	// the GIL is always locked when calling c code from python which means that the exported functions in this file
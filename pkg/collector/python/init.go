@@ -85,9 +85,12 @@ void GetHostname(char **);
 void GetVersion(char **);
 void Headers(char **);
 char * ReadPersistentCache(char *);
+char * ReadSharedCache(char *);
+char * ClaimSharedCache(char *, int);
 void SetCheckMetadata(char *, char *, char *);
 void SetExternalTags(char *, char *, char **);
 void WritePersistentCache(char *, char *);
+void WriteSharedCache(char *, char *, int);
 bool TracemallocEnabled();
 char* ObfuscateSQL(char *, char *, char **);
 char* ObfuscateSQLExecPlan(char *, bool, char **);
@@ -103,6 +106,9 @@ void initDatadogAgentModule(rtloader_t *rtloader) {
 	set_set_external_tags_cb(rtloader, SetExternalTags);
 	set_write_persistent_cache_cb(rtloader, WritePersistentCache);
 	set_read_persistent_cache_cb(rtloader, ReadPersistentCache);
+	set_write_shared_cache_cb(rtloader, WriteSharedCache);
+	set_read_shared_cache_cb(rtloader, ReadSharedCache);
+	set_claim_shared_cache_cb(rtloader, ClaimSharedCache);
 	set_tracemalloc_enabled_cb(rtloader, TracemallocEnabled);
 	set_obfuscate_sql_cb(rtloader, ObfuscateSQL);
 	set_obfuscate_sql_exec_plan_cb(rtloader, ObfuscateSQLExecPlan);
@@ -27,13 +27,16 @@ const (
 	runningChecksExpvarKey = "RunningChecks"
 	runsExpvarKey          = "Runs"
 	runningExpvarKey       = "Running"
+	timedOutExpvarKey      = "TimedOut"
+	timeoutsExpvarKey      = "Timeouts"
 	warningsExpvarKey      = "Warnings"
 )
 
 var (
-	runnerStats        *expvar.Map
-	runningChecksStats *expvar.Map
-	checkStats         *expCheckStats
+	runnerStats         *expvar.Map
+	runningChecksStats  *expvar.Map
+	timedOutChecksStats *expvar.Map
+	checkStats          *expCheckStats
 )
 
 // expCheckStats holds the stats from the running checks
@@ -44,10 +47,12 @@ type expCheckStats struct {
 
 func init() {
 	runningChecksStats = &expvar.Map{}
+	timedOutChecksStats = &expvar.Map{}
 
 	runnerStats = expvar.NewMap(runnerExpvarKey)
 	runnerStats.Set(checksExpvarKey, expvar.Func(expCheckStatsFunc))
 	runnerStats.Set(runningExpvarKey, runningChecksStats)
+	runnerStats.Set(timedOutExpvarKey, timedOutChecksStats)
 
 	newWorkersExpvar(runnerStats)
 
@@ -77,11 +82,15 @@ func Reset() {
 	// Clear running checks map
 	runningChecksStats.Init()
 
+	// Clear timed out checks map
+	timedOutChecksStats.Init()
+
 	// Clear top-level expvars on the runner
 	for _, key := range []string{
 		errorsExpvarKey,
 		runsExpvarKey,
 		runningChecksExpvarKey,
+		timeoutsExpvarKey,
 		warningsExpvarKey,
 	} {
 		runnerStats.Delete(key)
@@ -209,6 +218,29 @@ func DeleteRunningStats(id checkid.ID) {
 	runningChecksStats.Delete(string(id))
 }
 
+// Functions relating to the checks which exceeded their run timeout (`timedOutChecksStats`)
+
+// SetTimedOutStats sets the start time of a running check which exceeded its run timeout
+func SetTimedOutStats(id checkid.ID, t time.Time) {
+	timedOutChecksStats.Set(string(id), timestamp(t))
+}
+
+// GetTimedOutStats gets the start time of a running check which exceeded its run timeout
+func GetTimedOutStats(id checkid.ID) time.Time {
+	startTimeExpvar := timedOutChecksStats.Get(string(id))
+	if startTimeExpvar == nil {
+		// "Zero" time
+		return time.Time{}
+	}
+	return time.Time(startTimeExpvar.(timestamp))
+}
+
+// DeleteTimedOutStats clears the start time of a check which exceeded its run timeout
+// when it's complete
+func DeleteTimedOutStats(id checkid.ID) {
+	timedOutChecksStats.Delete(string(id))
+}
+
 // AddRunningCheckCount is used to increment and decrement the 'RunningChecks' expvar
 func AddRunningCheckCount(amount int) {
 	runnerStats.Add(runningChecksExpvarKey, int64(amount))
@@ -252,6 +284,20 @@ func GetWarningsCount() int64 {
 	return count.(*expvar.Int).Value()
 }
 
+// AddTimeoutsCount is used to increment the 'Timeouts' expvar
+func AddTimeoutsCount(amount int) {
+	runnerStats.Add(timeoutsExpvarKey, int64(amount))
+}
+
+// GetTimeoutsCount is used to get the value of 'Timeouts' expvar
+func GetTimeoutsCount() int64 {
+	count := runnerStats.Get(timeoutsExpvarKey)
+	if count == nil {
+		return 0
+	}
+	return count.(*expvar.Int).Value()
+}
+
 // AddErrorsCount is used to increment the 'Errors' expvar
 func AddErrorsCount(amount int) {
 	runnerStats.Add(errorsExpvarKey, int64(amount))
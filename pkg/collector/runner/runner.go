@@ -115,6 +115,13 @@ func (r *Runner) AddWorker() {
 
 // addWorker adds a new worker running in a separate goroutine
 func (r *Runner) newWorker() (*worker.Worker, error) {
+	// Replace the workers busy with checks which exceeded their run timeout, to preserve
+	// the capacity of the pool. The busy workers exit once their check returns.
+	var replaceFunc func()
+	if config.Datadog.GetBool("check_run_timeout_replace_worker") {
+		replaceFunc = r.AddWorker
+	}
+
 	worker, err := worker.NewWorker(
 		r.senderManager,
 		r.id,
@@ -122,6 +129,7 @@ func (r *Runner) newWorker() (*worker.Worker, error) {
 		r.pendingChecksChan,
 		r.checksTracker,
 		r.ShouldAddCheckStats,
+		replaceFunc,
 	)
 	if err != nil {
 		log.Errorf("Runner %d was unable to instantiate a worker: %s", r.id, err)
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// scheduleOptions holds the scheduling options of a check instance
+type scheduleOptions struct {
+	cron   cron.Schedule // when set, the check runs on this schedule instead of its interval
+	jitter time.Duration // maximum random delay added to each run of the check
+}
+
+// getScheduleOptions reads the `schedule` and `schedule_jitter` options of the check
+// instance. The jitter defaults to `defaultJitter`.
+func getScheduleOptions(c check.Check, defaultJitter time.Duration) (scheduleOptions, error) {
+	opts := scheduleOptions{jitter: defaultJitter}
+
+	instanceOptions := integration.CommonInstanceConfig{}
+	if err := yaml.Unmarshal([]byte(c.InstanceConfig()), &instanceOptions); err != nil {
+		return opts, nil
+	}
+
+	if instanceOptions.ScheduleJitter > 0 {
+		opts.jitter = time.Duration(instanceOptions.ScheduleJitter) * time.Second
+	}
+
+	if instanceOptions.Schedule != "" {
+		schedule, err := cron.ParseStandard(instanceOptions.Schedule)
+		if err != nil {
+			return opts, fmt.Errorf("invalid schedule %q: %s", instanceOptions.Schedule, err)
+		}
+		opts.cron = schedule
+	}
+
+	return opts, nil
+}
+
+// cronJob schedules a check on a cron schedule, in its own goroutine
+type cronJob struct {
+	check    check.Check
+	schedule cron.Schedule
+	jitter   time.Duration
+	running  bool          // protected by the Scheduler mutex
+	done     chan struct{} // to stop the job
+	next     time.Time
+	mu       sync.RWMutex // to protect next
+}
+
+func newCronJob(c check.Check, schedule cron.Schedule, jitter time.Duration) *cronJob {
+	return &cronJob{
+		check:    c,
+		schedule: schedule,
+		jitter:   jitter,
+	}
+}
+
+// nextRun returns the time of the next run of the check, jitter included
+func (j *cronJob) nextRun() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if j.next.IsZero() {
+		return j.schedule.Next(time.Now())
+	}
+	return j.next
+}
+
+// jitterDelay returns the random delay of the run of the given activation, up to the
+// jitter capped to the time until the following activation, like the queue jitter is
+// capped to the queue interval.
+func (j *cronJob) jitterDelay(activation time.Time) time.Duration {
+	jitter := j.jitter
+	if period := j.schedule.Next(activation).Sub(activation); jitter > period {
+		jitter = period
+	}
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// run schedules the check by posting it to the execution pipeline at every
+// activation of its schedule.
+// Not blocking, runs in a new goroutine.
+func (j *cronJob) run(s *Scheduler) {
+	j.done = make(chan struct{})
+	s.wgCron.Add(1)
+
+	go func(done <-chan struct{}) {
+		defer s.wgCron.Done()
+
+		var activation time.Time
+		for {
+			now := time.Now()
+			if activation.Before(now) {
+				activation = now
+			}
+			activation = j.schedule.Next(activation)
+
+			next := activation.Add(j.jitterDelay(activation))
+			j.mu.Lock()
+			j.next = next
+			j.mu.Unlock()
+
+			log.Debugf("Next run of check %s scheduled at %s", j.check.ID(), next)
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+			case <-done:
+				timer.Stop()
+				return
+			}
+
+			if !s.IsCheckScheduled(j.check.ID()) {
+				continue
+			}
+
+			select {
+			// blocking, we'll be here as long as it takes
+			case s.checksPipe <- j.check:
+			case <-done:
+				return
+			}
+		}
+	}(j.done)
+}
+
+// stop signals the job to stop. The Scheduler waits for its goroutine to exit
+// when stopping the queues.
+func (j *cronJob) stop() {
+	close(j.done)
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+func TestGetScheduleOptions(t *testing.T) {
+	c := &TestCheck{intl: 15 * time.Second}
+
+	opts, err := getScheduleOptions(c, 10*time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, opts.cron)
+	assert.Equal(t, 10*time.Second, opts.jitter)
+
+	c.instance = "schedule_jitter: 30"
+	opts, err = getScheduleOptions(c, 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, opts.jitter)
+
+	// every 15 minutes at :05
+	c.instance = "schedule: 5-59/15 * * * *"
+	opts, err = getScheduleOptions(c, 0)
+	require.NoError(t, err)
+	require.NotNil(t, opts.cron)
+	from := time.Date(2023, 11, 14, 10, 6, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2023, 11, 14, 10, 20, 0, 0, time.Local), opts.cron.Next(from))
+
+	c.instance = "schedule: every day"
+	_, err = getScheduleOptions(c, 0)
+	assert.Error(t, err)
+}
+
+func TestCronJitterDelay(t *testing.T) {
+	c := &TestCheck{intl: 15 * time.Second, instance: "schedule: '@every 1m'"}
+	opts, err := getScheduleOptions(c, time.Hour)
+	require.NoError(t, err)
+
+	// the jitter is capped to the schedule period
+	j := newCronJob(c, opts.cron, opts.jitter)
+	activation := opts.cron.Next(time.Now())
+	for i := 0; i < 100; i++ {
+		assert.Less(t, j.jitterDelay(activation), time.Minute)
+	}
+
+	j = newCronJob(c, opts.cron, 0)
+	assert.Zero(t, j.jitterDelay(activation))
+}
+
+func TestEnterCron(t *testing.T) {
+	ch := make(chan check.Check)
+	s := NewScheduler(ch)
+
+	c := &TestJobCheck{TestCheck: TestCheck{intl: 15 * time.Second, instance: "schedule: '@every 1s'"}, id: "cron"}
+	require.Nil(t, s.Enter(c))
+	assert.Len(t, s.jobQueues, 0)
+	assert.True(t, s.IsCheckScheduled(c.ID()))
+	s.Run()
+
+	select {
+	case scheduled := <-ch:
+		assert.Equal(t, c.ID(), scheduled.ID())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the cron check wasn't enqueued")
+	}
+
+	require.Nil(t, s.Cancel(c.ID()))
+	assert.False(t, s.IsCheckScheduled(c.ID()))
+
+	require.Nil(t, s.Stop())
+	// the cron jobs, the cancelled ones included, have exited, closing the pipe is safe
+	close(ch)
+}
+
+func TestEnterInvalidSchedule(t *testing.T) {
+	s := getScheduler()
+
+	err := s.Enter(&TestCheck{intl: 15 * time.Second, instance: "schedule: '61 * * * *'"})
+	assert.Error(t, err)
+	assert.Len(t, s.checkToCron, 0)
+	assert.Len(t, s.jobQueues, 0)
+}
@@ -8,6 +8,7 @@ package scheduler
 
 import (
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -69,16 +70,21 @@ type jobQueue struct {
 	currentBucketIdx    uint
 	schedulingBucketIdx uint
 	running             bool
+	spread              bool                         // spread the checks across the buckets and within each tick
+	jitters             map[checkid.ID]time.Duration // maximum random delay added to the runs of each check
+	delayed             sync.WaitGroup               // to track the delayed enqueuing goroutines
 	health              *health.Handle
 	mu                  sync.RWMutex // to protect critical sections in struct's fields
 }
 
 // newJobQueue creates a new jobQueue instance
-func newJobQueue(interval time.Duration) *jobQueue {
+func newJobQueue(interval time.Duration, spread bool) *jobQueue {
 	jq := &jobQueue{
 		interval:     interval,
 		stop:         make(chan bool),
 		stopped:      make(chan bool),
+		spread:       spread,
+		jitters:      make(map[checkid.ID]time.Duration),
 		health:       health.RegisterLiveness(fmt.Sprintf("collector-queue-%vs", interval.Seconds())),
 		bucketTicker: time.NewTicker(time.Second),
 	}
@@ -115,11 +121,34 @@ func newJobQueue(interval time.Duration) *jobQueue {
 	return jq
 }
 
-// addJob is a convenience method to add a check to a queue
-func (jq *jobQueue) addJob(c check.Check) {
+// addJob is a convenience method to add a check to a queue. Each run of the check
+// is delayed by a random duration up to `jitter`, capped to the queue interval.
+func (jq *jobQueue) addJob(c check.Check, jitter time.Duration) {
 	jq.mu.Lock()
 	defer jq.mu.Unlock()
 
+	if jitter > jq.interval {
+		jitter = jq.interval
+	}
+	if jitter > 0 {
+		jq.jitters[c.ID()] = jitter
+	}
+
+	if jq.spread {
+		// Checks scheduled to the least loaded bucket, starting from the sparse round-robin one
+		nb := uint(len(jq.buckets))
+		idx := jq.schedulingBucketIdx
+		for i := uint(0); i < nb; i++ {
+			candidate := (jq.schedulingBucketIdx + i*jq.sparseStep) % nb
+			if jq.buckets[candidate].size() < jq.buckets[idx].size() {
+				idx = candidate
+			}
+		}
+		jq.buckets[idx].addJob(c)
+		jq.schedulingBucketIdx = (idx + jq.sparseStep) % nb
+		return
+	}
+
 	// Checks scheduled to buckets scheduled with sparse round-robin
 	jq.buckets[jq.schedulingBucketIdx].addJob(c)
 	jq.schedulingBucketIdx = (jq.schedulingBucketIdx + jq.sparseStep) % uint(len(jq.buckets))
@@ -131,6 +160,7 @@ func (jq *jobQueue) removeJob(id checkid.ID) error {
 
 	for _, bucket := range jq.buckets {
 		if found := bucket.removeJob(id); found {
+			delete(jq.jitters, id)
 			return nil
 		}
 	}
@@ -156,23 +186,98 @@ func (jq *jobQueue) stats() map[string]interface{} {
 	}
 }
 
+// nextRuns returns the time of the next tick scheduling each check of the queue,
+// not accounting for the jitter and spreading delays.
+func (jq *jobQueue) nextRuns(now time.Time) map[checkid.ID]time.Time {
+	jq.mu.RLock()
+	defer jq.mu.RUnlock()
+
+	lastTick := jq.lastTick
+	if lastTick.IsZero() {
+		lastTick = now
+	}
+
+	runs := make(map[checkid.ID]time.Time)
+	nb := uint(len(jq.buckets))
+	for idx, bucket := range jq.buckets {
+		ticks := (uint(idx) + nb - jq.currentBucketIdx) % nb
+		next := lastTick.Add(time.Duration(ticks+1) * time.Second)
+
+		bucket.mu.RLock()
+		for _, c := range bucket.jobs {
+			runs[c.ID()] = next
+		}
+		bucket.mu.RUnlock()
+	}
+	return runs
+}
+
 // run schedules the checks in the queue by posting them to the
 // execution pipeline.
 // Not blocking, runs in a new goroutine.
 func (jq *jobQueue) run(s *Scheduler) {
+	// closed once the queue stops, to cancel the delayed enqueuing goroutines
+	stopDelayed := make(chan struct{})
 
 	go func() {
 		log.Debugf("Job queue is running...")
-		for jq.process(s) {
+		for jq.process(s, stopDelayed) {
 			// empty
 		}
+		close(stopDelayed)
+		jq.delayed.Wait()
 		jq.stopped <- true
 	}()
 }
 
+// delay returns how long to wait before enqueuing the check at position `idx` of a bucket
+// holding `size` checks: its offset within the tick when spreading, plus a random jitter.
+func (jq *jobQueue) delay(c check.Check, idx int, size int) time.Duration {
+	var delay time.Duration
+	if jq.spread && size > 1 {
+		delay = time.Duration(idx) * time.Second / time.Duration(size)
+	}
+
+	jq.mu.RLock()
+	jitter := jq.jitters[c.ID()]
+	jq.mu.RUnlock()
+	if jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return delay
+}
+
+// enqueueDelayed enqueues the check after `delay`, in a new goroutine.
+// The enqueuing is cancelled if the queue stops meanwhile.
+func (jq *jobQueue) enqueueDelayed(s *Scheduler, c check.Check, delay time.Duration, stopDelayed <-chan struct{}) {
+	jq.delayed.Add(1)
+
+	go func() {
+		defer jq.delayed.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-stopDelayed:
+			return
+		}
+
+		if !s.IsCheckScheduled(c.ID()) {
+			return
+		}
+
+		select {
+		case s.checksPipe <- c:
+		case <-stopDelayed:
+		}
+	}()
+}
+
 // process  enqueues the checks at a tick, and returns whether the queue
 // should listen to the following tick (or stop)
-func (jq *jobQueue) process(s *Scheduler) bool {
+func (jq *jobQueue) process(s *Scheduler, stopDelayed <-chan struct{}) bool {
 
 	select {
 	case <-jq.stop:
@@ -197,11 +302,16 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 
 		log.Tracef("Jobs in bucket: %v", jobs)
 
-		for _, check := range jobs {
+		for idx, check := range jobs {
 			if !s.IsCheckScheduled(check.ID()) {
 				continue
 			}
 
+			if delay := jq.delay(check, idx, len(jobs)); delay > 0 {
+				jq.enqueueDelayed(s, check, delay, stopDelayed)
+				continue
+			}
+
 			select {
 			// blocking, we'll be here as long as it takes
 			case s.checksPipe <- check:
@@ -6,12 +6,15 @@
 package scheduler
 
 import (
+	"fmt"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/util/testutil"
 )
@@ -66,3 +69,85 @@ func TestBucket_RemoveJob(t *testing.T) {
 	// use the bucket, just to keep it alive during the earlier GC run
 	bucket.addJob(&TestJobCheck{id: "here so the GC doesn't GC the entire bucket"})
 }
+
+func TestJobQueue_Jitter(t *testing.T) {
+	jq := newJobQueue(5*time.Second, false)
+
+	jittered := &TestJobCheck{id: "jittered"}
+	capped := &TestJobCheck{id: "capped"}
+	jq.addJob(jittered, 2*time.Second)
+	jq.addJob(capped, time.Minute)
+	jq.addJob(&TestJobCheck{id: "none"}, 0)
+
+	assert.Equal(t, map[checkid.ID]time.Duration{"jittered": 2 * time.Second, "capped": 5 * time.Second}, jq.jitters)
+
+	for i := 0; i < 100; i++ {
+		delay := jq.delay(jittered, 0, 1)
+		assert.True(t, delay >= 0 && delay < 2*time.Second, "delay out of the jitter range: %s", delay)
+	}
+	assert.Zero(t, jq.delay(&TestJobCheck{id: "none"}, 0, 1))
+
+	require.Nil(t, jq.removeJob("jittered"))
+	assert.NotContains(t, jq.jitters, checkid.ID("jittered"))
+}
+
+func TestJobQueue_Spread(t *testing.T) {
+	jq := newJobQueue(4*time.Second, true)
+
+	for i := 0; i < 4; i++ {
+		jq.addJob(&TestJobCheck{id: fmt.Sprintf("check-%d", i)}, 0)
+	}
+	for _, bucket := range jq.buckets {
+		assert.Equal(t, 1, bucket.size())
+	}
+
+	// the new checks fill the least loaded buckets
+	removed := jq.buckets[2].jobs[0].ID()
+	require.Nil(t, jq.removeJob(removed))
+	jq.addJob(&TestJobCheck{id: "new"}, 0)
+	assert.Equal(t, checkid.ID("new"), jq.buckets[2].jobs[0].ID())
+
+	// the checks of a bucket are spread within the tick
+	c := &TestJobCheck{id: "spread"}
+	assert.Zero(t, jq.delay(c, 0, 4))
+	assert.Equal(t, 500*time.Millisecond, jq.delay(c, 2, 4))
+}
+
+func TestJobQueue_NextRuns(t *testing.T) {
+	jq := newJobQueue(3*time.Second, false)
+	for i := 0; i < 3; i++ {
+		jq.addJob(&TestJobCheck{id: fmt.Sprintf("check-%d", i)}, 0)
+	}
+
+	tick := time.Unix(1000, 0)
+	jq.lastTick = tick
+	jq.currentBucketIdx = 1
+
+	runs := jq.nextRuns(time.Now())
+	assert.Len(t, runs, 3)
+	for idx, bucket := range jq.buckets {
+		expected := map[int]time.Time{0: tick.Add(3 * time.Second), 1: tick.Add(time.Second), 2: tick.Add(2 * time.Second)}[idx]
+		assert.Equal(t, expected, runs[bucket.jobs[0].ID()])
+	}
+}
+
+func TestJobQueue_DelayedEnqueue(t *testing.T) {
+	ch := make(chan check.Check, 1)
+	s := NewScheduler(ch)
+	s.defaultJitter = 500 * time.Millisecond
+
+	c := &TestJobCheck{TestCheck: TestCheck{intl: time.Second}, id: "jittered"}
+	require.Nil(t, s.Enter(c))
+	s.Run()
+
+	select {
+	case scheduled := <-ch:
+		assert.Equal(t, c.ID(), scheduled.ID())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the jittered check wasn't enqueued")
+	}
+
+	require.Nil(t, s.Stop())
+	// the pending delayed enqueuing goroutines have exited, closing the pipe is safe
+	close(ch)
+}
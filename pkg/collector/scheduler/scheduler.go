@@ -13,6 +13,7 @@ import (
 
 	"go.uber.org/atomic"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -48,17 +49,21 @@ type Scheduler struct {
 	started          chan bool                   // Used to internally communicate the queues are up
 	jobQueues        map[time.Duration]*jobQueue // We have one scheduling queue for every interval
 	tlmTrackedChecks map[checkid.ID]string       // Keep track of the checks that are tracked with telemetry
+	defaultJitter    time.Duration               // Maximum random delay added to the check runs, unless set on the instance
+	spread           bool                        // Spread the checks evenly across the buckets of the queues
 	mu               sync.Mutex                  // To protect critical sections in struct's fields
 
 	checkToQueue map[checkid.ID]*jobQueue // Keep track of what is the queue for any Check
+	checkToCron  map[checkid.ID]*cronJob  // Keep track of the checks scheduled on a cron schedule
 	// To protect checkToQueue. Using mu would create a deadlock when stopping the Scheduler. 'jobQueue' is calling
 	// 'IsCheckScheduled' right when then 'Stop' function is called and mu is already lock. for this reason we have
 	// to lock: one for the Scheduler and a dedicated one for the 'IsCheckScheduled' method. This way 'jobQueue' and
-	// metadata provider can call 'IsCheckScheduled' without creating a deadlock.
+	// metadata provider can call 'IsCheckScheduled' without creating a deadlock. It also protects checkToCron.
 	checkToQueueMutex sync.RWMutex
 
 	cancelOneTime chan bool      // Used to internally communicate a cancel signal to one-time schedule goroutines
 	wgOneTime     sync.WaitGroup // WaitGroup to track the exit of one-time schedule goroutines
+	wgCron        sync.WaitGroup // WaitGroup to track the exit of the cron jobs goroutines, cancelled ones included
 }
 
 // NewScheduler create a Scheduler and returns a pointer to it.
@@ -70,14 +75,18 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 		started:          make(chan bool),
 		jobQueues:        make(map[time.Duration]*jobQueue),
 		checkToQueue:     make(map[checkid.ID]*jobQueue),
+		checkToCron:      make(map[checkid.ID]*cronJob),
 		tlmTrackedChecks: make(map[checkid.ID]string),
+		defaultJitter:    config.Datadog.GetDuration("check_scheduling.jitter"),
+		spread:           config.Datadog.GetBool("check_scheduling.spread"),
 		running:          atomic.NewBool(false),
 		cancelOneTime:    make(chan bool),
 		wgOneTime:        sync.WaitGroup{},
 	}
 }
 
-// Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value.
+// Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value,
+// or to the cron schedule set with the `schedule` option of its instance.
 // If the interval is 0, the check is supposed to run only once.
 func (s *Scheduler) Enter(check check.Check) error {
 	// enqueue immediately if this is a one-time schedule
@@ -86,6 +95,15 @@ func (s *Scheduler) Enter(check check.Check) error {
 		return nil
 	}
 
+	opts, err := getScheduleOptions(check, s.defaultJitter)
+	if err != nil {
+		return err
+	}
+	if opts.cron != nil {
+		s.enterCron(check, opts)
+		return nil
+	}
+
 	if check.Interval() < minAllowedInterval {
 		return fmt.Errorf("schedule interval must be greater than %v or 0", minAllowedInterval)
 	}
@@ -97,20 +115,49 @@ func (s *Scheduler) Enter(check check.Check) error {
 	defer s.mu.Unlock()
 
 	if _, ok := s.jobQueues[check.Interval()]; !ok {
-		s.jobQueues[check.Interval()] = newJobQueue(check.Interval())
+		s.jobQueues[check.Interval()] = newJobQueue(check.Interval(), s.spread)
 		s.startQueue(s.jobQueues[check.Interval()])
 		if check.IsTelemetryEnabled() {
 			tlmQueuesCount.Inc()
 		}
 		schedulerQueuesCount.Add(1)
 	}
-	s.jobQueues[check.Interval()].addJob(check)
+	s.jobQueues[check.Interval()].addJob(check, opts.jitter)
 
 	// map each check to the Job Queue it was assigned to
 	s.checkToQueueMutex.Lock()
 	s.checkToQueue[check.ID()] = s.jobQueues[check.Interval()]
 	s.checkToQueueMutex.Unlock()
 
+	s.trackEnteredCheck(check)
+	return nil
+}
+
+// enterCron schedules a check on its cron schedule, in a dedicated job
+func (s *Scheduler) enterCron(check check.Check, opts scheduleOptions) {
+	log.Infof("Scheduling check %s on a cron schedule", check.ID())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := newCronJob(check, opts.cron, opts.jitter)
+
+	s.checkToQueueMutex.Lock()
+	if previous, ok := s.checkToCron[check.ID()]; ok && previous.running {
+		previous.stop()
+	}
+	s.checkToCron[check.ID()] = job
+	s.checkToQueueMutex.Unlock()
+
+	// like the queues, the job starts scheduling the check right away
+	s.startCronJob(job)
+
+	s.trackEnteredCheck(check)
+}
+
+// trackEnteredCheck updates the telemetry and expvars once a check is scheduled.
+// Must be called with the Scheduler mutex held.
+func (s *Scheduler) trackEnteredCheck(check check.Check) {
 	schedulerChecksEntered.Add(1)
 	if check.IsTelemetryEnabled() {
 		checkName := check.String()
@@ -118,7 +165,7 @@ func (s *Scheduler) Enter(check check.Check) error {
 		tlmChecksEntered.Inc(checkName)
 	}
 	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
-	return nil
+	schedulerExpvars.Set("NextRuns", expvar.Func(expNextRuns(s)))
 }
 
 // Cancel remove a Check from the scheduled queue. If the check is not
@@ -131,16 +178,24 @@ func (s *Scheduler) Cancel(id checkid.ID) error {
 
 	log.Infof("Unscheduling check %s", string(id))
 
-	if _, ok := s.checkToQueue[id]; !ok {
-		return nil
-	}
+	if job, ok := s.checkToCron[id]; ok {
+		// don't wait for the job to stop, it may be waiting on the checkToQueue lock
+		if job.running {
+			job.stop()
+		}
+		delete(s.checkToCron, id)
+	} else {
+		if _, ok := s.checkToQueue[id]; !ok {
+			return nil
+		}
 
-	// remove it from the queue
-	err := s.checkToQueue[id].removeJob(id)
-	if err != nil {
-		return fmt.Errorf("unable to remove the Job from the queue: %s", err)
+		// remove it from the queue
+		err := s.checkToQueue[id].removeJob(id)
+		if err != nil {
+			return fmt.Errorf("unable to remove the Job from the queue: %s", err)
+		}
+		delete(s.checkToQueue, id)
 	}
-	delete(s.checkToQueue, id)
 
 	schedulerChecksEntered.Add(-1)
 	if checkName, ok := s.tlmTrackedChecks[id]; ok {
@@ -214,10 +269,39 @@ func (s *Scheduler) IsCheckScheduled(id checkid.ID) bool {
 	s.checkToQueueMutex.RLock()
 	defer s.checkToQueueMutex.RUnlock()
 
+	if _, found := s.checkToCron[id]; found {
+		return true
+	}
 	_, found := s.checkToQueue[id]
 	return found
 }
 
+// NextRuns returns the time of the next run of each scheduled check. For the checks
+// scheduled at an interval, the jitter and spreading delays are not accounted for.
+func (s *Scheduler) NextRuns() map[checkid.ID]time.Time {
+	s.checkToQueueMutex.RLock()
+	defer s.checkToQueueMutex.RUnlock()
+
+	now := time.Now()
+	runs := make(map[checkid.ID]time.Time)
+
+	queues := make(map[*jobQueue]struct{})
+	for _, q := range s.checkToQueue {
+		queues[q] = struct{}{}
+	}
+	for q := range queues {
+		for id, next := range q.nextRuns(now) {
+			runs[id] = next
+		}
+	}
+
+	for id, job := range s.checkToCron {
+		runs[id] = job.nextRun()
+	}
+
+	return runs
+}
+
 // stopQueues shuts down the timers for each active queue
 // Blocks until all the queues have fully stopped
 func (s *Scheduler) stopQueues() {
@@ -235,6 +319,15 @@ func (s *Scheduler) stopQueues() {
 			q.running = false
 		}
 	}
+
+	log.Debugf("Stopping %v cron job(s)", len(s.checkToCron))
+	for _, job := range s.checkToCron {
+		if job.running {
+			job.stop()
+			job.running = false
+		}
+	}
+	s.wgCron.Wait()
 }
 
 // startQueues loads the timer for each queue
@@ -246,6 +339,9 @@ func (s *Scheduler) startQueues() {
 	for _, q := range s.jobQueues {
 		s.startQueue(q)
 	}
+	for _, job := range s.checkToCron {
+		s.startCronJob(job)
+	}
 }
 
 // startQueue starts a queue (non-blocking operation) if it's not running yet
@@ -256,6 +352,14 @@ func (s *Scheduler) startQueue(q *jobQueue) {
 	}
 }
 
+// startCronJob starts a cron job (non-blocking operation) if it's not running yet
+func (s *Scheduler) startCronJob(job *cronJob) {
+	if !job.running {
+		job.run(s)
+		job.running = true
+	}
+}
+
 // enqueueOnce enqueues a check once to the checksPipe.
 // Do not block, in case the runner has not started yet.
 // The queuing can be cancelled by closing the `cancelOneTime` channel.
@@ -285,3 +389,15 @@ func expQueues(s *Scheduler) func() interface{} {
 		return queues
 	}
 }
+
+// expNextRuns return a function to get the next run time of the checks, as unix timestamps
+func expNextRuns(s *Scheduler) func() interface{} {
+	return func() interface{} {
+		nextRuns := make(map[string]int64)
+
+		for id, next := range s.NextRuns() {
+			nextRuns[string(id)] = next.Unix()
+		}
+		return nextRuns
+	}
+}
@@ -12,17 +12,21 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/collector/check/stub"
 )
 
 // FIXTURE
 type TestCheck struct {
 	stub.StubCheck
-	intl time.Duration
+	intl     time.Duration
+	instance string
 }
 
 func (c *TestCheck) Interval() time.Duration { return c.intl }
 
+func (c *TestCheck) InstanceConfig() string { return c.instance }
+
 var initialMinAllowedInterval = minAllowedInterval
 
 func consume(c chan check.Check, stop chan bool) {
@@ -199,3 +203,24 @@ func TestStopOneTimeSchedule(t *testing.T) {
 	// sleep to make the runtime schedule the hanging goroutines, if there are any
 	time.Sleep(time.Millisecond)
 }
+
+func TestNextRuns(t *testing.T) {
+	s := getScheduler()
+
+	intervalCheck := &TestJobCheck{TestCheck: TestCheck{intl: 10 * time.Second}, id: "interval"}
+	cronCheck := &TestJobCheck{TestCheck: TestCheck{intl: 15 * time.Second, instance: "schedule: '@daily'"}, id: "cron"}
+	assert.Nil(t, s.Enter(intervalCheck))
+	assert.Nil(t, s.Enter(cronCheck))
+	defer s.Stop()
+
+	now := time.Now()
+	runs := s.NextRuns()
+	assert.Len(t, runs, 2)
+	assert.WithinDuration(t, now, runs["interval"], 11*time.Second)
+	assert.True(t, runs["cron"].After(now))
+	assert.Equal(t, 0, runs["cron"].Hour())
+	assert.Equal(t, 0, runs["cron"].Minute())
+
+	assert.Nil(t, s.Cancel("cron"))
+	assert.NotContains(t, s.NextRuns(), checkid.ID("cron"))
+}
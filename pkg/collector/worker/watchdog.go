@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/internal/middleware"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/expvars"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/hostname"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	serviceCheckRunTimeoutKey = "datadog.agent.check_run_timeout"
+)
+
+// runTimeout returns the run timeout of a check: the `run_timeout` of its instance, or of
+// its init_config, or the global `check_run_timeout`. Zero means no timeout.
+func runTimeout(c check.Check) time.Duration {
+	instanceOptions := integration.CommonInstanceConfig{}
+	if err := yaml.Unmarshal([]byte(c.InstanceConfig()), &instanceOptions); err == nil && instanceOptions.RunTimeout > 0 {
+		return time.Duration(instanceOptions.RunTimeout) * time.Second
+	}
+
+	globalOptions := integration.CommonGlobalConfig{}
+	if err := yaml.Unmarshal([]byte(c.InitConfig()), &globalOptions); err == nil && globalOptions.RunTimeout > 0 {
+		return time.Duration(globalOptions.RunTimeout) * time.Second
+	}
+
+	return config.Datadog.GetDuration("check_run_timeout")
+}
+
+// runWatchdog watches a check run, and handles it when it exceeds its timeout: the check is
+// marked as timed out in the runner stats, a critical service check is sent, the Go checks
+// are cancelled and, if the worker can be replaced, its replacement is requested.
+type runWatchdog struct {
+	worker    *Worker
+	check     check.Check
+	startTime time.Time
+	timeout   time.Duration
+	timer     *time.Timer
+	replaced  bool
+	done      chan struct{}
+}
+
+func (w *Worker) startWatchdog(c check.Check, startTime time.Time, timeout time.Duration) *runWatchdog {
+	wd := &runWatchdog{
+		worker:    w,
+		check:     c,
+		startTime: startTime,
+		timeout:   timeout,
+		done:      make(chan struct{}),
+	}
+	wd.timer = time.AfterFunc(timeout, wd.fire)
+	return wd
+}
+
+func (wd *runWatchdog) fire() {
+	defer close(wd.done)
+
+	c := wd.check
+	message := fmt.Sprintf("Check run exceeded its timeout of %s", wd.timeout)
+	log.Warnc(message, "check", c)
+
+	expvars.SetTimedOutStats(c.ID(), wd.startTime)
+	expvars.AddTimeoutsCount(1)
+	wd.sendServiceCheck(servicecheck.ServiceCheckCritical, message)
+
+	c.Stop()
+	cancelGoCheck(c)
+
+	if wd.worker.replaceFunc != nil {
+		log.Warnf("Runner %d, worker %d: replacing the worker, busy with check %s", wd.worker.runnerID, wd.worker.ID, c.ID())
+		wd.worker.replaceFunc()
+		wd.replaced = true
+	}
+}
+
+// cancelGoCheck cancels a Go check to interrupt its run. The checks scheduled by the collector
+// are wrapped, and the Cancel of the wrapper also destroys the sender of the check, which stays
+// scheduled: the wrapped check is cancelled instead.
+func cancelGoCheck(c check.Check) {
+	if wrapper, ok := c.(*middleware.CheckWrapper); ok {
+		c = wrapper.Inner()
+	}
+	if goCheck, ok := c.(check.GoCheck); ok && goCheck.IsGoCheck() {
+		c.Cancel()
+	}
+}
+
+// stop stops watching the run once it is complete. It returns whether the run exceeded its
+// timeout, and whether the worker was replaced meanwhile.
+func (wd *runWatchdog) stop() (timedOut bool, replaced bool) {
+	if wd.timer.Stop() {
+		return false, false
+	}
+	<-wd.done
+
+	expvars.DeleteTimedOutStats(wd.check.ID())
+	wd.sendServiceCheck(servicecheck.ServiceCheckOK, "")
+	return true, wd.replaced
+}
+
+func (wd *runWatchdog) sendServiceCheck(status servicecheck.ServiceCheckStatus, message string) {
+	sender, err := wd.worker.getDefaultSenderFunc()
+	if err != nil || sender == nil {
+		log.Errorf("Error getting default sender: %v. Not sending run timeout status check for %s", err, wd.check)
+		return
+	}
+	hname, _ := hostname.Get(context.TODO())
+	tags := []string{fmt.Sprintf("check:%s", wd.check.String()), fmt.Sprintf("check_id:%s", wd.check.ID())}
+	sender.ServiceCheck(serviceCheckRunTimeoutKey, status, hname, tags, message)
+	sender.Commit()
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package worker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/collector/internal/middleware"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/expvars"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/tracker"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+// hungCheck is a Go check whose runs block until they are released.
+type hungCheck struct {
+	testCheck
+	instanceConfig string
+	initConfig     string
+	release        chan struct{}
+	cancelCount    *atomic.Uint64
+}
+
+func newHungCheck(t *testing.T, id string) *hungCheck {
+	c := &hungCheck{
+		release:     make(chan struct{}),
+		cancelCount: atomic.NewUint64(0),
+	}
+	c.testCheck = *newCheck(t, id, false, func(checkid.ID) { <-c.release })
+	return c
+}
+
+func (c *hungCheck) Run() error             { return c.testCheck.Run() }
+func (c *hungCheck) Cancel()                { c.cancelCount.Inc() }
+func (c *hungCheck) IsGoCheck() bool        { return true }
+func (c *hungCheck) InstanceConfig() string { return c.instanceConfig }
+func (c *hungCheck) InitConfig() string     { return c.initConfig }
+
+func setupTimeoutSender(t *testing.T, checkName string, id string) *mocksender.MockSender {
+	mockSender := mocksender.NewMockSender("")
+	tags := []string{"check:" + checkName, "check_id:" + id}
+	mockSender.On("ServiceCheck", serviceCheckRunTimeoutKey, servicecheck.ServiceCheckCritical, "myhost", tags, mock.AnythingOfType("string")).Return().Once()
+	mockSender.On("ServiceCheck", serviceCheckRunTimeoutKey, servicecheck.ServiceCheckOK, "myhost", tags, "").Return().Once()
+	mockSender.On("Commit").Return()
+	config.Datadog.SetWithoutSource("integration_check_status_enabled", false)
+	return mockSender
+}
+
+func TestRunTimeout(t *testing.T) {
+	config.Datadog.SetWithoutSource("check_run_timeout", 30*time.Second)
+	defer config.Datadog.SetWithoutSource("check_run_timeout", 0)
+
+	c := newHungCheck(t, "check:123")
+	assert.Equal(t, 30*time.Second, runTimeout(c))
+
+	c.initConfig = "run_timeout: 20"
+	assert.Equal(t, 20*time.Second, runTimeout(c))
+
+	c.instanceConfig = "run_timeout: 10"
+	assert.Equal(t, 10*time.Second, runTimeout(c))
+}
+
+func TestWorkerRunTimeout(t *testing.T) {
+	expvars.Reset()
+	config.Datadog.SetWithoutSource("hostname", "myhost")
+	config.Datadog.SetWithoutSource("check_run_timeout", 50*time.Millisecond)
+	defer config.Datadog.SetWithoutSource("check_run_timeout", 0)
+
+	c := newHungCheck(t, "hung:123")
+	mockSender := setupTimeoutSender(t, "hung", "hung:123")
+
+	pendingChecksChan := make(chan check.Check, 1)
+	pendingChecksChan <- c
+	close(pendingChecksChan)
+
+	worker, err := newWorkerWithOptions(
+		100,
+		200,
+		pendingChecksChan,
+		tracker.NewRunningChecksTracker(),
+		func(id checkid.ID) bool { return true },
+		nil,
+		func() (sender.Sender, error) { return mockSender, nil },
+		pollingInterval,
+	)
+	require.Nil(t, err)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker.Run()
+	}()
+
+	require.Eventually(t, func() bool { return !expvars.GetTimedOutStats(c.ID()).IsZero() }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, int(expvars.GetTimeoutsCount()))
+	assert.Equal(t, 1, int(c.cancelCount.Load()))
+
+	close(c.release)
+	wg.Wait()
+
+	assert.True(t, expvars.GetTimedOutStats(c.ID()).IsZero())
+	assertErrorCount(t, c, 1)
+	mockSender.AssertExpectations(t)
+	AssertAsyncWorkerCount(t, 0)
+}
+
+// destroyCountingSenderManager counts the senders destroyed.
+type destroyCountingSenderManager struct {
+	aggregator.NoOpSenderManager
+	destroyCount *atomic.Uint64
+}
+
+func (m destroyCountingSenderManager) DestroySender(checkid.ID) { m.destroyCount.Inc() }
+
+func TestWorkerRunTimeoutWrappedCheck(t *testing.T) {
+	expvars.Reset()
+	config.Datadog.SetWithoutSource("hostname", "myhost")
+	config.Datadog.SetWithoutSource("check_run_timeout", 50*time.Millisecond)
+	defer config.Datadog.SetWithoutSource("check_run_timeout", 0)
+
+	c := newHungCheck(t, "hung:789")
+	mockSender := setupTimeoutSender(t, "hung", "hung:789")
+	senderManager := destroyCountingSenderManager{destroyCount: atomic.NewUint64(0)}
+
+	// the collector schedules wrapped checks
+	pendingChecksChan := make(chan check.Check, 1)
+	pendingChecksChan <- middleware.NewCheckWrapper(c, senderManager)
+	close(pendingChecksChan)
+
+	worker, err := newWorkerWithOptions(
+		100,
+		200,
+		pendingChecksChan,
+		tracker.NewRunningChecksTracker(),
+		func(id checkid.ID) bool { return true },
+		nil,
+		func() (sender.Sender, error) { return mockSender, nil },
+		pollingInterval,
+	)
+	require.Nil(t, err)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker.Run()
+	}()
+
+	require.Eventually(t, func() bool { return c.cancelCount.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	close(c.release)
+	wg.Wait()
+
+	// the check stays scheduled, its sender must not be destroyed
+	assert.Zero(t, senderManager.destroyCount.Load())
+	assertErrorCount(t, c, 1)
+	mockSender.AssertExpectations(t)
+	AssertAsyncWorkerCount(t, 0)
+}
+
+func TestWorkerReplacement(t *testing.T) {
+	expvars.Reset()
+	config.Datadog.SetWithoutSource("hostname", "myhost")
+
+	c := newHungCheck(t, "hung:456")
+	c.instanceConfig = "run_timeout: 1"
+	mockSender := setupTimeoutSender(t, "hung", "hung:456")
+
+	// the channel isn't closed: the worker exits because it was replaced
+	pendingChecksChan := make(chan check.Check, 1)
+	pendingChecksChan <- c
+
+	replaced := atomic.NewUint64(0)
+	worker, err := newWorkerWithOptions(
+		100,
+		200,
+		pendingChecksChan,
+		tracker.NewRunningChecksTracker(),
+		func(id checkid.ID) bool { return true },
+		func() { replaced.Inc() },
+		func() (sender.Sender, error) { return mockSender, nil },
+		pollingInterval,
+	)
+	require.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run()
+	}()
+
+	require.Eventually(t, func() bool { return replaced.Load() == 1 }, 3*time.Second, 10*time.Millisecond)
+	close(c.release)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "the replaced worker didn't exit")
+	}
+	assertErrorCount(t, c, 1)
+	mockSender.AssertExpectations(t)
+	AssertAsyncWorkerCount(t, 0)
+}
@@ -49,12 +49,16 @@ type Worker struct {
 	checksTracker           *tracker.RunningChecksTracker
 	getDefaultSenderFunc    func() (sender.Sender, error)
 	pendingChecksChan       chan check.Check
+	replaceFunc             func()
 	runnerID                int
 	shouldAddCheckStatsFunc func(id checkid.ID) bool
 	utilizationTickInterval time.Duration
 }
 
-// NewWorker returns an instance of a `Worker` after parameter sanity checks are passed
+// NewWorker returns an instance of a `Worker` after parameter sanity checks are passed.
+// `replaceFunc` is optional: when set, it is called when the worker is busy with a check
+// which exceeded its run timeout, to add a worker replacing it, and the worker exits once
+// the check returns.
 func NewWorker(
 	senderManager sender.SenderManager,
 	runnerID int,
@@ -62,6 +66,7 @@ func NewWorker(
 	pendingChecksChan chan check.Check,
 	checksTracker *tracker.RunningChecksTracker,
 	shouldAddCheckStatsFunc func(id checkid.ID) bool,
+	replaceFunc func(),
 ) (*Worker, error) {
 
 	if checksTracker == nil {
@@ -82,6 +87,7 @@ func NewWorker(
 		pendingChecksChan,
 		checksTracker,
 		shouldAddCheckStatsFunc,
+		replaceFunc,
 		senderManager.GetDefaultSender,
 		pollingInterval,
 	)
@@ -96,6 +102,7 @@ func newWorkerWithOptions(
 	pendingChecksChan chan check.Check,
 	checksTracker *tracker.RunningChecksTracker,
 	shouldAddCheckStatsFunc func(id checkid.ID) bool,
+	replaceFunc func(),
 	getDefaultSenderFunc func() (sender.Sender, error),
 	utilizationTickInterval time.Duration,
 ) (*Worker, error) {
@@ -111,6 +118,7 @@ func newWorkerWithOptions(
 		Name:                    workerName,
 		checksTracker:           checksTracker,
 		pendingChecksChan:       pendingChecksChan,
+		replaceFunc:             replaceFunc,
 		runnerID:                runnerID,
 		shouldAddCheckStatsFunc: shouldAddCheckStatsFunc,
 		getDefaultSenderFunc:    getDefaultSenderFunc,
@@ -148,11 +156,25 @@ func (w *Worker) Run() {
 
 		utilizationTracker.CheckStarted()
 
+		// Watch the run if the check has a run timeout
+		var watchdog *runWatchdog
+		if timeout := runTimeout(check); timeout > 0 && !longRunning {
+			watchdog = w.startWatchdog(check, checkStartTime, timeout)
+		}
+
 		// Run the check
 		checkErr := check.Run()
 
 		utilizationTracker.CheckFinished()
 
+		timedOut, replaced := false, false
+		if watchdog != nil {
+			timedOut, replaced = watchdog.stop()
+		}
+		if timedOut && checkErr == nil {
+			checkErr = fmt.Errorf("check run exceeded its timeout of %s, it ran for %s", watchdog.timeout, time.Since(checkStartTime))
+		}
+
 		expvars.DeleteRunningStats(check.ID())
 
 		checkWarnings := check.GetWarnings()
@@ -205,6 +227,11 @@ func (w *Worker) Run() {
 		}
 
 		checkLogger.CheckFinished()
+
+		if replaced {
+			log.Infof("Runner %d, worker %d: the worker was replaced while busy, exiting", w.runnerID, w.ID)
+			break
+		}
 	}
 
 	log.Debugf("Runner %d, worker %d: Finished processing checks.", w.runnerID, w.ID)
@@ -122,16 +122,16 @@ func TestWorkerInit(t *testing.T) {
 	mockShouldAddStatsFunc := func(id checkid.ID) bool { return true }
 
 	senderManager := aggregator.NewNoOpSenderManager()
-	_, err := NewWorker(senderManager, 1, 2, nil, checksTracker, mockShouldAddStatsFunc)
+	_, err := NewWorker(senderManager, 1, 2, nil, checksTracker, mockShouldAddStatsFunc, nil)
 	require.NotNil(t, err)
 
-	_, err = NewWorker(senderManager, 1, 2, pendingChecksChan, nil, mockShouldAddStatsFunc)
+	_, err = NewWorker(senderManager, 1, 2, pendingChecksChan, nil, mockShouldAddStatsFunc, nil)
 	require.NotNil(t, err)
 
-	_, err = NewWorker(senderManager, 1, 2, pendingChecksChan, checksTracker, nil)
+	_, err = NewWorker(senderManager, 1, 2, pendingChecksChan, checksTracker, nil, nil)
 	require.NotNil(t, err)
 
-	worker, err := NewWorker(senderManager, 1, 2, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	worker, err := NewWorker(senderManager, 1, 2, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, worker)
 }
@@ -150,7 +150,7 @@ func TestWorkerInitExpvarStats(t *testing.T) {
 		go func(idx int) {
 			defer wg.Done()
 
-			worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 1, idx, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+			worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 1, idx, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 			assert.Nil(t, err)
 
 			worker.Run()
@@ -172,7 +172,7 @@ func TestWorkerName(t *testing.T) {
 
 	for _, id := range []int{1, 100, 500} {
 		expectedName := fmt.Sprintf("worker_%d", id)
-		worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 1, id, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+		worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 1, id, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 		assert.Nil(t, err)
 		assert.NotNil(t, worker)
 
@@ -224,7 +224,7 @@ func TestWorker(t *testing.T) {
 	pendingChecksChan <- testCheck1
 	close(pendingChecksChan)
 
-	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 	require.Nil(t, err)
 
 	wg.Add(1)
@@ -283,6 +283,7 @@ func TestWorkerUtilizationExpvars(t *testing.T) {
 		pendingChecksChan,
 		checksTracker,
 		mockShouldAddStatsFunc,
+		nil,
 		func() (sender.Sender, error) { return nil, nil },
 		100*time.Millisecond,
 	)
@@ -354,7 +355,7 @@ func TestWorkerErrorAndWarningHandling(t *testing.T) {
 	}
 	close(pendingChecksChan)
 
-	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 	require.Nil(t, err)
 	AssertAsyncWorkerCount(t, 0)
 
@@ -399,7 +400,7 @@ func TestWorkerConcurrentCheckScheduling(t *testing.T) {
 	pendingChecksChan <- testCheck
 	close(pendingChecksChan)
 
-	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc, nil)
 	require.Nil(t, err)
 
 	worker.Run()
@@ -453,7 +454,7 @@ func TestWorkerStatsAddition(t *testing.T) {
 	pendingChecksChan <- squelchedStatsCheck
 	close(pendingChecksChan)
 
-	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, shouldAddStatsFunc)
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), 100, 200, pendingChecksChan, checksTracker, shouldAddStatsFunc, nil)
 	require.Nil(t, err)
 
 	worker.Run()
@@ -502,6 +503,7 @@ func TestWorkerServiceCheckSending(t *testing.T) {
 		pendingChecksChan,
 		checksTracker,
 		mockShouldAddStatsFunc,
+		nil,
 		func() (sender.Sender, error) {
 			return mockSender, nil
 		},
@@ -572,6 +574,7 @@ func TestWorkerSenderNil(t *testing.T) {
 		pendingChecksChan,
 		checksTracker,
 		mockShouldAddStatsFunc,
+		nil,
 		func() (sender.Sender, error) {
 			return nil, fmt.Errorf("testerr")
 		},
@@ -612,6 +615,7 @@ func TestWorkerServiceCheckSendingLongRunningTasks(t *testing.T) {
 		pendingChecksChan,
 		checksTracker,
 		mockShouldAddStatsFunc,
+		nil,
 		func() (sender.Sender, error) {
 			return mockSender, nil
 		},
@@ -188,6 +188,12 @@ type (
 	Listeners = pkgconfigsetup.Listeners
 	// MappingProfile Alias
 	MappingProfile = pkgconfigsetup.MappingProfile
+	// TagRule Alias
+	TagRule = pkgconfigsetup.TagRule
+	// TagValueRewrite Alias
+	TagValueRewrite = pkgconfigsetup.TagValueRewrite
+	// GraphiteTemplate Alias
+	GraphiteTemplate = pkgconfigsetup.GraphiteTemplate
 	// Endpoint Alias
 	Endpoint = pkgconfigsetup.Endpoint
 )
@@ -248,6 +254,10 @@ var (
 
 	// GetRemoteConfigurationAllowedIntegrations Alias
 	GetRemoteConfigurationAllowedIntegrations = pkgconfigsetup.GetRemoteConfigurationAllowedIntegrations
+	// GetDogstatsdTagRules Alias
+	GetDogstatsdTagRules = pkgconfigsetup.GetDogstatsdTagRules
+	// GetDogstatsdGraphiteTemplates Alias
+	GetDogstatsdGraphiteTemplates = pkgconfigsetup.GetDogstatsdGraphiteTemplates
 	// LoadProxyFromEnv Alias
 	LoadProxyFromEnv = pkgconfigsetup.LoadProxyFromEnv
 
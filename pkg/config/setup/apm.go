@@ -133,6 +133,16 @@ func setupAPM(config pkgconfigmodel.Config) {
 	config.BindEnv("apm_config.obfuscation.credit_cards.enabled", "DD_APM_OBFUSCATION_CREDIT_CARDS_ENABLED")
 	config.BindEnv("apm_config.obfuscation.credit_cards.luhn", "DD_APM_OBFUSCATION_CREDIT_CARDS_LUHN")
 	config.BindEnvAndSetDefault("apm_config.debug.port", 5012, "DD_APM_DEBUG_PORT")
+	config.BindEnv("apm_config.capture.path", "DD_APM_CAPTURE_PATH")
+	config.BindEnv("apm_config.failover.endpoints", "DD_APM_FAILOVER_ENDPOINTS")
+	config.BindEnv("apm_config.failover.api_key", "DD_APM_FAILOVER_API_KEY")
+	config.BindEnv("apm_config.failover.after_seconds", "DD_APM_FAILOVER_AFTER_SECONDS")
+	config.BindEnvAndSetDefault("apm_config.spill.enabled", false, "DD_APM_SPILL_ENABLED")
+	config.BindEnv("apm_config.spill.path", "DD_APM_SPILL_PATH")
+	config.BindEnv("apm_config.spill.max_size_mb", "DD_APM_SPILL_MAX_SIZE_MB")
+	config.BindEnv("apm_config.spill.max_age_seconds", "DD_APM_SPILL_MAX_AGE_SECONDS")
+	config.BindEnvAndSetDefault("apm_config.openmetrics_stats.enabled", false, "DD_APM_OPENMETRICS_STATS_ENABLED")
+	config.BindEnv("apm_config.openmetrics_stats.max_series", "DD_APM_OPENMETRICS_STATS_MAX_SERIES")
 	config.BindEnv("apm_config.features", "DD_APM_FEATURES")
 	config.SetEnvKeyTransformer("apm_config.features", func(s string) interface{} {
 		// Either commas or spaces can be used as separators.
@@ -174,6 +174,31 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
 }
 
+// TagRule represent a rule rewriting the tags of the dogstatsd metrics matching a pattern
+type TagRule struct {
+	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	Drop      []string          `mapstructure:"drop" json:"drop" yaml:"drop"`
+	KeepOnly  []string          `mapstructure:"keep_only" json:"keep_only" yaml:"keep_only"`
+	Rename    map[string]string `mapstructure:"rename" json:"rename" yaml:"rename"`
+	Rewrite   []TagValueRewrite `mapstructure:"rewrite" json:"rewrite" yaml:"rewrite"`
+}
+
+// TagValueRewrite represent the rewriting of the values of a tag
+type TagValueRewrite struct {
+	Tag     string `mapstructure:"tag" json:"tag" yaml:"tag"`
+	Match   string `mapstructure:"match" json:"match" yaml:"match"`
+	Replace string `mapstructure:"replace" json:"replace" yaml:"replace"`
+}
+
+// GraphiteTemplate represent a template converting the paths of the Graphite metrics matching
+// a pattern into a metric name and tags
+type GraphiteTemplate struct {
+	Match    string   `mapstructure:"match" json:"match" yaml:"match"`
+	Template string   `mapstructure:"template" json:"template" yaml:"template"`
+	Tags     []string `mapstructure:"tags" json:"tags" yaml:"tags"`
+}
+
 // Endpoint represent a datadog endpoint
 type Endpoint struct {
 	Site   string `mapstructure:"site" json:"site" yaml:"site"`
@@ -254,6 +279,13 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("enable_gohai", true)
 	config.BindEnvAndSetDefault("check_runners", int64(4))
 	config.BindEnvAndSetDefault("check_cancel_timeout", 500*time.Millisecond)
+	config.BindEnvAndSetDefault("check_run_timeout", time.Duration(0))
+	config.BindEnvAndSetDefault("check_run_timeout_replace_worker", false)
+	config.BindEnvAndSetDefault("check_scheduling.jitter", time.Duration(0))
+	config.BindEnvAndSetDefault("check_scheduling.spread", false)
+	config.BindEnvAndSetDefault("check_plugins.directory", "")
+	config.BindEnvAndSetDefault("check_plugins.start_timeout", 10*time.Second)
+	config.BindEnvAndSetDefault("check_plugins.max_restart_backoff", 5*time.Minute)
 	config.BindEnvAndSetDefault("auth_token_file_path", "")
 	config.BindEnv("bind_host")
 	config.BindEnvAndSetDefault("health_port", int64(0))
@@ -425,6 +457,15 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("proc_root", "/proc")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnvAndSetDefault("histogram_percentiles_sketch.enabled", false)
+	config.BindEnv("histogram_overrides")
+	config.SetEnvKeyTransformer("histogram_overrides", func(in string) interface{} {
+		var overrides []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
 	config.BindEnvAndSetDefault("aggregator_use_tags_store", true)
@@ -490,6 +531,16 @@ func InitConfig(config pkgconfigmodel.Config) {
 	// Experimental and not officially supported for now.
 	// Options are: udp, uds, named_pipe
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
+	// Protocol spoken on each listener, by listener type. Listener types are: udp, uds, tcp, named_pipe
+	// Protocols are: dogstatsd (default), graphite, statsd
+	config.BindEnvAndSetDefault("dogstatsd_listener_protocols", map[string]string{})
+	config.SetEnvKeyTransformer("dogstatsd_listener_protocols", func(in string) interface{} {
+		var protocols map[string]string
+		if err := json.Unmarshal([]byte(in), &protocols); err != nil {
+			log.Errorf(`"dogstatsd_listener_protocols" can not be parsed: %v`, err)
+		}
+		return protocols
+	})
 
 	// The following options allow to configure how the dogstatsd intake buffers and queues incoming datagrams.
 	// When a datagram is received it is first added to a datagrams buffer. This buffer fills up until
@@ -504,6 +555,14 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", "")        // Notice: empty means feature disabled
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "") // Experimental || Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)       // Notice: 0 means TCP port closed
+	// TLS is enabled on the TCP listener when both a certificate and a key are set. Client certificates
+	// are required and verified against dogstatsd_tcp_tls_client_ca_file when it is set.
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_client_ca_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_idle_timeout", 5*time.Minute) // Notice: 0 means connections never time out
+	config.BindEnvAndSetDefault("dogstatsd_tcp_max_connections", 1024)       // Notice: 0 means no limit
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -519,6 +578,13 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Limit the number of dogstatsd contexts of each metric, and of distinct values of some tag keys for each
+	// metric. Contexts over the limits are dropped, or aggregated into contexts where the value of the tag with
+	// the most distinct values is replaced by "overflow" when the overflow strategy is used.
+	// Options for the strategy are: drop, overflow
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0) // Notice: 0 means no limit
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.tag_value_limits", map[string]int{})
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.overflow_strategy", "drop")
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -541,6 +607,14 @@ func InitConfig(config pkgconfigmodel.Config) {
 	// How many metrics maximum in payloads sent by the no-aggregation pipeline to the intake.
 	config.BindEnvAndSetDefault("dogstatsd_no_aggregation_pipeline_batch_size", 2048)
 
+	// Prometheus remote_write receiver, submitting the received samples to the no-aggregation pipeline.
+	config.BindEnvAndSetDefault("prometheus_remote_write.enabled", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.port", 9201)
+	config.BindEnvAndSetDefault("prometheus_remote_write.namespace", "")
+	config.BindEnvAndSetDefault("prometheus_remote_write.max_request_size", 10*1024*1024) // in bytes, once decompressed
+	config.BindEnvAndSetDefault("prometheus_scrape_endpoint.enabled", false)
+	config.BindEnvAndSetDefault("prometheus_scrape_endpoint.port", 9202)
+
 	// To enable the following feature, GODEBUG must contain `madvdontneed=1`
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.enabled", false)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.low_soft_limit", 0.7)
@@ -554,6 +628,14 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.soft_limit_freeos_check.max", 0.1)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.soft_limit_freeos_check.factor", 1.5)
 
+	// Per-origin sampling, requires the origin detection over UDS
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.max_rate", 10000) // messages per second and origin
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.min_sample_rate", 0.01)
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.window", 10*time.Second)
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.server_side", false)
+	config.BindEnvAndSetDefault("dogstatsd_origin_sampling.hints_socket", "")
+
 	config.BindEnv("dogstatsd_mapper_profiles")
 	config.SetEnvKeyTransformer("dogstatsd_mapper_profiles", func(in string) interface{} {
 		var mappings []MappingProfile
@@ -563,6 +645,24 @@ func InitConfig(config pkgconfigmodel.Config) {
 		return mappings
 	})
 
+	config.BindEnv("dogstatsd_tag_rules")
+	config.SetEnvKeyTransformer("dogstatsd_tag_rules", func(in string) interface{} {
+		var rules []TagRule
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
+	config.BindEnv("dogstatsd_graphite_templates")
+	config.SetEnvKeyTransformer("dogstatsd_graphite_templates", func(in string) interface{} {
+		var templates []GraphiteTemplate
+		if err := json.Unmarshal([]byte(in), &templates); err != nil {
+			log.Errorf(`"dogstatsd_graphite_templates" can not be parsed: %v`, err)
+		}
+		return templates
+	})
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -656,6 +756,7 @@ func InitConfig(config pkgconfigmodel.Config) {
 	config.BindEnvAndSetDefault("prometheus_scrape.service_endpoints", false) // Enables Service Endpoints checks in the prometheus config provider
 	config.BindEnv("prometheus_scrape.checks")                                // Defines any extra prometheus/openmetrics check configurations to be handled by the prometheus config provider
 	config.BindEnvAndSetDefault("prometheus_scrape.version", 1)               // Version of the openmetrics check to be scheduled by the Prometheus auto-discovery
+	config.BindEnvAndSetDefault("prometheus_scrape.use_core_check", false)    // Schedules the openmetrics core check instead of the Python one
 
 	// Network Devices Monitoring
 	bindEnvAndSetLogsConfigKeys(config, "network_devices.metadata.")
@@ -2038,6 +2139,31 @@ func getDogstatsdMappingProfilesConfig(config pkgconfigmodel.Reader) ([]MappingP
 	return mappings, nil
 }
 
+// GetDogstatsdTagRules returns the rules rewriting the tags of the dogstatsd metrics
+func GetDogstatsdTagRules(config pkgconfigmodel.Reader) ([]TagRule, error) {
+	var rules []TagRule
+	if config.IsSet("dogstatsd_tag_rules") {
+		err := config.UnmarshalKey("dogstatsd_tag_rules", &rules)
+		if err != nil {
+			return []TagRule{}, log.Errorf("Could not parse dogstatsd_tag_rules: %v", err)
+		}
+	}
+	return rules, nil
+}
+
+// GetDogstatsdGraphiteTemplates returns the templates converting the paths of the Graphite metrics
+// received by dogstatsd
+func GetDogstatsdGraphiteTemplates(config pkgconfigmodel.Reader) ([]GraphiteTemplate, error) {
+	var templates []GraphiteTemplate
+	if config.IsSet("dogstatsd_graphite_templates") {
+		err := config.UnmarshalKey("dogstatsd_graphite_templates", &templates)
+		if err != nil {
+			return []GraphiteTemplate{}, log.Errorf("Could not parse dogstatsd_graphite_templates: %v", err)
+		}
+	}
+	return templates, nil
+}
+
 // IsCLCRunner returns whether the Agent is in cluster check runner mode
 func IsCLCRunner(config pkgconfigmodel.Reader) bool {
 	if !config.GetBool("clc_runner_enabled") {
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// generateMetric buffers the sample of a generate_metric rule when the content matches its pattern.
+// The named capture groups of the pattern, other than its value group, are submitted as tags
+// along with the tags of the log source.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, content []byte, msg *message.Message) {
+	if p.metricSubmitter == nil {
+		return
+	}
+
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return
+	}
+
+	value := 1.0
+	tags := metricTags(msg)
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		if name != rule.ValueGroup {
+			tags = append(tags, name+":"+string(match[i]))
+			continue
+		}
+
+		var err error
+		if value, err = strconv.ParseFloat(string(match[i]), 64); err != nil {
+			log.Debugf("Invalid value %q for the metric of processing rule %s: %s", match[i], rule.Name, err)
+			return
+		}
+	}
+
+	if rule.MetricType != config.MetricTypeGauge && rule.MetricType != config.MetricTypeDistribution {
+		value = 1
+	}
+	p.metricSubmitter.submit(metricSample{name: rule.MetricName, metricType: rule.MetricType, value: value, tags: tags})
+}
+
+// metricTags returns the tags of the log source of a message
+func metricTags(msg *message.Message) []string {
+	originTags := msg.Origin.Tags()
+	tags := make([]string, 0, len(originTags)+4)
+	tags = append(tags, originTags...)
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	return tags
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newMetricRule(pattern, metricName, metricType, valueGroup string) *config.ProcessingRule {
+	return &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Name:       "test",
+		Pattern:    pattern,
+		Regex:      regexp.MustCompile(pattern),
+		MetricName: metricName,
+		MetricType: metricType,
+		ValueGroup: valueGroup,
+	}
+}
+
+func TestGenerateMetric(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+	submitter := NewMetricSubmitter(sender)
+	submitter.Start()
+
+	p := &Processor{
+		processingRules: []*config.ProcessingRule{
+			newMetricRule(`" (?P<status>5\d\d) (?P<bytes>\d+)`, "nginx.errors", config.MetricTypeCount, ""),
+			newMetricRule(`" (?P<status>\d{3}) (?P<bytes>\d+)`, "nginx.bytes", config.MetricTypeDistribution, "bytes"),
+			newProcessingRule(config.ExcludeAtMatch, "", `" 5\d\d `),
+		},
+		metricSubmitter: submitter,
+	}
+	source := sources.NewLogSource("", &config.LogsConfig{Source: "nginx", Service: "web", Tags: []string{"env:dev"}})
+
+	// the metrics are generated from the lines excluded by the following rules
+	assert.False(t, p.applyRedactingRules(newMessage([]byte(`"GET / HTTP/1.1" 503 120`), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte(`"GET / HTTP/1.1" 200 2048`), source, "")))
+	submitter.Stop()
+
+	tags := []string{"env:dev", "source:nginx", "service:web"}
+	sender.AssertMetric(t, "Count", "nginx.errors", 1, "", append([]string{"status:503", "bytes:120"}, tags...))
+	sender.AssertNumberOfCalls(t, "Count", 1)
+	sender.AssertMetric(t, "Distribution", "nginx.bytes", 120, "", append([]string{"status:503"}, tags...))
+	sender.AssertMetric(t, "Distribution", "nginx.bytes", 2048, "", append([]string{"status:200"}, tags...))
+	sender.AssertMetricNotTaggedWith(t, "Distribution", "nginx.bytes", []string{"bytes:2048"})
+}
+
+func TestGenerateMetricGauge(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		newMetricRule(`queue size: (?P<size>\S+)`, "queue.size", config.MetricTypeGauge, "size"),
+	}}}
+	submitter := NewMetricSubmitter(sender)
+	submitter.Start()
+	p := &Processor{metricSubmitter: submitter}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("queue size: 42"), &source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("queue size: unknown"), &source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("hello"), &source, "")))
+	submitter.Stop()
+
+	sender.AssertMetric(t, "Gauge", "queue.size", 42, "", nil)
+	sender.AssertNumberOfCalls(t, "Gauge", 1)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		newMetricRule("error", "errors", config.MetricTypeCount, ""),
+	}}}
+	p := &Processor{}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("error"), &source, "")))
+}
+
+func TestMetricSubmitterCommitsOnStop(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.On("Commit").Return()
+
+	submitter := NewMetricSubmitter(sender)
+	submitter.Start()
+	submitter.Stop()
+
+	sender.AssertCalled(t, "Commit")
+	sender.AssertNotCalled(t, "Count", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetricSubmitterDropsWhenFull(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	// the submitter isn't started, so nothing consumes the buffer
+	submitter := NewMetricSubmitter(sender)
+	for i := 0; i < metricsBufferSize+10; i++ {
+		submitter.submit(metricSample{name: "errors", value: 1})
+	}
+	assert.Equal(t, int64(10), submitter.dropped.Load())
+
+	submitter.Start()
+	submitter.Stop()
+
+	sender.AssertNumberOfCalls(t, "Count", metricsBufferSize)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+	assert.Zero(t, submitter.dropped.Load())
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// metricsCommitInterval is the interval at which the metrics generated from the logs are committed
+	metricsCommitInterval = 15 * time.Second
+	// metricsBufferSize is the number of generated samples buffered before new ones are dropped
+	metricsBufferSize = 4096
+)
+
+// metricSample is a sample generated from a log line
+type metricSample struct {
+	name       string
+	metricType string
+	value      float64
+	tags       []string
+}
+
+// MetricSubmitter submits the metrics generated by the processors of all the pipelines
+// from a single goroutine, so that the processors never wait on the aggregator.
+// Samples are dropped when the buffer is full.
+type MetricSubmitter struct {
+	sender  sender.Sender
+	samples chan metricSample
+	dropped *atomic.Int64
+	done    chan struct{}
+}
+
+// NewMetricSubmitter returns a new submitter of the metrics generated from the logs.
+func NewMetricSubmitter(sender sender.Sender) *MetricSubmitter {
+	return &MetricSubmitter{
+		sender:  sender,
+		samples: make(chan metricSample, metricsBufferSize),
+		dropped: atomic.NewInt64(0),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the submitter.
+func (s *MetricSubmitter) Start() {
+	go s.run()
+}
+
+// Stop submits and commits the buffered samples, then stops the submitter.
+// The processors must be stopped first.
+func (s *MetricSubmitter) Stop() {
+	close(s.samples)
+	<-s.done
+}
+
+// submit buffers a sample without blocking, it's dropped when the buffer is full.
+func (s *MetricSubmitter) submit(sample metricSample) {
+	select {
+	case s.samples <- sample:
+	default:
+		s.dropped.Inc()
+	}
+}
+
+func (s *MetricSubmitter) run() {
+	defer close(s.done)
+
+	commitTicker := time.NewTicker(metricsCommitInterval)
+	defer commitTicker.Stop()
+
+	for {
+		select {
+		case sample, ok := <-s.samples:
+			if !ok {
+				s.commit()
+				return
+			}
+			s.send(sample)
+		case <-commitTicker.C:
+			s.commit()
+		}
+	}
+}
+
+func (s *MetricSubmitter) send(sample metricSample) {
+	switch sample.metricType {
+	case config.MetricTypeGauge:
+		s.sender.Gauge(sample.name, sample.value, "", sample.tags)
+	case config.MetricTypeDistribution:
+		s.sender.Distribution(sample.name, sample.value, "", sample.tags)
+	default:
+		s.sender.Count(sample.name, sample.value, "", sample.tags)
+	}
+}
+
+func (s *MetricSubmitter) commit() {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		log.Warnf("Dropped %d samples of the metrics generated from the logs, the submission buffer is full", dropped)
+	}
+	s.sender.Commit()
+}
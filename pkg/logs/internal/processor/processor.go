@@ -30,11 +30,12 @@ type Processor struct {
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
+	metricSubmitter           *MetricSubmitter // nil when the generate_metric rules are ignored
 	mu                        sync.Mutex
 }
 
 // New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSubmitter *MetricSubmitter) *Processor {
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan, // strategy input
@@ -42,6 +43,7 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		metricSubmitter:           metricSubmitter,
 	}
 }
 
@@ -136,6 +138,8 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.GenerateMetric:
+			p.generateMetric(rule, content, msg)
 		}
 	}
 
@@ -35,6 +35,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	endpoints *config.Endpoints,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
+	metricSubmitter *processor.MetricSubmitter,
 	serverless bool,
 	pipelineID int) *Pipeline {
 
@@ -61,7 +62,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver, metricSubmitter)
 
 	return &Pipeline{
 		InputChan: inputChan,
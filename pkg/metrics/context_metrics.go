@@ -44,6 +44,12 @@ func (a *AddSampleTelemetry) Inc(isStateful bool) {
 
 // AddSample add a sample to the current ContextMetrics and initialize a new metrics if needed.
 func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config) error {
+	return m.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, t, config, nil)
+}
+
+// AddSampleWithHistogramConfig is AddSample, with histogramConfig overriding the configuration
+// of the histogram created for a new histogram or historate context. histogramConfig may be nil.
+func (m ContextMetrics) AddSampleWithHistogramConfig(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) error {
 	if math.IsInf(sample.Value, 0) || math.IsNaN(sample.Value) {
 		return fmt.Errorf("sample with value '%v'", sample.Value)
 	}
@@ -58,9 +64,13 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval, config) // default histogram configuration (no call to `configure`) for now
+			h := NewHistogram(interval, config)
+			histogramConfig.apply(h)
+			m[contextKey] = h
 		case HistorateType:
-			m[contextKey] = NewHistorate(interval, config) // internal histogram has the configuration for now
+			h := NewHistorate(interval, config) // internal histogram has the configuration
+			histogramConfig.apply(&h.histogram)
+			m[contextKey] = h
 		case SetType:
 			m[contextKey] = NewSet()
 		case CounterType:
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+const (
+	// HistogramDistributionCopy sends the samples of the histogram as a distribution as well,
+	// named after the histogram_copy_to_distribution_prefix setting.
+	HistogramDistributionCopy = "copy"
+	// HistogramDistributionConvert sends the samples of the histogram as a distribution
+	// of the same name instead of aggregating them.
+	HistogramDistributionConvert = "convert"
+)
+
+// histogramOverrideConfig is the configuration of an entry of histogram_overrides. The
+// aggregates and percentiles are pointers to tell an empty list from a missing one.
+type histogramOverrideConfig struct {
+	Match        string    `mapstructure:"match"`
+	MatchType    string    `mapstructure:"match_type"`
+	Check        string    `mapstructure:"check"`
+	Instance     string    `mapstructure:"instance"`
+	Aggregates   *[]string `mapstructure:"aggregates"`
+	Percentiles  *[]string `mapstructure:"percentiles"`
+	Distribution string    `mapstructure:"distribution"`
+}
+
+// HistogramConfig overrides the configuration of a histogram. A nil Aggregates or
+// Percentiles keeps the value of histogram_aggregates or histogram_percentiles.
+type HistogramConfig struct {
+	Aggregates  []string
+	Percentiles []int // each in the 1-100 range, sorted
+	// Distribution is HistogramDistributionCopy, HistogramDistributionConvert or empty.
+	Distribution string
+}
+
+type histogramOverride struct {
+	regex    *regexp.Regexp
+	check    string
+	instance string
+	config   *HistogramConfig
+}
+
+// HistogramOverrides resolves the configuration of the histograms from the histogram_overrides
+// setting. It is safe for concurrent use.
+type HistogramOverrides struct {
+	overrides []histogramOverride
+}
+
+// NewHistogramOverrides returns the HistogramOverrides defined in the configuration, or
+// nil if there are none.
+func NewHistogramOverrides(config pkgconfigmodel.Reader) (*HistogramOverrides, error) {
+	if !config.IsSet("histogram_overrides") {
+		return nil, nil
+	}
+	var configs []histogramOverrideConfig
+	if err := config.UnmarshalKey("histogram_overrides", &configs); err != nil {
+		return nil, fmt.Errorf("could not parse histogram_overrides: %v", err)
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	o := &HistogramOverrides{}
+	for i, c := range configs {
+		if c.Match == "" {
+			return nil, fmt.Errorf("histogram override num %d: match is required", i)
+		}
+		matchType := c.MatchType
+		if matchType == "" {
+			matchType = MatchTypeWildcard
+		}
+		regex, err := CompileMatchPattern(c.Match, matchType)
+		if err != nil {
+			return nil, fmt.Errorf("histogram override num %d: %v", i, err)
+		}
+		if c.Distribution != "" && c.Distribution != HistogramDistributionCopy && c.Distribution != HistogramDistributionConvert {
+			return nil, fmt.Errorf("histogram override num %d: invalid distribution `%s`, must be `copy` or `convert`", i, c.Distribution)
+		}
+		hc := &HistogramConfig{Distribution: c.Distribution}
+		if c.Aggregates != nil {
+			hc.Aggregates = append([]string{}, *c.Aggregates...)
+		}
+		if c.Percentiles != nil {
+			hc.Percentiles = (&histogramPercentilesConfig{Percentiles: *c.Percentiles}).percentiles()
+			sort.Ints(hc.Percentiles)
+		}
+		o.overrides = append(o.overrides, histogramOverride{
+			regex:    regex,
+			check:    c.Check,
+			instance: c.Instance,
+			config:   hc,
+		})
+	}
+	return o, nil
+}
+
+// Resolve returns the configuration of the first override matching the metric name, and
+// the check and instance names when the metric is sent by a check. It returns nil when no
+// override matches, or when o is nil.
+func (o *HistogramOverrides) Resolve(name, checkName, instanceName string) *HistogramConfig {
+	if o == nil {
+		return nil
+	}
+	for _, override := range o.overrides {
+		if override.check != "" && override.check != checkName {
+			continue
+		}
+		if override.instance != "" && override.instance != instanceName {
+			continue
+		}
+		if override.regex.MatchString(name) {
+			return override.config
+		}
+	}
+	return nil
+}
+
+// apply overrides the configuration of h with c, if c is not nil.
+func (c *HistogramConfig) apply(h *Histogram) {
+	if c == nil {
+		return
+	}
+	if c.Aggregates != nil {
+		h.aggregates = c.Aggregates
+	}
+	if c.Percentiles != nil {
+		h.percentiles = c.Percentiles
+	}
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+func newHistogramOverridesConfig(overrides interface{}) pkgconfigmodel.Config {
+	cfg := pkgconfigmodel.NewConfig("datadog", "DD", strings.NewReplacer(".", "_"))
+	cfg.SetWithoutSource("histogram_aggregates", []string{"max", "median", "avg", "count"})
+	cfg.SetWithoutSource("histogram_percentiles", []string{"0.95"})
+	if overrides != nil {
+		cfg.SetWithoutSource("histogram_overrides", overrides)
+	}
+	return cfg
+}
+
+func TestNewHistogramOverridesEmpty(t *testing.T) {
+	o, err := NewHistogramOverrides(newHistogramOverridesConfig(nil))
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	o, err = NewHistogramOverrides(newHistogramOverridesConfig([]interface{}{}))
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	// a nil HistogramOverrides never overrides anything
+	assert.Nil(t, o.Resolve("foo", "", ""))
+}
+
+func TestNewHistogramOverridesErrors(t *testing.T) {
+	for name, override := range map[string]map[string]interface{}{
+		"missing match":         {"aggregates": []string{"avg"}},
+		"invalid match type":    {"match": "foo", "match_type": "glob"},
+		"invalid wildcard":      {"match": "foo.**"},
+		"invalid regex":         {"match": "foo.(", "match_type": "regex"},
+		"invalid distribution":  {"match": "foo", "distribution": "replace"},
+		"invalid configuration": {"match": []string{"foo"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewHistogramOverrides(newHistogramOverridesConfig([]interface{}{override}))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHistogramOverridesResolve(t *testing.T) {
+	o, err := NewHistogramOverrides(newHistogramOverridesConfig([]interface{}{
+		map[string]interface{}{
+			"match":       "http.*.duration",
+			"percentiles": []string{"0.99", "0.5", "0.75"},
+		},
+		map[string]interface{}{
+			"match":      "http.*",
+			"aggregates": []string{"avg", "count"},
+		},
+		map[string]interface{}{
+			"match":        `^db\..+`,
+			"match_type":   "regex",
+			"check":        "postgres",
+			"instance":     "main",
+			"distribution": "convert",
+		},
+		map[string]interface{}{
+			"match":        "db.*",
+			"check":        "postgres",
+			"distribution": "copy",
+			"aggregates":   []string{},
+		},
+	}))
+	require.NoError(t, err)
+
+	// the first matching override is used
+	assert.Equal(t, &HistogramConfig{Percentiles: []int{50, 75, 99}}, o.Resolve("http.request.duration", "", ""))
+	assert.Equal(t, &HistogramConfig{Aggregates: []string{"avg", "count"}}, o.Resolve("http.request", "", ""))
+	assert.Nil(t, o.Resolve("http.request.size.bytes", "", ""))
+
+	// overrides can be restricted to a check and its instance
+	assert.Nil(t, o.Resolve("db.query.duration", "", ""))
+	assert.Nil(t, o.Resolve("db.query.duration", "mysql", "main"))
+	assert.Equal(t, &HistogramConfig{Distribution: HistogramDistributionConvert}, o.Resolve("db.query.duration", "postgres", "main"))
+	assert.Nil(t, o.Resolve("db.query.duration", "postgres", "replica"))
+	assert.Equal(t, &HistogramConfig{Aggregates: []string{}, Distribution: HistogramDistributionCopy}, o.Resolve("db.query", "postgres", "replica"))
+}
+
+func TestAddSampleWithHistogramConfig(t *testing.T) {
+	defaultAggregates = nil
+	defaultPercentiles = nil
+	cfg := setupConfig()
+	contextMetrics := MakeContextMetrics()
+	histogramConfig := &HistogramConfig{Aggregates: []string{"avg"}, Percentiles: []int{50, 99}}
+
+	for i := 1; i <= 100; i++ {
+		err := contextMetrics.AddSampleWithHistogramConfig(ckey.ContextKey(1), &MetricSample{Mtype: HistogramType, Value: float64(i)}, 12340, 10, nil, cfg, histogramConfig)
+		require.NoError(t, err)
+		err = contextMetrics.AddSampleWithHistogramConfig(ckey.ContextKey(2), &MetricSample{Mtype: HistogramType, Value: float64(i)}, 12340, 10, nil, cfg, nil)
+		require.NoError(t, err)
+	}
+
+	series, errs := contextMetrics.Flush(12350)
+	require.Empty(t, errs)
+
+	suffixes := map[ckey.ContextKey][]string{}
+	for _, serie := range series {
+		suffixes[serie.ContextKey] = append(suffixes[serie.ContextKey], serie.NameSuffix)
+	}
+	assert.Equal(t, []string{".avg", ".50percentile", ".99percentile"}, suffixes[1])
+	assert.Equal(t, []string{".max", ".median", ".avg", ".count", ".95percentile"}, suffixes[2])
+}
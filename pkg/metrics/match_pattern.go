@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MatchTypeWildcard is the type of the name patterns where `*` matches any part of a
+	// metric name between two dots.
+	MatchTypeWildcard = "wildcard"
+	// MatchTypeRegex is the type of the name patterns which are regular expressions.
+	MatchTypeRegex = "regex"
+)
+
+var allowedWildcardMatchPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.]+$`)
+
+// CompileMatchPattern returns the regex matching the whole metric names matched by the
+// pattern match, of type MatchTypeWildcard or MatchTypeRegex.
+func CompileMatchPattern(match string, matchType string) (*regexp.Regexp, error) {
+	switch matchType {
+	case MatchTypeWildcard:
+		if !allowedWildcardMatchPattern.MatchString(match) {
+			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it does not match allowed match regex `%s`", match, allowedWildcardMatchPattern)
+		}
+		if strings.Contains(match, "**") {
+			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it should not contain consecutive `*`", match)
+		}
+		match = strings.Replace(match, ".", "\\.", -1)
+		match = strings.Replace(match, "*", "([^.]*)", -1)
+	case MatchTypeRegex:
+	default:
+		return nil, fmt.Errorf("invalid match type `%s`, must be `%s` or `%s`", matchType, MatchTypeWildcard, MatchTypeRegex)
+	}
+	regex, err := regexp.Compile("^" + match + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid match `%s`. cannot compile regex: %v", match, err)
+	}
+	return regex, nil
+}
@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileMatchPattern(t *testing.T) {
+	regex, err := CompileMatchPattern("custom.*.latency", MatchTypeWildcard)
+	require.NoError(t, err)
+	assert.True(t, regex.MatchString("custom.api.latency"))
+	assert.False(t, regex.MatchString("custom.api.v1.latency"))
+	assert.False(t, regex.MatchString("customXapi.latency"))
+
+	regex, err = CompileMatchPattern(`custom\.(api|db)\..*`, MatchTypeRegex)
+	require.NoError(t, err)
+	assert.True(t, regex.MatchString("custom.db.v1.latency"))
+	assert.False(t, regex.MatchString("other.custom.db.latency"))
+
+	for _, tc := range []struct{ match, matchType string }{
+		{"custom.**", MatchTypeWildcard},
+		{"custom.[a-z]", MatchTypeWildcard},
+		{"custom.(", MatchTypeRegex},
+		{"custom.*", "glob"},
+	} {
+		_, err := CompileMatchPattern(tc.match, tc.matchType)
+		assert.Error(t, err, tc.match)
+	}
+}
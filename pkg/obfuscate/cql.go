@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// ObfuscateCQLString quantizes and obfuscates the given Cassandra CQL statement. It uses the
+// SQL obfuscator configuration, along with support for CQL-specific literals such as UUIDs,
+// blobs, durations and collections.
+func (o *Obfuscator) ObfuscateCQLString(in string) (*ObfuscatedQuery, error) {
+	opts := o.opts.SQL
+	if opts.ObfuscationMode != "" {
+		// sqllexer doesn't support CQL, the statement is obfuscated as generic SQL
+		opts.DBMS = ""
+		return o.ObfuscateWithSQLLexer(in, &opts)
+	}
+	opts.DBMS = DBMSCassandra
+	// CQL statements are cached separately from SQL ones as they are tokenized differently.
+	key := "cql:" + in
+	if v, ok := o.queryCache.Get(key); ok {
+		return v.(*ObfuscatedQuery), nil
+	}
+	oq, err := o.obfuscateSQLString(in, &opts)
+	if err != nil {
+		return oq, err
+	}
+	o.queryCache.Set(key, oq, oq.Cost())
+	return oq, nil
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscateCQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+		tables  string
+	}{
+		{
+			in:     "SELECT * FROM ks.users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+			out:    "SELECT * FROM ks.users WHERE id = ?",
+			tables: "ks.users",
+		},
+		{
+			in:     "SELECT name FROM users WHERE id IN (e89b0000-e89b-12d3-a456-426614174000, 00000000-0000-0000-0000-000000000000)",
+			out:    "SELECT name FROM users WHERE id IN ( ? )",
+			tables: "users",
+		},
+		{
+			in:     "INSERT INTO ks.blobs (id, data) VALUES (now(), 0xCAFEBABE) USING TTL 86400",
+			out:    "INSERT INTO ks.blobs ( id, data ) VALUES ( now ( ), ? ) USING TTL ?",
+			tables: "ks.blobs",
+		},
+		{
+			in:     "UPDATE users USING TTL 3600 AND TIMESTAMP 1691062023 SET emails = emails + {'a@b.com', 'c@d.com'} WHERE id = 5",
+			out:    "UPDATE users USING TTL ? AND TIMESTAMP ? SET emails = emails + ? WHERE id = ?",
+			tables: "users",
+		},
+		{
+			in:     "UPDATE users SET prefs = {'theme': 'dark', 'nested': {'it''s': 1}}, tags = ['a', 'b'] WHERE id = 5",
+			out:    "UPDATE users SET prefs = ? tags = [ ? ] WHERE id = ?",
+			tables: "users",
+		},
+		{
+			in:     "INSERT INTO events (id, window) VALUES (?, 1h30m)",
+			out:    "INSERT INTO events ( id, window ) VALUES ( ? )",
+			tables: "events",
+		},
+		{
+			in:     "SELECT * FROM events WHERE id = :id AND name = 'foo' LIMIT 10",
+			out:    "SELECT * FROM events WHERE id = :id AND name = ? LIMIT ?",
+			tables: "events",
+		},
+		{
+			in:  "BEGIN BATCH INSERT INTO t1 (a) VALUES (1); UPDATE t2 SET b = 'x' WHERE a = 2; APPLY BATCH",
+			out: "BEGIN BATCH INSERT INTO t1 ( a ) VALUES ( ? ) UPDATE t2 SET b = ? WHERE a = ? APPLY BATCH",
+			// tables from every statement of the batch are collected
+			tables: "t1,t2",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{SQL: SQLConfig{TableNames: true}})
+			oq, err := o.ObfuscateCQLString(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+			assert.Equal(t, tt.tables, oq.Metadata.TablesCSV)
+		})
+	}
+}
+
+func TestObfuscateCQLErrors(t *testing.T) {
+	o := NewObfuscator(Config{})
+	_, err := o.ObfuscateCQLString("UPDATE t SET m = {'a': 1 WHERE id = 1")
+	assert.Error(t, err)
+}
+
+func TestObfuscateCQLSQLLexer(t *testing.T) {
+	o := NewObfuscator(Config{SQL: SQLConfig{ObfuscationMode: ObfuscateOnly, DBMS: DBMSSQLServer}})
+	oq, err := o.ObfuscateCQLString("SELECT * FROM ks.users WHERE name = 'bob' AND age > 30")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM ks.users WHERE name = ? AND age > ?", oq.Query)
+}
+
+func TestObfuscateCQLCache(t *testing.T) {
+	o := NewObfuscator(Config{SQL: SQLConfig{Cache: true}})
+	defer o.Stop()
+	in := "SELECT * FROM t WHERE m = {'a': 1}"
+	oq, err := o.ObfuscateCQLString(in)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE m = ?", oq.Query)
+	// the same statement obfuscated as SQL is not mixed up with the CQL result
+	_, err = o.ObfuscateSQLString(in)
+	assert.NoError(t, err)
+}
+
+func TestIsUUID(t *testing.T) {
+	assert.True(t, isUUID([]byte("123e4567-e89b-12d3-a456-426614174000")))
+	assert.True(t, isUUID([]byte("123E4567-E89B-12D3-A456-426614174000)")))
+	assert.False(t, isUUID([]byte("123e4567-e89b-12d3-a456-42661417400")))
+	assert.False(t, isUUID([]byte("123e4567-e89b-12d3-a456-426614174000a")))
+	assert.False(t, isUUID([]byte("123e4567_e89b-12d3-a456-426614174000")))
+}
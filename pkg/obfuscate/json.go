@@ -10,11 +10,31 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
-// ObfuscateMongoDBString obfuscates the given MongoDB JSON query.
+// ObfuscateMongoDBString obfuscates the given MongoDB JSON query. Queries which are not strict JSON
+// and don't start like a JSON object or array are obfuscated using the relaxed Mongo shell syntax
+// parser (see ObfuscateMongoDBShellString), which only accepts documents and method chains on db:
+// any other text is replaced by "...".
 func (o *Obfuscator) ObfuscateMongoDBString(cmd string) string {
-	return obfuscateJSONString(cmd, o.mongo)
+	if o.mongo == nil || cmd == "" {
+		// obfuscator is disabled or string is empty
+		return cmd
+	}
+	out, err := o.mongo.obfuscate([]byte(cmd))
+	if err != nil && !startsLikeJSON(cmd) {
+		// a shell command rather than a truncated or invalid JSON query, which keeps
+		// the partial output of the JSON obfuscator.
+		out, _ = obfuscateMongoShell(cmd, o.mongo.keepKeys)
+	}
+	return out
+}
+
+// startsLikeJSON reports whether the first non-space character of s opens a JSON object or array.
+func startsLikeJSON(s string) bool {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
 }
 
 // ObfuscateElasticSearchString obfuscates the given ElasticSearch JSON query.
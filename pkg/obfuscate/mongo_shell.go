@@ -0,0 +1,399 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errMongoShellEOF is returned when a Mongo shell query ends unexpectedly.
+var errMongoShellEOF = errors.New("unexpected end of query")
+
+// ObfuscateMongoDBShellString obfuscates the given MongoDB query written using the Mongo shell syntax,
+// e.g. db.users.find({name: 'foo', _id: ObjectId("...")}).sort({age: -1}). Method chains and keys are
+// kept, while all values are replaced by "?". Values of the keys configured in Mongo.KeepValues are kept.
+func (o *Obfuscator) ObfuscateMongoDBShellString(cmd string) string {
+	if o.mongo == nil || cmd == "" {
+		// obfuscator is disabled or string is empty
+		return cmd
+	}
+	out, _ := obfuscateMongoShell(cmd, o.mongo.keepKeys)
+	// similarly to the JSON obfuscator, a parsing error means that we have only obfuscated
+	// part of the query, which is safe to return.
+	return out
+}
+
+// mongoShellObfuscator is a relaxed parser for the Mongo shell syntax. It accepts unquoted and
+// single-quoted keys and strings, constructor calls (ObjectId, ISODate, NumberLong...), regular
+// expressions and trailing commas.
+type mongoShellObfuscator struct {
+	in       string
+	pos      int
+	out      strings.Builder
+	keepKeys map[string]bool
+}
+
+func obfuscateMongoShell(in string, keepKeys map[string]bool) (string, error) {
+	p := &mongoShellObfuscator{in: in, keepKeys: keepKeys}
+	p.out.Grow(len(in))
+	if err := p.parse(); err != nil {
+		p.out.WriteString("...")
+		return p.out.String(), err
+	}
+	return p.out.String(), nil
+}
+
+// parse parses the whole query: either a single document, or method chains on the database
+// separated by semicolons. Anything else is rejected, as its identifiers could be sensitive.
+func (p *mongoShellObfuscator) parse() error {
+	p.skipSpace()
+	switch p.peek() {
+	case '{', '[':
+		if err := p.value(false); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos < len(p.in) {
+			return p.errorf("unexpected %q after document", p.peek())
+		}
+		return nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.in) {
+			return nil
+		}
+		if err := p.chain(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch c := p.peek(); c {
+		case 0:
+			return nil
+		case ';':
+			p.pos++
+			p.out.WriteByte(c)
+		default:
+			return p.errorf("unexpected %q after method chain", c)
+		}
+	}
+}
+
+// chain parses a chain of method calls on the database, e.g. db.users.find({}).limit(1).
+// Collection and method names are kept, call arguments are obfuscated.
+func (p *mongoShellObfuscator) chain() error {
+	if id := p.identifier(); id != "db" {
+		return p.errorf("expected a method chain on db")
+	}
+	p.out.WriteString("db")
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '.':
+			p.pos++
+			p.skipSpace()
+			if !isMongoIdentStart(p.peek()) {
+				return p.errorf("expected a name after '.'")
+			}
+			p.out.WriteByte('.')
+			p.out.WriteString(p.identifier())
+		case '(':
+			if err := p.arguments(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// arguments parses the arguments of a call, obfuscating all of them.
+func (p *mongoShellObfuscator) arguments() error {
+	p.pos++ // (
+	p.out.WriteByte('(')
+	for n := 0; ; n++ {
+		p.skipSpace()
+		if p.peek() == ')' {
+			p.pos++
+			p.out.WriteByte(')')
+			return nil
+		}
+		if n > 0 {
+			p.out.WriteString(", ")
+		}
+		if err := p.value(false); err != nil {
+			return err
+		}
+		if err := p.separator(')'); err != nil {
+			return err
+		}
+	}
+}
+
+// separator consumes the comma following a value, if any. It fails if neither a comma nor
+// the closing delimiter follows.
+func (p *mongoShellObfuscator) separator(closing byte) error {
+	p.skipSpace()
+	switch p.peek() {
+	case ',':
+		p.pos++
+		return nil
+	case closing:
+		return nil
+	case 0:
+		return errMongoShellEOF
+	default:
+		return p.errorf("expected ',' or %q, got %q", closing, p.peek())
+	}
+}
+
+// value parses a value. Objects and arrays are recursed into, all other values are replaced
+// by "?" unless keep is true, in which case they are written as is.
+func (p *mongoShellObfuscator) value(keep bool) error {
+	p.skipSpace()
+	start := p.pos
+	switch c := p.peek(); {
+	case c == 0:
+		return errMongoShellEOF
+	case c == '{' && !keep:
+		return p.object()
+	case c == '[' && !keep:
+		return p.array()
+	case c == '{' || c == '[' || c == '(':
+		if err := p.skipBalanced(); err != nil {
+			return err
+		}
+	case c == '"' || c == '\'':
+		if err := p.skipString(); err != nil {
+			return err
+		}
+	case c == '/':
+		if err := p.skipRegex(); err != nil {
+			return err
+		}
+	case c == '-' || c == '+' || c == '.' || isDigit(rune(c)):
+		p.pos++
+		for p.pos < len(p.in) && (isMongoIdentPart(p.in[p.pos]) || p.in[p.pos] == '.' || p.in[p.pos] == '+' || p.in[p.pos] == '-') {
+			p.pos++
+		}
+	case isMongoIdentStart(c):
+		// literals (true, null, NaN...), constructors (ObjectId("..."), new Date(...)) or functions
+		id := p.identifier()
+		p.skipSpace()
+		if id == "new" || id == "function" {
+			p.identifier()
+			p.skipSpace()
+		}
+		if p.peek() == '(' {
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+			p.skipSpace()
+		}
+		if id == "function" && p.peek() == '{' {
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+		}
+	default:
+		return p.errorf("unexpected %q", c)
+	}
+	if keep {
+		p.out.WriteString(strings.TrimSpace(p.in[start:p.pos]))
+	} else {
+		p.out.WriteByte('?')
+	}
+	return nil
+}
+
+func (p *mongoShellObfuscator) object() error {
+	p.pos++ // {
+	p.out.WriteByte('{')
+	for n := 0; ; n++ {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			p.out.WriteByte('}')
+			return nil
+		}
+		if n > 0 {
+			p.out.WriteString(", ")
+		}
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			start := p.pos
+			if err := p.skipString(); err != nil {
+				return err
+			}
+			p.out.WriteString(p.in[start:p.pos])
+			key = p.in[start+1 : p.pos-1]
+		case isMongoIdentPart(c):
+			key = p.identifier()
+			p.out.WriteString(key)
+		case c == 0:
+			return errMongoShellEOF
+		default:
+			return p.errorf("unexpected %q in object key", c)
+		}
+		p.skipSpace()
+		if p.peek() != ':' {
+			return p.errorf("expected ':' after object key %q", key)
+		}
+		p.pos++
+		p.out.WriteString(": ")
+		if err := p.value(p.keepKeys[key]); err != nil {
+			return err
+		}
+		if err := p.separator('}'); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *mongoShellObfuscator) array() error {
+	p.pos++ // [
+	p.out.WriteByte('[')
+	for n := 0; ; n++ {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.pos++
+			p.out.WriteByte(']')
+			return nil
+		}
+		if n > 0 {
+			p.out.WriteString(", ")
+		}
+		if err := p.value(false); err != nil {
+			return err
+		}
+		if err := p.separator(']'); err != nil {
+			return err
+		}
+	}
+}
+
+// skipBalanced skips over a parenthesized, bracketed or braced expression, including any
+// nested expression or string it contains.
+func (p *mongoShellObfuscator) skipBalanced() error {
+	var stack []byte
+	for p.pos < len(p.in) {
+		switch c := p.in[p.pos]; c {
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != openingDelimiter(c) {
+				return p.errorf("unbalanced %q", c)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				p.pos++
+				return nil
+			}
+		case '"', '\'':
+			if err := p.skipString(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.pos++
+	}
+	return errMongoShellEOF
+}
+
+// skipString skips over a single- or double-quoted string.
+func (p *mongoShellObfuscator) skipString() error {
+	quote := p.in[p.pos]
+	for p.pos++; p.pos < len(p.in); p.pos++ {
+		switch p.in[p.pos] {
+		case '\\':
+			p.pos++
+		case quote:
+			p.pos++
+			return nil
+		}
+	}
+	return errMongoShellEOF
+}
+
+// skipRegex skips over a regular expression literal, along with its flags.
+func (p *mongoShellObfuscator) skipRegex() error {
+	inClass := false
+	for p.pos++; p.pos < len(p.in); p.pos++ {
+		switch p.in[p.pos] {
+		case '\\':
+			p.pos++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			p.pos++
+			for p.pos < len(p.in) && unicode.IsLetter(rune(p.in[p.pos])) {
+				p.pos++
+			}
+			return nil
+		}
+	}
+	return errMongoShellEOF
+}
+
+func (p *mongoShellObfuscator) identifier() string {
+	start := p.pos
+	for p.pos < len(p.in) && isMongoIdentPart(p.in[p.pos]) {
+		p.pos++
+	}
+	return p.in[start:p.pos]
+}
+
+func (p *mongoShellObfuscator) skipSpace() {
+	for p.pos < len(p.in) {
+		r, n := utf8.DecodeRuneInString(p.in[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += n
+	}
+}
+
+// peek returns the next byte of the query, or 0 at the end of the query.
+func (p *mongoShellObfuscator) peek() byte {
+	if p.pos >= len(p.in) {
+		return 0
+	}
+	return p.in[p.pos]
+}
+
+func (p *mongoShellObfuscator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %v", p.pos, fmt.Errorf(format, args...))
+}
+
+// openingDelimiter returns the opening counterpart of the given closing delimiter.
+func openingDelimiter(c byte) byte {
+	switch c {
+	case ')':
+		return '('
+	case ']':
+		return '['
+	default:
+		return '{'
+	}
+}
+
+func isMongoIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+func isMongoIdentPart(c byte) bool {
+	return isMongoIdentStart(c) || isDigit(rune(c))
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateMongoDBShellString(t *testing.T) {
+	o := NewObfuscator(Config{Mongo: JSONConfig{Enabled: true, KeepValues: []string{"limit"}}})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`db.users.find({a: ObjectId("5f8f8c44b54764421b7156c5")})`,
+			`db.users.find({a: ?})`,
+		},
+		{
+			`db.users.find({name: 'john', age: {$gt: 25, $lte: NumberLong(60)}, tags: ['a', "b",]}).sort({age: -1}).limit(10)`,
+			`db.users.find({name: ?, age: {$gt: ?, $lte: ?}, tags: [?, ?]}).sort({age: ?}).limit(?)`,
+		},
+		{
+			`db.getCollection("orders").aggregate([{$match: {created: {$gte: ISODate("2023-01-01T00:00:00Z")}}}, {$limit: 5}])`,
+			`db.getCollection(?).aggregate([{$match: {created: {$gte: ?}}}, {$limit: ?}])`,
+		},
+		{
+			`db.logs.find({msg: /err(or)?[/]x/i, "nested.key": new Date(2020, 1, 1), ok: true, n: null})`,
+			`db.logs.find({msg: ?, "nested.key": ?, ok: ?, n: ?})`,
+		},
+		{
+			`db.items.find({$where: function() { return this.a == "}" }})`,
+			`db.items.find({$where: ?})`,
+		},
+		{
+			// values of keep_values keys are kept
+			`{find: 'users', filter: {name: 'x'}, limit: 10}`,
+			`{find: ?, filter: {name: ?}, limit: 10}`,
+		},
+		{
+			`db.users.updateOne({_id: 1}, {$set: {email: "a@b.c"}}); db.users.count()`,
+			`db.users.updateOne({_id: ?}, {$set: {email: ?}});db.users.count()`,
+		},
+		{
+			// truncated queries are obfuscated as far as possible
+			`db.users.find({name: 'john', age: {$gt`,
+			`db.users.find({name: ?, age: {$gt...`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			assert.Equal(t, tt.out, o.ObfuscateMongoDBShellString(tt.in))
+		})
+	}
+}
+
+func TestObfuscateMongoDBStringFallback(t *testing.T) {
+	o := NewObfuscator(Config{Mongo: JSONConfig{Enabled: true}})
+	// strict JSON goes through the JSON obfuscator
+	assert.Equal(t, `{"find":"?","filter":{"a":"?"}}`, o.ObfuscateMongoDBString(`{"find": "users", "filter": {"a": 1}}`))
+	// anything else through the Mongo shell obfuscator
+	assert.Equal(t, `db.users.find({a: ?})`, o.ObfuscateMongoDBString(`db.users.find({a: 1})`))
+	// truncated or invalid JSON keeps the partial output of the JSON obfuscator
+	assert.Equal(t, `{"find":"?","filter":{"password":"?"...`, o.ObfuscateMongoDBString(`{"find": "users", "filter": {"password": "hunter`))
+	assert.Equal(t, `[{"a":"?"},{"b":"?"...`, o.ObfuscateMongoDBString(` [{"a": 1}, {"b": "x`))
+	assert.Equal(t, `{...`, o.ObfuscateMongoDBString(`{find: 'users'}`))
+
+	// text which is neither a document nor a method chain on db is not kept
+	for in, out := range map[string]string{
+		`secretToken`:                  `...`,
+		`password hunter2 = 1`:         `...`,
+		`users.find({a: 1})`:           `...`,
+		`db.users.find({a: 1}) secret`: `db.users.find({a: ?})...`,
+		`db.users.find(); secret`:      `db.users.find();...`,
+		`db. 'secret'`:                 `db...`,
+	} {
+		assert.Equal(t, out, o.ObfuscateMongoDBString(in), in)
+		assert.Equal(t, out, o.ObfuscateMongoDBShellString(in), in)
+	}
+
+	disabled := NewObfuscator(Config{})
+	assert.Equal(t, `db.users.find({a: 1})`, disabled.ObfuscateMongoDBShellString(`db.users.find({a: 1})`))
+}
@@ -47,7 +47,7 @@ func (f *metadataFinderFilter) Filter(token, lastToken TokenKind, buffer []byte)
 	}
 	if f.collectCommands {
 		switch token {
-		case Select, Update, Insert, Delete, Join, Alter, Drop, Create, Grant, Revoke, Commit, Begin, Truncate:
+		case Select, Update, Insert, Delete, Join, Alter, Drop, Create, Grant, Revoke, Commit, Begin, Truncate, Merge, Copy, Optimize:
 			command := strings.ToUpper(token.String())
 			f.size += int64(len(command))
 			f.commands = append(f.commands, command)
@@ -68,6 +68,10 @@ func (f *metadataFinderFilter) Filter(token, lastToken TokenKind, buffer []byte)
 		case Update, Into:
 			// UPDATE [tableName]
 			// INSERT INTO [tableName]
+			if lastToken == Update && strings.EqualFold(string(buffer), "SET") {
+				// MERGE ... WHEN MATCHED THEN UPDATE SET ...
+				break
+			}
 			tableName := string(buffer)
 			if f.replaceDigits {
 				tableNameCopy := make([]byte, len(buffer))
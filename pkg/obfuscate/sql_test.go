@@ -7,9 +7,12 @@ package obfuscate
 
 import (
 	"bytes"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -1320,6 +1323,76 @@ func TestObfuscatorDBMSBehavior(t *testing.T) {
 	}
 }
 
+// sqlDialectTestFiles contains the tests for the SQL dialects with a dedicated tokenizer behavior.
+var sqlDialectTestFiles = []string{
+	"./testdata/sql_oracle.xml",
+	"./testdata/sql_snowflake.xml",
+	"./testdata/sql_clickhouse.xml",
+}
+
+type xmlSQLDialectTests struct {
+	XMLName xml.Name             `xml:"SQLDialectTests"`
+	DBMS    string               `xml:"DBMS"`
+	Tests   []*xmlSQLDialectTest `xml:"TestSuite>Test"`
+}
+
+type xmlSQLDialectTest struct {
+	Tag      string
+	In       string
+	Out      string
+	Tables   string
+	Commands string
+}
+
+func TestSQLDialects(t *testing.T) {
+	for _, path := range sqlDialectTestFiles {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		var suite xmlSQLDialectTests
+		err = xml.NewDecoder(f).Decode(&suite)
+		f.Close()
+		require.NoError(t, err, path)
+		require.NotEmpty(t, suite.Tests, path)
+
+		o := NewObfuscator(Config{SQL: SQLConfig{
+			DBMS:            suite.DBMS,
+			TableNames:      true,
+			CollectCommands: true,
+		}})
+		for _, tt := range suite.Tests {
+			t.Run(tt.Tag, func(t *testing.T) {
+				assert := assert.New(t)
+				oq, err := o.ObfuscateSQLString(tt.In)
+				require.NoError(t, err)
+				assert.Equal(tt.Out, oq.Query)
+				assert.Equal(tt.Tables, oq.Metadata.TablesCSV)
+				var commands []string
+				if tt.Commands != "" {
+					commands = strings.Split(tt.Commands, ",")
+				}
+				assert.ElementsMatch(commands, oq.Metadata.Commands)
+			})
+		}
+	}
+}
+
+func TestSQLDialectErrors(t *testing.T) {
+	for _, tt := range []struct {
+		dbms, in, err string
+	}{
+		{DBMSOracle, "SELECT q'[abc' FROM dual", "unexpected EOF in string"},
+		{DBMSOracle, `SELECT * FROM "HR"."EMP`, "unexpected EOF in identifier"},
+		{DBMSClickHouse, "SELECT * FROM t WHERE id = {id:UInt64", "unexpected EOF in query parameter"},
+		{DBMSClickHouse, "SELECT * FROM `db`.`t", "unexpected EOF in identifier"},
+	} {
+		t.Run(tt.dbms, func(t *testing.T) {
+			_, err := NewObfuscator(Config{SQL: SQLConfig{DBMS: tt.dbms}}).ObfuscateSQLString(tt.in)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
 func TestSQLTokenizerIgnoreEscapeFalse(t *testing.T) {
 	cases := []sqlTokenizerTestCase{
 		{
@@ -87,6 +87,11 @@ const (
 	JSONAllKeysExist   // ?&
 	JSONDelete         // #-
 
+	// Dialect specific commands
+	Merge    // Oracle, Snowflake
+	Copy     // Snowflake
+	Optimize // ClickHouse
+
 	// FilteredGroupable specifies that the given token has been discarded by one of the
 	// token filters and that it is groupable together with consecutive FilteredGroupable
 	// tokens.
@@ -163,6 +168,9 @@ var tokenKindStrings = map[TokenKind]string{
 	JSONAnyKeysExist:             "JSONAnyKeysExist",
 	JSONAllKeysExist:             "JSONAllKeysExist",
 	JSONDelete:                   "JSONDelete",
+	Merge:                        "Merge",
+	Copy:                         "Copy",
+	Optimize:                     "Optimize",
 }
 
 func (k TokenKind) String() string {
@@ -182,6 +190,12 @@ const (
 	DBMSMySQL = "mysql"
 	// DBMSOracle is an Oracle Server
 	DBMSOracle = "oracle"
+	// DBMSCassandra is an Apache Cassandra (or compatible) server, queried using CQL
+	DBMSCassandra = "cassandra"
+	// DBMSSnowflake is a Snowflake data warehouse
+	DBMSSnowflake = "snowflake"
+	// DBMSClickHouse is a ClickHouse Server
+	DBMSClickHouse = "clickhouse"
 )
 
 const escapeCharacter = '\\'
@@ -250,6 +264,27 @@ var keywords = map[string]TokenKind{
 	"JOIN":      Join,
 }
 
+// dialectKeywords holds the keywords which are only recognized for a given DBMS.
+var dialectKeywords = map[string]map[string]TokenKind{
+	DBMSOracle:     {"MERGE": Merge},
+	DBMSSnowflake:  {"MERGE": Merge, "COPY": Copy},
+	DBMSClickHouse: {"OPTIMIZE": Optimize},
+}
+
+// quotedIdentifierDelimiter returns the character used to quote identifiers for the given DBMS,
+// for which qualified identifiers made of quoted parts are scanned as a whole (e.g. "schema"."table").
+// It returns 0 if identifiers are not handled this way.
+func quotedIdentifierDelimiter(dbms string) rune {
+	switch dbms {
+	case DBMSOracle, DBMSSnowflake:
+		return '"'
+	case DBMSClickHouse:
+		return '`'
+	default:
+		return 0
+	}
+}
+
 // Err returns the last error that the tokenizer encountered, or nil.
 func (tkn *SQLTokenizer) Err() error { return tkn.err }
 
@@ -272,6 +307,10 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 	tkn.SkipBlank()
 
 	switch ch := tkn.lastChar; {
+	case tkn.cfg.DBMS == DBMSCassandra && isUUID(tkn.unread()):
+		return tkn.scanUUID()
+	case tkn.cfg.DBMS == DBMSOracle && isOracleQuotedString(tkn.unread()):
+		return tkn.scanOracleQuotedString()
 	case isLeadingLetter(ch) &&
 		!(tkn.cfg.DBMS == DBMSPostgres && ch == '@'):
 		// The '@' symbol should not be considered part of an identifier in
@@ -279,6 +318,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		// and ch is '@'.
 		return tkn.scanIdentifier()
 	case isDigit(ch):
+		if tkn.cfg.DBMS == DBMSCassandra {
+			return tkn.scanCQLNumber()
+		}
 		return tkn.scanNumber(false)
 	default:
 		tkn.advance()
@@ -459,8 +501,14 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			if quotedIdentifierDelimiter(tkn.cfg.DBMS) == ch {
+				return tkn.scanQualifiedIdentifier(ch, DoubleQuotedString, 0)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if quotedIdentifierDelimiter(tkn.cfg.DBMS) == ch {
+				return tkn.scanQualifiedIdentifier(ch, ID, 0)
+			}
 			return tkn.scanString(ch, ID)
 		case '%':
 			if tkn.lastChar == '(' {
@@ -473,6 +521,11 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			// modulo operator (e.g. 'id % 8')
 			return TokenKind(ch), tkn.bytes()
 		case '$':
+			if tkn.cfg.DBMS == DBMSSnowflake && (isDigit(tkn.lastChar) || isLetter(tkn.lastChar)) {
+				// Snowflake uses $1, $2, etc. to refer to the columns of staged files, and
+				// $name to refer to session variables.
+				return tkn.scanIdentifier()
+			}
 			if isDigit(tkn.lastChar) {
 				// TODO(gbbr): the first digit after $ does not necessarily guarantee
 				// that this isn't a dollar-quoted string constant. We might eventually
@@ -519,6 +572,14 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			}
 			fallthrough
 		case '{':
+			if tkn.cfg.DBMS == DBMSCassandra {
+				// CQL map, set and user-defined type literals, e.g. {'a': 1, 'b': {2, 3}}
+				return tkn.scanCollectionLiteral()
+			}
+			if tkn.cfg.DBMS == DBMSClickHouse {
+				// query parameters, e.g. {id:UInt64}
+				return tkn.scanClickHouseParameter()
+			}
 			if tkn.pos == 1 || tkn.curlys > 0 {
 				// Do not fully obfuscate top-level SQL escape sequences like {{[?=]call procedure-name[([parameter][,parameter]...)]}.
 				// We want these to display a bit more context than just a plain '?'
@@ -593,10 +654,27 @@ func toUpper(src, dst []byte) []byte {
 }
 
 func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
+	stage := tkn.cfg.DBMS == DBMSSnowflake && tkn.lastChar == '@'
 	tkn.advance()
-	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || strings.ContainsRune(".*$", tkn.lastChar) {
+	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || strings.ContainsRune(".*$", tkn.lastChar) ||
+		(stage && strings.ContainsRune("/~%-", tkn.lastChar)) {
+		// Snowflake stages may be followed by a path, e.g. @my_stage/path/to/file.csv
 		tkn.advance()
 	}
+	if delim := quotedIdentifierDelimiter(tkn.cfg.DBMS); delim != 0 && tkn.lastChar == delim {
+		if n := tkn.off - utf8.RuneLen(delim); n > 0 && tkn.buf[n-1] == '.' {
+			// a qualified identifier continuing with a quoted part, e.g. schema."table"
+			tkn.advance()
+			return tkn.scanQualifiedIdentifier(delim, ID, n)
+		}
+	}
+	if tkn.cfg.DBMS == DBMSSnowflake && tkn.lastChar == ':' {
+		if b := tkn.unread(); len(b) > 1 && (isLeadingLetter(rune(b[1])) || b[1] == '"') {
+			// semi-structured data path, e.g. src:customer[0].name
+			tkn.scanSnowflakePath()
+			return ID, tkn.bytes()
+		}
+	}
 
 	t := tkn.bytes()
 	// Space allows us to upper-case identifiers 256 bytes long or less without allocating heap
@@ -607,6 +685,9 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	if keywordID, found := keywords[string(upper)]; found {
 		return keywordID, t
 	}
+	if keywordID, found := dialectKeywords[tkn.cfg.DBMS][string(upper)]; found {
+		return keywordID, t
+	}
 	return ID, t
 }
 
@@ -629,6 +710,225 @@ func (tkn *SQLTokenizer) scanFormatParameter(prefix rune) (TokenKind, []byte) {
 	return Variable, tkn.bytes()
 }
 
+// uuidLen is the length of the canonical textual representation of a UUID.
+const uuidLen = 36
+
+// isUUID reports whether b starts with a UUID literal in its canonical form
+// (e.g. 123e4567-e89b-12d3-a456-426614174000), not followed by an identifier character.
+func isUUID(b []byte) bool {
+	if len(b) < uuidLen {
+		return false
+	}
+	for i := 0; i < uuidLen; i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if b[i] != '-' {
+				return false
+			}
+		default:
+			if digitVal(rune(b[i])) >= 16 {
+				return false
+			}
+		}
+	}
+	if len(b) > uuidLen {
+		if r, _ := utf8.DecodeRune(b[uuidLen:]); isLetter(r) || isDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanUUID scans a CQL UUID (or TimeUUID) literal. It must only be called after
+// isUUID reported true.
+func (tkn *SQLTokenizer) scanUUID() (TokenKind, []byte) {
+	for i := 0; i < uuidLen; i++ {
+		tkn.advance()
+	}
+	// UUIDs are constants and get the same treatment as numbers.
+	return Number, tkn.bytes()
+}
+
+// scanCQLNumber scans a CQL number, which might be followed by duration units
+// (e.g. 1h30m or 2d12h).
+func (tkn *SQLTokenizer) scanCQLNumber() (TokenKind, []byte) {
+	kind, buf := tkn.scanNumber(false)
+	if kind != Number || !unicode.IsLetter(tkn.lastChar) {
+		return kind, buf
+	}
+	for unicode.IsLetter(tkn.lastChar) || isDigit(tkn.lastChar) {
+		tkn.advance()
+	}
+	return Number, tkn.bytes()
+}
+
+// scanCollectionLiteral scans a CQL collection or user-defined type literal delimited
+// by curly braces, which may contain nested collections and quoted strings.
+func (tkn *SQLTokenizer) scanCollectionLiteral() (TokenKind, []byte) {
+	depth := 1
+	for depth > 0 {
+		switch ch := tkn.lastChar; ch {
+		case EndChar:
+			tkn.setErr("unexpected EOF in collection literal")
+			return LexError, tkn.bytes()
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '\'', '"':
+			tkn.advance()
+			for tkn.lastChar != ch {
+				if tkn.lastChar == EndChar {
+					tkn.setErr("unexpected EOF in string")
+					return LexError, tkn.bytes()
+				}
+				tkn.advance()
+			}
+		}
+		tkn.advance()
+	}
+	// collection literals only hold constants and are obfuscated as a whole.
+	return String, tkn.bytes()
+}
+
+// isOracleQuotedString reports whether b starts with an Oracle string literal using
+// alternative quoting, e.g. q'[it's]' or nq'{it's}'.
+func isOracleQuotedString(b []byte) bool {
+	if len(b) > 0 && (b[0] == 'n' || b[0] == 'N') {
+		b = b[1:]
+	}
+	return len(b) > 2 && (b[0] == 'q' || b[0] == 'Q') && b[1] == '\''
+}
+
+// scanOracleQuotedString scans an Oracle string literal using alternative quoting. It must
+// only be called after isOracleQuotedString reported true.
+// See: https://docs.oracle.com/en/database/oracle/oracle-database/19/sqlrf/Literals.html#GUID-1824CBAA-6E16-4921-B2A6-112FB02248DA
+func (tkn *SQLTokenizer) scanOracleQuotedString() (TokenKind, []byte) {
+	for tkn.lastChar != '\'' {
+		tkn.advance()
+	}
+	tkn.advance()
+	closing := tkn.lastChar
+	switch closing {
+	case '[':
+		closing = ']'
+	case '{':
+		closing = '}'
+	case '(':
+		closing = ')'
+	case '<':
+		closing = '>'
+	}
+	for {
+		tkn.advance()
+		if tkn.lastChar == EndChar {
+			tkn.setErr("unexpected EOF in string")
+			return LexError, tkn.bytes()
+		}
+		if tkn.lastChar == closing {
+			if b := tkn.unread(); len(b) > 1 && b[1] == '\'' {
+				break
+			}
+		}
+	}
+	tkn.advance()
+	tkn.advance()
+	return String, tkn.bytes()
+}
+
+// scanQualifiedIdentifier scans an identifier made of dot-separated parts, which are either
+// quoted using delim or unquoted (e.g. "schema"."table" or `db`.events). The quotes are removed.
+// It must be called right after an opening delimiter, the first n bytes of the buffer holding
+// the parts of the identifier scanned so far.
+func (tkn *SQLTokenizer) scanQualifiedIdentifier(delim rune, kind TokenKind, n int) (TokenKind, []byte) {
+	// the identifier is written in place, over the bytes which were already scanned.
+	out := tkn.buf[:n]
+	quoted := true
+	for {
+		if quoted {
+			for {
+				ch := tkn.lastChar
+				if ch == EndChar {
+					tkn.setErr("unexpected EOF in identifier")
+					return LexError, out
+				}
+				tkn.advance()
+				if ch == delim {
+					if tkn.lastChar != delim {
+						break
+					}
+					// doubled delimiter
+					tkn.advance()
+				}
+				out = utf8.AppendRune(out, ch)
+			}
+		} else {
+			for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '$' || tkn.lastChar == '*' {
+				out = utf8.AppendRune(out, tkn.lastChar)
+				tkn.advance()
+			}
+		}
+		if tkn.lastChar != '.' {
+			break
+		}
+		out = append(out, '.')
+		tkn.advance()
+		if quoted = tkn.lastChar == delim; quoted {
+			tkn.advance()
+		}
+	}
+	if len(out) == 0 {
+		// keep the delimiters of empty identifiers, see scanString
+		return kind, append(runeBytes(delim), runeBytes(delim)...)
+	}
+	return kind, out
+}
+
+// scanSnowflakePath scans the path following a Snowflake semi-structured column, starting
+// at the colon (e.g. :customer[0]."first name").
+func (tkn *SQLTokenizer) scanSnowflakePath() {
+	tkn.advance() // :
+	for {
+		switch ch := tkn.lastChar; {
+		case isLetter(ch) || isDigit(ch) || ch == '.' || ch == '$':
+			tkn.advance()
+		case ch == '"' || ch == '[':
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			for tkn.advance(); tkn.lastChar != closing && tkn.lastChar != EndChar; tkn.advance() {
+			}
+			tkn.advance()
+		default:
+			return
+		}
+	}
+}
+
+// scanClickHouseParameter scans a ClickHouse query parameter, e.g. {id:UInt64}.
+func (tkn *SQLTokenizer) scanClickHouseParameter() (TokenKind, []byte) {
+	for tkn.lastChar != '}' {
+		if tkn.lastChar == EndChar {
+			tkn.setErr("unexpected EOF in query parameter")
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+	}
+	tkn.advance()
+	// parameters are placeholders for values and are kept, similarly to bind variables.
+	return ValueArg, tkn.bytes()
+}
+
+// unread returns the part of the query which has not been scanned yet, starting
+// with tkn.lastChar.
+func (tkn *SQLTokenizer) unread() []byte {
+	if tkn.lastChar == EndChar {
+		return nil
+	}
+	return tkn.buf[tkn.off-utf8.RuneLen(tkn.lastChar):]
+}
+
 // scanDollarQuotedString scans a Postgres dollar-quoted string constant.
 // See: https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-DOLLAR-QUOTING
 func (tkn *SQLTokenizer) scanDollarQuotedString() (TokenKind, []byte) {
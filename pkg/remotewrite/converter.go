@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	metricNameLabel = "__name__"
+	bucketLabel     = "le"
+	// staleNaN is the NaN value Prometheus uses to mark a series as stale.
+	staleNaN uint64 = 0x7ff0000000000002
+
+	// counterExpiry is how long the last value of a counter is kept when the counter
+	// is not received anymore.
+	counterExpiry = 15 * time.Minute
+)
+
+// conversionStats counts what happened to the samples of a request.
+type conversionStats struct {
+	gauges   int
+	counters int
+	// dropped counts the samples without name, with an invalid value, or out of order
+	dropped int
+	// pending counts the first samples of the counters, which are only used as reference
+	pending int
+}
+
+// converter turns the remote_write time series into metric samples. It remembers the
+// types of the metric families sent in the metadata, and the last value of each counter
+// to compute its increase between two samples. It is safe for concurrent use.
+type converter struct {
+	namespace string
+	hostname  string
+
+	mu          sync.Mutex
+	types       map[string]metricType   // by metric family name
+	counters    map[string]counterState // by series key
+	lastExpired time.Time
+}
+
+type counterState struct {
+	value     float64
+	timestamp int64
+	lastSeen  time.Time
+}
+
+func newConverter(namespace, hostname string) *converter {
+	if namespace != "" && !strings.HasSuffix(namespace, ".") {
+		namespace += "."
+	}
+	return &converter{
+		namespace: namespace,
+		hostname:  hostname,
+		types:     make(map[string]metricType),
+		counters:  make(map[string]counterState),
+	}
+}
+
+// convert calls emit for each metric sample built from the request.
+//
+// Counters, and the _bucket, _sum and _count series of the histograms and summaries, are
+// submitted as counts of their increase since their previous sample. Every other series
+// is submitted as a gauge. Without metadata for the family of a series, its type is
+// guessed from its name suffix.
+func (c *converter) convert(req *writeRequest, now time.Time, emit func(metrics.MetricSample)) conversionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, md := range req.metadata {
+		if md.metricFamilyName != "" {
+			c.types[md.metricFamilyName] = md.metricType
+		}
+	}
+
+	var stats conversionStats
+	for _, ts := range req.timeseries {
+		name := ""
+		for _, l := range ts.labels {
+			if l.name == metricNameLabel {
+				name = l.value
+				break
+			}
+		}
+		if name == "" {
+			stats.dropped += len(ts.samples)
+			continue
+		}
+
+		metricName, counter, bucket := c.resolve(name)
+		tags := make([]string, 0, len(ts.labels))
+		for _, l := range ts.labels {
+			if l.name == metricNameLabel || l.value == "" {
+				continue
+			}
+			if bucket && l.name == bucketLabel {
+				tags = append(tags, "upper_bound:"+formatUpperBound(l.value))
+				continue
+			}
+			tags = append(tags, l.name+":"+l.value)
+		}
+
+		var key string
+		if counter {
+			key = seriesKey(ts.labels)
+		}
+		for _, s := range ts.samples {
+			if math.Float64bits(s.value) == staleNaN {
+				if counter {
+					delete(c.counters, key)
+				}
+				stats.dropped++
+				continue
+			}
+			if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+				stats.dropped++
+				continue
+			}
+
+			sample := metrics.MetricSample{
+				Name:       c.namespace + metricName,
+				Value:      s.value,
+				Mtype:      metrics.GaugeType,
+				Tags:       tags,
+				Host:       c.hostname,
+				SampleRate: 1,
+				Timestamp:  float64(s.timestamp) / 1000,
+			}
+			if counter {
+				delta, ok, reference := c.counterIncrease(key, s, now)
+				if !ok {
+					if reference {
+						stats.pending++
+					} else {
+						stats.dropped++
+					}
+					continue
+				}
+				sample.Value = delta
+				sample.Mtype = metrics.CounterType
+				stats.counters++
+			} else {
+				stats.gauges++
+			}
+			emit(sample)
+		}
+	}
+
+	if now.Sub(c.lastExpired) > time.Minute {
+		c.expireCounters(now)
+		c.lastExpired = now
+	}
+	return stats
+}
+
+// resolve returns the name of the metric submitted for the series, whether it is a
+// counter, and whether it is a histogram bucket.
+func (c *converter) resolve(name string) (string, bool, bool) {
+	if t, found := c.types[name]; found {
+		return name, t == metricTypeCounter, false
+	}
+
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+		family, found := strings.CutSuffix(name, suffix)
+		if !found {
+			continue
+		}
+		t, known := c.types[family]
+		switch suffix {
+		case "_bucket":
+			if !known || t == metricTypeHistogram {
+				return family + ".bucket", true, true
+			}
+			if t == metricTypeGaugeHistogram {
+				return family + ".bucket", false, true
+			}
+		case "_sum", "_count":
+			if !known || t == metricTypeHistogram || t == metricTypeSummary {
+				return family + "." + suffix[1:], true, false
+			}
+		case "_total":
+			if !known || t == metricTypeCounter {
+				return name, true, false
+			}
+		}
+		return name, false, false
+	}
+	return name, false, false
+}
+
+// counterIncrease returns the increase of the counter since its previous sample and true,
+// or false if the increase cannot be computed. In the latter case, it also returns
+// whether the sample is the first of the counter.
+func (c *converter) counterIncrease(key string, s sample, now time.Time) (float64, bool, bool) {
+	state, found := c.counters[key]
+	if !found {
+		c.counters[key] = counterState{value: s.value, timestamp: s.timestamp, lastSeen: now}
+		return 0, false, true
+	}
+	if s.timestamp <= state.timestamp {
+		// duplicate or out of order sample
+		return 0, false, false
+	}
+
+	delta := s.value - state.value
+	if delta < 0 {
+		// the counter has been reset
+		delta = s.value
+	}
+	c.counters[key] = counterState{value: s.value, timestamp: s.timestamp, lastSeen: now}
+	return delta, true, false
+}
+
+func (c *converter) expireCounters(now time.Time) {
+	for key, state := range c.counters {
+		if now.Sub(state.lastSeen) > counterExpiry {
+			delete(c.counters, key)
+		}
+	}
+}
+
+// seriesKey returns a key identifying the series from its labels, which remote_write
+// senders sort by name.
+func seriesKey(labels []label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0xff)
+		b.WriteString(l.value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// formatUpperBound formats the le label of a bucket like the openmetrics check does.
+func formatUpperBound(le string) string {
+	if le == "+Inf" {
+		return "inf"
+	}
+	return le
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func series(name string, labels []label, samples ...sample) timeSeries {
+	return timeSeries{
+		labels:  append([]label{{name: metricNameLabel, value: name}}, labels...),
+		samples: samples,
+	}
+}
+
+func convertAll(c *converter, req *writeRequest, now time.Time) ([]metrics.MetricSample, conversionStats) {
+	var samples []metrics.MetricSample
+	stats := c.convert(req, now, func(s metrics.MetricSample) {
+		samples = append(samples, s)
+	})
+	return samples, stats
+}
+
+func TestConvertGauge(t *testing.T) {
+	c := newConverter("prom", "myhost")
+	samples, stats := convertAll(c, &writeRequest{timeseries: []timeSeries{
+		series("node_load1", []label{{name: "instance", value: "a:9100"}, {name: "job", value: "node"}, {name: "empty"}},
+			sample{value: 1.5, timestamp: 1700000000123}),
+	}}, time.Now())
+
+	assert.Equal(t, conversionStats{gauges: 1}, stats)
+	assert.Equal(t, []metrics.MetricSample{{
+		Name:       "prom.node_load1",
+		Value:      1.5,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"instance:a:9100", "job:node"},
+		Host:       "myhost",
+		SampleRate: 1,
+		Timestamp:  1700000000.123,
+	}}, samples)
+}
+
+func TestConvertCounter(t *testing.T) {
+	c := newConverter("", "myhost")
+	now := time.Now()
+	labels := []label{{name: "code", value: "200"}}
+
+	samples, stats := convertAll(c, &writeRequest{timeseries: []timeSeries{
+		series("http_requests_total", labels, sample{value: 10, timestamp: 1000}, sample{value: 15, timestamp: 2000}),
+	}}, now)
+	assert.Equal(t, conversionStats{counters: 1, pending: 1}, stats)
+	assert.Len(t, samples, 1)
+	assert.Equal(t, "http_requests_total", samples[0].Name)
+	assert.Equal(t, metrics.CounterType, samples[0].Mtype)
+	assert.Equal(t, 5.0, samples[0].Value)
+	assert.Equal(t, 2.0, samples[0].Timestamp)
+
+	// the last value is kept between requests, and resets are detected
+	samples, stats = convertAll(c, &writeRequest{timeseries: []timeSeries{
+		series("http_requests_total", labels, sample{value: 15, timestamp: 2000}, sample{value: 3, timestamp: 3000}, sample{value: 7, timestamp: 4000}),
+	}}, now)
+	assert.Equal(t, conversionStats{counters: 2, dropped: 1}, stats)
+	assert.Equal(t, 3.0, samples[0].Value)
+	assert.Equal(t, 4.0, samples[1].Value)
+
+	// a stale marker forgets the counter
+	_, stats = convertAll(c, &writeRequest{timeseries: []timeSeries{
+		series("http_requests_total", labels, sample{value: math.Float64frombits(staleNaN), timestamp: 5000}, sample{value: 8, timestamp: 6000}),
+	}}, now)
+	assert.Equal(t, conversionStats{pending: 1, dropped: 1}, stats)
+
+	// counters not received anymore expire
+	convertAll(c, &writeRequest{}, now.Add(counterExpiry+time.Minute))
+	assert.Empty(t, c.counters)
+}
+
+func TestConvertInvalidSamples(t *testing.T) {
+	c := newConverter("", "myhost")
+	samples, stats := convertAll(c, &writeRequest{timeseries: []timeSeries{
+		{labels: []label{{name: "job", value: "node"}}, samples: []sample{{value: 1, timestamp: 1000}}},
+		series("up", nil, sample{value: math.NaN(), timestamp: 1000}, sample{value: math.Inf(1), timestamp: 2000}, sample{value: 1, timestamp: 3000}),
+	}}, time.Now())
+	assert.Equal(t, conversionStats{gauges: 1, dropped: 3}, stats)
+	assert.Len(t, samples, 1)
+}
+
+func TestResolve(t *testing.T) {
+	c := newConverter("", "myhost")
+	c.convert(&writeRequest{metadata: []metricMetadata{
+		{metricType: metricTypeCounter, metricFamilyName: "requests"},
+		{metricType: metricTypeGauge, metricFamilyName: "queue_count"},
+		{metricType: metricTypeGauge, metricFamilyName: "temperature"},
+		{metricType: metricTypeHistogram, metricFamilyName: "latency"},
+		{metricType: metricTypeGaugeHistogram, metricFamilyName: "queue_size"},
+		{metricType: metricTypeSummary, metricFamilyName: "gc_duration"},
+	}}, time.Now(), func(metrics.MetricSample) {})
+
+	for _, tc := range []struct {
+		name    string
+		ddName  string
+		counter bool
+		bucket  bool
+	}{
+		// with metadata
+		{"requests_total", "requests_total", true, false},
+		{"queue_count", "queue_count", false, false},
+		{"temperature", "temperature", false, false},
+		{"temperature_total", "temperature_total", false, false},
+		{"latency_bucket", "latency.bucket", true, true},
+		{"latency_sum", "latency.sum", true, false},
+		{"latency_count", "latency.count", true, false},
+		{"queue_size_bucket", "queue_size.bucket", false, true},
+		{"queue_size_gcount", "queue_size_gcount", false, false},
+		{"gc_duration", "gc_duration", false, false},
+		{"gc_duration_sum", "gc_duration.sum", true, false},
+		{"gc_duration_count", "gc_duration.count", true, false},
+		// without metadata
+		{"errors_total", "errors_total", true, false},
+		{"size_bucket", "size.bucket", true, true},
+		{"size_sum", "size.sum", true, false},
+		{"size_count", "size.count", true, false},
+		{"up", "up", false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ddName, counter, bucket := c.resolve(tc.name)
+			assert.Equal(t, tc.ddName, ddName)
+			assert.Equal(t, tc.counter, counter)
+			assert.Equal(t, tc.bucket, bucket)
+		})
+	}
+}
+
+func TestConvertBucket(t *testing.T) {
+	c := newConverter("", "myhost")
+	now := time.Now()
+	req := func(value float64, timestamp int64) *writeRequest {
+		return &writeRequest{timeseries: []timeSeries{
+			series("latency_bucket", []label{{name: "le", value: "0.5"}}, sample{value: value, timestamp: timestamp}),
+			series("latency_bucket", []label{{name: "le", value: "+Inf"}}, sample{value: 2 * value, timestamp: timestamp}),
+		}}
+	}
+	convertAll(c, req(1, 1000), now)
+	samples, _ := convertAll(c, req(3, 2000), now)
+
+	assert.Len(t, samples, 2)
+	assert.Equal(t, "latency.bucket", samples[0].Name)
+	assert.Equal(t, []string{"upper_bound:0.5"}, samples[0].Tags)
+	assert.Equal(t, 2.0, samples[0].Value)
+	assert.Equal(t, []string{"upper_bound:inf"}, samples[1].Tags)
+	assert.Equal(t, 4.0, samples[1].Value)
+}
@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The types below are the subset of the Prometheus remote_write 1.0 protobuf messages
+// (prometheus/prompb) used by the receiver. They are decoded by hand to avoid pulling
+// the Prometheus module in the agent.
+
+// metricType is the type of a metric family, as sent in the metadata of a WriteRequest.
+type metricType int32
+
+const (
+	metricTypeUnknown        metricType = 0
+	metricTypeCounter        metricType = 1
+	metricTypeGauge          metricType = 2
+	metricTypeHistogram      metricType = 3
+	metricTypeGaugeHistogram metricType = 4
+	metricTypeSummary        metricType = 5
+)
+
+type writeRequest struct {
+	timeseries []timeSeries
+	metadata   []metricMetadata
+	// nativeHistograms counts the native histogram samples, which are not supported
+	nativeHistograms int
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64 // in milliseconds
+}
+
+type metricMetadata struct {
+	metricType       metricType
+	metricFamilyName string
+}
+
+// Field numbers of the remote_write messages.
+const (
+	writeRequestTimeseriesField = 1
+	writeRequestMetadataField   = 3
+
+	timeSeriesLabelsField     = 1
+	timeSeriesSamplesField    = 2
+	timeSeriesHistogramsField = 4
+
+	labelNameField  = 1
+	labelValueField = 2
+
+	sampleValueField     = 1
+	sampleTimestampField = 2
+
+	metadataTypeField             = 1
+	metadataMetricFamilyNameField = 2
+)
+
+// decodeWriteRequest decodes a serialized WriteRequest.
+func decodeWriteRequest(b []byte) (*writeRequest, error) {
+	req := &writeRequest{}
+	err := decodeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == writeRequestTimeseriesField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			ts, histograms, err := decodeTimeSeries(v)
+			if err != nil {
+				return 0, fmt.Errorf("invalid timeseries: %v", err)
+			}
+			req.timeseries = append(req.timeseries, ts)
+			req.nativeHistograms += histograms
+			return n, nil
+		case num == writeRequestMetadataField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			md, err := decodeMetadata(v)
+			if err != nil {
+				return 0, fmt.Errorf("invalid metadata: %v", err)
+			}
+			req.metadata = append(req.metadata, md)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeTimeSeries(b []byte) (timeSeries, int, error) {
+	var ts timeSeries
+	histograms := 0
+	err := decodeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == timeSeriesLabelsField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			l, err := decodeLabel(v)
+			if err != nil {
+				return 0, err
+			}
+			ts.labels = append(ts.labels, l)
+			return n, nil
+		case num == timeSeriesSamplesField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			s, err := decodeSample(v)
+			if err != nil {
+				return 0, err
+			}
+			ts.samples = append(ts.samples, s)
+			return n, nil
+		case num == timeSeriesHistogramsField:
+			histograms++
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return ts, histograms, err
+}
+
+func decodeLabel(b []byte) (label, error) {
+	var l label
+	err := decodeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ == protowire.BytesType && (num == labelNameField || num == labelValueField) {
+			v, n := protowire.ConsumeBytes(b)
+			if num == labelNameField {
+				l.name = string(v)
+			} else {
+				l.value = string(v)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return l, err
+}
+
+func decodeSample(b []byte) (sample, error) {
+	var s sample
+	err := decodeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == sampleValueField && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			s.value = math.Float64frombits(v)
+			return n, nil
+		case num == sampleTimestampField && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			s.timestamp = int64(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return s, err
+}
+
+func decodeMetadata(b []byte) (metricMetadata, error) {
+	var md metricMetadata
+	err := decodeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == metadataTypeField && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			md.metricType = metricType(v)
+			return n, nil
+		case num == metadataMetricFamilyNameField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			md.metricFamilyName = string(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return md, err
+}
+
+// decodeMessage calls decodeField for each field of the message. decodeField returns the
+// length of the field value it consumed, or a negative length if the value is malformed.
+func decodeMessage(b []byte, decodeField func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := decodeField(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite implements a server receiving metrics with the Prometheus
+// remote_write protocol, and submitting them with their timestamp to the no-aggregation
+// pipeline of the aggregator.
+package remotewrite
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/httpserver"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Path is the path of the remote_write endpoint.
+const Path = "/api/v1/write"
+
+var (
+	tlmRequests = telemetry.NewCounter("prometheus_remote_write", "requests",
+		[]string{"status"}, "Count of the remote_write requests received, by response status code")
+	tlmSamples = telemetry.NewCounter("prometheus_remote_write", "samples",
+		[]string{"state"}, "Count of the remote_write samples received, by what happened to them")
+	tlmSamplesGauge            = tlmSamples.WithValues("gauge")
+	tlmSamplesCounter          = tlmSamples.WithValues("counter")
+	tlmSamplesPending          = tlmSamples.WithValues("pending")
+	tlmSamplesDropped          = tlmSamples.WithValues("dropped")
+	tlmSamplesNativeHistograms = tlmSamples.WithValues("native_histogram")
+)
+
+// sampleSender is the part of the demultiplexer used by the server.
+type sampleSender interface {
+	GetMetricSamplePool() *metrics.MetricSamplePool
+	SendSamplesWithoutAggregation(samples metrics.MetricSampleBatch)
+}
+
+var serverInstance httpserver.Global
+
+// IsEnabled returns whether the remote_write receiver is enabled in the Agent configuration.
+func IsEnabled(cfg model.Reader) bool {
+	return cfg.GetBool("prometheus_remote_write.enabled")
+}
+
+// StartServer starts the global remote_write server.
+func StartServer(agentHostname string, demux aggregator.Demultiplexer, cfg model.Reader) error {
+	return serverInstance.Start(func() (*httpserver.Server, error) {
+		return NewServer(agentHostname, demux, cfg)
+	})
+}
+
+// StopServer stops the global remote_write server, if it is running.
+func StopServer() {
+	serverInstance.Stop()
+}
+
+// IsRunning returns whether the remote_write server is currently running.
+func IsRunning() bool {
+	return serverInstance.IsRunning()
+}
+
+// NewServer configures and returns a running remote_write server.
+func NewServer(agentHostname string, sender sampleSender, cfg model.Reader) (*httpserver.Server, error) {
+	addr := net.JoinHostPort(config.GetBindHostFromConfig(cfg), strconv.Itoa(cfg.GetInt("prometheus_remote_write.port")))
+	mux := http.NewServeMux()
+	mux.Handle(Path, newHandler(agentHostname, sender, cfg))
+	return httpserver.Start("Prometheus remote_write server", addr, mux)
+}
+
+// handler decodes the remote_write requests and submits their samples.
+type handler struct {
+	converter      *converter
+	sender         sampleSender
+	maxRequestSize int
+}
+
+func newHandler(agentHostname string, sender sampleSender, cfg model.Reader) *handler {
+	return &handler{
+		converter:      newConverter(cfg.GetString("prometheus_remote_write.namespace"), agentHostname),
+		sender:         sender,
+		maxRequestSize: cfg.GetInt("prometheus_remote_write.max_request_size"),
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.handle(r)
+	tlmRequests.Inc(strconv.Itoa(status))
+	if err != nil {
+		log.Debugf("Rejecting Prometheus remote_write request from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// handle processes the request and returns the status code of the response.
+func (h *handler) handle(r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method)
+	}
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if contentType := r.Header.Get("Content-Type"); strings.Contains(contentType, "proto=io.prometheus.write.v2") {
+		// remote_write 2.0 senders fall back to 1.0 when receiving this status
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, int64(h.maxRequestSize)+1))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("can't read body: %v", err)
+	}
+	if len(compressed) > h.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("body larger than %d bytes", h.maxRequestSize)
+	}
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("can't decompress body: %v", err)
+	}
+	if size > h.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("decompressed body larger than %d bytes", h.maxRequestSize)
+	}
+	payload, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("can't decompress body: %v", err)
+	}
+	req, err := decodeWriteRequest(payload)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("can't decode write request: %v", err)
+	}
+
+	h.submit(req)
+	return http.StatusNoContent, nil
+}
+
+// submit converts the request and sends its samples to the no-aggregation pipeline.
+func (h *handler) submit(req *writeRequest) {
+	pool := h.sender.GetMetricSamplePool()
+	batch := pool.GetBatch()
+	n := 0
+	stats := h.converter.convert(req, time.Now(), func(sample metrics.MetricSample) {
+		if n == len(batch) {
+			h.sender.SendSamplesWithoutAggregation(batch)
+			batch = pool.GetBatch()
+			n = 0
+		}
+		batch[n] = sample
+		n++
+	})
+	if n > 0 {
+		h.sender.SendSamplesWithoutAggregation(batch[:n])
+	} else {
+		pool.PutBatch(batch)
+	}
+
+	tlmSamplesGauge.Add(float64(stats.gauges))
+	tlmSamplesCounter.Add(float64(stats.counters))
+	tlmSamplesPending.Add(float64(stats.pending))
+	tlmSamplesDropped.Add(float64(stats.dropped))
+	tlmSamplesNativeHistograms.Add(float64(req.nativeHistograms))
+}
@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type senderMock struct {
+	pool    *metrics.MetricSamplePool
+	batches int
+	samples []metrics.MetricSample
+}
+
+func (s *senderMock) GetMetricSamplePool() *metrics.MetricSamplePool {
+	return s.pool
+}
+
+func (s *senderMock) SendSamplesWithoutAggregation(samples metrics.MetricSampleBatch) {
+	s.batches++
+	s.samples = append(s.samples, samples...)
+	s.pool.PutBatch(samples)
+}
+
+// encodeWriteRequest serializes a WriteRequest the way the prompb package does.
+func encodeWriteRequest(req *writeRequest) []byte {
+	var b []byte
+	for _, ts := range req.timeseries {
+		var tsb []byte
+		for _, l := range ts.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, labelNameField, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, labelValueField, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			tsb = protowire.AppendTag(tsb, timeSeriesLabelsField, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, lb)
+		}
+		for _, s := range ts.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, sampleValueField, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(s.value))
+			sb = protowire.AppendTag(sb, sampleTimestampField, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(s.timestamp))
+			tsb = protowire.AppendTag(tsb, timeSeriesSamplesField, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, sb)
+		}
+		b = protowire.AppendTag(b, writeRequestTimeseriesField, protowire.BytesType)
+		b = protowire.AppendBytes(b, tsb)
+	}
+	for _, md := range req.metadata {
+		var mb []byte
+		mb = protowire.AppendTag(mb, metadataTypeField, protowire.VarintType)
+		mb = protowire.AppendVarint(mb, uint64(md.metricType))
+		mb = protowire.AppendTag(mb, metadataMetricFamilyNameField, protowire.BytesType)
+		mb = protowire.AppendString(mb, md.metricFamilyName)
+		// help, unused by the receiver
+		mb = protowire.AppendTag(mb, 4, protowire.BytesType)
+		mb = protowire.AppendString(mb, "some help")
+		b = protowire.AppendTag(b, writeRequestMetadataField, protowire.BytesType)
+		b = protowire.AppendBytes(b, mb)
+	}
+	return b
+}
+
+func TestDecodeWriteRequest(t *testing.T) {
+	req := &writeRequest{
+		timeseries: []timeSeries{
+			series("up", []label{{name: "job", value: "node"}}, sample{value: 1, timestamp: 1000}, sample{value: 0, timestamp: 2000}),
+			series("requests_total", nil, sample{value: -2.5, timestamp: 1700000000000}),
+		},
+		metadata: []metricMetadata{{metricType: metricTypeCounter, metricFamilyName: "requests"}},
+	}
+	decoded, err := decodeWriteRequest(encodeWriteRequest(req))
+	require.NoError(t, err)
+	assert.Equal(t, req, decoded)
+
+	_, err = decodeWriteRequest([]byte{0x0a, 0x10, 0x01})
+	assert.Error(t, err)
+}
+
+func newTestHandler(t *testing.T, maxRequestSize int) (*handler, *senderMock) {
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("prometheus_remote_write.namespace", "prom")
+	cfg.SetWithoutSource("prometheus_remote_write.max_request_size", maxRequestSize)
+	sender := &senderMock{pool: metrics.NewMetricSamplePool(2, false)}
+	return newHandler("myhost", sender, cfg), sender
+}
+
+func post(h http.Handler, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h, sender := newTestHandler(t, 1024*1024)
+
+	var ts []timeSeries
+	for i := 0; i < 5; i++ {
+		ts = append(ts, series("temperature", []label{{name: "sensor", value: fmt.Sprint(i)}}, sample{value: float64(i), timestamp: 1000}))
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(&writeRequest{timeseries: ts}))
+	w := post(h, body, map[string]string{
+		"Content-Encoding": "snappy",
+		"Content-Type":     "application/x-protobuf",
+	})
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	// the samples are split in batches of the size of the pool
+	assert.Equal(t, 3, sender.batches)
+	require.Len(t, sender.samples, 5)
+	for i, s := range sender.samples {
+		assert.Equal(t, "prom.temperature", s.Name)
+		assert.Equal(t, float64(i), s.Value)
+		assert.Equal(t, []string{fmt.Sprintf("sensor:%d", i)}, s.Tags)
+		assert.Equal(t, 1.0, s.Timestamp)
+		assert.Equal(t, "myhost", s.Host)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h, sender := newTestHandler(t, 64)
+	body := snappy.Encode(nil, encodeWriteRequest(&writeRequest{timeseries: []timeSeries{
+		series("up", nil, sample{value: 1, timestamp: 1000}),
+	}}))
+
+	r := httptest.NewRequest(http.MethodGet, Path, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = post(h, body, map[string]string{"Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = post(h, body, map[string]string{"Content-Type": "application/x-protobuf;proto=io.prometheus.write.v2.Request"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = post(h, []byte{0xff}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(h, snappy.Encode(nil, []byte{0x0a, 0x10, 0x01}), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(h, snappy.Encode(nil, bytes.Repeat([]byte{0}, 65)), nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	assert.Empty(t, sender.samples)
+}
@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scrapeendpoint
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+const (
+	typeGauge   = "gauge"
+	typeSummary = "summary"
+
+	// quantileLabel is the label holding the quantile of the summaries, the tags
+	// converted to it are dropped from the summaries.
+	quantileLabel = "quantile"
+)
+
+// quantiles are the quantiles exposed for the sketches.
+var quantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// family is a metric family: the metrics sharing a name and a type.
+type family struct {
+	typ     string
+	metrics []metric
+}
+
+// metric is a metric of a family, rendered without its name.
+type metric struct {
+	labels string
+	write  func(b *strings.Builder, name, labels string)
+}
+
+// renderOpenMetrics renders the series and sketches in the OpenMetrics text format. The series
+// are exposed as gauges with the value of their last point, whatever their type, and the
+// sketches as summaries. The metric names and the tags are sanitized to follow the
+// Prometheus data model.
+func renderOpenMetrics(series metrics.Series, sketches metrics.SketchSeriesList) string {
+	families := make(map[string]*family)
+	add := func(name, typ string, m metric) {
+		f, found := families[name]
+		if !found {
+			f = &family{typ: typ}
+			families[name] = f
+		} else if f.typ != typ {
+			// a family can only have a single type, the first one wins
+			return
+		}
+		f.metrics = append(f.metrics, m)
+	}
+
+	for _, serie := range series {
+		if len(serie.Points) == 0 {
+			continue
+		}
+		value := serie.Points[len(serie.Points)-1].Value
+		add(sanitizeMetricName(serie.Name), typeGauge, metric{
+			labels: labelsFromTags(serie.Tags, serie.Host, serie.Device, false),
+			write: func(b *strings.Builder, name, labels string) {
+				writeSample(b, name, labels, value)
+			},
+		})
+	}
+
+	for _, sketch := range sketches {
+		if len(sketch.Points) == 0 || sketch.Points[len(sketch.Points)-1].Sketch == nil {
+			continue
+		}
+		s := sketch.Points[len(sketch.Points)-1].Sketch
+		add(sanitizeMetricName(sketch.Name), typeSummary, metric{
+			labels: labelsFromTags(sketch.Tags, sketch.Host, "", true),
+			write: func(b *strings.Builder, name, labels string) {
+				writeSummary(b, name, labels, s)
+			},
+		})
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := families[name]
+		sort.SliceStable(f.metrics, func(i, j int) bool { return f.metrics[i].labels < f.metrics[j].labels })
+
+		b.WriteString("# TYPE ")
+		b.WriteString(name)
+		b.WriteByte(' ')
+		b.WriteString(f.typ)
+		b.WriteByte('\n')
+		for i, m := range f.metrics {
+			// the sanitization can make several contexts identical, keep the first one
+			if i > 0 && m.labels == f.metrics[i-1].labels {
+				continue
+			}
+			m.write(&b, name, m.labels)
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+func writeSummary(b *strings.Builder, name, labels string, s *quantile.Sketch) {
+	cfg := quantile.Default()
+	for _, q := range quantiles {
+		quantileLabels := quantileLabel + `="` + strconv.FormatFloat(q, 'g', -1, 64) + `"`
+		if labels != "" {
+			quantileLabels = labels + "," + quantileLabels
+		}
+		writeSample(b, name, quantileLabels, s.Quantile(cfg, q))
+	}
+	writeSample(b, name+"_sum", labels, s.Basic.Sum)
+	writeSample(b, name+"_count", labels, float64(s.Basic.Cnt))
+}
+
+func writeSample(b *strings.Builder, name, labels string, value float64) {
+	b.WriteString(name)
+	if labels != "" {
+		b.WriteByte('{')
+		b.WriteString(labels)
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labelsFromTags converts the tags, the host and the device of a context into rendered
+// labels, sorted by name. The `key:value` tags become a `key` label, and the tags without
+// value a label with the `true` value. The values of the tags sharing a key are joined
+// with commas.
+func labelsFromTags(tags tagset.CompositeTags, host, device string, dropQuantile bool) string {
+	values := make(map[string][]string, tags.Len()+2)
+	addLabel := func(name, value string) {
+		name = sanitizeLabelName(name)
+		if dropQuantile && name == quantileLabel {
+			return
+		}
+		values[name] = append(values[name], value)
+	}
+	tags.ForEach(func(tag string) {
+		if key, value, found := strings.Cut(tag, ":"); found {
+			addLabel(key, value)
+		} else {
+			addLabel(tag, "true")
+		}
+	})
+	if host != "" {
+		addLabel("host", host)
+	}
+	if device != "" {
+		addLabel("device", device)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		v := values[name]
+		sort.Strings(v)
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueReplacer.Replace(strings.Join(v, ",")))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// sanitizeMetricName replaces the characters not allowed in a Prometheus metric name,
+// including the dots, with underscores.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in a Prometheus label name with
+// underscores. The names reserved for internal use, starting with `__`, are prefixed.
+func sanitizeLabelName(name string) string {
+	name = sanitizeName(name, false)
+	if strings.HasPrefix(name, "__") {
+		name = "tag" + name
+	}
+	return name
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	if name[0] >= '0' && name[0] <= '9' {
+		b.WriteByte('_')
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || (allowColon && c == ':') {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
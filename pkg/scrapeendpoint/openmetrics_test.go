@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scrapeendpoint
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestRenderOpenMetrics(t *testing.T) {
+	sketch := &quantile.Sketch{}
+	for i := 1; i <= 100; i++ {
+		sketch.Insert(quantile.Default(), float64(i))
+	}
+
+	series := metrics.Series{
+		{
+			Name:   "system.load.1",
+			Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2.5}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:web", "role:db"}),
+			Host:   "myhost",
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:   "system.load.1",
+			Points: []metrics.Point{{Ts: 20, Value: math.Inf(1)}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:dev", "canary"}),
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:   "disk.used",
+			Points: []metrics.Point{{Ts: 20, Value: 12}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"path:C:\\\"data\"", "__name__:foo", "1st:a"}),
+			Device: "sda",
+			MType:  metrics.APICountType,
+		},
+		{
+			// no points
+			Name:  "empty",
+			MType: metrics.APIGaugeType,
+		},
+	}
+	sketches := metrics.SketchSeriesList{
+		{
+			Name:   "request.duration",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"quantile:high", "service:api"}),
+			Host:   "myhost",
+			Points: []metrics.SketchPoint{{Ts: 20, Sketch: sketch}},
+		},
+		{
+			// the name of a family can't be reused with another type
+			Name:   "disk.used",
+			Points: []metrics.SketchPoint{{Ts: 20, Sketch: sketch}},
+		},
+	}
+
+	assert.Equal(t, `# TYPE disk_used gauge
+disk_used{_1st="a",device="sda",path="C:\\\"data\"",tag__name__="foo"} 12
+# TYPE request_duration summary
+request_duration{host="myhost",service="api",quantile="0.5"} `+formatFloat(sketch.Quantile(quantile.Default(), 0.5))+`
+request_duration{host="myhost",service="api",quantile="0.75"} `+formatFloat(sketch.Quantile(quantile.Default(), 0.75))+`
+request_duration{host="myhost",service="api",quantile="0.9"} `+formatFloat(sketch.Quantile(quantile.Default(), 0.9))+`
+request_duration{host="myhost",service="api",quantile="0.95"} `+formatFloat(sketch.Quantile(quantile.Default(), 0.95))+`
+request_duration{host="myhost",service="api",quantile="0.99"} `+formatFloat(sketch.Quantile(quantile.Default(), 0.99))+`
+request_duration_sum{host="myhost",service="api"} 5050
+request_duration_count{host="myhost",service="api"} 100
+# TYPE system_load_1 gauge
+system_load_1{canary="true",env="dev"} +Inf
+system_load_1{env="prod",host="myhost",role="db,web"} 2.5
+# EOF
+`, renderOpenMetrics(series, sketches))
+}
+
+func TestRenderOpenMetricsEmpty(t *testing.T) {
+	assert.Equal(t, "# EOF\n", renderOpenMetrics(nil, nil))
+}
+
+func TestSanitizeNames(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", sanitizeMetricName("system.cpu.user"))
+	assert.Equal(t, "my:metric_name_", sanitizeMetricName("my:metric-name!"))
+	assert.Equal(t, "_2xx_count", sanitizeMetricName("2xx.count"))
+	assert.Equal(t, "_", sanitizeMetricName(""))
+
+	assert.Equal(t, "kube_namespace", sanitizeLabelName("kube-namespace"))
+	assert.Equal(t, "a_b", sanitizeLabelName("a:b"))
+	assert.Equal(t, "tag__meta", sanitizeLabelName("__meta"))
+}
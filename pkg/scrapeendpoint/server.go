@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package scrapeendpoint implements a local Prometheus scrape endpoint exposing the series
+// and sketches of the last flush of the aggregator in the OpenMetrics format.
+package scrapeendpoint
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/httpserver"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// Path is the path of the scrape endpoint.
+	Path = "/metrics"
+
+	contentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// flushSource is the part of the demultiplexer used by the server.
+type flushSource interface {
+	LastFlushedMetrics() (metrics.Series, metrics.SketchSeriesList)
+}
+
+var serverInstance httpserver.Global
+
+// IsEnabled returns whether the scrape endpoint is enabled in the Agent configuration.
+func IsEnabled(cfg model.Reader) bool {
+	return cfg.GetBool("prometheus_scrape_endpoint.enabled")
+}
+
+// StartServer starts the global scrape endpoint server.
+func StartServer(source flushSource, cfg model.Reader) error {
+	return serverInstance.Start(func() (*httpserver.Server, error) {
+		return NewServer(source, cfg)
+	})
+}
+
+// StopServer stops the global scrape endpoint server, if it is running.
+func StopServer() {
+	serverInstance.Stop()
+}
+
+// IsRunning returns whether the scrape endpoint server is currently running.
+func IsRunning() bool {
+	return serverInstance.IsRunning()
+}
+
+// NewServer configures and returns a running scrape endpoint server.
+func NewServer(source flushSource, cfg model.Reader) (*httpserver.Server, error) {
+	addr := net.JoinHostPort(config.GetBindHostFromConfig(cfg), strconv.Itoa(cfg.GetInt("prometheus_scrape_endpoint.port")))
+	mux := http.NewServeMux()
+	mux.Handle(Path, &handler{source: source})
+	return httpserver.Start("Prometheus scrape endpoint", addr, mux)
+}
+
+// handler renders the last flushed metrics.
+type handler struct {
+	source flushSource
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, fmt.Sprintf("unsupported method %s", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	series, sketches := h.source.LastFlushedMetrics()
+	body := renderOpenMetrics(series, sketches)
+	w.Header().Set("Content-Type", contentType)
+	if _, err := io.WriteString(w, body); err != nil {
+		log.Debugf("Writing Prometheus scrape response to %s: %v", r.RemoteAddr, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scrapeendpoint
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type sourceMock struct {
+	series   metrics.Series
+	sketches metrics.SketchSeriesList
+}
+
+func (s *sourceMock) LastFlushedMetrics() (metrics.Series, metrics.SketchSeriesList) {
+	return s.series, s.sketches
+}
+
+func TestHandler(t *testing.T) {
+	h := &handler{source: &sourceMock{series: metrics.Series{
+		{Name: "system.uptime", Points: []metrics.Point{{Ts: 10, Value: 42}}},
+	}}}
+
+	r := httptest.NewRequest(http.MethodGet, Path, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE system_uptime gauge\nsystem_uptime 42\n# EOF\n", w.Body.String())
+
+	r = httptest.NewRequest(http.MethodPost, Path, nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestServer(t *testing.T) {
+	cfg := config.Mock(t)
+	cfg.SetWithoutSource("bind_host", "127.0.0.1")
+	cfg.SetWithoutSource("prometheus_scrape_endpoint.port", 0)
+
+	server, err := NewServer(&sourceMock{}, cfg)
+	require.NoError(t, err)
+	defer server.Stop()
+
+	resp, err := http.Get("http://" + server.Addr + Path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "# EOF\n", string(body))
+}
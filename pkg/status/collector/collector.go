@@ -42,6 +42,16 @@ func PopulateStatus(stats map[string]interface{}) {
 	json.Unmarshal(checkSchedulerStatsJSON, &checkSchedulerStats) //nolint:errcheck
 	stats["checkSchedulerStats"] = checkSchedulerStats
 
+	schedulerData := expvar.Get("scheduler")
+	if schedulerData != nil {
+		schedulerStatsJSON := []byte(schedulerData.String())
+		schedulerStats := make(map[string]interface{})
+		json.Unmarshal(schedulerStatsJSON, &schedulerStats) //nolint:errcheck
+		stats["schedulerStats"] = schedulerStats
+	} else {
+		stats["schedulerStats"] = nil
+	}
+
 	pyLoaderData := expvar.Get("pyLoader")
 	if pyLoaderData != nil {
 		pyLoaderStatsJSON := []byte(pyLoaderData.String())
@@ -62,6 +72,16 @@ func PopulateStatus(stats map[string]interface{}) {
 		stats["pythonInit"] = nil
 	}
 
+	sharedFetchCacheData := expvar.Get("sharedFetchCache")
+	if sharedFetchCacheData != nil {
+		sharedFetchCacheJSON := []byte(sharedFetchCacheData.String())
+		sharedFetchCacheStats := make(map[string]interface{})
+		json.Unmarshal(sharedFetchCacheJSON, &sharedFetchCacheStats) //nolint:errcheck
+		stats["sharedFetchCacheStats"] = sharedFetchCacheStats
+	} else {
+		stats["sharedFetchCacheStats"] = nil
+	}
+
 	inventories := expvar.Get("inventories")
 	var inventoriesStats map[string]interface{}
 	if inventories != nil {
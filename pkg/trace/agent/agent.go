@@ -123,6 +123,13 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		DebugServer:           api.NewDebugServer(conf),
 	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector)
+	agnt.DebugServer.AddRoute("/debug/capture/", agnt.Receiver.Capture.CaptureHandler())
+	if conf.OpenMetricsStats {
+		exporter := stats.NewOpenMetricsExporter(conf.OpenMetricsStatsMaxSeries)
+		agnt.Concentrator.Exporter = exporter
+		agnt.ClientStatsAggregator.Exporter = exporter
+		agnt.DebugServer.AddRoute("/metrics", exporter)
+	}
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector)
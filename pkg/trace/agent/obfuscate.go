@@ -35,7 +35,11 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		if span.Resource == "" {
 			return
 		}
-		oq, err := o.ObfuscateSQLString(span.Resource)
+		obfuscateQuery := o.ObfuscateSQLString
+		if span.Type == "cassandra" {
+			obfuscateQuery = o.ObfuscateCQLString
+		}
+		oq, err := obfuscateQuery(span.Resource)
 		if err != nil {
 			// we have an error, discard the SQL to avoid polluting user resources.
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -97,7 +101,11 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	o := a.obfuscator
 	switch b.Type {
 	case "sql", "cassandra":
-		oq, err := o.ObfuscateSQLString(b.Resource)
+		obfuscateQuery := o.ObfuscateSQLString
+		if b.Type == "cassandra" {
+			obfuscateQuery = o.ObfuscateCQLString
+		}
+		oq, err := obfuscateQuery(b.Resource)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
 			b.Resource = textNonParsable
@@ -64,6 +64,7 @@ func TestObfuscateStatsGroup(t *testing.T) {
 	}{
 		{statsGroup("sql", "SELECT 1 FROM db"), "SELECT ? FROM db"},
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
+		{statsGroup("cassandra", "SELECT * FROM ks.t WHERE id = 123e4567-e89b-12d3-a456-426614174000"), "SELECT * FROM ks.t WHERE id = ?"},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
 	} {
@@ -104,6 +105,20 @@ func TestObfuscateDefaults(t *testing.T) {
 		assert.Equal(t, "UPDATE users ( name ) SET ( ? )", span.Meta["sql.query"])
 		assert.Equal(t, "UPDATE users ( name ) SET ( ? )", span.Resource)
 	})
+
+	t.Run("cassandra", func(t *testing.T) {
+		query := "UPDATE ks.users SET emails = {'a@b.com'} WHERE id = 123e4567-e89b-12d3-a456-426614174000"
+		span := &pb.Span{
+			Type:     "cassandra",
+			Resource: query,
+			Meta:     map[string]string{"sql.query": query},
+		}
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "UPDATE ks.users SET emails = ? WHERE id = ?", span.Meta["sql.query"])
+		assert.Equal(t, "UPDATE ks.users SET emails = ? WHERE id = ?", span.Resource)
+	})
 }
 
 func agentWithDefaults(features ...string) (agnt *Agent, stop func()) {
@@ -220,6 +235,26 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("mongodb/json", testConfig(
+		"mongodb",
+		"mongodb.query",
+		`{"find": "users", "filter": {"age": 25}}`,
+		`{"find":"?","filter":{"age":"?"}}`,
+		&config.ObfuscationConfig{
+			Mongo: obfuscate.JSONConfig{Enabled: true},
+		},
+	))
+
+	t.Run("mongodb/shell", testConfig(
+		"mongodb",
+		"mongodb.query",
+		`db.users.find({_id: ObjectId("5f8f8c44b54764421b7156c5"), age: {$gt: 25}})`,
+		`db.users.find({_id: ?, age: {$gt: ?}})`,
+		&config.ObfuscationConfig{
+			Mongo: obfuscate.JSONConfig{Enabled: true},
+		},
+	))
+
 	t.Run("memcached/enabled", testConfig(
 		"memcached",
 		"memcached.command",
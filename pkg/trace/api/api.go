@@ -70,6 +70,9 @@ type HTTPReceiver struct {
 
 	telemetryCollector telemetry.TelemetryCollector
 
+	// Capture records incoming tracer payloads on demand.
+	Capture *TraceCapture
+
 	rateLimiterResponse int // HTTP status code when refusing
 
 	wg   sync.WaitGroup // waits for all requests to be processed
@@ -111,6 +114,8 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 
 		telemetryCollector: telemetryCollector,
 
+		Capture: NewTraceCapture(conf.CapturePath),
+
 		rateLimiterResponse: rateLimiterResponse,
 
 		exit: make(chan struct{}),
@@ -290,6 +295,9 @@ func (r *HTTPReceiver) Stop() error {
 	}
 	r.wg.Wait()
 	close(r.out)
+	if err := r.Capture.Stop(); err != nil {
+		log.Errorf("Error stopping trace capture: %v", err)
+	}
 	return nil
 }
 
@@ -306,6 +314,12 @@ func (r *HTTPReceiver) handleWithVersion(v Version, f func(Version, http.Respons
 			return
 		}
 
+		if r.Capture.IsOngoing() {
+			body, record := r.Capture.wrap(v, req)
+			req.Body = body
+			defer record()
+		}
+
 		// TODO(x): replace with http.MaxBytesReader?
 		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 
@@ -0,0 +1,458 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+)
+
+// captureFileTemplate is the name of the files created in the capture directory.
+const captureFileTemplate = "datadog-trace-capture-%d.gz"
+
+// captureFileVersion is the version of the capture file format. It must be bumped
+// whenever the record encoding changes.
+const captureFileVersion uint8 = 1
+
+// captureHeader prefixes every (uncompressed) capture stream. The last byte holds
+// the file format version.
+var captureHeader = []byte{'D', 'D', 'T', 'R', 'C', 'A', 'P', 0}
+
+// captureQueueSize is the number of records waiting to be written to the capture file
+// above which new records are dropped.
+const captureQueueSize = 1000
+
+// maxCaptureRecordSize bounds the size of any single field read back from a capture
+// file, protecting the reader against corrupted input.
+const maxCaptureRecordSize = 512 * 1024 * 1024
+
+var (
+	// ErrCaptureOngoing is returned when a capture is started while another one is running.
+	ErrCaptureOngoing = errors.New("a trace capture is already in progress")
+
+	// errCaptureHeader is returned when reading a stream which is not a trace capture.
+	errCaptureHeader = errors.New("invalid trace capture header")
+)
+
+// CaptureRecord holds a tracer payload as it was received by the HTTP receiver.
+type CaptureRecord struct {
+	// Time is the time at which the payload was received.
+	Time time.Time
+	// Version is the API version of the endpoint that received the payload.
+	Version Version
+	// Path is the URL path of the request.
+	Path string
+	// Header holds the request headers.
+	Header http.Header
+	// Body is the raw, undecoded request body.
+	Body []byte
+}
+
+// TraceCapture records incoming tracer payloads to a gzip-compressed file. A capture
+// is started on demand and automatically stops after the requested duration. Records
+// are compressed and written by a separate goroutine so that recording never slows
+// down the receiver; they are dropped when it can not keep up.
+type TraceCapture struct {
+	dir string
+
+	mu      sync.Mutex
+	ongoing bool
+	cur     *captureFile // current (or last) capture
+	timer   *time.Timer
+}
+
+// captureFile is the file of a capture, along with the queue of the records to write to it.
+type captureFile struct {
+	path    string
+	file    *os.File
+	zw      *gzip.Writer
+	bw      *bufio.Writer
+	records chan *CaptureRecord
+	done    chan struct{} // closed once all records are written
+	count   *atomic.Int64 // records written
+}
+
+// run writes the queued records to the file until the queue is closed.
+func (cf *captureFile) run() {
+	defer close(cf.done)
+	for rec := range cf.records {
+		if err := writeCaptureRecord(cf.bw, rec); err != nil {
+			log.Errorf("Error writing trace capture record: %v", err)
+			metrics.Count("datadog.trace_agent.receiver.capture.errors", 1, nil, 1)
+			continue
+		}
+		cf.count.Inc()
+		metrics.Count("datadog.trace_agent.receiver.capture.payloads", 1, nil, 1)
+	}
+}
+
+// close waits for the queued records to be written, then flushes and closes the file.
+func (cf *captureFile) close() error {
+	close(cf.records)
+	<-cf.done
+	err := cf.bw.Flush()
+	if cerr := cf.zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := cf.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewTraceCapture returns a TraceCapture writing its files in dir.
+func NewTraceCapture(dir string) *TraceCapture {
+	return &TraceCapture{dir: dir}
+}
+
+// Start starts a new capture lasting for duration d and returns the path of the
+// capture file. A capture can be stopped early by calling Stop.
+func (tc *TraceCapture) Start(d time.Duration) (string, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.ongoing {
+		return "", ErrCaptureOngoing
+	}
+	if tc.dir == "" {
+		return "", errors.New("trace capture directory is not configured (apm_config.capture.path)")
+	}
+	if err := os.MkdirAll(tc.dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create capture directory: %v", err)
+	}
+	path := filepath.Join(tc.dir, fmt.Sprintf(captureFileTemplate, time.Now().UnixNano()))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(f)
+	bw := bufio.NewWriter(zw)
+	hdr := make([]byte, len(captureHeader))
+	copy(hdr, captureHeader)
+	hdr[len(hdr)-1] = captureFileVersion
+	if _, err := bw.Write(hdr); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	tc.ongoing = true
+	tc.cur = &captureFile{
+		path:    path,
+		file:    f,
+		zw:      zw,
+		bw:      bw,
+		records: make(chan *CaptureRecord, captureQueueSize),
+		done:    make(chan struct{}),
+		count:   atomic.NewInt64(0),
+	}
+	go tc.cur.run()
+	tc.timer = time.AfterFunc(d, func() {
+		if err := tc.Stop(); err != nil {
+			log.Errorf("Error stopping trace capture: %v", err)
+		}
+	})
+	log.Infof("Started trace capture to %s for %s", path, d)
+	return path, nil
+}
+
+// Stop stops the ongoing capture, writing the queued records before flushing and
+// closing the capture file. It is a no-op if no capture is running.
+func (tc *TraceCapture) Stop() error {
+	tc.mu.Lock()
+	if !tc.ongoing {
+		tc.mu.Unlock()
+		return nil
+	}
+	tc.ongoing = false
+	tc.timer.Stop()
+	tc.timer = nil
+	cf := tc.cur
+	tc.mu.Unlock()
+
+	// no more records are queued once the capture is not ongoing
+	err := cf.close()
+	log.Infof("Stopped trace capture to %s: %d payloads recorded", cf.path, cf.count.Load())
+	return err
+}
+
+// IsOngoing reports whether a capture is currently running.
+func (tc *TraceCapture) IsOngoing() bool {
+	if tc == nil {
+		return false
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.ongoing
+}
+
+// Status returns the path of the current (or last) capture file, the number of
+// payloads recorded into it and whether it is still ongoing.
+func (tc *TraceCapture) Status() (path string, count int64, ongoing bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.cur == nil {
+		return "", 0, false
+	}
+	return tc.cur.path, tc.cur.count.Load(), tc.ongoing
+}
+
+// Record queues rec to be written to the ongoing capture. It is a no-op if no capture
+// is running, and rec is dropped if too many records are already queued.
+func (tc *TraceCapture) Record(rec *CaptureRecord) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if !tc.ongoing {
+		return
+	}
+	select {
+	case tc.cur.records <- rec:
+	default:
+		metrics.Count("datadog.trace_agent.receiver.capture.dropped", 1, nil, 1)
+	}
+}
+
+// defaultCaptureDuration is the duration of a capture when none is specified.
+const defaultCaptureDuration = time.Minute
+
+// maxCaptureDuration is the longest capture that can be requested.
+const maxCaptureDuration = time.Hour
+
+// captureStatus is the JSON representation of a capture returned by the debug endpoints.
+type captureStatus struct {
+	Path     string `json:"path"`
+	Payloads int64  `json:"payloads"`
+	Ongoing  bool   `json:"ongoing"`
+}
+
+// CaptureHandler returns the handler of the /debug/capture/ endpoints of the debug server:
+//   - POST /debug/capture/start?duration=<duration> starts a capture (default: 1m);
+//   - POST /debug/capture/stop stops the ongoing capture;
+//   - GET /debug/capture/status reports the state of the current (or last) capture.
+func (tc *TraceCapture) CaptureHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/debug/capture/start":
+			if req.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			d := defaultCaptureDuration
+			if v := req.URL.Query().Get("duration"); v != "" {
+				var err error
+				if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+					http.Error(w, "duration must be a positive duration (e.g. 30s)", http.StatusBadRequest)
+					return
+				}
+			}
+			if d > maxCaptureDuration {
+				http.Error(w, fmt.Sprintf("duration can not exceed %s", maxCaptureDuration), http.StatusBadRequest)
+				return
+			}
+			if _, err := tc.Start(d); err != nil {
+				status := http.StatusInternalServerError
+				if err == ErrCaptureOngoing {
+					status = http.StatusConflict
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+		case "/debug/capture/stop":
+			if req.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := tc.Stop(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case "/debug/capture/status":
+		default:
+			http.NotFound(w, req)
+			return
+		}
+		path, count, ongoing := tc.Status()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(captureStatus{ //nolint:errcheck
+			Path:     path,
+			Payloads: count,
+			Ongoing:  ongoing,
+		})
+	})
+}
+
+// wrap returns a reader which copies everything read from req.Body, along with a
+// function recording it once the request has been handled.
+func (tc *TraceCapture) wrap(v Version, req *http.Request) (io.ReadCloser, func()) {
+	var buf bytes.Buffer
+	body := &teeReadCloser{Reader: io.TeeReader(req.Body, &buf), Closer: req.Body}
+	rec := &CaptureRecord{
+		Time:    time.Now(),
+		Version: v,
+		Path:    req.URL.Path,
+		Header:  req.Header.Clone(),
+	}
+	return body, func() {
+		rec.Body = buf.Bytes()
+		tc.Record(rec)
+	}
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func writeCaptureRecord(w io.Writer, rec *CaptureRecord) error {
+	var hdrs [][2]string
+	for k, vs := range rec.Header {
+		for _, v := range vs {
+			hdrs = append(hdrs, [2]string{k, v})
+		}
+	}
+	if err := binary.Write(w, binary.LittleEndian, rec.Time.UnixNano()); err != nil {
+		return err
+	}
+	if err := writeCaptureBytes(w, []byte(rec.Version)); err != nil {
+		return err
+	}
+	if err := writeCaptureBytes(w, []byte(rec.Path)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(hdrs))); err != nil {
+		return err
+	}
+	for _, kv := range hdrs {
+		if err := writeCaptureBytes(w, []byte(kv[0])); err != nil {
+			return err
+		}
+		if err := writeCaptureBytes(w, []byte(kv[1])); err != nil {
+			return err
+		}
+	}
+	return writeCaptureBytes(w, rec.Body)
+}
+
+func writeCaptureBytes(w io.Writer, b []byte) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// CaptureReader reads records from a trace capture file.
+type CaptureReader struct {
+	zr *gzip.Reader
+	r  *bufio.Reader
+}
+
+// NewCaptureReader returns a CaptureReader reading from r. It fails if r does not
+// hold a trace capture.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(zr)
+	hdr := make([]byte, len(captureHeader))
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, errCaptureHeader
+	}
+	if !bytes.Equal(hdr[:len(hdr)-1], captureHeader[:len(captureHeader)-1]) {
+		return nil, errCaptureHeader
+	}
+	if v := hdr[len(hdr)-1]; v == 0 || v > captureFileVersion {
+		return nil, fmt.Errorf("unsupported trace capture version %d", v)
+	}
+	return &CaptureReader{zr: zr, r: br}, nil
+}
+
+// Next returns the next record of the capture. It returns io.EOF once all
+// records have been read.
+func (cr *CaptureReader) Next() (*CaptureRecord, error) {
+	var ts int64
+	if err := binary.Read(cr.r, binary.LittleEndian, &ts); err != nil {
+		return nil, err
+	}
+	v, err := cr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	path, err := cr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	var n uint32
+	if err := binary.Read(cr.r, binary.LittleEndian, &n); err != nil {
+		return nil, noEOF(err)
+	}
+	hdr := make(http.Header, n)
+	for i := uint32(0); i < n; i++ {
+		k, err := cr.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		v, err := cr.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		hdr[string(k)] = append(hdr[string(k)], string(v))
+	}
+	body, err := cr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	return &CaptureRecord{
+		Time:    time.Unix(0, ts),
+		Version: Version(v),
+		Path:    string(path),
+		Header:  hdr,
+		Body:    body,
+	}, nil
+}
+
+// Close closes the reader.
+func (cr *CaptureReader) Close() error {
+	return cr.zr.Close()
+}
+
+func (cr *CaptureReader) readBytes() ([]byte, error) {
+	var n uint32
+	if err := binary.Read(cr.r, binary.LittleEndian, &n); err != nil {
+		return nil, noEOF(err)
+	}
+	if n > maxCaptureRecordSize {
+		return nil, fmt.Errorf("trace capture record too large: %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cr.r, b); err != nil {
+		return nil, noEOF(err)
+	}
+	return b, nil
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF; it is used when a record is truncated.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
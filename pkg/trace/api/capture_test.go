@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestTraceCapture(t *testing.T) {
+	tc := NewTraceCapture(t.TempDir())
+	assert.False(t, tc.IsOngoing())
+
+	path, err := tc.Start(time.Minute)
+	require.NoError(t, err)
+	assert.True(t, tc.IsOngoing())
+	_, err = tc.Start(time.Minute)
+	assert.Equal(t, ErrCaptureOngoing, err)
+
+	now := time.Now()
+	in := []*CaptureRecord{
+		{
+			Time:    now,
+			Version: v04,
+			Path:    "/v0.4/traces",
+			Header:  http.Header{"Content-Type": {"application/msgpack"}, "X-Datadog-Trace-Count": {"2"}},
+			Body:    []byte{0x92, 0x90, 0x90},
+		},
+		{
+			Time:    now.Add(time.Second),
+			Version: V07,
+			Path:    "/v0.7/traces",
+			Header:  http.Header{},
+			Body:    []byte{},
+		},
+	}
+	for _, rec := range in {
+		tc.Record(rec)
+	}
+	require.NoError(t, tc.Stop())
+	assert.False(t, tc.IsOngoing())
+	tc.Record(in[0]) // no-op once stopped
+
+	_, count, _ := tc.Status()
+	assert.EqualValues(t, 2, count)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	cr, err := NewCaptureReader(f)
+	require.NoError(t, err)
+	for _, want := range in {
+		got, err := cr.Next()
+		require.NoError(t, err)
+		assert.True(t, want.Time.Equal(got.Time))
+		assert.Equal(t, want.Version, got.Version)
+		assert.Equal(t, want.Path, got.Path)
+		assert.Equal(t, want.Header, got.Header)
+		assert.Equal(t, want.Body, got.Body)
+	}
+	_, err = cr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTraceCaptureFull(t *testing.T) {
+	// the writer is not running, records are only queued
+	tc := NewTraceCapture(t.TempDir())
+	tc.ongoing = true
+	tc.cur = &captureFile{records: make(chan *CaptureRecord, 2), count: atomic.NewInt64(0)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			tc.Record(&CaptureRecord{Path: "/v0.4/traces"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Record should not block when the queue is full")
+	}
+	assert.Len(t, tc.cur.records, 2)
+}
+
+func TestTraceCaptureTimeout(t *testing.T) {
+	tc := NewTraceCapture(t.TempDir())
+	_, err := tc.Start(10 * time.Millisecond)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !tc.IsOngoing() }, time.Second, 5*time.Millisecond)
+}
+
+func TestCaptureReaderInvalid(t *testing.T) {
+	_, err := NewCaptureReader(strings.NewReader("not a capture"))
+	assert.Error(t, err)
+}
+
+func TestCaptureHandler(t *testing.T) {
+	tc := NewTraceCapture(t.TempDir())
+	h := tc.CaptureHandler()
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/debug/capture/start").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/debug/capture/start?duration=abc").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/debug/capture/start?duration=2h").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/debug/capture/unknown").Code)
+
+	rec := do(http.MethodPost, "/debug/capture/start?duration=1m")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status captureStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.True(t, status.Ongoing)
+	assert.NotEmpty(t, status.Path)
+
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/debug/capture/start").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/debug/capture/stop").Code)
+	rec = do(http.MethodGet, "/debug/capture/status")
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.False(t, status.Ongoing)
+}
+
+func TestReceiverCapture(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.CapturePath = t.TempDir()
+	r := newTestReceiverFromConfig(conf)
+	path, err := r.Capture.Start(time.Minute)
+	require.NoError(t, err)
+
+	body := []byte("[]")
+	req := httptest.NewRequest(http.MethodPost, "/v0.3/traces", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.handleWithVersion(v03, r.handleTraces).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, r.Capture.Stop())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	cr, err := NewCaptureReader(f)
+	require.NoError(t, err)
+	got, err := cr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, v03, got.Version)
+	assert.Equal(t, "/v0.3/traces", got.Path)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, body, got.Body)
+}
@@ -31,13 +31,22 @@ const (
 type DebugServer struct {
 	conf   *config.AgentConfig
 	server *http.Server
+	mux    *http.ServeMux
 }
 
 // NewDebugServer returns a debug server
 func NewDebugServer(conf *config.AgentConfig) *DebugServer {
-	return &DebugServer{
+	ds := &DebugServer{
 		conf: conf,
+		mux:  http.NewServeMux(),
 	}
+	ds.setupMux()
+	return ds
+}
+
+// AddRoute adds a route to the DebugServer. It must be called before Start.
+func (ds *DebugServer) AddRoute(route string, handler http.Handler) {
+	ds.mux.Handle(route, handler)
 }
 
 // Start configures and starts the http server
@@ -49,7 +58,7 @@ func (ds *DebugServer) Start() {
 	ds.server = &http.Server{
 		ReadTimeout:  defaultTimeout,
 		WriteTimeout: defaultTimeout,
-		Handler:      ds.mux(),
+		Handler:      ds.mux,
 	}
 	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", ds.conf.DebugServerPort))
 	if err != nil {
@@ -75,8 +84,8 @@ func (ds *DebugServer) Stop() {
 	}
 }
 
-func (ds *DebugServer) mux() *http.ServeMux {
-	mux := http.NewServeMux()
+func (ds *DebugServer) setupMux() {
+	mux := ds.mux
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -108,5 +117,4 @@ func (ds *DebugServer) mux() *http.ServeMux {
 		w.Header().Set("Access-Control-Allow-Origin", "http://127.0.0.1:"+ds.conf.GUIPort)
 		expvar.Handler().ServeHTTP(w, req)
 	}))
-	return mux
 }
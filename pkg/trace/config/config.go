@@ -339,6 +339,21 @@ type AgentConfig struct {
 	// case, the sender will drop failed payloads when it is unable to enqueue
 	// them for another retry.
 	MaxSenderRetries int
+	// FailoverEndpoints specifies an ordered list of endpoints to which traces and stats
+	// are sent in place of the main endpoint, once it has been failing for FailoverAfter.
+	// Each endpoint is only used after the previous one has been failing for that long,
+	// and the main endpoint is tried again after the same period. They all use the API key
+	// set in apm_config.failover.api_key, failover being disabled when it is missing.
+	FailoverEndpoints []*Endpoint
+	FailoverAfter     time.Duration
+	// SpillEnabled enables writing the trace and stats payloads which could not be
+	// delivered to SpillPath, instead of dropping them. They are sent again once the
+	// intake can be reached. For each endpoint and payload type, spilled payloads take
+	// at most SpillMaxSize bytes, and are discarded after SpillMaxAge.
+	SpillEnabled bool
+	SpillPath    string
+	SpillMaxSize int64
+	SpillMaxAge  time.Duration
 
 	// internal telemetry
 	StatsdEnabled  bool
@@ -436,6 +451,18 @@ type AgentConfig struct {
 	// DebugServerPort defines the port used by the debug server
 	DebugServerPort int
 
+	// OpenMetricsStats enables the export of the computed APM stats in the Prometheus
+	// text format on the /metrics endpoint of the debug server.
+	OpenMetricsStats bool
+
+	// OpenMetricsStatsMaxSeries limits the number of series exported by the
+	// OpenMetrics stats endpoint. If not set (0) it will default to 10000.
+	OpenMetricsStatsMaxSeries int
+
+	// CapturePath specifies the directory in which trace captures started from the
+	// debug server are written.
+	CapturePath string
+
 	// Install Signature
 	InstallSignature InstallSignatureConfig
 }
@@ -493,6 +520,9 @@ func New() *AgentConfig {
 		StatsWriter:             new(WriterConfig),
 		TraceWriter:             new(WriterConfig),
 		ConnectionResetInterval: 0, // disabled
+		FailoverAfter:           time.Minute,
+		SpillMaxSize:            512 * 1024 * 1024, // 512MB
+		SpillMaxAge:             24 * time.Hour,
 
 		StatsdHost:    "localhost",
 		StatsdPort:    8125,
@@ -35,9 +35,11 @@ const (
 // This and the aggregator timestamp alignment ensure that all counts will have at most one point per second per agent for a specific granularity.
 // While distributions are not tied to the agent.
 type ClientStatsAggregator struct {
-	In      chan *pb.ClientStatsPayload
-	out     chan *pb.StatsPayload
-	buckets map[int64]*bucket // buckets used to aggregate client stats
+	In  chan *pb.ClientStatsPayload
+	out chan *pb.StatsPayload
+	// Exporter, if set, additionally receives every flushed stats payload.
+	Exporter *OpenMetricsExporter
+	buckets  map[int64]*bucket // buckets used to aggregate client stats
 
 	flushTicker         *time.Ticker
 	oldestTs            time.Time
@@ -147,13 +149,15 @@ func (a *ClientStatsAggregator) flush(p []*pb.ClientStatsPayload) {
 		return
 	}
 
-	a.out <- &pb.StatsPayload{
+	sp := &pb.StatsPayload{
 		Stats:          p,
 		AgentEnv:       a.agentEnv,
 		AgentHostname:  a.agentHostname,
 		AgentVersion:   a.agentVersion,
 		ClientComputed: true,
 	}
+	a.Exporter.Add(sp)
+	a.out <- sp
 }
 
 // alignAggTs aligns time to the aggregator timestamps.
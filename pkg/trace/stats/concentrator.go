@@ -30,6 +30,9 @@ type Concentrator struct {
 	In  chan Input
 	Out chan *pb.StatsPayload
 
+	// Exporter, if set, additionally receives every flushed stats payload.
+	Exporter *OpenMetricsExporter
+
 	// bucket duration in nanoseconds
 	bsize int64
 	// Timestamp of the oldest time bucket for which we allow data.
@@ -154,10 +157,14 @@ func (c *Concentrator) Run() {
 	for {
 		select {
 		case <-flushTicker.C:
-			c.Out <- c.Flush(false)
+			p := c.Flush(false)
+			c.Exporter.Add(p)
+			c.Out <- p
 		case <-c.exit:
 			log.Info("Exiting concentrator, computing remaining stats")
-			c.Out <- c.Flush(true)
+			p := c.Flush(true)
+			c.Exporter.Add(p)
+			c.Out <- p
 			return
 		}
 	}
@@ -0,0 +1,299 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/DataDog/sketches-go/ddsketch/pb/sketchpb"
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+const (
+	// openMetricsSeriesExpiry is the time after which a series which did not receive
+	// any stats is no longer exported. Its counts are kept so that they stay monotonic
+	// if it receives stats again, until its room is needed for a new series.
+	openMetricsSeriesExpiry = 10 * time.Minute
+
+	// defaultOpenMetricsMaxSeries is the default maximum number of series exported.
+	defaultOpenMetricsMaxSeries = 10000
+)
+
+// openMetricsQuantiles are the quantiles exported for the duration summary.
+var openMetricsQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// openMetricsSeries holds the aggregated stats of a single group of spans. Counts are
+// cumulative since the series was created, quantiles are those of the last flushed bucket.
+type openMetricsSeries struct {
+	labels       string
+	hits         uint64
+	errors       uint64
+	topLevelHits uint64
+	duration     uint64
+	quantiles    []float64
+	lastSeen     time.Time
+}
+
+// OpenMetricsExporter keeps the stats computed by the concentrator and the client stats
+// aggregator and exposes them in the Prometheus text exposition format, so that they can
+// be scraped locally without querying Datadog.
+type OpenMetricsExporter struct {
+	maxSeries int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	series  map[string]*openMetricsSeries // live and expired series
+	expired int                           // number of expired series, as of the last call to expire
+	dropped uint64
+}
+
+// NewOpenMetricsExporter returns a new OpenMetricsExporter exporting at most maxSeries
+// series. A maxSeries of 0 uses the default limit.
+func NewOpenMetricsExporter(maxSeries int) *OpenMetricsExporter {
+	if maxSeries <= 0 {
+		maxSeries = defaultOpenMetricsMaxSeries
+	}
+	return &OpenMetricsExporter{
+		maxSeries: maxSeries,
+		now:       time.Now,
+		series:    make(map[string]*openMetricsSeries),
+	}
+}
+
+// Add aggregates the given stats payload into the exported series. It is a no-op
+// on a nil exporter.
+func (e *OpenMetricsExporter) Add(p *pb.StatsPayload) {
+	if e == nil || p == nil {
+		return
+	}
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire(now)
+	for _, csp := range p.Stats {
+		env := csp.Env
+		if env == "" {
+			env = p.AgentEnv
+		}
+		for _, b := range csp.Stats {
+			for _, gs := range b.Stats {
+				e.addGroupedStats(now, env, gs)
+			}
+		}
+	}
+}
+
+func (e *OpenMetricsExporter) addGroupedStats(now time.Time, env string, gs *pb.ClientGroupedStats) {
+	labels := openMetricsLabels(env, gs)
+	s, ok := e.series[labels]
+	if !ok {
+		if len(e.series) >= e.maxSeries && !e.evictExpired(now) {
+			e.dropped++
+			return
+		}
+		s = &openMetricsSeries{labels: labels}
+		e.series[labels] = s
+	}
+	s.hits += gs.Hits
+	s.errors += gs.Errors
+	s.topLevelHits += gs.TopLevelHits
+	s.duration += gs.Duration
+	s.lastSeen = now
+	if q, err := summaryQuantiles(gs.OkSummary, gs.ErrorSummary); err != nil {
+		log.Debugf("Unable to decode stats sketches for OpenMetrics export: %v", err)
+	} else if q != nil {
+		s.quantiles = q
+	}
+}
+
+// expire counts the series which have not been updated for openMetricsSeriesExpiry.
+// Callers must guard!
+func (e *OpenMetricsExporter) expire(now time.Time) {
+	e.expired = 0
+	for _, s := range e.series {
+		if s.isExpired(now) {
+			e.expired++
+		}
+	}
+}
+
+// evictExpired removes the series which expired first, to make room for a new series.
+// It reports whether a series was removed. Callers must guard!
+func (e *OpenMetricsExporter) evictExpired(now time.Time) bool {
+	if e.expired == 0 {
+		return false
+	}
+	var oldest *openMetricsSeries
+	for _, s := range e.series {
+		if s.isExpired(now) && (oldest == nil || s.lastSeen.Before(oldest.lastSeen)) {
+			oldest = s
+		}
+	}
+	if oldest == nil {
+		// the expired series received stats again
+		e.expired = 0
+		return false
+	}
+	delete(e.series, oldest.labels)
+	e.expired--
+	return true
+}
+
+// isExpired reports whether the series s has not been updated for openMetricsSeriesExpiry.
+func (s *openMetricsSeries) isExpired(now time.Time) bool {
+	return now.Sub(s.lastSeen) > openMetricsSeriesExpiry
+}
+
+// summaryQuantiles merges the encoded ok and error sketches and returns the
+// openMetricsQuantiles of the merged sketch, in nanoseconds. It returns nil if
+// both sketches are empty.
+func summaryQuantiles(summaries ...[]byte) ([]float64, error) {
+	var merged *ddsketch.DDSketch
+	for _, b := range summaries {
+		if len(b) == 0 {
+			continue
+		}
+		var msg sketchpb.DDSketch
+		if err := proto.Unmarshal(b, &msg); err != nil {
+			return nil, err
+		}
+		sk, err := ddsketch.FromProto(&msg)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = sk
+			continue
+		}
+		if err := merged.MergeWith(sk); err != nil {
+			return nil, err
+		}
+	}
+	if merged == nil || merged.IsEmpty() {
+		return nil, nil
+	}
+	return merged.GetValuesAtQuantiles(openMetricsQuantiles)
+}
+
+// openMetricsLabels returns the rendered label set identifying the given stats group.
+func openMetricsLabels(env string, gs *pb.ClientGroupedStats) string {
+	type label struct{ k, v string }
+	labels := []label{
+		{"env", env},
+		{"service", gs.Service},
+		{"name", gs.Name},
+		{"resource", gs.Resource},
+		{"span_kind", gs.SpanKind},
+	}
+	if gs.HTTPStatusCode != 0 {
+		labels = append(labels, label{"http_status_code", strconv.FormatUint(uint64(gs.HTTPStatusCode), 10)})
+	}
+	if gs.Synthetics {
+		labels = append(labels, label{"synthetics", "true"})
+	}
+	peer := make([]label, 0, len(gs.PeerTags))
+	for _, t := range gs.PeerTags {
+		k, v, ok := strings.Cut(t, ":")
+		if !ok {
+			continue
+		}
+		peer = append(peer, label{"peer_" + sanitizeLabelName(k), v})
+	}
+	sort.Slice(peer, func(i, j int) bool { return peer[i].k < peer[j].k })
+	labels = append(labels, peer...)
+
+	var sb strings.Builder
+	for i, l := range labels {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(l.k)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(l.v))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+// sanitizeLabelName replaces all characters which are not allowed in a Prometheus
+// label name by underscores.
+func sanitizeLabelName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes s according to the Prometheus text exposition format.
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+// ServeHTTP implements http.Handler, writing all the series in the Prometheus
+// text exposition format.
+func (e *OpenMetricsExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	e.write(bw)
+	bw.Flush() //nolint:errcheck
+}
+
+func (e *OpenMetricsExporter) write(w *bufio.Writer) {
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	series := make([]*openMetricsSeries, 0, len(e.series))
+	for _, s := range e.series {
+		if !s.isExpired(now) {
+			series = append(series, s)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+
+	counter := func(name, help string, value func(*openMetricsSeries) uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, s := range series {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, s.labels, value(s))
+		}
+	}
+	counter("datadog_trace_hits_total", "Number of spans.", func(s *openMetricsSeries) uint64 { return s.hits })
+	counter("datadog_trace_errors_total", "Number of spans with an error.", func(s *openMetricsSeries) uint64 { return s.errors })
+	counter("datadog_trace_top_level_hits_total", "Number of top-level spans.", func(s *openMetricsSeries) uint64 { return s.topLevelHits })
+
+	const name = "datadog_trace_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Duration of spans, quantiles are computed over the last stats bucket.\n# TYPE %s summary\n", name, name)
+	for _, s := range series {
+		for i, q := range s.quantiles {
+			fmt.Fprintf(w, "%s{%s,quantile=\"%s\"} %s\n", name, s.labels, formatFloat(openMetricsQuantiles[i]), formatFloat(q/1e9))
+		}
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, s.labels, formatFloat(float64(s.duration)/1e9))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, s.labels, s.hits)
+	}
+
+	fmt.Fprintf(w, "# HELP datadog_trace_stats_series_dropped_total Number of stats groups not exported because the series limit was reached.\n")
+	fmt.Fprintf(w, "# TYPE datadog_trace_stats_series_dropped_total counter\n")
+	fmt.Fprintf(w, "datadog_trace_stats_series_dropped_total %d\n", e.dropped)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
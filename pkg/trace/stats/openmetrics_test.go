@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func scrape(t *testing.T, e *OpenMetricsExporter) string {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	return string(body)
+}
+
+func TestOpenMetricsExporter(t *testing.T) {
+	now := time.Now()
+	c := NewTestConcentrator(now)
+	c.peerTagsAggregation = true
+	c.peerTagKeys = []string{"db.instance"}
+	spans := []*pb.Span{
+		testSpan(now, 1, 0, int64(100*time.Millisecond), 2, "A1", "resource1", 0, map[string]string{"span.kind": "client", "db.instance": "i-1234"}),
+		testSpan(now, 2, 0, int64(300*time.Millisecond), 2, "A1", "resource1", 1, map[string]string{"span.kind": "client", "db.instance": "i-1234"}),
+		testSpan(now, 3, 0, int64(50*time.Millisecond), 2, "A2", `res"2`, 0, nil),
+	}
+	traceutil.ComputeTopLevel(spans)
+	c.addNow(toProcessedTrace(spans, "none", ""), "")
+
+	e := NewOpenMetricsExporter(0)
+	e.Add(c.flushNow(now.UnixNano()+int64(c.bufferLen)*c.bsize, true))
+
+	out := scrape(t, e)
+	a1 := `env="none",service="A1",name="query",resource="resource1",span_kind="client",peer_db_instance="i-1234"`
+	a2 := `env="none",service="A2",name="query",resource="res\"2",span_kind=""`
+	assert.Contains(t, out, "# TYPE datadog_trace_hits_total counter\n")
+	assert.Contains(t, out, "datadog_trace_hits_total{"+a1+"} 2\n")
+	assert.Contains(t, out, "datadog_trace_errors_total{"+a1+"} 1\n")
+	assert.Contains(t, out, "datadog_trace_hits_total{"+a2+"} 1\n")
+	assert.Contains(t, out, "# TYPE datadog_trace_duration_seconds summary\n")
+	assert.Contains(t, out, "datadog_trace_duration_seconds_sum{"+a1+"} 0.4\n")
+	assert.Contains(t, out, "datadog_trace_duration_seconds_count{"+a1+"} 2\n")
+	assert.Contains(t, out, "datadog_trace_duration_seconds{"+a1+`,quantile="0.5"}`)
+	assert.Contains(t, out, "datadog_trace_stats_series_dropped_total 0\n")
+
+	// counts are cumulative
+	e.Add(c.flushNow(now.UnixNano()+int64(c.bufferLen)*c.bsize, true))
+	c.addNow(toProcessedTrace(spans, "none", ""), "")
+	e.Add(c.flushNow(now.UnixNano()+int64(c.bufferLen)*c.bsize, true))
+	assert.Contains(t, scrape(t, e), "datadog_trace_hits_total{"+a1+"} 4\n")
+}
+
+func TestOpenMetricsExporterConcentratorStop(t *testing.T) {
+	now := time.Now()
+	c := NewTestConcentrator(now)
+	c.Exporter = NewOpenMetricsExporter(0)
+	spans := []*pb.Span{testSpan(now, 1, 0, int64(100*time.Millisecond), 0, "A1", "resource1", 0, nil)}
+	traceutil.ComputeTopLevel(spans)
+	c.addNow(toProcessedTrace(spans, "none", ""), "")
+
+	c.Start()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Stop()
+	}()
+	p := <-c.Out
+	<-done
+	require.Len(t, p.Stats, 1)
+	// the stats flushed on stop are exported
+	assert.Contains(t, scrape(t, c.Exporter), `datadog_trace_hits_total{env="none",service="A1",name="query",resource="resource1",span_kind=""} 1`+"\n")
+}
+
+func TestOpenMetricsExporterMaxSeries(t *testing.T) {
+	e := NewOpenMetricsExporter(1)
+	e.Add(&pb.StatsPayload{
+		AgentEnv: "prod",
+		Stats: []*pb.ClientStatsPayload{{
+			Stats: []*pb.ClientStatsBucket{{
+				Stats: []*pb.ClientGroupedStats{
+					{Service: "a", Name: "op", Resource: "r", Hits: 3},
+					{Service: "b", Name: "op", Resource: "r", Hits: 5},
+				},
+			}},
+		}},
+	})
+	out := scrape(t, e)
+	assert.Equal(t, 1, strings.Count(out, "datadog_trace_hits_total{"))
+	assert.Contains(t, out, `datadog_trace_hits_total{env="prod",service="a",name="op",resource="r",span_kind=""} 3`)
+	assert.Contains(t, out, "datadog_trace_stats_series_dropped_total 1\n")
+}
+
+func TestOpenMetricsExporterExpiry(t *testing.T) {
+	payload := func(service string, hits uint64) *pb.StatsPayload {
+		return &pb.StatsPayload{AgentEnv: "prod", Stats: []*pb.ClientStatsPayload{{
+			Stats: []*pb.ClientStatsBucket{{Stats: []*pb.ClientGroupedStats{{Service: service, Hits: hits}}}},
+		}}}
+	}
+	a := `datadog_trace_hits_total{env="prod",service="a",name="",resource="",span_kind=""}`
+	b := `datadog_trace_hits_total{env="prod",service="b",name="",resource="",span_kind=""}`
+
+	t.Run("cumulative", func(t *testing.T) {
+		now := time.Now()
+		e := NewOpenMetricsExporter(0)
+		e.now = func() time.Time { return now }
+		e.Add(payload("a", 1))
+		assert.Contains(t, scrape(t, e), a+" 1\n")
+
+		// the expired series is no longer exported
+		now = now.Add(openMetricsSeriesExpiry + time.Second)
+		e.Add(payload("b", 1))
+		out := scrape(t, e)
+		assert.NotContains(t, out, a)
+		assert.Contains(t, out, b+" 1\n")
+
+		// but its counts carry on once it receives stats again
+		e.Add(payload("a", 2))
+		assert.Contains(t, scrape(t, e), a+" 3\n")
+	})
+
+	t.Run("max-series", func(t *testing.T) {
+		now := time.Now()
+		e := NewOpenMetricsExporter(1)
+		e.now = func() time.Time { return now }
+		e.Add(payload("a", 1))
+		e.Add(payload("b", 1))
+		assert.NotContains(t, scrape(t, e), b, "the series limit is reached")
+
+		// the expired series makes room for the new one
+		now = now.Add(openMetricsSeriesExpiry + time.Second)
+		e.Add(payload("b", 1))
+		require.Len(t, e.series, 1)
+		out := scrape(t, e)
+		assert.Contains(t, out, b+" 1\n")
+		assert.Contains(t, out, "datadog_trace_stats_series_dropped_total 1\n")
+	})
+}
+
+func TestOpenMetricsExporterNil(t *testing.T) {
+	var e *OpenMetricsExporter
+	assert.NotPanics(t, func() { e.Add(&pb.StatsPayload{}) })
+}
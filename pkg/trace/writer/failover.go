@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// target specifies a destination of the sender.
+type target struct {
+	url    *url.URL
+	apiKey string
+}
+
+// failover selects the target that a sender writes to out of an ordered list made of
+// its primary target followed by its failover targets. The next target in the list is
+// used only once the active one has been failing for a given period. When a failover
+// target is active, the primary one is tried again after that same period, and the
+// failover target is used again if the primary one is still failing.
+type failover struct {
+	targets []target
+	after   time.Duration
+	now     func() time.Time
+
+	mu           sync.Mutex // guards below
+	active       int        // index of the active target
+	failingSince time.Time  // time of the first of the consecutive failures of the active target
+	switchedAt   time.Time  // time at which the active target was last changed
+
+	// fallback is the index of the failover target to use again if the primary target
+	// fails while being probed, and fallbackFailingSince the failingSince of that target.
+	// fallback is 0 when the primary target is not being probed.
+	fallback             int
+	fallbackFailingSince time.Time
+}
+
+// newFailover returns a new failover using primary until it has been failing for after,
+// then the targets in the given order.
+func newFailover(primary target, targets []target, after time.Duration) *failover {
+	return &failover{
+		targets: append([]target{primary}, targets...),
+		after:   after,
+		now:     time.Now,
+	}
+}
+
+// current returns the active target along with its index.
+func (f *failover) current() (int, target) {
+	if len(f.targets) == 1 {
+		// fast path
+		return 0, f.targets[0]
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.active > 0 && now.Sub(f.switchedAt) >= f.after {
+		// probe the primary target again; a single failure is enough to go back to the
+		// failover target since it was already failing when it was last used.
+		log.Infof("Trying primary endpoint %s again after failover.", f.targets[0].url.Host)
+		f.fallback, f.fallbackFailingSince = f.active, f.failingSince
+		f.active = 0
+		f.switchedAt = now
+		f.failingSince = time.Time{}
+	}
+	return f.active, f.targets[f.active]
+}
+
+// failed reports that sending to the target at index i failed with a retriable error.
+func (f *failover) failed(i int) {
+	if len(f.targets) == 1 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i != f.active {
+		// outdated report
+		return
+	}
+	now := f.now()
+	if f.active == 0 && f.fallback > 0 {
+		// the primary target is still failing; resume where the failover stood, so
+		// that a failing failover target is still given up on in order.
+		f.active, f.failingSince = f.fallback, f.fallbackFailingSince
+		f.fallback, f.fallbackFailingSince = 0, time.Time{}
+		f.switchedAt = now
+		log.Debugf("Primary endpoint %s is still failing, using %s again.", f.targets[0].url.Host, f.targets[f.active].url.Host)
+		return
+	}
+	if f.failingSince.IsZero() {
+		f.failingSince = now
+		return
+	}
+	if now.Sub(f.failingSince) < f.after || f.active == len(f.targets)-1 {
+		return
+	}
+	f.active++
+	f.switchedAt = now
+	f.failingSince = time.Time{}
+	log.Warnf("Endpoint %s has been failing for %s, failing over to %s.", f.targets[i].url.Host, f.after, f.targets[f.active].url.Host)
+}
+
+// succeeded reports that sending to the target at index i succeeded.
+func (f *failover) succeeded(i int) {
+	if len(f.targets) == 1 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i != f.active {
+		return
+	}
+	f.failingSince = time.Time{}
+	if i == 0 {
+		// the primary target is back
+		f.fallback, f.fallbackFailingSince = 0, time.Time{}
+	}
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailover(t *testing.T) {
+	newTarget := func(host string) target {
+		return target{url: &url.URL{Scheme: "https", Host: host}, apiKey: host + "-key"}
+	}
+	newTestFailover := func() (*failover, *time.Time) {
+		now := time.Unix(1700000000, 0)
+		f := newFailover(newTarget("primary"), []target{newTarget("secondary"), newTarget("tertiary")}, time.Minute)
+		f.now = func() time.Time { return now }
+		return f, &now
+	}
+	active := func(f *failover) string {
+		_, t := f.current()
+		return t.url.Host
+	}
+
+	t.Run("single", func(t *testing.T) {
+		f := newFailover(newTarget("primary"), nil, time.Minute)
+		for i := 0; i < 10; i++ {
+			f.failed(0)
+		}
+		assert.Equal(t, "primary", active(f))
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		assert := assert.New(t)
+		f, now := newTestFailover()
+
+		f.failed(0)
+		*now = now.Add(30 * time.Second)
+		f.failed(0)
+		assert.Equal("primary", active(f), "primary failing for less than a minute")
+
+		*now = now.Add(31 * time.Second)
+		f.failed(0)
+		i, tgt := f.current()
+		assert.Equal(1, i)
+		assert.Equal("secondary", tgt.url.Host)
+		assert.Equal("secondary-key", tgt.apiKey)
+
+		// failures reported for the previous target are ignored
+		f.failed(0)
+		assert.Equal("secondary", active(f))
+	})
+
+	t.Run("recovery", func(t *testing.T) {
+		assert := assert.New(t)
+		f, now := newTestFailover()
+
+		f.failed(0)
+		*now = now.Add(2 * time.Minute)
+		f.failed(0)
+		assert.Equal("secondary", active(f))
+		f.succeeded(1)
+
+		// the primary is probed again after a minute
+		*now = now.Add(time.Minute)
+		assert.Equal("primary", active(f))
+		// and a single failure switches back to the secondary
+		f.failed(0)
+		assert.Equal("secondary", active(f))
+
+		*now = now.Add(time.Minute)
+		assert.Equal("primary", active(f))
+		f.succeeded(0)
+		*now = now.Add(time.Hour)
+		assert.Equal("primary", active(f))
+	})
+
+	t.Run("last", func(t *testing.T) {
+		f, now := newTestFailover()
+		for i := 0; i < 3; i++ {
+			idx, _ := f.current()
+			f.failed(idx)
+			*now = now.Add(2 * time.Minute)
+			f.failed(idx)
+		}
+		// the primary is being probed again
+		assert.Equal(t, "primary", active(f))
+		f.failed(0)
+		idx, _ := f.current()
+		assert.Equal(t, 2, idx, "the last target is used again")
+	})
+
+	t.Run("order", func(t *testing.T) {
+		assert := assert.New(t)
+		f, now := newTestFailover()
+
+		f.failed(0)
+		*now = now.Add(2 * time.Minute)
+		f.failed(0)
+		assert.Equal("secondary", active(f))
+		f.failed(1)
+
+		// the probe of the primary fails, the secondary is used again and it has
+		// been failing since before the probe
+		*now = now.Add(time.Minute)
+		assert.Equal("primary", active(f))
+		f.failed(0)
+		assert.Equal("secondary", active(f))
+		*now = now.Add(time.Second)
+		f.failed(1)
+		assert.Equal("tertiary", active(f))
+
+		// the primary is probed again, then the tertiary is used again
+		*now = now.Add(time.Minute)
+		assert.Equal("primary", active(f))
+		f.failed(0)
+		assert.Equal("tertiary", active(f))
+
+		// the primary is back
+		*now = now.Add(time.Minute)
+		assert.Equal("primary", active(f))
+		f.succeeded(0)
+		f.failed(0)
+		assert.Equal("primary", active(f), "a single failure after recovering does not fail over")
+	})
+}
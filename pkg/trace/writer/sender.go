@@ -16,7 +16,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,15 +38,19 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 	}
 	// spread out the the maximum connection limit (climit) between senders
 	maxConns := math.Max(1, float64(climit/len(cfg.Endpoints)))
-	senders := make([]*sender, len(cfg.Endpoints))
-	for i, endpoint := range cfg.Endpoints {
+	parseURL := func(endpoint *config.Endpoint) *url.URL {
 		url, err := url.Parse(endpoint.Host + path)
 		if err != nil {
 			telemetryCollector.SendStartupError(telemetry.InvalidIntakeEndpoint, err)
 			log.Criticalf("Invalid host endpoint: %q", endpoint.Host)
 			os.Exit(1)
 		}
-		senders[i] = newSender(&senderConfig{
+		return url
+	}
+	senders := make([]*sender, len(cfg.Endpoints))
+	for i, endpoint := range cfg.Endpoints {
+		url := parseURL(endpoint)
+		scfg := &senderConfig{
 			client:     cfg.NewHTTPClient(),
 			maxConns:   int(maxConns),
 			maxQueued:  qsize,
@@ -53,11 +59,38 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			apiKey:     endpoint.APIKey,
 			recorder:   r,
 			userAgent:  fmt.Sprintf("Datadog Trace Agent/%s/%s", cfg.AgentVersion, cfg.GitCommit),
-		})
+		}
+		if i == 0 {
+			// failover only applies to the main endpoint
+			for _, e := range cfg.FailoverEndpoints {
+				scfg.failover = append(scfg.failover, target{url: parseURL(e), apiKey: e.APIKey})
+			}
+			scfg.failoverAfter = cfg.FailoverAfter
+		}
+		if cfg.SpillEnabled {
+			dir := filepath.Join(cfg.SpillPath, spillDirName(url))
+			spill, err := newSpill(dir, cfg.SpillMaxSize, cfg.SpillMaxAge)
+			if err != nil {
+				log.Errorf("Unable to spill payloads to %s, undelivered payloads will be dropped: %v", dir, err)
+			} else {
+				scfg.spill = spill
+			}
+		}
+		senders[i] = newSender(scfg)
 	}
 	return senders
 }
 
+// spillDirName returns the name of the directory holding the payloads spilled for the given URL.
+func spillDirName(u *url.URL) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, u.Host+u.Path)
+}
+
 // eventRecorder implementations are able to take note of events happening in
 // the sender.
 type eventRecorder interface {
@@ -79,6 +112,9 @@ const (
 	// eventTypeDropped specifies that a payload had to be dropped to make room
 	// in the queue.
 	eventTypeDropped
+	// eventTypeSpilled specifies that a payload which could not be delivered was
+	// written to disk, to be sent again later.
+	eventTypeSpilled
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -86,6 +122,7 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeSpilled:  "eventTypeSpilled",
 }
 
 // String implements fmt.Stringer.
@@ -135,6 +172,13 @@ type senderConfig struct {
 	recorder eventRecorder
 	// userAgent is the computed user agent we'll use when communicating with Datadog
 	userAgent string
+	// failover specifies the ordered list of targets to use in place of url once it
+	// has been failing for failoverAfter.
+	failover      []target
+	failoverAfter time.Duration
+	// spill, if set, is used to store on disk the payloads which could not be
+	// delivered instead of dropping them.
+	spill *spill
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -146,6 +190,8 @@ type sender struct {
 	inflight   *atomic.Int32 // inflight payloads
 	attempt    *atomic.Int32 // active retry attempt
 	maxRetries int32
+	failover   *failover
+	replaying  *atomic.Bool // reports whether spilled payloads are being queued again
 
 	mu     sync.RWMutex // guards closed
 	closed bool         // closed reports if the loop is stopped
@@ -159,6 +205,8 @@ func newSender(cfg *senderConfig) *sender {
 		inflight:   atomic.NewInt32(0),
 		attempt:    atomic.NewInt32(0),
 		maxRetries: int32(cfg.maxRetries),
+		failover:   newFailover(target{url: cfg.url, apiKey: cfg.apiKey}, cfg.failover, cfg.failoverAfter),
+		replaying:  atomic.NewBool(false),
 	}
 	for i := 0; i < cfg.maxConns; i++ {
 		go s.loop()
@@ -217,6 +265,9 @@ func (s *sender) Push(p *payload) {
 	s.mu.RLock()
 	if s.closed {
 		s.mu.RUnlock()
+		// the payload can no longer be sent
+		s.inflight.Inc()
+		s.spillOrDrop(p, &eventData{bytes: p.body.Len(), count: 1})
 		return
 	}
 	s.mu.RUnlock()
@@ -231,14 +282,16 @@ func (s *sender) Push(p *payload) {
 
 // sendPayload sends the payload p to the destination URL.
 func (s *sender) sendPayload(p *payload) {
-	req, err := p.httpRequest(s.cfg.url)
+	i, target := s.failover.current()
+	req, err := p.httpRequest(target.url)
 	if err != nil {
 		log.Errorf("http.Request: %s", err)
 		return
 	}
 	start := time.Now()
-	err = s.do(req)
+	err = s.do(req, target.apiKey)
 	stats := &eventData{
+		host:     target.url.Hostname(),
 		bytes:    p.body.Len(),
 		count:    1,
 		duration: time.Since(start),
@@ -250,11 +303,12 @@ func (s *sender) sendPayload(p *payload) {
 	switch err.(type) {
 	case *retriableError:
 		// request failed again, but can be retried
+		s.failover.failed(i)
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 		if s.closed {
-			s.releasePayload(p, eventTypeDropped, stats)
 			// sender is stopped
+			s.spillOrDrop(p, stats)
 			return
 		}
 		s.attempt.Inc()
@@ -267,8 +321,7 @@ func (s *sender) sendPayload(p *payload) {
 		}
 		if p.retries.Load() >= s.maxRetries {
 			log.Warnf("Dropping Payload after %d retries.\n", p.retries.Load())
-			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			s.spillOrDrop(p, stats)
 			return
 		}
 		select {
@@ -278,7 +331,7 @@ func (s *sender) sendPayload(p *payload) {
 		case <-time.After(10 * time.Millisecond):
 			log.Warnf("Sender queue full. Failed payload dropped after only %d retries.\n", p.retries.Load())
 			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			s.spillOrDrop(p, stats)
 		}
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
@@ -290,7 +343,9 @@ func (s *sender) sendPayload(p *payload) {
 				break
 			}
 		}
+		s.failover.succeeded(i)
 		s.releasePayload(p, eventTypeSent, stats)
+		s.requeueSpilled()
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
@@ -318,13 +373,70 @@ func (s *sender) releasePayload(p *payload, t eventType, data *eventData) {
 	s.inflight.Dec()
 }
 
+// spillOrDrop writes the payload p, which could not be delivered, to disk if spilling is
+// enabled, and drops it otherwise. The payload is released in both cases.
+func (s *sender) spillOrDrop(p *payload, data *eventData) {
+	if s.cfg.spill == nil {
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	if err := s.cfg.spill.write(p); err != nil {
+		log.Warnf("Unable to spill undelivered payload to disk: %v", err)
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	s.releasePayload(p, eventTypeSpilled, data)
+}
+
+// requeueSpilled queues the spilled payloads again, from oldest to newest, until the
+// queue is full. It is a no-op if spilling is disabled or if it is already running.
+func (s *sender) requeueSpilled() {
+	if s.cfg.spill == nil || !s.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer s.replaying.Store(false)
+		for {
+			f, p := s.cfg.spill.oldest()
+			if p == nil {
+				// nothing left
+				return
+			}
+			if !s.tryPush(p) {
+				ppool.Put(p)
+				return
+			}
+			s.cfg.spill.remove(f)
+		}
+	}()
+}
+
+// tryPush pushes p onto the sender's queue without blocking. It reports whether the
+// payload was queued.
+func (s *sender) tryPush(p *payload) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.queue <- p:
+		s.inflight.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
 // recordEvent records the occurrence of the given event type t. It additionally
 // passes on the data and augments it with additional information.
 func (s *sender) recordEvent(t eventType, data *eventData) {
 	if s.cfg.recorder == nil {
 		return
 	}
-	data.host = s.cfg.url.Hostname()
+	if data.host == "" {
+		data.host = s.cfg.url.Hostname()
+	}
 	data.connectionFill = float64(s.inflight.Load())
 	data.queueFill = float64(len(s.queue)) / float64(cap(s.queue))
 	s.cfg.recorder.recordEvent(t, data)
@@ -341,8 +453,8 @@ const (
 	headerUserAgent = "User-Agent"
 )
 
-func (s *sender) do(req *http.Request) error {
-	req.Header.Set(headerAPIKey, s.cfg.apiKey)
+func (s *sender) do(req *http.Request, apiKey string) error {
+	req.Header.Set(headerAPIKey, apiKey)
 	req.Header.Set(headerUserAgent, s.cfg.userAgent)
 	resp, err := s.cfg.client.Do(req)
 	if err != nil {
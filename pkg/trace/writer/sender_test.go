@@ -228,6 +228,68 @@ func TestSender(t *testing.T) {
 			assert.True(time.Since(start)-failed[i].duration < time.Second)
 		}
 	})
+
+	t.Run("spill", func(t *testing.T) {
+		assert := assert.New(t)
+		server := newTestServer()
+		defer server.Close()
+		defer useBackoffDuration(0)()
+
+		var recorder mockRecorder
+		cfg := testSenderConfig(server.URL)
+		cfg.recorder = &recorder
+		cfg.maxRetries = 1
+		spill, err := newSpill(t.TempDir(), 1024*1024, time.Hour)
+		assert.NoError(err)
+		cfg.spill = spill
+		s := newSender(cfg)
+
+		s.Push(expectResponses(503, 200))
+		s.WaitForInflight()
+		assert.Len(recorder.data(eventTypeSpilled), 1)
+		assert.Len(recorder.data(eventTypeDropped), 0)
+		assert.Equal(0, server.Accepted())
+
+		// a successful send queues the spilled payloads again
+		s.Push(expectResponses(200))
+		assert.Eventually(func() bool {
+			return server.Accepted() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		s.Stop()
+		_, p := spill.oldest()
+		assert.Nil(p)
+
+		// payloads pushed once the sender is stopped are spilled too
+		s.Push(expectResponses(200))
+		assert.Len(recorder.data(eventTypeSpilled), 2)
+		assert.Len(recorder.data(eventTypeDropped), 0)
+		_, p = spill.oldest()
+		assert.NotNil(p)
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		assert := assert.New(t)
+		// the primary endpoint is unreachable
+		primary := newTestServer()
+		primary.Close()
+		secondary := newTestServer()
+		defer secondary.Close()
+		defer useBackoffDuration(time.Millisecond)()
+
+		cfg := testSenderConfig(primary.URL)
+		secondaryURL, err := url.Parse(secondary.URL + "/")
+		assert.NoError(err)
+		cfg.failover = []target{{url: secondaryURL, apiKey: "secondary-key"}}
+		cfg.failoverAfter = 20 * time.Millisecond
+		cfg.maxConns = 1
+		cfg.maxRetries = 1000
+		s := newSender(cfg)
+
+		s.Push(expectResponses(200))
+		s.Stop()
+		assert.Equal(1, secondary.Accepted())
+		assert.Equal("secondary-key", secondary.Payloads()[0].headers[http.CanonicalHeaderKey(headerAPIKey)])
+	})
 }
 
 func TestPayload(t *testing.T) {
@@ -284,6 +346,7 @@ func useBackoffDuration(d time.Duration) func() {
 type mockRecorder struct {
 	mu                             sync.RWMutex
 	retry, sent, dropped, rejected []*eventData
+	spilled                        []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -299,6 +362,8 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeSpilled:
+		return r.spilled
 	default:
 		panic("unknown event")
 	}
@@ -317,5 +382,7 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeSpilled:
+		r.spilled = append(r.spilled, data)
 	}
 }
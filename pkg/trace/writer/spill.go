@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// spillFileExt is the extension of the files holding spilled payloads.
+const spillFileExt = ".payload"
+
+// errSpillTooLarge is returned when a payload is larger than the maximum size of the spill.
+var errSpillTooLarge = errors.New("payload exceeds the maximum spill size")
+
+// spill stores on disk the payloads which could not be delivered, so that they can be
+// sent again once the intake is reachable. Each payload is written to its own file,
+// named after the time at which it was spilled so that files sort from oldest to newest.
+// The total size of the files is bounded by maxSize, oldest payloads being removed to
+// make room for new ones, and payloads older than maxAge are discarded. The directory
+// is only listed on start, the spilled files being tracked in memory afterwards.
+type spill struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex  // guards below
+	seq   uint64      // sequence number, disambiguates files spilled at the same time
+	size  int64       // total size of the spilled files
+	files []spillFile // spilled files, from oldest to newest
+}
+
+// newSpill returns a new spill storing payloads in dir. Payloads spilled by a
+// previous run and still present in dir are kept.
+func newSpill(dir string, maxSize int64, maxAge time.Duration) (*spill, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &spill{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+	files, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		s.size += f.size
+	}
+	s.files = files
+	return s, nil
+}
+
+// spillFile describes a file holding a spilled payload.
+type spillFile struct {
+	path    string
+	size    int64
+	spilled time.Time
+}
+
+// list returns the spilled files found in the directory, from oldest to newest.
+func (s *spill) list() ([]spillFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]spillFile, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, spillFileExt) {
+			continue
+		}
+		ts, _, _ := strings.Cut(name, "-")
+		ns, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spillFile{
+			path:    filepath.Join(s.dir, name),
+			size:    info.Size(),
+			spilled: time.Unix(0, ns),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// write persists the payload p, removing the oldest payloads if needed to stay within
+// the maximum size.
+func (s *spill) write(p *payload) error {
+	data := encodeSpilledPayload(p)
+	size := int64(len(data))
+	if size > s.maxSize {
+		return errSpillTooLarge
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.files) > 0 && s.size+size > s.maxSize {
+		log.Debugf("Spill full, discarding oldest payload %s", s.files[0].path)
+		s.removeLocked(s.files[0])
+	}
+	now := s.now()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", now.UnixNano(), s.seq%1e6, spillFileExt)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	path := filepath.Join(s.dir, name)
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.files = append(s.files, spillFile{path: path, size: size, spilled: now})
+	s.size += size
+	return nil
+}
+
+// oldest returns the oldest spilled payload which has not expired along with the file
+// holding it, discarding expired payloads along the way. It returns a nil payload when
+// the spill is empty. The file is not removed; see remove.
+func (s *spill) oldest() (spillFile, *payload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for len(s.files) > 0 {
+		f := s.files[0]
+		if now.Sub(f.spilled) > s.maxAge {
+			log.Debugf("Discarding expired spilled payload %s", f.path)
+			s.removeLocked(f)
+			continue
+		}
+		p, err := readSpilledPayload(f.path)
+		if err != nil {
+			log.Warnf("Discarding unreadable spilled payload %s: %v", f.path, err)
+			s.removeLocked(f)
+			continue
+		}
+		return f, p
+	}
+	return spillFile{}, nil
+}
+
+// remove removes the spilled file f.
+func (s *spill) remove(f spillFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(f)
+}
+
+// removeLocked removes the spilled file f. It is a no-op if f was already removed, for
+// instance to make room for newer payloads. Callers must guard!
+func (s *spill) removeLocked(f spillFile) {
+	// f is nearly always the oldest file
+	i := 0
+	for i < len(s.files) && s.files[i].path != f.path {
+		i++
+	}
+	if i == len(s.files) {
+		return
+	}
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Error removing spilled payload: %v", err)
+	}
+	s.files = append(s.files[:i], s.files[i+1:]...)
+	s.size -= f.size
+	if s.size < 0 {
+		s.size = 0
+	}
+}
+
+// encodeSpilledPayload encodes p as its headers, each one written as a length-prefixed
+// key and value after the number of headers, followed by its body.
+func encodeSpilledPayload(p *payload) []byte {
+	size := binary.MaxVarintLen64 + p.body.Len()
+	for k, v := range p.headers {
+		size += 2*binary.MaxVarintLen64 + len(k) + len(v)
+	}
+	data := make([]byte, 0, size)
+	data = binary.AppendUvarint(data, uint64(len(p.headers)))
+	for k, v := range p.headers {
+		data = binary.AppendUvarint(data, uint64(len(k)))
+		data = append(data, k...)
+		data = binary.AppendUvarint(data, uint64(len(v)))
+		data = append(data, v...)
+	}
+	return append(data, p.body.Bytes()...)
+}
+
+// readSpilledPayload reads the payload encoded by encodeSpilledPayload from the given file.
+func readSpilledPayload(path string) (*payload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		if _, err := io.CopyN(&sb, r, int64(n)); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string)
+	for i := uint64(0); i < n; i++ {
+		k, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		headers[k] = v
+	}
+	p := newPayload(headers)
+	if _, err := p.body.ReadFrom(r); err != nil {
+		ppool.Put(p)
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpill(t *testing.T) {
+	newTestPayload := func(body string) *payload {
+		p := newPayload(map[string]string{"Content-Type": "application/msgpack", "X-Datadog-Reported-Languages": "go"})
+		p.body.WriteString(body)
+		return p
+	}
+	size := int64(len(encodeSpilledPayload(newTestPayload("0123456789"))))
+
+	t.Run("roundtrip", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpill(t.TempDir(), 10*size, time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, s.write(newTestPayload("0123456789")))
+		require.NoError(t, s.write(newTestPayload("abcdefghij")))
+		assert.Equal(2*size, s.size)
+
+		for _, want := range []string{"0123456789", "abcdefghij"} {
+			f, p := s.oldest()
+			require.NotNil(t, p)
+			assert.Equal(want, p.body.String())
+			assert.Equal(map[string]string{"Content-Type": "application/msgpack", "X-Datadog-Reported-Languages": "go"}, p.headers)
+			s.remove(f)
+		}
+		_, p := s.oldest()
+		assert.Nil(p)
+		assert.Zero(s.size)
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpill(t.TempDir(), 2*size, time.Hour)
+		require.NoError(t, err)
+
+		for _, body := range []string{"0000000000", "1111111111", "2222222222"} {
+			require.NoError(t, s.write(newTestPayload(body)))
+		}
+		assert.Equal(2*size, s.size)
+		_, p := s.oldest()
+		assert.Equal("1111111111", p.body.String(), "oldest payload should have been removed")
+
+		assert.ErrorIs(s.write(newTestPayload(strings.Repeat("x", int(2*size)))), errSpillTooLarge)
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpill(t.TempDir(), 10*size, time.Hour)
+		require.NoError(t, err)
+		now := time.Now()
+		s.now = func() time.Time { return now }
+
+		require.NoError(t, s.write(newTestPayload("0000000000")))
+		now = now.Add(30 * time.Minute)
+		require.NoError(t, s.write(newTestPayload("1111111111")))
+		now = now.Add(31 * time.Minute)
+
+		_, p := s.oldest()
+		assert.Equal("1111111111", p.body.String())
+		assert.Equal(size, s.size)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		s, err := newSpill(dir, 10*size, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, s.write(newTestPayload("0123456789")))
+		// files which were not written by the spill are ignored
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("hello"), 0600))
+
+		s, err = newSpill(dir, 10*size, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(size, s.size)
+		_, p := s.oldest()
+		assert.Equal("0123456789", p.body.String())
+	})
+
+	t.Run("index", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		s, err := newSpill(dir, 2*size, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, s.write(newTestPayload("0000000000")))
+		require.NoError(t, s.write(newTestPayload("1111111111")))
+		// the directory is not listed again once the spill is started
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001-000001"+spillFileExt), []byte("foreign"), 0600))
+
+		// the payload being replayed is discarded to make room for a newer one
+		f, p := s.oldest()
+		assert.Equal("0000000000", p.body.String())
+		require.NoError(t, s.write(newTestPayload("2222222222")))
+		s.remove(f)
+		assert.Equal(2*size, s.size, "a discarded payload should only be accounted for once")
+
+		for _, want := range []string{"1111111111", "2222222222"} {
+			f, p := s.oldest()
+			require.NotNil(t, p)
+			assert.Equal(want, p.body.String())
+			s.remove(f)
+		}
+		_, p = s.oldest()
+		assert.Nil(p)
+		assert.Zero(s.size)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		name := "00000000000000000001-000001" + spillFileExt
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte{0x05, 0xff}, 0600))
+		s, err := newSpill(dir, 10*size, time.Hour)
+		require.NoError(t, err)
+		s.now = func() time.Time { return time.Unix(0, 2) }
+
+		_, p := s.oldest()
+		assert.Nil(p)
+		assert.NoFileExists(filepath.Join(dir, name))
+	})
+}
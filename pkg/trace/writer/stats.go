@@ -361,5 +361,10 @@ func (w *StatsWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Stats writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpilled:
+		w.easylog.Warn("Stats payload could not be delivered and was spilled to disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.stats_writer.spilled", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.stats_writer.spilled_bytes", int64(data.bytes), nil, 1)
 	}
 }
@@ -335,5 +335,10 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Trace Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpilled:
+		w.easylog.Warn("Trace payload could not be delivered and was spilled to disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.spilled", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.trace_writer.spilled_bytes", int64(data.bytes), nil, 1)
 	}
 }
@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package cachedfetch
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// purgeInterval is the minimum interval between two purges of the expired entries
+const purgeInterval = time.Minute
+
+var errFetchPanicked = errors.New("the fetch panicked")
+
+// Shared is the cache shared by the checks of the collector, Go and Python ones, to avoid
+// fetching the same data from the same endpoint, or running the same command, in several
+// check instances during a run interval. Its stats are published in the `sharedFetchCache`
+// expvar.
+var Shared = NewSharedCache()
+
+func init() {
+	expvar.Publish("sharedFetchCache", expvar.Func(func() interface{} {
+		return Shared.Stats()
+	}))
+}
+
+// SharedCache caches fetched values by key, typically the URL or the command they were
+// fetched with, for a TTL set by the caller. Concurrent fetches of the same key are
+// coalesced: a single fetch is made, whose result is returned to all the callers.
+type SharedCache struct {
+	mu        sync.Mutex
+	entries   map[string]*sharedEntry
+	lastPurge time.Time
+
+	hits      *atomic.Uint64
+	misses    *atomic.Uint64
+	coalesced *atomic.Uint64
+	errors    *atomic.Uint64
+}
+
+type sharedEntry struct {
+	value     interface{}
+	expiresAt time.Time
+	// done is closed when the fetch in flight completes, it's nil when no fetch is in flight
+	done chan struct{}
+	err  error
+	// claimed is set when the fetch in flight is made by a caller of Claim, and completed by Set
+	claimed bool
+}
+
+// SharedCacheStats holds the stats of a SharedCache
+type SharedCacheStats struct {
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Coalesced uint64
+	Errors    uint64
+	// HitRate is the ratio of the lookups served from the cache, including the coalesced ones
+	HitRate float64
+}
+
+// NewSharedCache returns an empty SharedCache
+func NewSharedCache() *SharedCache {
+	return &SharedCache{
+		entries:   make(map[string]*sharedEntry),
+		lastPurge: time.Now(),
+		hits:      atomic.NewUint64(0),
+		misses:    atomic.NewUint64(0),
+		coalesced: atomic.NewUint64(0),
+		errors:    atomic.NewUint64(0),
+	}
+}
+
+// Fetch returns the value cached for the key if it hasn't expired, or calls fetch and caches
+// its result for ttl. Callers fetching a key while a fetch of it is in flight wait for its
+// result instead of fetching it again, until their context is done. Errors aren't cached.
+func (c *SharedCache) Fetch(ctx context.Context, key string, ttl time.Duration, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	now := time.Now()
+	c.purgeLocked(now)
+
+	entry, ok := c.entries[key]
+	if ok && entry.done == nil && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Inc()
+		return entry.value, nil
+	}
+
+	if ok && entry.done != nil {
+		c.mu.Unlock()
+		c.coalesced.Inc()
+		select {
+		case <-entry.done:
+			return entry.value, entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// the entry in flight is replaced once its fetch completes, so that the waiters of the
+	// previous one can read its result without locking
+	inflight := &sharedEntry{done: make(chan struct{})}
+	c.entries[key] = inflight
+	c.mu.Unlock()
+	c.misses.Inc()
+
+	// the result is recorded even if fetch panics, not to block the waiters
+	value, err := interface{}(nil), errFetchPanicked
+	defer func() {
+		c.mu.Lock()
+		inflight.value, inflight.err = value, err
+		if err != nil {
+			c.errors.Inc()
+			delete(c.entries, key)
+		} else {
+			c.entries[key] = &sharedEntry{value: value, expiresAt: time.Now().Add(ttl)}
+		}
+		c.mu.Unlock()
+		close(inflight.done)
+	}()
+
+	value, err = fetch(ctx)
+	return value, err
+}
+
+// Claim returns the value cached for the key if it hasn't expired, waiting for the fetch of the
+// key in flight if any, like Fetch. Otherwise the caller claims the key and must fetch its value
+// and cache it with Set: the other callers of Claim and Fetch wait for it meanwhile. The claim is
+// released when ctx is done, returning ctx.Err() to the waiters. This is meant for the callers
+// which can't pass a fetch function, like the Python checks.
+func (c *SharedCache) Claim(ctx context.Context, key string) (value interface{}, claimed bool, err error) {
+	c.mu.Lock()
+	now := time.Now()
+	c.purgeLocked(now)
+
+	entry, ok := c.entries[key]
+	if ok && entry.done == nil && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Inc()
+		return entry.value, false, nil
+	}
+
+	if ok && entry.done != nil {
+		c.mu.Unlock()
+		c.coalesced.Inc()
+		select {
+		case <-entry.done:
+			return entry.value, false, entry.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+
+	inflight := &sharedEntry{done: make(chan struct{}), claimed: true}
+	c.entries[key] = inflight
+	c.mu.Unlock()
+	c.misses.Inc()
+
+	go func() {
+		select {
+		case <-inflight.done:
+		case <-ctx.Done():
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			// the claim may have been completed meanwhile
+			if c.entries[key] != inflight {
+				return
+			}
+			inflight.err = ctx.Err()
+			c.errors.Inc()
+			delete(c.entries, key)
+			close(inflight.done)
+		}
+	}()
+	return nil, true, nil
+}
+
+// Get returns the value cached for the key, and whether it was found and hasn't expired
+func (c *SharedCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if !ok || entry.done != nil || !time.Now().Before(entry.expiresAt) {
+		c.misses.Inc()
+		return nil, false
+	}
+	c.hits.Inc()
+	return entry.value, true
+}
+
+// Set caches the value for the key for ttl, and returns it to the callers waiting for the key
+// if it was claimed. It doesn't affect a fetch of the key in flight.
+func (c *SharedCache) Set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.purgeLocked(now)
+	entry, ok := c.entries[key]
+	if ok && entry.done != nil && !entry.claimed {
+		return
+	}
+	c.entries[key] = &sharedEntry{value: value, expiresAt: now.Add(ttl)}
+	if ok && entry.claimed {
+		entry.value = value
+		close(entry.done)
+	}
+}
+
+// Stats returns the stats of the cache
+func (c *SharedCache) Stats() SharedCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	stats := SharedCacheStats{
+		Entries:   entries,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Errors:    c.errors.Load(),
+	}
+	if lookups := stats.Hits + stats.Misses + stats.Coalesced; lookups > 0 {
+		stats.HitRate = float64(stats.Hits+stats.Coalesced) / float64(lookups)
+	}
+	return stats
+}
+
+// purgeLocked removes the expired entries, at most once per purgeInterval. It must be
+// called with the lock held.
+func (c *SharedCache) purgeLocked(now time.Time) {
+	if now.Sub(c.lastPurge) < purgeInterval {
+		return
+	}
+	c.lastPurge = now
+
+	for key, entry := range c.entries {
+		if entry.done == nil && !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
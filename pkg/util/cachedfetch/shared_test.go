@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package cachedfetch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestSharedCacheFetch(t *testing.T) {
+	c := NewSharedCache()
+	calls := 0
+	fetch := func(context.Context) (interface{}, error) {
+		calls++
+		return fmt.Sprintf("value-%d", calls), nil
+	}
+
+	v, err := c.Fetch(context.Background(), "http://localhost/metrics", time.Hour, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "value-1", v)
+
+	v, err = c.Fetch(context.Background(), "http://localhost/metrics", time.Hour, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "value-1", v)
+
+	// expired
+	v, err = c.Fetch(context.Background(), "other", 0, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "value-2", v)
+	v, err = c.Fetch(context.Background(), "other", 0, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "value-3", v)
+
+	assert.Equal(t, SharedCacheStats{Entries: 2, Hits: 1, Misses: 3, HitRate: 0.25}, c.Stats())
+}
+
+func TestSharedCacheFetchError(t *testing.T) {
+	c := NewSharedCache()
+
+	_, err := c.Fetch(context.Background(), "key", time.Hour, func(context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("unreachable")
+	})
+	assert.EqualError(t, err, "unreachable")
+
+	// errors aren't cached
+	v, err := c.Fetch(context.Background(), "key", time.Hour, func(context.Context) (interface{}, error) {
+		return "value", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, uint64(1), c.Stats().Errors)
+
+	// a panicking fetch doesn't leave the key in flight
+	assert.Panics(t, func() {
+		c.Fetch(context.Background(), "panic", time.Hour, func(context.Context) (interface{}, error) { //nolint:errcheck
+			panic("boom")
+		})
+	})
+	_, ok := c.Get("panic")
+	assert.False(t, ok)
+}
+
+func TestSharedCacheFetchCoalesced(t *testing.T) {
+	c := NewSharedCache()
+	calls := atomic.NewInt32(0)
+	release := make(chan struct{})
+	fetch := func(context.Context) (interface{}, error) {
+		calls.Inc()
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Fetch(context.Background(), "key", time.Hour, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+		}()
+	}
+
+	// wait for the callers to be waiting for the fetch in flight
+	require.Eventually(t, func() bool { return c.Stats().Coalesced == 9 }, 5*time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 0.9, c.Stats().HitRate)
+
+	// waiters give up when their context is done
+	c.Set("other", "stale", 0)
+	block := make(chan struct{})
+	defer close(block)
+	go c.Fetch(context.Background(), "slow", time.Hour, func(context.Context) (interface{}, error) { //nolint:errcheck
+		<-block
+		return nil, nil
+	})
+	require.Eventually(t, func() bool { return c.Stats().Misses == 2 }, 5*time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Fetch(ctx, "slow", time.Hour, fetch)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSharedCacheClaim(t *testing.T) {
+	c := NewSharedCache()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, claimed, err := c.Claim(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// the other callers wait for the value of the claimant
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, claimed, err := c.Claim(ctx, "key")
+			assert.NoError(t, err)
+			assert.False(t, claimed)
+			assert.Equal(t, "value", v)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := c.Fetch(ctx, "key", time.Hour, func(context.Context) (interface{}, error) {
+			return "fetched", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v)
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Coalesced == 4 }, 5*time.Second, time.Millisecond)
+
+	c.Set("key", "value", time.Hour)
+	wg.Wait()
+
+	v, claimed, err := c.Claim(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "value", v)
+}
+
+func TestSharedCacheClaimReleased(t *testing.T) {
+	c := NewSharedCache()
+
+	claimCtx, cancelClaim := context.WithCancel(context.Background())
+	_, claimed, err := c.Claim(claimCtx, "key")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, claimed, err := c.Claim(context.Background(), "key")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, claimed)
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Coalesced == 1 }, 5*time.Second, time.Millisecond)
+
+	// the waiters are released when the claimant gives up, and the key can be claimed again
+	cancelClaim()
+	<-done
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, claimed, err = c.Claim(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestSharedCacheGetSet(t *testing.T) {
+	c := NewSharedCache()
+
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+
+	c.Set("key", "value", time.Hour)
+	v, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", v)
+
+	c.Set("key", "value", 0)
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+
+	// the expired entries are purged
+	c.lastPurge = time.Now().Add(-purgeInterval)
+	c.Set("other", "value", time.Hour)
+	assert.Equal(t, 1, c.Stats().Entries)
+}
@@ -27,7 +27,8 @@ nr_throttled 0
 throttled_usec 0`
 	sampleCgroupV2CpuWeight       = "16"
 	sampleCgroupV2CpuMax          = "40000 100000"
-	sampleCgroupV2CpuPressure     = "some avg10=42.64 avg60=43.72 avg300=25.76 total=114289003"
+	sampleCgroupV2CpuPressure     = `some avg10=42.64 avg60=43.72 avg300=25.76 total=114289003
+full avg10=1.50 avg60=2.25 avg300=0.75 total=2047009`
 	sampleCgroupV2CpuSetEffective = "0-3"
 )
 
@@ -82,6 +83,12 @@ func TestCgroupV2CPUStats(t *testing.T) {
 			Avg300: pointer.Ptr(25.76),
 			Total:  pointer.Ptr(uint64(114289003)),
 		},
+		PSIFull: PSIStats{
+			Avg10:  pointer.Ptr(1.50),
+			Avg60:  pointer.Ptr(2.25),
+			Avg300: pointer.Ptr(0.75),
+			Total:  pointer.Ptr(uint64(2047009)),
+		},
 	}, *stats))
 
 	// Test reading files in CPU controllers, all files present except 1 (cpu.shares)
@@ -107,6 +114,12 @@ func TestCgroupV2CPUStats(t *testing.T) {
 			Avg300: pointer.Ptr(25.76),
 			Total:  pointer.Ptr(uint64(114289003)),
 		},
+		PSIFull: PSIStats{
+			Avg10:  pointer.Ptr(1.50),
+			Avg60:  pointer.Ptr(2.25),
+			Avg300: pointer.Ptr(0.75),
+			Total:  pointer.Ptr(uint64(2047009)),
+		},
 	}, *stats))
 }
 
@@ -132,6 +132,12 @@ func parse2ColumnStats(fr fileReader, path string, keyColumn, valueColumn int, v
 	return err
 }
 
+// ParsePSI parses a Pressure Stall Information file, the host files in /proc/pressure
+// having the same format as the cgroupv2 ones
+func ParsePSI(path string, somePsi, fullPsi *PSIStats) error {
+	return parsePSI(defaultFileReader, path, somePsi, fullPsi)
+}
+
 // format is "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
 func parsePSI(fr fileReader, path string, somePsi, fullPsi *PSIStats) error {
 	return parseColumnStats(fr, path, func(fields []string) error {
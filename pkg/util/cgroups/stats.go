@@ -81,6 +81,7 @@ type CPUStats struct {
 	SchedulerQuota  *uint64
 
 	PSISome PSIStats
+	PSIFull PSIStats // cgroupv2 only, since kernel 5.13
 }
 
 // PIDStats store stats about running threads and processes
@@ -31,6 +31,9 @@ type CollectorMetadata = provider.CollectorMetadata
 // Provider interface allows to mock the metrics provider
 type Provider = provider.Provider
 
+// PressureStats stores the Pressure Stall Information averages.
+type PressureStats = provider.PressureStats
+
 // ContainerMemStats stores memory statistics.
 type ContainerMemStats = provider.ContainerMemStats
 
@@ -11,6 +11,14 @@ import "time"
 // All fields are float64 as that's is required by the sender API.
 // Common units: nanoseconds, bytes
 
+// PressureStats stores the Pressure Stall Information averages over 10, 60 and 300 seconds,
+// as percentages (0-100) of the time some (partial) or all (full) tasks were stalled.
+type PressureStats struct {
+	Avg10  *float64
+	Avg60  *float64
+	Avg300 *float64
+}
+
 // ContainerMemStats stores memory statistics.
 type ContainerMemStats struct {
 	// Common fields
@@ -27,6 +35,9 @@ type ContainerMemStats struct {
 	Cache            *float64
 	OOMEvents        *float64 // Number of events where memory allocation failed
 	PartialStallTime *float64 // Correspond to PSI Some total
+	FullStallTime    *float64 // Correspond to PSI Full total
+	PartialStall     *PressureStats
+	FullStall        *PressureStats
 	Peak             *float64
 	Pgfault          *float64
 	Pgmajfault       *float64
@@ -53,6 +64,9 @@ type ContainerCPUStats struct {
 	ThrottledPeriods *float64
 	ThrottledTime    *float64
 	PartialStallTime *float64 // Correspond to PSI Some total
+	FullStallTime    *float64 // Correspond to PSI Full total
+	PartialStall     *PressureStats
+	FullStall        *PressureStats
 }
 
 // DeviceIOStats stores Device IO stats.
@@ -74,6 +88,9 @@ type ContainerIOStats struct {
 
 	// Linux only
 	PartialStallTime *float64 // Correspond to PSI Some total
+	FullStallTime    *float64 // Correspond to PSI Full total
+	PartialStall     *PressureStats
+	FullStall        *PressureStats
 
 	Devices map[string]DeviceIOStats
 }
@@ -37,7 +37,8 @@ func buildIOStats(procPath string, cgs *cgroups.IOStats) *provider.ContainerIOSt
 	convertField(cgs.WriteBytes, &cs.WriteBytes)
 	convertField(cgs.ReadOperations, &cs.ReadOperations)
 	convertField(cgs.WriteOperations, &cs.WriteOperations)
-	convertFieldAndUnit(cgs.PSISome.Total, &cs.PartialStallTime, float64(time.Microsecond))
+	convertPSIStats(cgs.PSISome, &cs.PartialStallTime, &cs.PartialStall)
+	convertPSIStats(cgs.PSIFull, &cs.FullStallTime, &cs.FullStall)
 
 	deviceMapping, err := GetDiskDeviceMapping(procPath)
 	if err != nil {
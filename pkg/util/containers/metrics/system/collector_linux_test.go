@@ -71,6 +71,12 @@ func TestBuildContainerMetrics(t *testing.T) {
 					PSISome: cgroups.PSIStats{
 						Total: pointer.Ptr(uint64(97)),
 					},
+					PSIFull: cgroups.PSIStats{
+						Avg10:  pointer.Ptr(1.5),
+						Avg60:  pointer.Ptr(0.5),
+						Avg300: pointer.Ptr(0.25),
+						Total:  pointer.Ptr(uint64(42)),
+					},
 				},
 				IOStats: &cgroups.IOStats{
 					ReadBytes:       pointer.Ptr(uint64(100)),
@@ -78,7 +84,10 @@ func TestBuildContainerMetrics(t *testing.T) {
 					ReadOperations:  pointer.Ptr(uint64(10)),
 					WriteOperations: pointer.Ptr(uint64(20)),
 					PSISome: cgroups.PSIStats{
-						Total: pointer.Ptr(uint64(98)),
+						Avg10:  pointer.Ptr(12.5),
+						Avg60:  pointer.Ptr(10.0),
+						Avg300: pointer.Ptr(8.0),
+						Total:  pointer.Ptr(uint64(98)),
 					},
 					// Device will be ignored as no matching device name
 					Devices: map[string]cgroups.DeviceIOStats{
@@ -120,7 +129,13 @@ func TestBuildContainerMetrics(t *testing.T) {
 					SwapLimit:        pointer.Ptr(500.0),
 					OOMEvents:        pointer.Ptr(10.0),
 					PartialStallTime: pointer.Ptr(97000.0),
-					Peak:             pointer.Ptr(1024.0),
+					FullStallTime:    pointer.Ptr(42000.0),
+					FullStall: &provider.PressureStats{
+						Avg10:  pointer.Ptr(1.5),
+						Avg60:  pointer.Ptr(0.5),
+						Avg300: pointer.Ptr(0.25),
+					},
+					Peak: pointer.Ptr(1024.0),
 				},
 				IO: &provider.ContainerIOStats{
 					ReadBytes:        pointer.Ptr(100.0),
@@ -128,6 +143,11 @@ func TestBuildContainerMetrics(t *testing.T) {
 					ReadOperations:   pointer.Ptr(10.0),
 					WriteOperations:  pointer.Ptr(20.0),
 					PartialStallTime: pointer.Ptr(98000.0),
+					PartialStall: &provider.PressureStats{
+						Avg10:  pointer.Ptr(12.5),
+						Avg60:  pointer.Ptr(10.0),
+						Avg300: pointer.Ptr(8.0),
+					},
 				},
 				PID: &provider.ContainerPIDStats{
 					ThreadCount: pointer.Ptr(10.0),
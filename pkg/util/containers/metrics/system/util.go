@@ -7,7 +7,13 @@
 
 package system
 
-import "github.com/DataDog/datadog-agent/pkg/util/pointer"
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cgroups"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics/provider"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+)
 
 func convertField(s *uint64, t **float64) {
 	if s != nil {
@@ -20,3 +26,14 @@ func convertFieldAndUnit(s *uint64, t **float64, multiplier float64) {
 		*t = pointer.Ptr(float64(*s) * multiplier)
 	}
 }
+
+func convertPSIStats(s cgroups.PSIStats, total **float64, averages **provider.PressureStats) {
+	convertFieldAndUnit(s.Total, total, float64(time.Microsecond))
+	if s.Avg10 != nil || s.Avg60 != nil || s.Avg300 != nil {
+		*averages = &provider.PressureStats{
+			Avg10:  s.Avg10,
+			Avg60:  s.Avg60,
+			Avg300: s.Avg300,
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package httpserver implements the HTTP servers the Agent starts next to its API,
+// such as the Prometheus remote_write receiver and scrape endpoint.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const stopTimeout = 5 * time.Second
+
+// Server serves HTTP requests on a TCP address.
+type Server struct {
+	// Addr is the address the server listens on.
+	Addr   string
+	name   string
+	server *http.Server
+}
+
+// Start listens on addr and returns a Server serving the requests with handler. name
+// designates the server in the logs.
+func Start(name, addr string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %v", addr, err)
+	}
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("%s stopped: %v", name, err)
+		}
+	}()
+	log.Infof("%s listening on %s", name, listener.Addr())
+
+	return &Server{
+		Addr:   listener.Addr().String(),
+		name:   name,
+		server: server,
+	}, nil
+}
+
+// Stop stops the Server, waiting for the requests in flight for a few seconds.
+func (s *Server) Stop() {
+	log.Infof("Stop listening on %s", s.Addr)
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Errorf("Stopping %s: %v", s.name, err)
+	}
+}
+
+// Global holds the global instance of a Server. The zero value holds no server.
+type Global struct {
+	mu     sync.Mutex
+	server *Server
+}
+
+// Start starts the global server with newServer, stopping the previous one if any.
+func (g *Global) Start(newServer func() (*Server, error)) error {
+	server, err := newServer()
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.server != nil {
+		g.server.Stop()
+	}
+	g.server = server
+	return nil
+}
+
+// Stop stops the global server, if it is running.
+func (g *Global) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.server != nil {
+		g.server.Stop()
+		g.server = nil
+	}
+}
+
+// IsRunning returns whether the global server is currently running.
+func (g *Global) IsRunning() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.server != nil
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobal(t *testing.T) {
+	var g Global
+	assert.False(t, g.IsRunning())
+
+	var addr string
+	err := g.Start(func() (*Server, error) {
+		s, err := Start("test server", "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		if s != nil {
+			addr = s.Addr
+		}
+		return s, err
+	})
+	require.NoError(t, err)
+	assert.True(t, g.IsRunning())
+
+	resp, err := http.Get("http://" + addr)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	g.Stop()
+	assert.False(t, g.IsRunning())
+	_, err = http.Get("http://" + addr)
+	assert.Error(t, err)
+
+	// a server failing to start is not running
+	err = g.Start(func() (*Server, error) { return nil, errors.New("can't listen") })
+	assert.Error(t, err)
+	assert.False(t, g.IsRunning())
+}
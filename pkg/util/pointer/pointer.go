@@ -11,6 +11,14 @@ func Ptr[T any](v T) *T {
 	return &v
 }
 
+// ValueOrDefault returns the value p points to, or defaultValue if p is nil.
+func ValueOrDefault[T any](p *T, defaultValue T) T {
+	if p == nil {
+		return defaultValue
+	}
+	return *p
+}
+
 // UIntPtrToFloatPtr converts a uint64 value to float64 and returns a pointer.
 func UIntPtrToFloatPtr(u *uint64) *float64 {
 	if u == nil {
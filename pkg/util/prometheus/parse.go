@@ -9,6 +9,11 @@ Package prometheus provides utility functions to deal with prometheus endpoints
 package prometheus
 
 import (
+	"bytes"
+	"io"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
 )
@@ -51,3 +56,38 @@ func ParseMetricsWithFilter(data []byte, filter []string) ([]*MetricFamily, erro
 func ParseMetrics(data []byte) ([]*MetricFamily, error) {
 	return ParseMetricsWithFilter(data, nil)
 }
+
+// ParseMetricFamiliesWithFilter parses the metric families from the input data, which is either
+// in the delimited protobuf or in the text format. With the text format, the lines which
+// contain text that matches the passed in filter are ignored. The families are sorted by name.
+func ParseMetricFamiliesWithFilter(data []byte, format expfmt.Format, filter []string) ([]*dto.MetricFamily, error) {
+	var families []*dto.MetricFamily
+
+	if format == expfmt.FmtProtoDelim {
+		decoder := expfmt.NewDecoder(bytes.NewReader(data), format)
+		for {
+			family := &dto.MetricFamily{}
+			if err := decoder.Decode(family); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+			families = append(families, family)
+		}
+	} else {
+		var parser expfmt.TextParser
+		mf, err := parser.TextToMetricFamilies(NewReader(data, filter))
+		if err != nil {
+			return nil, err
+		}
+		for _, family := range mf {
+			families = append(families, family)
+		}
+	}
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+	return families, nil
+}
@@ -6,7 +6,14 @@
 package prometheus
 
 import (
+	"bytes"
 	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestParseMetrics(t *testing.T) {
@@ -37,3 +44,35 @@ func TestParseMetrics(t *testing.T) {
 	}
 
 }
+
+func TestParseMetricFamiliesWithFilter(t *testing.T) {
+	text := `# TYPE http_requests_total counter
+http_requests_total{code="200"} 10
+http_requests_total{code="500"} 2
+# TYPE go_goroutines gauge
+go_goroutines 42
+`
+
+	families, err := ParseMetricFamiliesWithFilter([]byte(text), expfmt.FmtText, []string{`code="500"`})
+	require.NoError(t, err)
+	require.Len(t, families, 2)
+	assert.Equal(t, "go_goroutines", families[0].GetName())
+	assert.Equal(t, "http_requests_total", families[1].GetName())
+	assert.Equal(t, dto.MetricType_COUNTER, families[1].GetType())
+	require.Len(t, families[1].Metric, 1)
+	assert.Equal(t, 10.0, families[1].Metric[0].GetCounter().GetValue())
+
+	// the same families, encoded in the delimited protobuf format
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtProtoDelim)
+	for _, family := range families {
+		require.NoError(t, encoder.Encode(family))
+	}
+
+	decoded, err := ParseMetricFamiliesWithFilter(buf.Bytes(), expfmt.FmtProtoDelim, nil)
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	for i := range families {
+		assert.True(t, proto.Equal(families[i], decoded[i]))
+	}
+}
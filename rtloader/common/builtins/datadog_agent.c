@@ -20,6 +20,9 @@ static cb_set_check_metadata_t cb_set_check_metadata = NULL;
 static cb_set_external_tags_t cb_set_external_tags = NULL;
 static cb_write_persistent_cache_t cb_write_persistent_cache = NULL;
 static cb_read_persistent_cache_t cb_read_persistent_cache = NULL;
+static cb_write_shared_cache_t cb_write_shared_cache = NULL;
+static cb_read_shared_cache_t cb_read_shared_cache = NULL;
+static cb_claim_shared_cache_t cb_claim_shared_cache = NULL;
 static cb_obfuscate_sql_t cb_obfuscate_sql = NULL;
 static cb_obfuscate_sql_exec_plan_t cb_obfuscate_sql_exec_plan = NULL;
 static cb_get_process_start_time_t cb_get_process_start_time = NULL;
@@ -36,6 +39,9 @@ static PyObject *set_check_metadata(PyObject *self, PyObject *args);
 static PyObject *set_external_tags(PyObject *self, PyObject *args);
 static PyObject *write_persistent_cache(PyObject *self, PyObject *args);
 static PyObject *read_persistent_cache(PyObject *self, PyObject *args);
+static PyObject *write_shared_cache(PyObject *self, PyObject *args);
+static PyObject *read_shared_cache(PyObject *self, PyObject *args);
+static PyObject *claim_shared_cache(PyObject *self, PyObject *args);
 static PyObject *obfuscate_sql(PyObject *self, PyObject *args, PyObject *kwargs);
 static PyObject *obfuscate_sql_exec_plan(PyObject *self, PyObject *args, PyObject *kwargs);
 static PyObject *get_process_start_time(PyObject *self, PyObject *args, PyObject *kwargs);
@@ -52,6 +58,9 @@ static PyMethodDef methods[] = {
     { "set_external_tags", set_external_tags, METH_VARARGS, "Send external host tags." },
     { "write_persistent_cache", write_persistent_cache, METH_VARARGS, "Store a value for a given key." },
     { "read_persistent_cache", read_persistent_cache, METH_VARARGS, "Retrieve the value associated with a key." },
+    { "write_shared_cache", write_shared_cache, METH_VARARGS, "Store a value shared by all the checks for a given key and TTL." },
+    { "read_shared_cache", read_shared_cache, METH_VARARGS, "Retrieve the value shared by all the checks for a key, if it hasn't expired." },
+    { "claim_shared_cache", claim_shared_cache, METH_VARARGS, "Retrieve the value shared by all the checks for a key, or claim the key to fetch its value." },
     { "obfuscate_sql", (PyCFunction)obfuscate_sql, METH_VARARGS|METH_KEYWORDS, "Obfuscate & normalize a SQL string." },
     { "obfuscate_sql_exec_plan", (PyCFunction)obfuscate_sql_exec_plan, METH_VARARGS|METH_KEYWORDS, "Obfuscate & normalize a SQL Execution Plan." },
     { "get_process_start_time", (PyCFunction)get_process_start_time, METH_NOARGS, "Get agent process startup time, in seconds since the epoch." },
@@ -115,6 +124,21 @@ void _set_read_persistent_cache_cb(cb_read_persistent_cache_t cb)
     cb_read_persistent_cache = cb;
 }
 
+void _set_write_shared_cache_cb(cb_write_shared_cache_t cb)
+{
+    cb_write_shared_cache = cb;
+}
+
+void _set_read_shared_cache_cb(cb_read_shared_cache_t cb)
+{
+    cb_read_shared_cache = cb;
+}
+
+void _set_claim_shared_cache_cb(cb_claim_shared_cache_t cb)
+{
+    cb_claim_shared_cache = cb;
+}
+
 void _set_set_external_tags_cb(cb_set_external_tags_t cb)
 {
     cb_set_external_tags = cb;
@@ -510,6 +534,122 @@ static PyObject *read_persistent_cache(PyObject *self, PyObject *args)
     return retval;
 }
 
+/*! \fn PyObject *write_shared_cache(PyObject *self, PyObject *args)
+    \brief This function implements the `datadog_agent.write_shared_cache` method, storing
+    the value for the key in the cache shared by all the checks, for a TTL in seconds.
+    \param self A PyObject* pointer to the `datadog_agent` module.
+    \param args A PyObject* pointer to a 3-ary tuple containing the key, the value to store
+    and the TTL.
+    \return A PyObject* pointer to `None`.
+
+    This function is callable as the `datadog_agent.write_shared_cache` Python method and
+    uses the `cb_write_shared_cache()` callback to store the value in the agent with CGO.
+    If the callback has not been set `None` will be returned.
+*/
+static PyObject *write_shared_cache(PyObject *self, PyObject *args)
+{
+    // callback must be set
+    if (cb_write_shared_cache == NULL) {
+        Py_RETURN_NONE;
+    }
+
+    char *key, *value;
+    int ttl;
+
+    // datadog_agent.write_shared_cache(key, value, ttl)
+    if (!PyArg_ParseTuple(args, "ssi", &key, &value, &ttl)) {
+        return NULL;
+    }
+
+    Py_BEGIN_ALLOW_THREADS
+    cb_write_shared_cache(key, value, ttl);
+    Py_END_ALLOW_THREADS
+
+    Py_RETURN_NONE;
+}
+
+/*! \fn PyObject *read_shared_cache(PyObject *self, PyObject *args)
+    \brief This function implements the `datadog_agent.read_shared_cache` method, retrieving
+    the value stored for the key in the cache shared by all the checks.
+    \param self A PyObject* pointer to the `datadog_agent` module.
+    \param args A PyObject* pointer to a tuple containing the key to retrieve.
+    \return A PyObject* pointer to the value, or to `None` if it isn't found or has expired.
+
+    This function is callable as the `datadog_agent.read_shared_cache` Python method and
+    uses the `cb_read_shared_cache()` callback to retrieve the value from the agent
+    with CGO. If the callback has not been set `None` will be returned.
+*/
+static PyObject *read_shared_cache(PyObject *self, PyObject *args)
+{
+    // callback must be set
+    if (cb_read_shared_cache == NULL) {
+        Py_RETURN_NONE;
+    }
+
+    char *key;
+
+    // datadog_agent.read_shared_cache(key)
+    if (!PyArg_ParseTuple(args, "s", &key)) {
+        return NULL;
+    }
+
+    char *v = NULL;
+    Py_BEGIN_ALLOW_THREADS
+    v = cb_read_shared_cache(key);
+    Py_END_ALLOW_THREADS
+
+    if (v == NULL) {
+        Py_RETURN_NONE;
+    }
+
+    PyObject *retval = PyStringFromCString(v);
+    cgo_free(v);
+    return retval;
+}
+
+/*! \fn PyObject *claim_shared_cache(PyObject *self, PyObject *args)
+    \brief This function implements the `datadog_agent.claim_shared_cache` method, retrieving
+    the value stored for the key in the cache shared by all the checks, or claiming the key.
+    \param self A PyObject* pointer to the `datadog_agent` module.
+    \param args A PyObject* pointer to a 2-ary tuple containing the key and the timeout of the
+    claim, in seconds.
+    \return A PyObject* pointer to the value, or to `None` if the caller must fetch the value
+    and store it with `datadog_agent.write_shared_cache`.
+
+    This function is callable as the `datadog_agent.claim_shared_cache` Python method and
+    uses the `cb_claim_shared_cache()` callback to retrieve the value from the agent with CGO.
+    When another caller claimed the key, it waits for its value, up to the timeout, with the
+    GIL released. If the callback has not been set `None` will be returned.
+*/
+static PyObject *claim_shared_cache(PyObject *self, PyObject *args)
+{
+    // callback must be set
+    if (cb_claim_shared_cache == NULL) {
+        Py_RETURN_NONE;
+    }
+
+    char *key;
+    int timeout;
+
+    // datadog_agent.claim_shared_cache(key, timeout)
+    if (!PyArg_ParseTuple(args, "si", &key, &timeout)) {
+        return NULL;
+    }
+
+    char *v = NULL;
+    Py_BEGIN_ALLOW_THREADS
+    v = cb_claim_shared_cache(key, timeout);
+    Py_END_ALLOW_THREADS
+
+    if (v == NULL) {
+        Py_RETURN_NONE;
+    }
+
+    PyObject *retval = PyStringFromCString(v);
+    cgo_free(v);
+    return retval;
+}
+
 /*! \fn PyObject *set_external_tags(PyObject *self, PyObject *args)
     \brief This function implements the `datadog_agent.set_external_tags` method,
     allowing to set additional external tags for hostnames.
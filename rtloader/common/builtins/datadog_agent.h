@@ -119,6 +119,30 @@
 
     The callback is expected to be provided by the rtloader caller - in go-context: CGO.
 */
+/*! \fn void _set_write_shared_cache_cb(cb_write_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow storing data in the cache shared
+    by all the checks.
+    \param object A function pointer with cb_write_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
+/*! \fn void _set_read_shared_cache_cb(cb_read_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow retrieving data from the cache
+    shared by all the checks.
+    \param object A function pointer with cb_read_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
+/*! \fn void _set_claim_shared_cache_cb(cb_claim_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow retrieving data from the cache
+    shared by all the checks, or claiming a key to fetch its value.
+    \param object A function pointer with cb_claim_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
 
 #include <Python.h>
 #include <rtloader_types.h>
@@ -146,6 +170,9 @@ void _set_set_check_metadata_cb(cb_set_check_metadata_t);
 void _set_set_external_tags_cb(cb_set_external_tags_t);
 void _set_write_persistent_cache_cb(cb_write_persistent_cache_t);
 void _set_read_persistent_cache_cb(cb_read_persistent_cache_t);
+void _set_write_shared_cache_cb(cb_write_shared_cache_t);
+void _set_read_shared_cache_cb(cb_read_shared_cache_t);
+void _set_claim_shared_cache_cb(cb_claim_shared_cache_t);
 void _set_obfuscate_sql_cb(cb_obfuscate_sql_t);
 void _set_obfuscate_sql_exec_plan_cb(cb_obfuscate_sql_exec_plan_t);
 void _set_get_process_start_time_cb(cb_get_process_start_time_t);
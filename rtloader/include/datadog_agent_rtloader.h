@@ -598,6 +598,39 @@ DATADOG_AGENT_RTLOADER_API void set_write_persistent_cache_cb(rtloader_t *, cb_w
 */
 DATADOG_AGENT_RTLOADER_API void set_read_persistent_cache_cb(rtloader_t *, cb_read_persistent_cache_t);
 
+/*! \fn void set_write_shared_cache_cb(rtloader_t *, cb_write_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow storing a value in the cache
+    shared by all the checks.
+    \param rtloader_t A rtloader_t * pointer to the RtLoader instance.
+    \param object A function pointer with cb_write_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
+DATADOG_AGENT_RTLOADER_API void set_write_shared_cache_cb(rtloader_t *, cb_write_shared_cache_t);
+
+/*! \fn void set_read_shared_cache_cb(rtloader_t *, cb_read_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow retrieving a value from the cache
+    shared by all the checks.
+    \param rtloader_t A rtloader_t * pointer to the RtLoader instance.
+    \param object A function pointer with cb_read_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
+DATADOG_AGENT_RTLOADER_API void set_read_shared_cache_cb(rtloader_t *, cb_read_shared_cache_t);
+
+/*! \fn void set_claim_shared_cache_cb(rtloader_t *, cb_claim_shared_cache_t)
+    \brief Sets a callback to be used by rtloader to allow retrieving a value from the cache
+    shared by all the checks, or claiming its key to fetch it.
+    \param rtloader_t A rtloader_t * pointer to the RtLoader instance.
+    \param object A function pointer with cb_claim_shared_cache_t prototype to the callback
+    function.
+
+    The callback is expected to be provided by the rtloader caller - in go-context: CGO.
+*/
+DATADOG_AGENT_RTLOADER_API void set_claim_shared_cache_cb(rtloader_t *, cb_claim_shared_cache_t);
+
 /*! \fn void set_obfuscate_sql_cb(rtloader_t *, cb_obfuscate_sql_t)
     \brief Sets a callback to be used by rtloader to allow retrieving a value for a given
     check instance.
@@ -424,6 +424,33 @@ public:
     */
     virtual void setReadPersistentCacheCb(cb_read_persistent_cache_t) = 0;
 
+    //! setWriteSharedCacheCb member.
+    /*!
+      \param A cb_write_shared_cache_t function pointer to the CGO callback.
+
+      This allows us to set the relevant CGO callback that will allow storing values in the
+      cache shared by all the checks.
+    */
+    virtual void setWriteSharedCacheCb(cb_write_shared_cache_t) = 0;
+
+    //! setReadSharedCacheCb member.
+    /*!
+      \param A cb_read_shared_cache_t function pointer to the CGO callback.
+
+      This allows us to set the relevant CGO callback that will allow retrieving values from
+      the cache shared by all the checks.
+    */
+    virtual void setReadSharedCacheCb(cb_read_shared_cache_t) = 0;
+
+    //! setClaimSharedCacheCb member.
+    /*!
+      \param A cb_claim_shared_cache_t function pointer to the CGO callback.
+
+      This allows us to set the relevant CGO callback that will allow retrieving values from
+      the cache shared by all the checks, or claiming their key to fetch them.
+    */
+    virtual void setClaimSharedCacheCb(cb_claim_shared_cache_t) = 0;
+
     //! setObfuscateSqlCb member.
     /*!
       \param A cb_obfuscate_sql_t function pointer to the CGO callback.
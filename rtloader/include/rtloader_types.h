@@ -131,6 +131,12 @@ typedef void (*cb_set_external_tags_t)(char *, char *, char **);
 typedef void (*cb_write_persistent_cache_t)(char *, char *);
 // (value)
 typedef char *(*cb_read_persistent_cache_t)(char *);
+// (key, value, ttl)
+typedef void (*cb_write_shared_cache_t)(char *, char *, int);
+// (key)
+typedef char *(*cb_read_shared_cache_t)(char *);
+// (key, timeout)
+typedef char *(*cb_claim_shared_cache_t)(char *, int);
 // (sql_query, options, error_message)
 typedef char *(*cb_obfuscate_sql_t)(char *, char *, char **);
 // (exec_plan, normalize, error_message)
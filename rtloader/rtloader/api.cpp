@@ -557,6 +557,21 @@ void set_read_persistent_cache_cb(rtloader_t *rtloader, cb_read_persistent_cache
     AS_TYPE(RtLoader, rtloader)->setReadPersistentCacheCb(cb);
 }
 
+void set_write_shared_cache_cb(rtloader_t *rtloader, cb_write_shared_cache_t cb)
+{
+    AS_TYPE(RtLoader, rtloader)->setWriteSharedCacheCb(cb);
+}
+
+void set_read_shared_cache_cb(rtloader_t *rtloader, cb_read_shared_cache_t cb)
+{
+    AS_TYPE(RtLoader, rtloader)->setReadSharedCacheCb(cb);
+}
+
+void set_claim_shared_cache_cb(rtloader_t *rtloader, cb_claim_shared_cache_t cb)
+{
+    AS_TYPE(RtLoader, rtloader)->setClaimSharedCacheCb(cb);
+}
+
 void set_obfuscate_sql_cb(rtloader_t *rtloader, cb_obfuscate_sql_t cb)
 {
     AS_TYPE(RtLoader, rtloader)->setObfuscateSqlCb(cb);
@@ -36,6 +36,9 @@ extern void setCheckMetadata(char*, char*, char*);
 extern void setExternalHostTags(char*, char*, char**);
 extern void writePersistentCache(char*, char*);
 extern char* readPersistentCache(char*);
+extern void writeSharedCache(char*, char*, int);
+extern char* readSharedCache(char*);
+extern char* claimSharedCache(char*, int);
 extern char* obfuscateSQL(char*, char*, char**);
 extern char* obfuscateSQLExecPlan(char*, bool, char**);
 extern double getProcessStartTime();
@@ -54,6 +57,9 @@ static void initDatadogAgentTests(rtloader_t *rtloader) {
    set_set_external_tags_cb(rtloader, setExternalHostTags);
    set_write_persistent_cache_cb(rtloader, writePersistentCache);
    set_read_persistent_cache_cb(rtloader, readPersistentCache);
+   set_write_shared_cache_cb(rtloader, writeSharedCache);
+   set_read_shared_cache_cb(rtloader, readSharedCache);
+   set_claim_shared_cache_cb(rtloader, claimSharedCache);
    set_obfuscate_sql_cb(rtloader, obfuscateSQL);
    set_obfuscate_sql_exec_plan_cb(rtloader, obfuscateSQLExecPlan);
    set_get_process_start_time_cb(rtloader, getProcessStartTime);
@@ -245,6 +251,30 @@ func readPersistentCache(key *C.char) *C.char {
 	return (*C.char)(helpers.TrackedCString("somevalue"))
 }
 
+//export writeSharedCache
+func writeSharedCache(key, value *C.char, ttl C.int) {
+	f, _ := os.OpenFile(tmpfile.Name(), os.O_APPEND|os.O_RDWR|os.O_CREATE, 0600)
+	defer f.Close()
+
+	f.WriteString(fmt.Sprintf("%s%s%d", C.GoString(key), C.GoString(value), int(ttl)))
+}
+
+//export readSharedCache
+func readSharedCache(key *C.char) *C.char {
+	if C.GoString(key) != "12345" {
+		return nil
+	}
+	return (*C.char)(helpers.TrackedCString("somevalue"))
+}
+
+//export claimSharedCache
+func claimSharedCache(key *C.char, timeout C.int) *C.char {
+	if C.GoString(key) != "12345" || timeout != 30 {
+		return nil
+	}
+	return (*C.char)(helpers.TrackedCString("somevalue"))
+}
+
 // sqlConfig holds the config for the python SQL obfuscator.
 type sqlConfig struct {
 	// TableNames specifies whether the obfuscator should extract and return table names as SQL metadata when obfuscating.
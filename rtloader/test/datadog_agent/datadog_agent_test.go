@@ -444,6 +444,53 @@ func TestReadPersistentCache(t *testing.T) {
 	}
 }
 
+func TestWriteSharedCache(t *testing.T) {
+	code := `
+	datadog_agent.write_shared_cache("12345", "someothervalue", 60)
+	`
+	out, err := run(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "12345someothervalue60" {
+		t.Errorf("Unexpected printed value: '%s'", out)
+	}
+}
+
+func TestReadSharedCache(t *testing.T) {
+	code := fmt.Sprintf(`
+	with open(r'%s', 'w') as f:
+		data = datadog_agent.read_shared_cache("12345")
+		assert type(data) == type("")
+		assert datadog_agent.read_shared_cache("unknown") is None
+		f.write(data)
+	`, tmpfile.Name())
+	out, err := run(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "somevalue" {
+		t.Errorf("Unexpected printed value: '%s'", out)
+	}
+}
+
+func TestClaimSharedCache(t *testing.T) {
+	code := fmt.Sprintf(`
+	with open(r'%s', 'w') as f:
+		data = datadog_agent.claim_shared_cache("12345", 30)
+		assert type(data) == type("")
+		assert datadog_agent.claim_shared_cache("unknown", 30) is None
+		f.write(data)
+	`, tmpfile.Name())
+	out, err := run(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "somevalue" {
+		t.Errorf("Unexpected printed value: '%s'", out)
+	}
+}
+
 func TestObfuscateSql(t *testing.T) {
 	helpers.ResetMemoryStats()
 
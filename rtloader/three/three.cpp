@@ -970,6 +970,21 @@ void Three::setReadPersistentCacheCb(cb_read_persistent_cache_t cb)
     _set_read_persistent_cache_cb(cb);
 }
 
+void Three::setWriteSharedCacheCb(cb_write_shared_cache_t cb)
+{
+    _set_write_shared_cache_cb(cb);
+}
+
+void Three::setReadSharedCacheCb(cb_read_shared_cache_t cb)
+{
+    _set_read_shared_cache_cb(cb);
+}
+
+void Three::setClaimSharedCacheCb(cb_claim_shared_cache_t cb)
+{
+    _set_claim_shared_cache_cb(cb);
+}
+
 void Three::setObfuscateSqlCb(cb_obfuscate_sql_t cb)
 {
     _set_obfuscate_sql_cb(cb);
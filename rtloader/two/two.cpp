@@ -968,6 +968,21 @@ void Two::setReadPersistentCacheCb(cb_read_persistent_cache_t cb)
     _set_read_persistent_cache_cb(cb);
 }
 
+void Two::setWriteSharedCacheCb(cb_write_shared_cache_t cb)
+{
+    _set_write_shared_cache_cb(cb);
+}
+
+void Two::setReadSharedCacheCb(cb_read_shared_cache_t cb)
+{
+    _set_read_shared_cache_cb(cb);
+}
+
+void Two::setClaimSharedCacheCb(cb_claim_shared_cache_t cb)
+{
+    _set_claim_shared_cache_cb(cb);
+}
+
 void Two::setObfuscateSqlCb(cb_obfuscate_sql_t cb)
 {
     _set_obfuscate_sql_cb(cb);
@@ -106,6 +106,9 @@ public:
     void setSetExternalTagsCb(cb_set_external_tags_t);
     void setWritePersistentCacheCb(cb_write_persistent_cache_t);
     void setReadPersistentCacheCb(cb_read_persistent_cache_t);
+    void setWriteSharedCacheCb(cb_write_shared_cache_t);
+    void setReadSharedCacheCb(cb_read_shared_cache_t);
+    void setClaimSharedCacheCb(cb_claim_shared_cache_t);
     void setObfuscateSqlCb(cb_obfuscate_sql_t);
     void setObfuscateSqlExecPlanCb(cb_obfuscate_sql_exec_plan_t);
     void setGetProcessStartTimeCb(cb_get_process_start_time_t);
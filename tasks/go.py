@@ -221,6 +221,7 @@ def generate_protobuf(ctx):
         'process': (False, False),
         'workloadmeta': (False, False),
         'languagedetection': (False, False),
+        'checkplugin': (False, False),
     }
 
     # maybe put this in a separate function